@@ -1,11 +1,41 @@
 import React, { useState, useEffect, useMemo, useCallback, useRef } from 'react';
 import { Plus, ChevronRight, Server, Folder, Cloud, HardDrive, ArrowRight, X, Loader, CheckCircle, Cpu, MemoryStick, Trash2, Edit, AlertTriangle, RefreshCw, List, Package, Info, ChevronUp, ChevronDown, Search, Play, Square, RotateCcw, Power, CheckCircle2, HelpCircle, XCircle, Network, Check, Palette, ExternalLink, Copy, Download } from 'lucide-react';
-import { formatBytes, formatDate, formatDuration, slugify, buildVmicPlan, vmImportNameError } from './utils';
+import { apiBase, formatBytes, formatDate, formatDuration, slugify, buildVmicPlan, vmImportNameError } from './utils';
 
 const getNestedValue = (obj, path) => {
     return path.split('.').reduce((acc, part) => acc && acc[part], obj);
 };
 
+// --- Streamed logs ---
+const MAX_STREAMED_LOG_LINES = 5000;
+
+// useLogStream follows a /logs/stream endpoint while enabled. Once the first
+// lines arrive they replace the fetched logs, tagged with their pod/container;
+// lines are applied in batches so busy pods don't re-render on every line.
+const useLogStream = (url, enabled, setLogs) => {
+    useEffect(() => {
+        if (!enabled || typeof EventSource === 'undefined') return;
+        let pending = [];
+        let replace = true;
+        const source = new EventSource(url);
+        // A reconnect replays the tail, so start over rather than repeat lines.
+        source.onopen = () => { pending = []; replace = true; };
+        source.addEventListener('log', (e) => {
+            const { pod, container, line } = JSON.parse(e.data);
+            pending.push(`[${pod}/${container}] ${line}`);
+        });
+        const timer = setInterval(() => {
+            if (!pending.length) return;
+            const batch = pending;
+            const reset = replace;
+            pending = [];
+            replace = false;
+            setLogs(prev => (reset ? [] : prev.split('\n')).concat(batch).slice(-MAX_STREAMED_LOG_LINES).join('\n'));
+        }, 500);
+        return () => { clearInterval(timer); source.close(); };
+    }, [url, enabled, setLogs]);
+};
+
 // --- Copy to Clipboard Button ---
 const CopyButton = ({ text, label = "Copy", className = "" }) => {
     const [copied, setCopied] = useState(false);
@@ -542,6 +572,9 @@ const SourceWizard = ({ onCancel, onSave, source }) => {
     const [datacenter, setDatacenter] = useState('');
     const [username, setUsername] = useState('');
     const [password, setPassword] = useState('');
+    const [insecureSkipVerify, setInsecureSkipVerify] = useState(true);
+    const [cacert, setCacert] = useState('');
+    const [thumbprint, setThumbprint] = useState('');
     const isEditMode = !!source;
 
     useEffect(() => {
@@ -551,11 +584,18 @@ const SourceWizard = ({ onCancel, onSave, source }) => {
             setEndpoint(source.spec.endpoint);
             setDatacenter(source.spec.dc);
             setUsername(source.spec.username || '');
+            setInsecureSkipVerify(source.spec?.insecureSkipVerify !== 'false');
+            setThumbprint(source.spec?.thumbprint || '');
         }
     }, [source, isEditMode]);
 
     const handleSubmit = () => {
-        const payload = { name, namespace, endpoint, datacenter, username, password };
+        const payload = {
+            name, namespace, endpoint, datacenter, username, password,
+            insecureSkipVerify,
+            cacert: insecureSkipVerify ? '' : cacert,
+            thumbprint: thumbprint.trim(),
+        };
         onSave(payload, isEditMode);
     };
 
@@ -590,6 +630,37 @@ const SourceWizard = ({ onCancel, onSave, source }) => {
                         <label className="block text-sm font-medium text-main">Password</label>
                         <input type="password" value={password} onChange={e => setPassword(e.target.value)} className="mt-1 block w-full form-input" placeholder={isEditMode ? "Leave blank to keep existing password" : ""} />
                     </div>
+                    <div className="border-t pt-4 mt-2">
+                        <div className="flex items-center">
+                            <input type="checkbox" id="sourceInsecureSkipVerify"
+                                checked={insecureSkipVerify}
+                                onChange={e => setInsecureSkipVerify(e.target.checked)}
+                                className="h-4 w-4 text-blue-600 focus:ring-blue-500 border-main rounded" />
+                            <label htmlFor="sourceInsecureSkipVerify" className="ml-2 block text-sm font-medium text-main">
+                                Skip TLS certificate verification
+                            </label>
+                        </div>
+                        <p className="text-xs text-secondary mt-1">
+                            {insecureSkipVerify
+                                ? 'The explorer will accept any vCenter certificate. Not recommended for production.'
+                                : 'The vCenter certificate will be validated. Provide a CA certificate below if using a private CA.'}
+                        </p>
+                        {!insecureSkipVerify && (
+                            <div className="mt-3">
+                                <label className="block text-sm font-medium text-main">CA Certificate (PEM)</label>
+                                <textarea rows={5} value={cacert} onChange={e => setCacert(e.target.value)}
+                                    className="mt-1 block w-full form-input font-mono text-xs"
+                                    placeholder={isEditMode && source.spec?.hasCACert ? "Leave blank to keep the existing CA certificate" : "-----BEGIN CERTIFICATE-----\n...\n-----END CERTIFICATE-----"} />
+                            </div>
+                        )}
+                        <div className="mt-3">
+                            <label className="block text-sm font-medium text-main">Pinned Certificate Thumbprint</label>
+                            <input type="text" value={thumbprint} onChange={e => setThumbprint(e.target.value)}
+                                className="mt-1 block w-full form-input font-mono text-xs"
+                                placeholder="AB:CD:EF:..." />
+                            <p className="text-xs text-secondary mt-1">Optional SHA-1 or SHA-256 fingerprint. When set, only a certificate with this fingerprint is accepted.</p>
+                        </div>
+                    </div>
                 </div>
                 <div className="p-4 border-t flex justify-end space-x-2">
                     <button onClick={onCancel} className="btn-secondary">Cancel</button>
@@ -877,12 +948,9 @@ const PlanDetails = ({ plan, onClose }) => {
     const [isLoadingDebug, setIsLoadingDebug] = useState(false);
     const [onlyRelevantLogs, setOnlyRelevantLogs] = useState(false);
     const [followLogs, setFollowLogs] = useState(true);
-    const followLogsRef = useRef(true);
     const [fontSize, setFontSize] = useState(10); // px
     const logsEndRef = useRef(null);
 
-    useEffect(() => { followLogsRef.current = followLogs; }, [followLogs]);
-
     const fetchLogs = useCallback(async (showAll = !onlyRelevantLogs, isBackground = false) => {
         if (!isBackground) setIsLoadingDebug(true);
         try {
@@ -896,15 +964,11 @@ const PlanDetails = ({ plan, onClose }) => {
         }
     }, [onlyRelevantLogs, plan.metadata.namespace, plan.metadata.name]);
 
-    useEffect(() => {
-        let interval;
-        if (showDebug === 'logs') {
-            interval = setInterval(() => {
-                if (followLogsRef.current) fetchLogs(!onlyRelevantLogs, true);
-            }, 2000);
-        }
-        return () => clearInterval(interval);
-    }, [showDebug, onlyRelevantLogs, plan.metadata.namespace, plan.metadata.name, fetchLogs]);
+    useLogStream(
+        `/api/v1/plans/${plan.metadata.namespace}/${plan.metadata.name}/logs/stream?tailLines=500${onlyRelevantLogs ? '' : '&all=true'}`,
+        showDebug === 'logs' && followLogs,
+        setLogs
+    );
 
     useEffect(() => {
         if (followLogs && logsEndRef.current) {
@@ -1193,6 +1257,7 @@ const SourceExplorer = ({ source, onClose, inventoryApiBase }) => {
     const [error, setError] = useState('');
     const [selectedVm, setSelectedVm] = useState(null);
     const [isOperating, setIsOperating] = useState(false);
+    const [inventoryView, setInventoryView] = useState('vms');
     const isForklift = !!inventoryApiBase;
 
     const fetchInventory = useCallback(async (keepSelection = false) => {
@@ -1200,7 +1265,14 @@ const SourceExplorer = ({ source, onClose, inventoryApiBase }) => {
         setError('');
         try {
             const apiBase = inventoryApiBase || '/api/v1/vcenter/inventory';
-            const response = await fetch(`${apiBase}/${source.metadata.namespace}/${source.metadata.name}`);
+            // Explicit refreshes (and refreshes after a VM operation) bypass the server-side inventory cache.
+            // Forklift providers can reach every datacenter, so browse all of them.
+            const params = new URLSearchParams();
+            if (keepSelection) params.set('refresh', 'true');
+            if (isForklift) params.set('datacenter', '*');
+            if (inventoryView !== 'vms') params.set('view', inventoryView);
+            const query = params.toString() ? `?${params.toString()}` : '';
+            const response = await fetch(`${apiBase}/${source.metadata.namespace}/${source.metadata.name}${query}`);
             if (!response.ok) {
                 const errData = await response.json();
                 throw new Error(errData.error || "Failed to fetch inventory");
@@ -1214,17 +1286,18 @@ const SourceExplorer = ({ source, onClose, inventoryApiBase }) => {
                 setSelectedVm(current => {
                     if (!current) return null;
                     // Find and update selected VM in new data
-                    const findVm = (node, name) => {
-                        if (node.type === 'VirtualMachine' && node.name === name) return node;
+                    // Match on the moRef so renames and duplicate names keep the right VM selected
+                    const findVm = (node, id) => {
+                        if (node.type === 'VirtualMachine' && node.id === id) return node;
                         if (node.children) {
                             for (const child of node.children) {
-                                const found = findVm(child, name);
+                                const found = findVm(child, id);
                                 if (found) return found;
                             }
                         }
                         return null;
                     };
-                    const updated = findVm(data, current.name);
+                    const updated = findVm(data, current.id);
                     return updated || current;
                 });
             }
@@ -1233,7 +1306,7 @@ const SourceExplorer = ({ source, onClose, inventoryApiBase }) => {
         } finally {
             setIsLoading(false);
         }
-    }, [source, inventoryApiBase]); // Now stable regardless of selection
+    }, [source, inventoryApiBase, inventoryView]); // Now stable regardless of selection
 
     const handlePowerOp = async (op) => {
         if (!selectedVm) return;
@@ -1242,7 +1315,7 @@ const SourceExplorer = ({ source, onClose, inventoryApiBase }) => {
             const response = await fetch(`/api/v1/vcenter/vm/${source.metadata.namespace}/${source.metadata.name}/power`, {
                 method: 'POST',
                 headers: { 'Content-Type': 'application/json' },
-                body: JSON.stringify({ vmName: selectedVm.name, operation: op })
+                body: JSON.stringify({ vmId: selectedVm.id, vmName: selectedVm.name, operation: op })
             });
             if (!response.ok) {
                 const data = await response.json();
@@ -1263,7 +1336,7 @@ const SourceExplorer = ({ source, onClose, inventoryApiBase }) => {
             const response = await fetch(`/api/v1/vcenter/vm/${source.metadata.namespace}/${source.metadata.name}/rename`, {
                 method: 'POST',
                 headers: { 'Content-Type': 'application/json' },
-                body: JSON.stringify({ oldName, newName })
+                body: JSON.stringify({ vmId: selectedVm?.id, oldName, newName })
             });
             if (!response.ok) {
                 const data = await response.json();
@@ -1285,7 +1358,7 @@ const SourceExplorer = ({ source, onClose, inventoryApiBase }) => {
             const response = await fetch(`/api/v1/vcenter/vm/${source.metadata.namespace}/${source.metadata.name}/mac`, {
                 method: 'POST',
                 headers: { 'Content-Type': 'application/json' },
-                body: JSON.stringify({ vmName, deviceKey: networkKey, newMac })
+                body: JSON.stringify({ vmId: selectedVm?.id, vmName, deviceKey: networkKey, newMac })
             });
             if (!response.ok) {
                 const data = await response.json();
@@ -1310,6 +1383,9 @@ const SourceExplorer = ({ source, onClose, inventoryApiBase }) => {
                         <button onClick={() => fetchInventory(true)} className="text-blue-500 hover:text-blue-700 p-1 rounded-full hover:bg-app transition-colors" title="Refresh Inventory">
                             <RefreshCw size={18} className={isLoading ? 'animate-spin' : ''} />
                         </button>
+                        {inventory?.lastRefreshed && (
+                            <span className="text-xs text-secondary">Last refreshed {new Date(inventory.lastRefreshed).toLocaleString()}</span>
+                        )}
                     </div>
                     <button onClick={onClose} className="p-2 rounded-full hover:bg-gray-200">
                         <X size={20} />
@@ -1331,7 +1407,7 @@ const SourceExplorer = ({ source, onClose, inventoryApiBase }) => {
                     ) : (
                         <div className="grid grid-cols-1 md:grid-cols-2 gap-4 h-full overflow-hidden">
                             <div className="border border-main rounded-md p-2 flex flex-col overflow-hidden bg-card shadow-sm font-sans">
-                                {inventory && <FilterableInventoryTree node={inventory} onVmSelect={setSelectedVm} currentlySelectedVm={selectedVm} />}
+                                {inventory && <FilterableInventoryTree node={inventory} onVmSelect={setSelectedVm} currentlySelectedVm={selectedVm} view={inventoryView} onViewChange={setInventoryView} />}
                             </div>
                             <div className="overflow-y-auto">
                                 <VmDetailsPanel
@@ -1356,8 +1432,13 @@ const SourceExplorer = ({ source, onClose, inventoryApiBase }) => {
 
 const VmIcon = ({ type }) => {
     switch (type) {
+        case 'vcenter': return <Server className="w-5 h-5 text-blue-500" />;
         case 'datacenter': return <Cloud className="w-5 h-5 text-blue-500" />;
         case 'ClusterComputeResource': return <Server className="w-5 h-5 text-purple-500" />;
+        case 'ComputeResource':
+        case 'HostSystem': return <Server className="w-5 h-5 text-secondary" />;
+        case 'ResourcePool': return <Cpu className="w-5 h-5 text-green-600" />;
+        case 'VirtualApp': return <Package className="w-5 h-5 text-green-600" />;
         case 'Folder': return <Folder className="w-5 h-5 text-yellow-600" />;
         case 'VirtualMachine': return <HardDrive className="w-5 h-5 text-secondary" />;
         case 'disk': return <HardDrive className="w-4 h-4 text-blue-400" />;
@@ -1389,7 +1470,7 @@ const filterInventory = (node, query) => {
     return null;
 };
 
-const FilterableInventoryTree = ({ node, onVmSelect, currentlySelectedVm }) => {
+const FilterableInventoryTree = ({ node, onVmSelect, currentlySelectedVm, view, onViewChange }) => {
     const [searchQuery, setSearchQuery] = useState('');
 
     const filteredNode = useMemo(() => {
@@ -1398,6 +1479,19 @@ const FilterableInventoryTree = ({ node, onVmSelect, currentlySelectedVm }) => {
 
     return (
         <div className="flex flex-col h-full overflow-hidden">
+            {onViewChange && (
+                <div className="mb-2 shrink-0 flex text-xs border border-main rounded-md overflow-hidden">
+                    {[['vms', 'VMs and Templates'], ['hosts', 'Hosts and Clusters']].map(([value, label]) => (
+                        <button
+                            key={value}
+                            onClick={() => onViewChange(value)}
+                            className={`flex-1 py-1 ${view === value ? 'bg-blue-500 text-white' : 'text-secondary hover:bg-app'}`}
+                        >
+                            {label}
+                        </button>
+                    ))}
+                </div>
+            )}
             <div className="mb-2 shrink-0 flex items-center">
                 {searchQuery && (
                     <button
@@ -1457,7 +1551,7 @@ const InventoryTree = ({ node, onVmSelect, currentlySelectedVm, level = 0, force
     return (
         <div style={{ paddingLeft: level > 0 ? '20px' : '0px' }}>
             <div
-                className={`flex items-center p-2 rounded-md cursor-pointer ${currentlySelectedVm?.name === node.name ? 'bg-blue-100' : 'hover:bg-app'}`}
+                className={`flex items-center p-2 rounded-md cursor-pointer ${(node.id ? currentlySelectedVm?.id === node.id : currentlySelectedVm?.name === node.name) ? 'bg-blue-100' : 'hover:bg-app'}`}
                 onClick={handleNodeClick}
             >
                 {isParent && <ChevronRight size={16} className={`mr-1 transform transition-transform ${isOpen ? 'rotate-90' : ''}`} />}
@@ -1571,8 +1665,35 @@ const VmDetailsPanel = ({ vm, onPowerOp, onRename, isOperating, onMacUpdate }) =
                         </div>
                         <div className="flex items-center">
                             <Folder size={16} className="mr-2 text-secondary" />
-                            <span className="truncate" title={vm.folder || '/'}>{vm.folder || '/'}</span>
+                            <span className="truncate" title={vm.folder || '/'}>{vm.datacenter ? `${vm.datacenter}: ` : ''}{vm.folder || '/'}</span>
                         </div>
+                        {vm.host && (
+                            <div className="flex items-center">
+                                <Server size={16} className="mr-2 text-secondary" />
+                                <span className="truncate" title={vm.hostId}>{vm.host}</span>
+                            </div>
+                        )}
+                        {(vm.guestOS || vm.firmware || vm.hardwareVersion || vm.toolsRunningStatus) && (
+                            <div>
+                                <h4 className="font-medium text-main mt-4 mb-1 border-b pb-1">Guest &amp; Hardware</h4>
+                                <dl className="grid grid-cols-2 gap-x-2 gap-y-1 mt-2 text-sm">
+                                    {[
+                                        ['Guest OS', vm.guestOS || vm.guestId],
+                                        ['Firmware', vm.firmware && `${vm.firmware.toUpperCase()}${vm.secureBoot ? ' (Secure Boot)' : ''}`],
+                                        ['Hardware', vm.hardwareVersion],
+                                        ['VMware Tools', vm.toolsRunningStatus && `${vm.toolsRunningStatus.replace(/^guestTools/, '')}${vm.toolsVersion ? ` (${vm.toolsVersion})` : ''}`],
+                                        ['Hostname', vm.guestHostname],
+                                        ['IP Addresses', (vm.guestIPs || []).join(', ')],
+                                        ['Hot Add', [vm.cpuHotAddEnabled && 'CPU', vm.memoryHotAddEnabled && 'Memory'].filter(Boolean).join(', ')],
+                                    ].filter(([, value]) => value).map(([label, value]) => (
+                                        <React.Fragment key={label}>
+                                            <dt className="text-secondary">{label}</dt>
+                                            <dd className="text-main truncate" title={value}>{value}</dd>
+                                        </React.Fragment>
+                                    ))}
+                                </dl>
+                            </div>
+                        )}
                         <div>
                             <h4 className="font-medium text-main mt-4 mb-1 border-b pb-1">Networks</h4>
                             <div className="space-y-2 mt-2">
@@ -1647,9 +1768,17 @@ const VmDetailsPanel = ({ vm, onPowerOp, onRename, isOperating, onMacUpdate }) =
                                             <span className="text-[10px] font-mono text-blue-600">{disk.busType}</span>
                                         </div>
                                         <div className="flex justify-between items-center text-xs">
-                                            <span className="text-secondary">{formatBytes(disk.capacity)}</span>
+                                            <span className="text-secondary">{formatBytes(disk.capacity)}{disk.provisioning ? ` · ${disk.provisioning}` : ''}</span>
                                             <span className="text-secondary opacity-70">Unit: {disk.unitNum}</span>
                                         </div>
+                                        {disk.filePath && <div className="text-[10px] font-mono text-secondary truncate mt-1" title={disk.filePath}>{disk.filePath}</div>}
+                                        {(disk.rdm || disk.independent || (disk.sharingMode && disk.sharingMode !== 'sharingNone')) && (
+                                            <div className="flex flex-wrap gap-1 mt-1">
+                                                {disk.rdm && <span className="text-[10px] px-1 rounded bg-yellow-100 text-yellow-800">RDM{disk.rdmCompatibilityMode ? ` (${disk.rdmCompatibilityMode.replace(/Mode$/, '')})` : ''}</span>}
+                                                {disk.independent && <span className="text-[10px] px-1 rounded bg-yellow-100 text-yellow-800">{disk.diskMode}</span>}
+                                                {disk.sharingMode && disk.sharingMode !== 'sharingNone' && <span className="text-[10px] px-1 rounded bg-yellow-100 text-yellow-800">{disk.sharingMode}</span>}
+                                            </div>
+                                        )}
                                     </div>
                                 ))}
                                 {(!vm.disks || vm.disks.length === 0) && <p className="text-xs text-secondary opacity-70 italic">No detailed disk info available.</p>}
@@ -1720,7 +1849,111 @@ const VmDetailsPanel = ({ vm, onPowerOp, onRename, isOperating, onMacUpdate }) =
     );
 };
 
+// Dry-runs a plan payload against the validate endpoint. Errors block
+// creation; warnings need the user's confirmation. A failing validation call
+// does not block, the create call reports its own errors.
+const confirmPlanValidation = async (url, payload) => {
+    let result;
+    try {
+        const response = await fetch(url, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(payload),
+        });
+        if (!response.ok) return true;
+        result = await response.json();
+    } catch (err) {
+        console.error("Plan validation failed:", err);
+        return true;
+    }
+    const list = (issues) => (issues || []).map(i => `- ${i.message}`).join('\n');
+    if (!result.valid) {
+        alert(`The plan cannot be created:\n${list(result.errors)}`);
+        return false;
+    }
+    if (result.warnings && result.warnings.length > 0) {
+        return window.confirm(`Please review before creating the plan:\n${list(result.warnings)}\n\nCreate it anyway?`);
+    }
+    return true;
+};
+
 // --- UPDATED WIZARD COMPONENT ---
+// Summarises a vCenter network's switch and VLAN, e.g. "DVS0 · VLAN 200" or "vSwitch0 · trunk 100-199"
+const formatNetworkBacking = (net) => {
+    const parts = [];
+    if (net.switch) parts.push(net.switch);
+    if (net.vlanType === 'vlan') parts.push(`VLAN ${net.vlanId}`);
+    if (net.vlanType === 'trunk') parts.push(`trunk ${(net.vlanRanges || []).map(r => r.start === r.end ? r.start : `${r.start}-${r.end}`).join(',')}`);
+    if (net.vlanType === 'pvlan') parts.push(`PVLAN ${net.pvlanId}`);
+    if (net.hostVlanIds) parts.push(`hosts disagree: ${net.hostVlanIds.join(', ')}`);
+    if (net.type === 'OpaqueNetwork') parts.push(`${net.opaqueNetworkType || 'opaque'} ${net.opaqueNetworkId || ''}`.trim());
+    return parts.join(' · ');
+};
+
+// Explains the suggested destination for a source network, or warns that none fits
+const NetworkSuggestionHint = ({ suggestion, selected }) => {
+    if (!suggestion) return null;
+    if (suggestion.noViableDestination) {
+        return (
+            <div className="text-xs text-yellow-600 mt-1 flex items-center">
+                <AlertTriangle size={12} className="mr-1 flex-shrink-0" />
+                {(suggestion.warnings || []).join('; ')}
+            </div>
+        );
+    }
+    const candidate = suggestion.candidates.find(c => c.destination === selected);
+    if (!candidate) {
+        return <div className="text-xs text-secondary mt-1">Suggested: {suggestion.suggested} ({suggestion.confidence} confidence)</div>;
+    }
+    const colors = { high: 'text-green-600', medium: 'text-blue-600', low: 'text-yellow-600' };
+    return (
+        <div className={`text-xs mt-1 ${colors[candidate.confidence] || 'text-secondary'}`} title={(suggestion.warnings || []).join('; ')}>
+            {candidate.confidence} confidence: {candidate.reasons.join(', ')}
+        </div>
+    );
+};
+
+// Explains why the selected StorageClass suits (or does not suit) a set of disks
+const StorageSuggestionHint = ({ suggestion, selected, warnings }) => {
+    if (!suggestion) return null;
+    const candidate = suggestion.candidates.find(c => c.storageClass === selected);
+    const rejected = suggestion.rejected.find(c => c.storageClass === selected);
+    if (rejected) {
+        return (
+            <div className="text-xs text-red-600 mt-1 flex items-center">
+                <AlertTriangle size={12} className="mr-1 flex-shrink-0" />
+                {rejected.reasons.join('; ')}
+            </div>
+        );
+    }
+    if (!candidate) {
+        return suggestion.suggested
+            ? <div className="text-xs text-secondary mt-1">Suggested: {suggestion.suggested}</div>
+            : <div className="text-xs text-yellow-600 mt-1">No StorageClass can hold {formatBytes(suggestion.requiredBytes)} of disks</div>;
+    }
+    return (
+        <div className={`text-xs mt-1 ${candidate.storageClass === suggestion.suggested ? 'text-green-600' : 'text-secondary'}`} title={(warnings || []).join('; ')}>
+            {candidate.storageClass === suggestion.suggested ? 'Suggested: ' : ''}{candidate.reasons.join(', ')}
+        </div>
+    );
+};
+
+// Shows how much room a StorageClass has left once the selected VMs are imported
+const CapacityHint = ({ plan, selected }) => {
+    const headroom = plan?.storageClasses?.find(h => h.storageClass === selected);
+    if (!headroom) return null;
+    if (headroom.fits === undefined) {
+        return <div className="text-xs text-secondary mt-1" title={(plan.warnings || []).join('; ')}>Capacity: {headroom.notes.join('; ')}</div>;
+    }
+    return (
+        <div className={`text-xs mt-1 ${headroom.fits ? 'text-secondary' : 'text-red-600'}`}>
+            Capacity: {formatBytes(plan.provisionedBytes)} provisioned ({formatBytes(plan.committedBytes)} in use)
+            {headroom.replicas > 1 ? ` × ${headroom.replicas} replicas` : ''}, {formatBytes(headroom.capacityBytes)} free
+            {headroom.fits ? ` — ${formatBytes(Math.min(headroom.headroomBytes, headroom.committedHeadroomBytes))} left after import` : ` — ${headroom.notes.join('; ')}`}
+        </div>
+    );
+};
+
 const CreatePlanWizard = ({ onCancel, onCreatePlan, capabilities, forkliftAvailable, forkliftNamespace }) => {
     const [step, setStep] = useState(1);
     const [engine, setEngine] = useState('vmic'); // 'vmic' or 'forklift'
@@ -1729,6 +1962,8 @@ const CreatePlanWizard = ({ onCancel, onCreatePlan, capabilities, forkliftAvaila
     const [ovaSources, setOvaSources] = useState([]);
     const [selectedSource, setSelectedSource] = useState("");
     const [vcenterInventory, setVcenterInventory] = useState(null);
+    const [vcenterNetworks, setVcenterNetworks] = useState([]);
+    const [inventoryView, setInventoryView] = useState('vms');
     const [isConnecting, setIsConnecting] = useState(false);
     const [connectionError, setConnectionError] = useState('');
     const [selectedVm, setSelectedVm] = useState(null);
@@ -1879,10 +2114,11 @@ const CreatePlanWizard = ({ onCancel, onCreatePlan, capabilities, forkliftAvaila
         }
     }, [selectedVm, engine]);
 
-    const handleSourceChange = async (sourceIdentifier) => {
+    const handleSourceChange = async (sourceIdentifier, view = inventoryView) => {
         setSelectedSource(sourceIdentifier);
         setSelectedVm(null);
         setOvaInventory(null);
+        setVcenterNetworks([]);
         if (!sourceIdentifier) {
             setVcenterInventory(null);
             return;
@@ -1924,9 +2160,13 @@ const CreatePlanWizard = ({ onCancel, onCreatePlan, capabilities, forkliftAvaila
         setConnectionError('');
         try {
             // Use different API endpoint depending on the engine
+            const params = new URLSearchParams();
+            if (engine === 'forklift') params.set('datacenter', '*');
+            if (view !== 'vms') params.set('view', view);
+            const query = params.toString() ? `?${params.toString()}` : '';
             const apiUrl = engine === 'forklift'
-                ? `/api/v1/forklift/inventory/${namespace}/${name}`
-                : `/api/v1/vcenter/inventory/${namespace}/${name}`;
+                ? `/api/v1/forklift/inventory/${namespace}/${name}${query}`
+                : `/api/v1/vcenter/inventory/${namespace}/${name}${query}`;
             const response = await fetch(apiUrl);
             if (!response.ok) {
                 const errData = await response.json();
@@ -1934,6 +2174,15 @@ const CreatePlanWizard = ({ onCancel, onCreatePlan, capabilities, forkliftAvaila
             }
             const data = await response.json();
             setVcenterInventory(data);
+
+            // Network details (switch, VLAN) only decorate the mapping step, so a failure here is not fatal
+            const networksUrl = engine === 'forklift'
+                ? `/api/v1/forklift/networks/${namespace}/${name}?datacenter=*`
+                : `/api/v1/vcenter/networks/${namespace}/${name}`;
+            fetch(networksUrl)
+                .then(res => res.ok ? res.json() : [])
+                .then(list => setVcenterNetworks(list || []))
+                .catch(() => setVcenterNetworks([]));
         } catch (error) {
             setConnectionError(error.message);
         } finally {
@@ -1955,15 +2204,90 @@ const CreatePlanWizard = ({ onCancel, onCreatePlan, capabilities, forkliftAvaila
             }).map(n => ({
                 key: n.id || n.name,
                 displayName: n.name || n.Name || 'Unknown Network',
-                id: n.id || n.name
+                id: n.id || n.name,
+                details: vcenterNetworks.find(v => v.id === n.id)
             }));
         }
         // For VMIC, use network name as key (existing behavior)
-        return [...new Set(networks.map(n => n.name || n.Name || 'Unknown Network'))].map(name => ({
-            key: name,
-            displayName: name
-        }));
-    }, [selectedVm, engine]);
+        return [...new Set(networks.map(n => n.name || n.Name || 'Unknown Network'))].map(name => {
+            const id = (networks.find(n => n.name === name) || {}).id;
+            return {
+                key: name,
+                displayName: name,
+                id,
+                details: vcenterNetworks.find(v => v.id === id)
+            };
+        });
+    }, [selectedVm, engine, vcenterNetworks]);
+
+    // VLAN-aware destination suggestions for the selected VM's networks, keyed by source network ID.
+    // High and medium confidence suggestions pre-fill mappings the user has not set yet.
+    const [networkSuggestions, setNetworkSuggestions] = useState({});
+    useEffect(() => {
+        setNetworkSuggestions({});
+        const ids = sourceNetworks.map(net => net.id).filter(Boolean);
+        if (!selectedSource || ids.length === 0 || sourceType === 'ova' || selectedProviderType === 'ova') return;
+        const [namespace, name] = selectedSource.split('/');
+        const base = engine === 'forklift' ? '/api/v1/forklift/networks' : '/api/v1/vcenter/networks';
+        fetch(`${base}/${namespace}/${name}/suggestions?network=${encodeURIComponent(ids.join(','))}`)
+            .then(res => res.ok ? res.json() : [])
+            .then(list => {
+                const byId = {};
+                (list || []).forEach(s => { byId[s.source.id] = s; });
+                setNetworkSuggestions(byId);
+                setNetworkMappings(prev => {
+                    const next = { ...prev };
+                    sourceNetworks.forEach(net => {
+                        const s = byId[net.id];
+                        if (!next[net.key] && s?.suggested && s.confidence !== 'low') next[net.key] = s.suggested;
+                    });
+                    return next;
+                });
+            })
+            .catch(err => console.error("Failed to fetch network mapping suggestions:", err));
+    }, [sourceNetworks, selectedSource, engine, sourceType, selectedProviderType]);
+
+    // StorageClass suggestions for the selected VM: one per datastore (Forklift) and one overall (VMIC).
+    // Suggestions only fill in choices the user has not made yet.
+    const [storageSuggestions, setStorageSuggestions] = useState(null);
+    useEffect(() => {
+        setStorageSuggestions(null);
+        if (!selectedSource || !selectedVm?.id || sourceType === 'ova' || selectedProviderType === 'ova') return;
+        const [namespace, name] = selectedSource.split('/');
+        const base = engine === 'forklift' ? '/api/v1/forklift/storage' : '/api/v1/vcenter/storage';
+        fetch(`${base}/${namespace}/${name}/suggestions?vm=${encodeURIComponent(selectedVm.id)}`)
+            .then(res => res.ok ? res.json() : null)
+            .then(data => {
+                if (!data) return;
+                setStorageSuggestions(data);
+                if (data.overall?.suggested) setStorageClass(prev => prev || data.overall.suggested);
+                if (engine !== 'forklift') return;
+                const fill = (setter, field) => setter(prev => {
+                    const next = { ...prev };
+                    data.datastores.forEach(ds => {
+                        if (ds.datastoreId && !next[ds.datastoreId] && ds[field]) next[ds.datastoreId] = ds[field];
+                    });
+                    return next;
+                });
+                fill(setForkliftStorageMappings, 'suggested');
+                fill(setForkliftVolumeModes, 'volumeMode');
+                fill(setForkliftAccessModes, 'accessMode');
+            })
+            .catch(err => console.error("Failed to fetch storage mapping suggestions:", err));
+    }, [selectedVm, selectedSource, engine, sourceType, selectedProviderType]);
+
+    // Per-StorageClass headroom once the selected VM has been imported.
+    const [capacityPlan, setCapacityPlan] = useState(null);
+    useEffect(() => {
+        setCapacityPlan(null);
+        if (!selectedSource || !selectedVm?.id || sourceType === 'ova' || selectedProviderType === 'ova') return;
+        const [namespace, name] = selectedSource.split('/');
+        const base = engine === 'forklift' ? '/api/v1/forklift/storage' : '/api/v1/vcenter/storage';
+        fetch(`${base}/${namespace}/${name}/capacity?vm=${encodeURIComponent(selectedVm.id)}`)
+            .then(res => res.ok ? res.json() : null)
+            .then(data => { if (data) setCapacityPlan(data); })
+            .catch(err => console.error("Failed to fetch capacity plan:", err));
+    }, [selectedVm, selectedSource, engine, sourceType, selectedProviderType]);
 
     // Extract unique datastores from the selected VM for Forklift storage mapping
     // For OVA providers, each disk is a separate storage mapping entry
@@ -1980,16 +2304,17 @@ const CreatePlanWizard = ({ onCancel, onCreatePlan, capabilities, forkliftAvaila
             }));
         }
 
-        // vSphere: per-datastore model
+        // vSphere: per-datastore model — every datastore the VM or any of its disks uses
         const datastores = [];
         const seen = new Set();
-        if (selectedVm.datastoreId) {
-            seen.add(selectedVm.datastoreId);
-            datastores.push({
-                id: selectedVm.datastoreId,
-                name: selectedVm.datastoreName || selectedVm.datastoreId,
-            });
-        }
+        const add = (id, name) => {
+            if (!id || seen.has(id)) return;
+            seen.add(id);
+            datastores.push({ id, name: name || id });
+        };
+        (selectedVm.datastores || []).forEach(ds => add(ds.id, ds.name));
+        (selectedVm.disks || []).forEach(disk => add(disk.datastoreId, disk.datastoreName));
+        add(selectedVm.datastoreId, selectedVm.datastoreName);
         return datastores;
     }, [selectedVm, engine, selectedProviderType]);
 
@@ -2102,6 +2427,8 @@ const CreatePlanWizard = ({ onCancel, onCreatePlan, capabilities, forkliftAvaila
                 sourceVmNetworks: JSON.stringify(selectedVm?.networks || []),
             };
 
+            if (!(await confirmPlanValidation('/api/v1/forklift/plans/validate', forkliftPayload))) return;
+
             try {
                 const response = await fetch('/api/v1/forklift/plans', {
                     method: 'POST',
@@ -2140,6 +2467,7 @@ const CreatePlanWizard = ({ onCancel, onCreatePlan, capabilities, forkliftAvaila
             diskBus,
         });
 
+        if (!(await confirmPlanValidation('/api/v1/plans/validate', plan))) return;
         onCreatePlan(plan);
     };
 
@@ -2286,7 +2614,13 @@ const CreatePlanWizard = ({ onCancel, onCreatePlan, capabilities, forkliftAvaila
                                         <button onClick={() => handleSourceChange(selectedSource)} className="text-blue-500 hover:text-blue-700"><RefreshCw size={16} /></button>
                                     </div>
                                     <div className="flex-grow overflow-hidden">
-                                        <FilterableInventoryTree node={vcenterInventory} onVmSelect={setSelectedVm} currentlySelectedVm={selectedVm} />
+                                        <FilterableInventoryTree
+                                            node={vcenterInventory}
+                                            onVmSelect={setSelectedVm}
+                                            currentlySelectedVm={selectedVm}
+                                            view={inventoryView}
+                                            onViewChange={view => { setInventoryView(view); handleSourceChange(selectedSource, view); }}
+                                        />
                                     </div>
                                 </div>
                                 <VmDetailsPanel vm={selectedVm} />
@@ -2379,6 +2713,8 @@ const CreatePlanWizard = ({ onCancel, onCreatePlan, capabilities, forkliftAvaila
                                     <option value="">Select a Storage Class</option>
                                     {storageClasses.map(sc => <option key={sc} value={sc}>{sc}</option>)}
                                 </select>
+                                <StorageSuggestionHint suggestion={storageSuggestions?.overall} selected={storageClass} warnings={storageSuggestions?.warnings} />
+                                <CapacityHint plan={capacityPlan} selected={storageClass} />
                             </div>
                         </div>
 
@@ -2602,6 +2938,7 @@ const CreatePlanWizard = ({ onCancel, onCreatePlan, capabilities, forkliftAvaila
                                             <div>
                                                 {net.displayName}
                                                 {engine === 'forklift' && net.id && <div className="text-xs text-secondary">{net.id}</div>}
+                                                {net.details && <div className="text-xs text-secondary">{formatNetworkBacking(net.details)}</div>}
                                             </div>
                                         </div>
                                         <div className="md:col-span-1 text-center hidden md:block">
@@ -2609,12 +2946,14 @@ const CreatePlanWizard = ({ onCancel, onCreatePlan, capabilities, forkliftAvaila
                                         </div>
                                         <div className="md:col-span-4">
                                             <select
+                                                value={networkMappings[net.key] || ''}
                                                 onChange={e => setNetworkMappings(prev => ({ ...prev, [net.key]: e.target.value }))}
                                                 className="form-select w-full text-sm"
                                             >
                                                 <option value="">Select Harvester Network</option>
                                                 {harvesterNetworks.map(hnet => <option key={hnet} value={hnet}>{hnet}</option>)}
                                             </select>
+                                            <NetworkSuggestionHint suggestion={networkSuggestions[net.id]} selected={networkMappings[net.key]} />
                                         </div>
 
                                         {engine !== 'forklift' && capabilities.hasAdvancedPower && (
@@ -2687,6 +3026,11 @@ const CreatePlanWizard = ({ onCancel, onCreatePlan, capabilities, forkliftAvaila
                                                         <option value="">Select Storage Class</option>
                                                         {storageClasses.map(sc => <option key={sc} value={sc}>{sc}</option>)}
                                                     </select>
+                                                    <StorageSuggestionHint
+                                                        suggestion={storageSuggestions?.datastores?.find(s => s.datastoreId === ds.id)}
+                                                        selected={forkliftStorageMappings[ds.id] || storageClass}
+                                                        warnings={storageSuggestions?.warnings}
+                                                    />
                                                 </div>
                                                 <div className="md:col-span-3 flex gap-2">
                                                     <select
@@ -2795,7 +3139,7 @@ const CreatePlanWizard = ({ onCancel, onCreatePlan, capabilities, forkliftAvaila
                                 <ul className="list-disc list-inside pl-4">
                                     <li>
                                         {sourceType === 'ova' ? ovaVmName : selectedVm?.name}
-                                        {engine === 'forklift' && selectedVm?.id && <span className="text-secondary text-xs"> (ID: {selectedVm.id})</span>}
+                                        {engine === 'forklift' && selectedVm?.id && <span className="text-secondary text-xs"> (ID: {selectedVm.id}{selectedVm.datacenter ? `, Datacenter: ${selectedVm.datacenter}` : ''})</span>}
                                         {engine !== 'forklift' && sourceType !== 'ova' && <span className="text-secondary text-xs"> (Folder: {selectedVm?.folder || '/'})</span>}
                                     </li>
                                 </ul>
@@ -3145,6 +3489,7 @@ const ForkliftProviderWizard = ({ onCancel, onSave, source, defaultNamespace, de
     const [providerType, setProviderType] = useState(defaultProviderType || 'vsphere');
     const [insecureSkipVerify, setInsecureSkipVerify] = useState(true);
     const [cacert, setCacert] = useState('');
+    const [thumbprint, setThumbprint] = useState('');
     const [vddkInitImage, setVddkInitImage] = useState('');
     const isEditMode = !!source;
 
@@ -3157,6 +3502,7 @@ const ForkliftProviderWizard = ({ onCancel, onSave, source, defaultNamespace, de
             setSdkEndpoint(source.spec?.settings?.sdkEndpoint || 'vcenter');
             setProviderType(source.spec?.type || 'vsphere');
             setInsecureSkipVerify(source.spec?.insecureSkipVerify !== 'false');
+            setThumbprint(source.spec?.thumbprint || '');
             setVddkInitImage(source.spec?.settings?.vddkInitImage || '');
         }
     }, [source, isEditMode]);
@@ -3169,6 +3515,7 @@ const ForkliftProviderWizard = ({ onCancel, onSave, source, defaultNamespace, de
                 name, namespace, url, username, password, sdkEndpoint, providerType: 'vsphere',
                 insecureSkipVerify,
                 cacert: insecureSkipVerify ? '' : cacert,
+                thumbprint: thumbprint.trim(),
                 vddkInitImage,
             }, isEditMode);
         }
@@ -3271,6 +3618,13 @@ const ForkliftProviderWizard = ({ onCancel, onSave, source, defaultNamespace, de
                                         </p>
                                     </div>
                                 )}
+                                <div className="mt-3">
+                                    <label className="block text-sm font-medium text-main">Pinned Certificate Thumbprint</label>
+                                    <input type="text" value={thumbprint} onChange={e => setThumbprint(e.target.value)}
+                                        className="mt-1 block w-full form-input font-mono text-xs"
+                                        placeholder="AB:CD:EF:..." />
+                                    <p className="text-xs text-secondary mt-1">Optional SHA-1 or SHA-256 fingerprint. When set, the inventory explorer only accepts a certificate with this fingerprint.</p>
+                                </div>
                             </div>
                             <div>
                                 <div className="flex items-center space-x-1">
@@ -3343,6 +3697,7 @@ const ForkliftProviderDetails = ({ provider, onClose }) => {
                                 <>
                                     <p><strong>TLS Verification:</strong> {provider.spec?.insecureSkipVerify === 'false' ? 'Enabled' : 'Skipped (insecure)'}</p>
                                     {provider.spec?.hasCACert && <p><strong>CA Certificate:</strong> Provided</p>}
+                                    {provider.spec?.thumbprint && <p><strong>Pinned Thumbprint:</strong> <span className="font-mono text-xs">{provider.spec.thumbprint}</span></p>}
                                     <p><strong>VDDK Init Image:</strong> {provider.spec?.settings?.vddkInitImage || <span className="text-secondary italic">Not configured (slower fallback)</span>}</p>
                                 </>
                             )}
@@ -3568,6 +3923,155 @@ const ForkliftPlansTable = ({ plans, onDelete, onViewDetails, sortConfig, onSort
     );
 };
 
+// Cutover control and precopy history for a warm Forklift migration.
+const WarmCutoverSection = ({ plan, migration }) => {
+    const url = `/api/v1/forklift/plans/${plan.metadata.namespace}/${plan.metadata.name}/cutover`;
+    const [status, setStatus] = useState(null);
+    const [cutoverAt, setCutoverAt] = useState('');
+    const [autoAfter, setAutoAfter] = useState('');
+    const [error, setError] = useState('');
+
+    const fetchCutover = useCallback(() => {
+        fetch(url).then(r => r.ok ? r.json() : null).then(data => {
+            setStatus(data);
+            if (data) setAutoAfter(data.autoCutoverAfter ? String(data.autoCutoverAfter) : '');
+        }).catch(() => {});
+    }, [url]);
+    useEffect(() => { fetchCutover(); }, [fetchCutover, migration]);
+
+    const send = async (method, payload) => {
+        setError('');
+        const res = await fetch(url, { method, headers: { 'Content-Type': 'application/json' }, body: payload ? JSON.stringify(payload) : undefined });
+        const data = await res.json();
+        if (!res.ok) { setError(data.error || 'Failed to update the cutover'); return; }
+        setStatus(data);
+    };
+
+    if (!status) return null;
+    return (
+        <div>
+            <h3 className="text-sm font-bold text-secondary uppercase tracking-wider mb-2">Cutover</h3>
+            <div className="p-3 bg-app rounded-md border text-sm space-y-3">
+                <p className="text-main">{status.cutover ? `Cutover at ${formatDate(status.cutover)}` : 'No cutover set; the migration keeps precopying.'}
+                    {status.autoCutoverAfter > 0 && !status.cutover && ` Cuts over automatically after ${status.autoCutoverAfter} successful precopies.`}</p>
+                {!status.completed && (
+                    <div className="flex flex-wrap items-center gap-2">
+                        <button onClick={() => window.confirm('Cut over now? The source VMs will be shut down.') && send('PUT', { now: true })} className="bg-green-500 hover:bg-green-600 text-white font-semibold px-3 py-1 rounded-md">Cut over now</button>
+                        <input type="datetime-local" value={cutoverAt} onChange={e => setCutoverAt(e.target.value)} className="p-1 border rounded-md bg-card text-main" />
+                        <button disabled={!cutoverAt} onClick={() => send('PUT', { cutover: new Date(cutoverAt).toISOString() })} className="btn-secondary px-3 py-1 rounded-md font-semibold disabled:opacity-50">Set time</button>
+                        <input type="number" min="0" value={autoAfter} onChange={e => setAutoAfter(e.target.value)} placeholder="Auto after N" className="w-28 p-1 border rounded-md bg-card text-main" />
+                        <button onClick={() => send('PUT', { autoCutoverAfter: parseInt(autoAfter, 10) || 0 })} className="btn-secondary px-3 py-1 rounded-md font-semibold">Save auto-cutover</button>
+                        {(status.cutover || status.autoCutoverAfter > 0) && <button onClick={() => send('DELETE')} className="btn-secondary px-3 py-1 rounded-md font-semibold">Clear</button>}
+                    </div>
+                )}
+                {error && <p className="text-red-600">{error}</p>}
+                {status.vms.map(vm => (
+                    <div key={vm.id || vm.name}>
+                        <div className="text-main font-medium">{vm.name || vm.id}: {vm.successes} precopies, {vm.failures} failed
+                            {vm.nextPrecopyAt && <span className="text-secondary font-normal"> — next at {formatDate(vm.nextPrecopyAt)}</span>}</div>
+                        {vm.precopies.length > 0 && (
+                            <table className="w-full text-xs mt-1">
+                                <thead><tr className="text-secondary text-left"><th>#</th><th>Started</th><th>Duration</th><th>Disks</th></tr></thead>
+                                <tbody>
+                                    {vm.precopies.map((p, i) => (
+                                        <tr key={i} className="text-main"><td>{i + 1}</td><td>{formatDate(p.start)}</td>
+                                            <td>{p.end ? formatDuration(p.start, p.end) : 'running'}</td><td>{p.disks}</td></tr>
+                                    ))}
+                                </tbody>
+                            </table>
+                        )}
+                    </div>
+                ))}
+            </div>
+        </div>
+    );
+};
+
+// Start time, maintenance window and warm cutover for a Forklift plan.
+const WEEKDAYS = ['Mon', 'Tue', 'Wed', 'Thu', 'Fri', 'Sat', 'Sun'];
+
+const ForkliftScheduleSection = ({ plan }) => {
+    const url = `/api/v1/forklift/plans/${plan.metadata.namespace}/${plan.metadata.name}/schedule`;
+    const [status, setStatus] = useState(null);
+    const [startAt, setStartAt] = useState('');
+    const [cutover, setCutover] = useState('');
+    const [useWindow, setUseWindow] = useState(false);
+    const [days, setDays] = useState([]);
+    const [windowStart, setWindowStart] = useState('22:00');
+    const [duration, setDuration] = useState('4h');
+    const [timeZone, setTimeZone] = useState(Intl.DateTimeFormat().resolvedOptions().timeZone || 'UTC');
+    const [error, setError] = useState('');
+
+    const fetchSchedule = useCallback(() => {
+        fetch(url).then(r => r.ok ? r.json() : null).then(setStatus).catch(() => {});
+    }, [url]);
+    useEffect(() => { fetchSchedule(); }, [fetchSchedule]);
+
+    const save = async () => {
+        setError('');
+        const payload = {};
+        if (startAt) payload.startAt = new Date(startAt).toISOString();
+        if (cutover) payload.cutover = new Date(cutover).toISOString();
+        if (useWindow) payload.window = { days, start: windowStart, duration, timeZone };
+        const res = await fetch(url, { method: 'PUT', headers: { 'Content-Type': 'application/json' }, body: JSON.stringify(payload) });
+        const data = await res.json();
+        if (!res.ok) { setError(data.error || 'Failed to save the schedule'); return; }
+        setStatus(data);
+    };
+    const remove = async () => {
+        await fetch(url, { method: 'DELETE' });
+        fetchSchedule();
+    };
+
+    const schedule = status?.schedule;
+    return (
+        <div>
+            <h3 className="text-sm font-bold text-secondary uppercase tracking-wider mb-2">Schedule</h3>
+            <div className="p-3 bg-app rounded-md border text-sm space-y-3">
+                {status?.scheduled ? (
+                    <div className="grid grid-cols-2 gap-x-2 gap-y-1">
+                        {status.nextStart && <><span className="text-secondary">Starts:</span><span className="text-main">{formatDate(status.nextStart)}</span></>}
+                        {schedule.startedAt && <><span className="text-secondary">Started:</span><span className="text-main">{formatDate(schedule.startedAt)}</span></>}
+                        {schedule.window && <><span className="text-secondary">Window:</span>
+                            <span className="text-main">{(schedule.window.days || []).join(', ') || 'Daily'} {schedule.window.start} for {schedule.window.duration} ({schedule.window.timeZone || 'UTC'}) — {status.inWindow ? 'open now' : `opens ${formatDate(status.nextWindow)}`}</span></>}
+                        {schedule.cutover && <><span className="text-secondary">Cutover:</span><span className="text-main">{formatDate(schedule.cutover)}</span></>}
+                        {schedule.message && <><span className="text-secondary">Last run:</span><span className="text-main">{schedule.message}</span></>}
+                    </div>
+                ) : <p className="text-secondary italic">Not scheduled; the migration starts when you run it.</p>}
+                <div className="grid grid-cols-2 gap-2">
+                    <label className="text-secondary">Start at
+                        <input type="datetime-local" value={startAt} onChange={e => setStartAt(e.target.value)} className="w-full p-1 border rounded-md bg-card text-main" />
+                    </label>
+                    {plan.spec?.warm && <label className="text-secondary">Cutover at
+                        <input type="datetime-local" value={cutover} onChange={e => setCutover(e.target.value)} className="w-full p-1 border rounded-md bg-card text-main" />
+                    </label>}
+                </div>
+                <label className="flex items-center text-main"><input type="checkbox" checked={useWindow} onChange={e => setUseWindow(e.target.checked)} className="mr-2" />Only start inside a maintenance window</label>
+                {useWindow && (
+                    <div className="space-y-2">
+                        <div className="flex flex-wrap gap-2">
+                            {WEEKDAYS.map(d => (
+                                <label key={d} className="flex items-center text-main"><input type="checkbox" className="mr-1" checked={days.includes(d)}
+                                    onChange={e => setDays(e.target.checked ? [...days, d] : days.filter(x => x !== d))} />{d}</label>
+                            ))}
+                        </div>
+                        <div className="grid grid-cols-3 gap-2">
+                            <input type="time" value={windowStart} onChange={e => setWindowStart(e.target.value)} className="p-1 border rounded-md bg-card text-main" />
+                            <input type="text" value={duration} onChange={e => setDuration(e.target.value)} placeholder="4h" className="p-1 border rounded-md bg-card text-main" />
+                            <input type="text" value={timeZone} onChange={e => setTimeZone(e.target.value)} placeholder="UTC" className="p-1 border rounded-md bg-card text-main" />
+                        </div>
+                    </div>
+                )}
+                {error && <p className="text-red-600">{error}</p>}
+                <div className="flex space-x-2">
+                    <button onClick={save} className="btn-secondary px-3 py-1 rounded-md font-semibold">Save schedule</button>
+                    {status?.scheduled && <button onClick={remove} className="btn-secondary px-3 py-1 rounded-md font-semibold">Remove</button>}
+                </div>
+            </div>
+        </div>
+    );
+};
+
 const ForkliftPlanDetails = ({ plan, onClose, onRunMigration, forkliftNamespace }) => {
     const [activeTab, setActiveTab] = useState('overview');
     const [logs, setLogs] = useState('');
@@ -3578,7 +4082,6 @@ const ForkliftPlanDetails = ({ plan, onClose, onRunMigration, forkliftNamespace
     const [onlyRelevantLogs, setOnlyRelevantLogs] = useState(false);
     const [errorsOnlyLogs, setErrorsOnlyLogs] = useState(false);
     const [followLogs, setFollowLogs] = useState(true);
-    const followLogsRef = useRef(true);
     const [fontSize, setFontSize] = useState(10);
     const logsEndRef = useRef(null);
     const [networkMap, setNetworkMap] = useState(null);
@@ -3586,9 +4089,6 @@ const ForkliftPlanDetails = ({ plan, onClose, onRunMigration, forkliftNamespace
     const [migration, setMigration] = useState(null);
     const [provider, setProvider] = useState(null);
 
-    // Keep ref in sync with state
-    useEffect(() => { followLogsRef.current = followLogs; }, [followLogs]);
-
     // Fetch all related objects
     const fetchRelatedObjects = useCallback(() => {
         const netRef = plan.spec?.map?.network;
@@ -3614,6 +4114,18 @@ const ForkliftPlanDetails = ({ plan, onClose, onRunMigration, forkliftNamespace
 
     useEffect(() => { fetchRelatedObjects(); }, [fetchRelatedObjects]);
 
+    // Follow this plan's Migration from the live status stream.
+    useEffect(() => {
+        const onMigration = (e) => {
+            const { type, object } = e.detail;
+            if (!object || object.metadata?.namespace !== plan.metadata.namespace || object.spec?.plan?.name !== plan.metadata.name) return;
+            if (type === 'deleted') setMigration(current => current?.metadata?.uid === object.metadata.uid ? null : current);
+            else if (type === 'added' || type === 'modified') setMigration(object);
+        };
+        window.addEventListener('forklift-migration', onMigration);
+        return () => window.removeEventListener('forklift-migration', onMigration);
+    }, [plan.metadata.namespace, plan.metadata.name]);
+
     const fetchLogs = useCallback(async (showAll = !onlyRelevantLogs, errorsFilter = errorsOnlyLogs, isBackground = false) => {
         if (!isBackground) setIsLoadingDebug(true);
         try {
@@ -3631,15 +4143,14 @@ const ForkliftPlanDetails = ({ plan, onClose, onRunMigration, forkliftNamespace
         }
     }, [onlyRelevantLogs, errorsOnlyLogs, plan.metadata.namespace, plan.metadata.name, forkliftNamespace]);
 
-    useEffect(() => {
-        let interval;
-        if (activeTab === 'debug' && debugMode === 'logs') {
-            interval = setInterval(() => {
-                if (followLogsRef.current) fetchLogs(!onlyRelevantLogs, errorsOnlyLogs, true);
-            }, 2000);
-        }
-        return () => clearInterval(interval);
-    }, [activeTab, debugMode, onlyRelevantLogs, errorsOnlyLogs, fetchLogs]);
+    const logStreamParams = new URLSearchParams({ forkliftNamespace: forkliftNamespace || plan.metadata.namespace, tailLines: '500' });
+    if (!onlyRelevantLogs) logStreamParams.set('all', 'true');
+    if (errorsOnlyLogs) logStreamParams.set('errors', 'true');
+    useLogStream(
+        `/api/v1/forklift/plans/${plan.metadata.namespace}/${plan.metadata.name}/logs/stream?${logStreamParams}`,
+        activeTab === 'debug' && debugMode === 'logs' && followLogs,
+        setLogs
+    );
 
     useEffect(() => {
         if (followLogs && logsEndRef.current) {
@@ -3971,6 +4482,7 @@ const ForkliftPlanDetails = ({ plan, onClose, onRunMigration, forkliftNamespace
                     </div>
                 ) : <p className="text-sm text-secondary italic">Loading...</p>}
             </div>
+            <ForkliftScheduleSection plan={plan} />
             {/* Provider */}
             {provider && (
                 <div>
@@ -4062,6 +4574,8 @@ const ForkliftPlanDetails = ({ plan, onClose, onRunMigration, forkliftNamespace
                     {migration.status?.phase && <span className="text-secondary text-xs ml-auto">Phase: {migration.status.phase}</span>}
                 </div>
 
+                {plan.spec?.warm && <WarmCutoverSection plan={plan} migration={migration} />}
+
                 {/* Migration Metadata */}
                 <div className="p-3 bg-app rounded-md border text-sm">
                     <div className="grid grid-cols-2 gap-x-2 gap-y-1.5">
@@ -4414,6 +4928,7 @@ export default function App() {
         localStorage.setItem('vm-import-theme', theme);
     }, [theme]);
     const [autoRefresh, setAutoRefresh] = useState(true);
+    const [liveUpdates, setLiveUpdates] = useState(false);
     const [page, setPage] = useState('plans');
     const [plans, setPlans] = useState([]);
     const [sources, setSources] = useState([]);
@@ -4715,17 +5230,54 @@ export default function App() {
         fetchSources();
         fetchOvaSources();
         const intervalId = setInterval(() => {
-            // Refresh if autoRefresh is enabled
+            // Refresh if autoRefresh is enabled; plans only while the live stream is down
             if (autoRefresh) {
-                fetchPlans();
+                if (!liveUpdates) fetchPlans();
                 if (forkliftAvailable) {
-                    fetchForkliftPlans();
+                    if (!liveUpdates) fetchForkliftPlans();
                     fetchForkliftProviders();
                 }
             }
         }, refreshInterval * 1000);
         return () => clearInterval(intervalId);
-    }, [refreshInterval, expandedPlans, autoRefresh, forkliftAvailable]);
+    }, [refreshInterval, expandedPlans, autoRefresh, forkliftAvailable, liveUpdates]);
+
+    // Live updates: the backend pushes plan and migration changes over SSE and
+    // resumes from the last event after a reconnect. Migration events are passed
+    // on to open plan details as a 'forklift-migration' window event.
+    useEffect(() => {
+        if (!autoRefresh || typeof EventSource === 'undefined') return;
+        const resources = forkliftAvailable ? 'plans,forkliftplans,migrations' : 'plans';
+        const source = new EventSource(`${apiBase}/api/v1/status/stream?resources=${resources}`);
+        const setters = { plans: setPlans, forkliftplans: setForkliftPlans };
+        const apply = (type) => (e) => {
+            const ev = JSON.parse(e.data);
+            if (ev.resource === 'migrations') {
+                window.dispatchEvent(new CustomEvent('forklift-migration', { detail: { ...ev, type } }));
+                return;
+            }
+            const set = setters[ev.resource];
+            if (!set) return;
+            if (type === 'sync') {
+                set(ev.items || []);
+                return;
+            }
+            const uid = ev.object?.metadata?.uid;
+            set(prev => {
+                if (type === 'deleted') return prev.filter(p => p.metadata.uid !== uid);
+                return prev.some(p => p.metadata.uid === uid) ? prev.map(p => p.metadata.uid === uid ? ev.object : p) : [...prev, ev.object];
+            });
+        };
+        ['sync', 'added', 'modified', 'deleted'].forEach(type => source.addEventListener(type, apply(type)));
+        source.onopen = () => setLiveUpdates(true);
+        source.onerror = () => setLiveUpdates(false);
+        // The backend reports lists and watches it could not keep up as
+        // 'streamerror'; the stream stays open and retries them.
+        source.addEventListener('streamerror', (e) => console.warn('Live updates:', JSON.parse(e.data).message));
+        // Resources whose CRDs are not installed are reported once as 'unavailable'.
+        source.addEventListener('unavailable', (e) => console.info('Live updates:', JSON.parse(e.data).message));
+        return () => { source.close(); setLiveUpdates(false); };
+    }, [autoRefresh, forkliftAvailable]);
 
     const handleCreatePlan = async (planPayload) => {
         try {
@@ -5054,6 +5606,7 @@ export default function App() {
                                     className="w-4 h-4 text-blue-600 border-main rounded focus:ring-blue-500"
                                 />
                                 <label htmlFor="autoRefreshPlans" className="text-sm font-medium text-main cursor-pointer">Auto-refresh</label>
+                                {autoRefresh && liveUpdates && <span className="text-xs px-2 py-0.5 rounded-full bg-green-100 text-green-800" title="Plans update live from the cluster">Live</span>}
                             </div>
                             <div className="flex items-center space-x-2">
                                 <button onClick={() => { fetchPlans(); if (forkliftAvailable) fetchForkliftPlans(); }} className="text-blue-500 hover:text-blue-700" title="Refresh Now"><RefreshCw size={20} /></button>
@@ -2,6 +2,7 @@ import React from 'react';
 import ReactDOM from 'react-dom/client';
 import './index.css';
 import App from './App';
+import { apiBase } from './utils';
 
 // Support being served under a sub-path (e.g. the Rancher cluster Service proxy
 // at /k8s/clusters/<id>/api/v1/namespaces/<ns>/services/http:<svc>:<port>/proxy/).
@@ -10,7 +11,6 @@ import App from './App';
 // proxy. At the app root this is a no-op (apiBase is empty), so direct
 // NodePort/Ingress access is unaffected. Static assets use relative paths via
 // "homepage": "." in package.json.
-const apiBase = window.location.pathname.replace(/[^/]*$/, '').replace(/\/$/, '');
 if (apiBase) {
   const originalFetch = window.fetch.bind(window);
   window.fetch = (input, init) => {
@@ -19,6 +19,15 @@ if (apiBase) {
     }
     return originalFetch(input, init);
   };
+  // Log streams use EventSource, which bypasses fetch.
+  if (typeof window.EventSource !== 'undefined') {
+    const OriginalEventSource = window.EventSource;
+    window.EventSource = class extends OriginalEventSource {
+      constructor(url, config) {
+        super(typeof url === 'string' && url.startsWith('/api/') ? apiBase + url : url, config);
+      }
+    };
+  }
 }
 
 const root = ReactDOM.createRoot(document.getElementById('root'));
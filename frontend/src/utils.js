@@ -1,5 +1,9 @@
 // frontend/src/utils.js — Shared utility functions
 
+// apiBase is the sub-path the app is served under ("" at the root), derived
+// from wherever index.html was loaded from; "/api/..." URLs go below it.
+export const apiBase = window.location.pathname.replace(/[^/]*$/, '').replace(/\/$/, '');
+
 export const formatBytes = (bytes, decimals = 2) => {
     if (!bytes || bytes === 0) return '0 Bytes';
     const k = 1024;
@@ -0,0 +1,257 @@
+// pkg/bulk_plans.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// BulkPlanDefaults are the settings shared by every VM of a bulk request.
+type BulkPlanDefaults struct {
+	StorageClass                   string           `json:"storageClass,omitempty"`
+	NetworkMapping                 []NetworkMapping `json:"networkMapping,omitempty"`
+	Folder                         string           `json:"folder,omitempty"`
+	ForcePowerOff                  *bool            `json:"forcePowerOff,omitempty"`
+	GracefulShutdownTimeoutSeconds int              `json:"gracefulShutdownTimeoutSeconds,omitempty"`
+	DefaultNetworkInterfaceModel   string           `json:"defaultNetworkInterfaceModel,omitempty"`
+	SkipPreflightChecks            *bool            `json:"skipPreflightChecks,omitempty"`
+	DefaultDiskBusType             string           `json:"defaultDiskBusType,omitempty"`
+	Schedule                       *metav1.Time     `json:"schedule,omitempty"`
+}
+
+// BulkPlanVM selects one source VM. Empty fields fall back to the defaults;
+// NetworkMapping entries replace the default entry for the same source
+// network.
+type BulkPlanVM struct {
+	Name           string            `json:"name"`               // exact source VM name, as VMIC looks it up
+	PlanName       string            `json:"planName,omitempty"` // generated from the VM name when empty
+	Folder         string            `json:"folder,omitempty"`
+	StorageClass   string            `json:"storageClass,omitempty"`
+	NetworkMapping []NetworkMapping  `json:"networkMapping,omitempty"`
+	Annotations    map[string]string `json:"annotations,omitempty"`
+}
+
+// CreateBulkPlansPayload creates one VirtualMachineImport per VM, all in the
+// same namespace and from the same source. With Validate set, each plan is
+// dry-run first and only created when it has no errors.
+type CreateBulkPlansPayload struct {
+	Namespace     string           `json:"namespace"`
+	SourceCluster SourceCluster    `json:"sourceCluster"`
+	NamePrefix    string           `json:"namePrefix,omitempty"`
+	Validate      bool             `json:"validate,omitempty"`
+	Defaults      BulkPlanDefaults `json:"defaults"`
+	VMs           []BulkPlanVM     `json:"vms"`
+}
+
+// BulkPlanResult is the outcome for one VM of a bulk request.
+type BulkPlanResult struct {
+	VirtualMachineName string            `json:"virtualMachineName"`
+	PlanName           string            `json:"planName,omitempty"`
+	Created            bool              `json:"created"`
+	Error              string            `json:"error,omitempty"`
+	Errors             []ValidationIssue `json:"errors,omitempty"`
+	Warnings           []ValidationIssue `json:"warnings,omitempty"`
+}
+
+// BulkPlansResponse lists a result per requested VM, in request order.
+type BulkPlansResponse struct {
+	Created int              `json:"created"`
+	Failed  int              `json:"failed"`
+	Results []BulkPlanResult `json:"results"`
+}
+
+// planNameInvalidChars matches the runs of characters a generated plan name
+// replaces with a hyphen.
+var planNameInvalidChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// generatePlanName derives an RFC-1123 plan name from a VM name, adding a
+// numeric suffix when the name is already in taken, and marks it taken.
+func generatePlanName(prefix, vmName string, taken map[string]bool) string {
+	base := strings.Trim(planNameInvalidChars.ReplaceAllString(strings.ToLower(prefix+vmName), "-"), "-")
+	if base == "" {
+		base = "import"
+	}
+	fit := func(suffix string) string {
+		name := base
+		if len(name)+len(suffix) > 63 {
+			name = strings.TrimRight(name[:63-len(suffix)], "-")
+		}
+		return name + suffix
+	}
+	name := fit("")
+	for i := 2; taken[name]; i++ {
+		name = fit("-" + strconv.Itoa(i))
+	}
+	taken[name] = true
+	return name
+}
+
+// mergeNetworkMappings overlays per-VM mappings on the defaults, keyed by
+// source network; defaults keep their order and new networks go last.
+func mergeNetworkMappings(defaults, overrides []NetworkMapping) []NetworkMapping {
+	merged := append([]NetworkMapping{}, defaults...)
+	for _, o := range overrides {
+		replaced := false
+		for i := range merged {
+			if merged[i].SourceNetwork == o.SourceNetwork {
+				merged[i] = o
+				replaced = true
+			}
+		}
+		if !replaced {
+			merged = append(merged, o)
+		}
+	}
+	return merged
+}
+
+// buildBulkPlans turns a bulk request into VirtualMachineImports. plans and
+// results are indexed alike; a VM that cannot become a valid plan gets a nil
+// plan and an error in its result. takenPlans and takenVMs hold the plan and
+// VirtualMachine names already in the namespace and are updated as names are
+// handed out.
+func buildBulkPlans(payload CreateBulkPlansPayload, takenPlans, takenVMs map[string]bool) ([]*VirtualMachineImport, []BulkPlanResult) {
+	plans := make([]*VirtualMachineImport, len(payload.VMs))
+	results := make([]BulkPlanResult, len(payload.VMs))
+	seen := map[string]bool{}
+
+	for i, vm := range payload.VMs {
+		results[i].VirtualMachineName = vm.Name
+		fail := func(format string, args ...interface{}) {
+			results[i].Error = fmt.Sprintf(format, args...)
+		}
+
+		// VMIC names the Harvester VM after the lowercased source VM name.
+		target := strings.ToLower(vm.Name)
+		switch {
+		case vm.Name == "":
+			fail("No source VM name given")
+			continue
+		case seen[vm.Name]:
+			fail("VM %s is listed more than once", vm.Name)
+			continue
+		case len(target) > 63 || !rfc1123Label.MatchString(target):
+			fail("VMIC would name the Harvester VM %q, which is not a valid RFC-1123 name; rename the VM in vCenter first", target)
+			continue
+		case takenVMs[target]:
+			fail("A VirtualMachine named %s already exists in namespace %s", target, payload.Namespace)
+			continue
+		}
+		seen[vm.Name] = true
+
+		name := vm.PlanName
+		if name == "" {
+			name = generatePlanName(payload.NamePrefix, vm.Name, takenPlans)
+		} else if len(name) > 63 || !rfc1123Label.MatchString(name) {
+			fail("Plan name %q is not a valid RFC-1123 name", name)
+			continue
+		} else if takenPlans[name] {
+			fail("A VirtualMachineImport named %s already exists in namespace %s", name, payload.Namespace)
+			continue
+		} else {
+			takenPlans[name] = true
+		}
+		takenVMs[target] = true
+		results[i].PlanName = name
+
+		d := payload.Defaults
+		plan := &VirtualMachineImport{
+			TypeMeta: metav1.TypeMeta{APIVersion: "migration.harvesterhci.io/v1beta1", Kind: "VirtualMachineImport"},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   payload.Namespace,
+				Annotations: vm.Annotations,
+			},
+			Spec: VirtualMachineImportSpec{
+				VirtualMachineName:             vm.Name,
+				SourceCluster:                  payload.SourceCluster,
+				NetworkMapping:                 mergeNetworkMappings(d.NetworkMapping, vm.NetworkMapping),
+				StorageClass:                   d.StorageClass,
+				Schedule:                       d.Schedule,
+				Folder:                         d.Folder,
+				ForcePowerOff:                  d.ForcePowerOff,
+				GracefulShutdownTimeoutSeconds: d.GracefulShutdownTimeoutSeconds,
+				DefaultNetworkInterfaceModel:   d.DefaultNetworkInterfaceModel,
+				SkipPreflightChecks:            d.SkipPreflightChecks,
+				DefaultDiskBusType:             d.DefaultDiskBusType,
+			},
+		}
+		if vm.StorageClass != "" {
+			plan.Spec.StorageClass = vm.StorageClass
+		}
+		if vm.Folder != "" {
+			plan.Spec.Folder = vm.Folder
+		}
+		plans[i] = plan
+	}
+	return plans, results
+}
+
+// createBulkPlans builds, optionally validates, and creates the plans of a
+// bulk request. A VM that fails never stops the others.
+func createBulkPlans(ctx context.Context, clients *K8sClients, payload CreateBulkPlansPayload) (BulkPlansResponse, error) {
+	takenPlans := map[string]bool{}
+	existing, err := clients.Dynamic.Resource(vmiGVR).Namespace(payload.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return BulkPlansResponse{}, fmt.Errorf("failed to list VirtualMachineImports in %s: %w", payload.Namespace, err)
+	}
+	for _, item := range existing.Items {
+		takenPlans[item.GetName()] = true
+	}
+	takenVMs := map[string]bool{}
+	vms, err := listHarvesterVMs(ctx, clients, payload.Namespace)
+	if err != nil {
+		return BulkPlansResponse{}, fmt.Errorf("failed to list VirtualMachines in %s: %w", payload.Namespace, err)
+	}
+	for _, item := range vms {
+		takenVMs[item.GetName()] = true
+	}
+
+	plans, results := buildBulkPlans(payload, takenPlans, takenVMs)
+	response := BulkPlansResponse{Results: results}
+	for i, plan := range plans {
+		result := &response.Results[i]
+		if plan != nil {
+			if payload.Validate {
+				validation := validateVMICPlan(ctx, clients, *plan)
+				result.Errors, result.Warnings = validation.Errors, validation.Warnings
+				if !validation.Valid {
+					result.Error = "Plan failed validation"
+				}
+			}
+			if result.Error == "" {
+				result.Error = createBulkPlan(ctx, clients, plan)
+			}
+		}
+		if result.Error == "" {
+			result.Created = true
+			response.Created++
+		} else {
+			response.Failed++
+		}
+	}
+	return response, nil
+}
+
+// createBulkPlan creates one VirtualMachineImport and returns the error
+// message, if any, for its result.
+func createBulkPlan(ctx context.Context, clients *K8sClients, plan *VirtualMachineImport) string {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(plan)
+	if err != nil {
+		return "Failed to convert plan to unstructured object: " + err.Error()
+	}
+	log.Infof("Creating VirtualMachineImport CR: %s in namespace %s", plan.ObjectMeta.Name, plan.ObjectMeta.Namespace)
+	if _, err := clients.Dynamic.Resource(vmiGVR).Namespace(plan.ObjectMeta.Namespace).Create(ctx, &unstructured.Unstructured{Object: obj}, metav1.CreateOptions{}); err != nil {
+		log.Errorf("Failed to create VirtualMachineImport CR %s/%s: %v", plan.ObjectMeta.Namespace, plan.ObjectMeta.Name, err)
+		return "Failed to create VirtualMachineImport CR: " + err.Error()
+	}
+	return ""
+}
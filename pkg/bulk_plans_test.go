@@ -0,0 +1,156 @@
+// pkg/bulk_plans_test.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestGeneratePlanName(t *testing.T) {
+	taken := map[string]bool{"import-web-01": true}
+	long := strings.Repeat("a", 70)
+
+	tests := []struct {
+		prefix, vm, want string
+	}{
+		{"", "Web_01 (prod)", "web-01-prod"},
+		{"import-", "web-01", "import-web-01-2"},
+		{"import-", "web-01", "import-web-01-3"},
+		{"", "___", "import"},
+		{"", long, strings.Repeat("a", 63)},
+		{"", long, strings.Repeat("a", 61) + "-2"},
+	}
+	for _, tc := range tests {
+		if got := generatePlanName(tc.prefix, tc.vm, taken); got != tc.want {
+			t.Errorf("generatePlanName(%q, %q) = %q, want %q", tc.prefix, tc.vm, got, tc.want)
+		}
+	}
+}
+
+func TestMergeNetworkMappings(t *testing.T) {
+	defaults := []NetworkMapping{{SourceNetwork: "VM Network", DestinationNetwork: "default/vlan10"}, {SourceNetwork: "Backup", DestinationNetwork: "default/vlan20"}}
+	got := mergeNetworkMappings(defaults, []NetworkMapping{{SourceNetwork: "Backup", DestinationNetwork: "default/vlan30"}, {SourceNetwork: "DMZ", DestinationNetwork: "default/vlan40"}})
+	if len(got) != 3 || got[0].DestinationNetwork != "default/vlan10" || got[1].DestinationNetwork != "default/vlan30" || got[2].SourceNetwork != "DMZ" {
+		t.Errorf("unexpected merge: %+v", got)
+	}
+	if defaults[1].DestinationNetwork != "default/vlan20" {
+		t.Error("expected the defaults to be left untouched")
+	}
+}
+
+func TestCreateBulkPlansHandlerWithVCSim(t *testing.T) {
+	env, vm := newValidationEnv(t)
+	handler := CreateBulkPlansHandler(env.clients)
+	ctx := context.Background()
+
+	existing := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "migration.harvesterhci.io/v1beta1",
+		"kind":       "VirtualMachineImport",
+		"metadata":   map[string]interface{}{"name": "import-web-01", "namespace": "default"},
+	}}
+	if _, err := env.clients.Dynamic.Resource(vmiGVR).Namespace("default").Create(ctx, existing, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	var mapped []NetworkMapping
+	for _, nic := range vm.Networks {
+		mapped = append(mapped, NetworkMapping{SourceNetwork: nic.Name, DestinationNetwork: "default/vlan100"})
+	}
+	source := SourceCluster{Namespace: env.source["namespace"], Name: env.source["name"]}
+
+	post := func(t *testing.T, payload CreateBulkPlansPayload, wantStatus int) BulkPlansResponse {
+		t.Helper()
+		rr := executeRequest(handler, "POST", "/api/v1/plans/bulk", payload, nil)
+		if rr.Code != wantStatus {
+			t.Fatalf("expected status %d, got %d: %s", wantStatus, rr.Code, rr.Body.String())
+		}
+		var response BulkPlansResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		return response
+	}
+
+	t.Run("one bad VM does not stop the batch", func(t *testing.T) {
+		response := post(t, CreateBulkPlansPayload{
+			Namespace:     "default",
+			SourceCluster: source,
+			NamePrefix:    "import-",
+			Defaults:      BulkPlanDefaults{StorageClass: "longhorn-1", NetworkMapping: mapped, DefaultDiskBusType: "virtio"},
+			VMs: []BulkPlanVM{
+				{Name: "web-01"},
+				{Name: "DB_01"},
+				{Name: "app-01", StorageClass: "harvester-longhorn", Folder: "DC0/vm/apps"},
+				{Name: "app-01"},
+				{Name: "cache-01", PlanName: "Cache"},
+			},
+		}, http.StatusMultiStatus)
+
+		if response.Created != 2 || response.Failed != 3 {
+			t.Fatalf("expected 2 created and 3 failed, got %+v", response.Results)
+		}
+		for i, want := range []string{"", "not a valid RFC-1123 name", "", "listed more than once", "not a valid RFC-1123 name"} {
+			if got := response.Results[i]; !strings.Contains(got.Error, want) || got.Created != (want == "") {
+				t.Errorf("result %d: expected error %q, got %+v", i, want, got)
+			}
+		}
+		if response.Results[0].PlanName != "import-web-01-2" || response.Results[2].PlanName != "import-app-01" {
+			t.Errorf("expected generated names to avoid the existing plan, got %+v", response.Results)
+		}
+
+		obj, err := env.clients.Dynamic.Resource(vmiGVR).Namespace("default").Get(ctx, "import-app-01", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		spec, _, _ := unstructured.NestedMap(obj.Object, "spec")
+		kind, _, _ := unstructured.NestedString(spec, "sourceCluster", "kind")
+		mappings, _, _ := unstructured.NestedSlice(spec, "networkMapping")
+		if spec["storageClass"] != "harvester-longhorn" || spec["folder"] != "DC0/vm/apps" || spec["defaultDiskBusType"] != "virtio" || kind != "VmwareSource" || len(mappings) != len(mapped) {
+			t.Errorf("expected per-VM overrides on top of the defaults, got %v", spec)
+		}
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		response := post(t, CreateBulkPlansPayload{
+			Namespace:     "forklift",
+			SourceCluster: source,
+			Validate:      true,
+			Defaults:      BulkPlanDefaults{StorageClass: "longhorn-1", NetworkMapping: mapped},
+			VMs:           []BulkPlanVM{{Name: "web-01"}, {Name: "nope"}},
+		}, http.StatusMultiStatus)
+
+		if web := response.Results[0]; !web.Created || issueCodes(web.Warnings) != "gracefulShutdownUnavailable" {
+			t.Errorf("expected web-01 created with a warning, got %+v", web)
+		}
+		if nope := response.Results[1]; nope.Created || issueCodes(nope.Errors) != "vmNotFound" {
+			t.Errorf("expected nope to fail validation, got %+v", nope)
+		}
+	})
+
+	t.Run("taken VM name and all created", func(t *testing.T) {
+		response := post(t, CreateBulkPlansPayload{Namespace: "vms", SourceCluster: source, VMs: []BulkPlanVM{{Name: "web-01"}}}, http.StatusMultiStatus)
+		if !strings.Contains(response.Results[0].Error, "already exists") {
+			t.Errorf("expected web-01 to clash with the existing VM, got %+v", response.Results[0])
+		}
+		response = post(t, CreateBulkPlansPayload{Namespace: "vms", SourceCluster: source, VMs: []BulkPlanVM{{Name: "db-01"}}}, http.StatusCreated)
+		if response.Results[0].PlanName != "db-01" {
+			t.Errorf("expected plan db-01, got %+v", response.Results[0])
+		}
+	})
+
+	t.Run("bad requests", func(t *testing.T) {
+		for _, payload := range []CreateBulkPlansPayload{
+			{Namespace: "default", SourceCluster: source},
+			{Namespace: "nope", SourceCluster: source, VMs: []BulkPlanVM{{Name: "web-01"}}},
+		} {
+			if rr := executeRequest(handler, "POST", "/api/v1/plans/bulk", payload, nil); rr.Code != http.StatusBadRequest {
+				t.Errorf("expected 400 for %+v, got %d", payload, rr.Code)
+			}
+		}
+	})
+}
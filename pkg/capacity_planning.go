@@ -0,0 +1,231 @@
+// pkg/capacity_planning.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const noProvisioner = "kubernetes.io/no-provisioner"
+
+// Where a StorageClass's free capacity comes from.
+const (
+	capacitySourceLonghorn      = "longhorn"
+	capacitySourceCSI           = "csiStorageCapacity"
+	capacitySourceStaticVolumes = "availablePersistentVolumes"
+	capacitySourceUnknown       = "unknown"
+)
+
+// CapacityVM is the disk footprint of one selected VM. ProvisionedBytes is
+// the size of its disks, what the destination volumes are created with;
+// CommittedBytes is how much of that is in use, capped at ProvisionedBytes
+// because swap and snapshot files are not migrated.
+type CapacityVM struct {
+	ID               string `json:"id"`
+	Name             string `json:"name"`
+	Disks            int    `json:"disks"`
+	ProvisionedBytes int64  `json:"provisionedBytes"`
+	CommittedBytes   int64  `json:"committedBytes"`
+}
+
+// StorageClassHeadroom compares the selected VMs with one StorageClass.
+// Capacities are per copy of the data, i.e. already divided by the replica
+// count, and are nil when the class's free space is unknown. HeadroomBytes
+// is what is left once volumes of the provisioned size exist;
+// CommittedHeadroomBytes once the data in use has been copied.
+type StorageClassHeadroom struct {
+	StorageClass           string   `json:"storageClass"`
+	Provisioner            string   `json:"provisioner"`
+	Default                bool     `json:"default,omitempty"`
+	Replicas               int      `json:"replicas"`
+	CapacitySource         string   `json:"capacitySource"`
+	UsedBytes              int64    `json:"usedBytes"`
+	Volumes                int      `json:"volumes"`
+	CapacityBytes          *int64   `json:"capacityBytes,omitempty"`
+	SchedulableBytes       *int64   `json:"schedulableBytes,omitempty"`
+	RequiredBytes          int64    `json:"requiredBytes"`
+	RequiredRawBytes       int64    `json:"requiredRawBytes"`
+	HeadroomBytes          *int64   `json:"headroomBytes,omitempty"`
+	CommittedHeadroomBytes *int64   `json:"committedHeadroomBytes,omitempty"`
+	Fits                   *bool    `json:"fits,omitempty"`
+	Notes                  []string `json:"notes"`
+}
+
+// CapacityPlan is the storage footprint of a selection of VMs set against
+// every StorageClass that could take it. Warnings say why free capacity
+// could not be read.
+type CapacityPlan struct {
+	VMs              []CapacityVM           `json:"vms"`
+	ProvisionedBytes int64                  `json:"provisionedBytes"`
+	CommittedBytes   int64                  `json:"committedBytes"`
+	Longhorn         *longhornCapacity      `json:"longhorn,omitempty"`
+	StorageClasses   []StorageClassHeadroom `json:"storageClasses"`
+	Warnings         []string               `json:"warnings,omitempty"`
+}
+
+// classUsage is what the cluster's PersistentVolumes say about one class.
+type classUsage struct {
+	usedBytes      int64
+	volumes        int
+	availableBytes int64 // unbound static volumes
+}
+
+// planCapacity sums the VMs' disks and rates every StorageClass against them.
+// Disks shared by several VMs are counted once. csiErr is why the
+// CSIStorageCapacities could not be read, if they could not.
+func planCapacity(vms []InventoryNode, classes []storagev1.StorageClass, longhorn *longhornCapacity, usage map[string]classUsage, csi map[string]int64, csiErr error) CapacityPlan {
+	plan := CapacityPlan{VMs: []CapacityVM{}, Longhorn: longhorn, StorageClasses: []StorageClassHeadroom{}}
+	seen := map[string]bool{}
+	for _, vm := range vms {
+		c := CapacityVM{ID: vm.ID, Name: vm.Name}
+		for _, disk := range vm.Disks {
+			if disk.FilePath != "" {
+				if seen[disk.FilePath] {
+					continue
+				}
+				seen[disk.FilePath] = true
+			}
+			c.Disks++
+			c.ProvisionedBytes += disk.Capacity
+		}
+		c.CommittedBytes = vm.CommittedBytes
+		if c.CommittedBytes == 0 || c.CommittedBytes > c.ProvisionedBytes {
+			c.CommittedBytes = c.ProvisionedBytes
+		}
+		plan.VMs = append(plan.VMs, c)
+		plan.ProvisionedBytes += c.ProvisionedBytes
+		plan.CommittedBytes += c.CommittedBytes
+	}
+
+	for _, sc := range classes {
+		if sc.Parameters["backingImage"] != "" {
+			continue
+		}
+		plan.StorageClasses = append(plan.StorageClasses, classHeadroom(sc, plan.ProvisionedBytes, plan.CommittedBytes, longhorn, usage[sc.Name], csi, csiErr))
+	}
+	sort.SliceStable(plan.StorageClasses, func(i, j int) bool {
+		return plan.StorageClasses[i].StorageClass < plan.StorageClasses[j].StorageClass
+	})
+	return plan
+}
+
+// classHeadroom works out how much of sc is left after provisioned bytes of
+// volumes holding committed bytes of data.
+func classHeadroom(sc storagev1.StorageClass, provisioned, committed int64, longhorn *longhornCapacity, usage classUsage, csi map[string]int64, csiErr error) StorageClassHeadroom {
+	h := StorageClassHeadroom{
+		StorageClass:   sc.Name,
+		Provisioner:    sc.Provisioner,
+		Default:        isDefaultStorageClass(sc),
+		Replicas:       1,
+		CapacitySource: capacitySourceUnknown,
+		UsedBytes:      usage.usedBytes,
+		Volumes:        usage.volumes,
+		RequiredBytes:  provisioned,
+		Notes:          []string{},
+	}
+	setHeadroom := func(capacity, schedulable int64) {
+		headroom := schedulable - provisioned
+		committedHeadroom := capacity - committed
+		fits := headroom >= 0 && committedHeadroom >= 0
+		h.CapacityBytes = &capacity
+		h.HeadroomBytes = &headroom
+		h.CommittedHeadroomBytes = &committedHeadroom
+		h.Fits = &fits
+	}
+
+	switch {
+	case sc.Provisioner == longhornProvisioner:
+		h.Replicas = defaultLonghornReplicas
+		if n, err := strconv.Atoi(sc.Parameters["numberOfReplicas"]); err == nil && n > 0 {
+			h.Replicas = n
+		}
+		h.RequiredRawBytes = provisioned * int64(h.Replicas)
+		if longhorn == nil {
+			h.Notes = append(h.Notes, "Longhorn nodes could not be read")
+			break
+		}
+		h.CapacitySource = capacitySourceLonghorn
+		schedulable := longhorn.SchedulableBytes / int64(h.Replicas)
+		h.SchedulableBytes = &schedulable
+		setHeadroom(longhorn.AvailableBytes/int64(h.Replicas), schedulable)
+		if provisioned > schedulable {
+			h.Notes = append(h.Notes, fmt.Sprintf("Longhorn would refuse %s of %d-replica volumes: only %s can still be scheduled at %d%% over-provisioning",
+				formatDiskSize(provisioned), h.Replicas, formatDiskSize(schedulable), longhorn.OverProvisioningPercentage))
+		}
+		if committed > *h.CapacityBytes {
+			h.Notes = append(h.Notes, fmt.Sprintf("the %s in use does not fit in the %s free for %d-replica volumes",
+				formatDiskSize(committed), formatDiskSize(*h.CapacityBytes), h.Replicas))
+		}
+		if longhorn.SchedulableNodes < h.Replicas {
+			h.Notes = append(h.Notes, fmt.Sprintf("only %d node(s) can hold replicas, so volumes would run degraded", longhorn.SchedulableNodes))
+		}
+	case sc.Provisioner == noProvisioner:
+		h.RequiredRawBytes = provisioned
+		h.CapacitySource = capacitySourceStaticVolumes
+		setHeadroom(usage.availableBytes, usage.availableBytes)
+		h.Notes = append(h.Notes, "no dynamic provisioner: capacity is the unbound PersistentVolumes of this class, and each disk needs one at least its size")
+	default:
+		h.RequiredRawBytes = provisioned
+		if capacity, ok := csi[sc.Name]; ok {
+			h.CapacitySource = capacitySourceCSI
+			setHeadroom(capacity, capacity)
+			h.Notes = append(h.Notes, "capacity as published by the CSI driver; replication by the storage backend is not accounted for")
+			break
+		}
+		if csiErr != nil {
+			h.Notes = append(h.Notes, fmt.Sprintf("free capacity unknown: CSIStorageCapacities could not be read: %v; %d volume(s) already use %s",
+				csiErr, usage.volumes, formatDiskSize(usage.usedBytes)))
+			break
+		}
+		h.Notes = append(h.Notes, fmt.Sprintf("free capacity unknown: %s publishes no CSIStorageCapacity; %d volume(s) already use %s",
+			sc.Provisioner, usage.volumes, formatDiskSize(usage.usedBytes)))
+	}
+	return h
+}
+
+// getClassUsage sums the PersistentVolumes of each StorageClass. Bound and
+// released volumes count as used; unbound ones as available.
+func getClassUsage(ctx context.Context, clients *K8sClients) (map[string]classUsage, error) {
+	pvs, err := clients.Clientset.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	usage := map[string]classUsage{}
+	for _, pv := range pvs.Items {
+		size := pv.Spec.Capacity[corev1.ResourceStorage]
+		u := usage[pv.Spec.StorageClassName]
+		if pv.Status.Phase == corev1.VolumeAvailable {
+			u.availableBytes += size.Value()
+		} else {
+			u.usedBytes += size.Value()
+			u.volumes++
+		}
+		usage[pv.Spec.StorageClassName] = u
+	}
+	return usage, nil
+}
+
+// getCSICapacity sums the CSIStorageCapacity objects of each StorageClass
+// across topology segments. It returns an empty map, and the error, when
+// they cannot be read.
+func getCSICapacity(ctx context.Context, clients *K8sClients) (map[string]int64, error) {
+	capacity := map[string]int64{}
+	list, err := clients.Clientset.StorageV1().CSIStorageCapacities(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		log.Warnf("Could not list CSIStorageCapacities, capacity will be unknown: %v", err)
+		return capacity, err
+	}
+	for _, c := range list.Items {
+		if c.Capacity != nil {
+			capacity[c.StorageClassName] += c.Capacity.Value()
+		}
+	}
+	return capacity, nil
+}
@@ -0,0 +1,178 @@
+// pkg/capacity_planning_test.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/vmware/govmomi/simulator"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestPlanCapacity(t *testing.T) {
+	vms := []InventoryNode{
+		{ID: "vm-1", Name: "web", CommittedBytes: 30 * gib, Disks: []VMDisk{
+			{Capacity: 40 * gib, FilePath: "[ds1] web/web.vmdk"},
+			{Capacity: 60 * gib, FilePath: "[ds1] web/web_1.vmdk"},
+		}},
+		// db shares web's second disk and reports no committed space.
+		{ID: "vm-2", Name: "db", Disks: []VMDisk{
+			{Capacity: 20 * gib, FilePath: "[ds1] db/db.vmdk"},
+			{Capacity: 60 * gib, FilePath: "[ds1] web/web_1.vmdk"},
+		}},
+	}
+	classes := []storagev1.StorageClass{
+		newStorageClass("harvester-longhorn", longhornProvisioner, true, "numberOfReplicas", "3"),
+		newStorageClass("longhorn-2", longhornProvisioner, false, "numberOfReplicas", "2"),
+		newStorageClass("longhorn-image-abc", longhornProvisioner, false, "backingImage", "default-image-abc"),
+		newStorageClass("local-storage", noProvisioner, false),
+		newStorageClass("ceph", "rbd.csi.ceph.com", false),
+		newStorageClass("nfs", "nfs.csi.k8s.io", false),
+	}
+	longhorn := &longhornCapacity{AvailableBytes: 240 * gib, SchedulableBytes: 300 * gib, SchedulableNodes: 3, OverProvisioningPercentage: 100}
+	usage := map[string]classUsage{
+		"local-storage": {availableBytes: 100 * gib},
+		"nfs":           {usedBytes: 500 * gib, volumes: 4},
+	}
+	csi := map[string]int64{"ceph": 100 * gib}
+
+	plan := planCapacity(vms, classes, longhorn, usage, csi, nil)
+	if plan.ProvisionedBytes != 120*gib || plan.CommittedBytes != 50*gib {
+		t.Fatalf("expected 120 GiB provisioned and 50 GiB committed, got %d and %d", plan.ProvisionedBytes/gib, plan.CommittedBytes/gib)
+	}
+	if plan.VMs[1].Disks != 1 || plan.VMs[1].CommittedBytes != 20*gib {
+		t.Errorf("expected db's shared disk counted once and committed capped at its size, got %+v", plan.VMs[1])
+	}
+
+	byName := map[string]StorageClassHeadroom{}
+	for _, h := range plan.StorageClasses {
+		byName[h.StorageClass] = h
+	}
+	if _, ok := byName["longhorn-image-abc"]; ok || len(plan.StorageClasses) != 5 {
+		t.Errorf("expected image classes to be left out, got %+v", plan.StorageClasses)
+	}
+
+	tests := []struct {
+		class             string
+		source            string
+		headroom          int64 // GiB
+		committedHeadroom int64 // GiB
+		fits              bool
+		note              string
+	}{
+		// 300/3 = 100 GiB schedulable for 120 GiB of volumes; 240/3 = 80 GiB free for 50 GiB of data.
+		{"harvester-longhorn", capacitySourceLonghorn, -20, 30, false, "Longhorn would refuse"},
+		{"longhorn-2", capacitySourceLonghorn, 30, 70, true, ""},
+		{"local-storage", capacitySourceStaticVolumes, -20, 50, false, "unbound PersistentVolumes"},
+		{"ceph", capacitySourceCSI, -20, 50, false, "published by the CSI driver"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.class, func(t *testing.T) {
+			h := byName[tc.class]
+			if h.CapacitySource != tc.source || h.HeadroomBytes == nil || h.CommittedHeadroomBytes == nil || h.Fits == nil {
+				t.Fatalf("expected %s capacity, got %+v", tc.source, h)
+			}
+			if *h.HeadroomBytes != tc.headroom*gib || *h.CommittedHeadroomBytes != tc.committedHeadroom*gib || *h.Fits != tc.fits {
+				t.Errorf("expected headroom %d/%d GiB (fits %v), got %d/%d GiB (fits %v)",
+					tc.headroom, tc.committedHeadroom, tc.fits, *h.HeadroomBytes/gib, *h.CommittedHeadroomBytes/gib, *h.Fits)
+			}
+			if tc.note != "" && !strings.Contains(strings.Join(h.Notes, "; "), tc.note) {
+				t.Errorf("expected a note containing %q, got %v", tc.note, h.Notes)
+			}
+		})
+	}
+
+	if h := byName["longhorn-2"]; h.RequiredRawBytes != 240*gib || *h.SchedulableBytes != 150*gib {
+		t.Errorf("expected 2 replicas to need 240 GiB raw of 150 GiB schedulable, got %+v", h)
+	}
+	if h := byName["nfs"]; h.CapacityBytes != nil || h.Fits != nil || h.UsedBytes != 500*gib || !strings.Contains(h.Notes[0], "4 volume(s)") {
+		t.Errorf("expected unknown nfs capacity with its current usage, got %+v", h)
+	}
+
+	// CSIStorageCapacities that cannot be read are noted on each class that
+	// depends on them.
+	plan = planCapacity(vms, classes, longhorn, usage, map[string]int64{}, errors.New("forbidden"))
+	for _, h := range plan.StorageClasses {
+		noted := strings.Contains(strings.Join(h.Notes, "; "), "CSIStorageCapacities could not be read: forbidden")
+		if want := h.StorageClass == "ceph" || h.StorageClass == "nfs"; noted != want || (want && h.Fits != nil) {
+			t.Errorf("%s: expected the read failure noted only on CSI classes, got %+v", h.StorageClass, h)
+		}
+	}
+}
+
+func TestCapacityPlanHandlersWithVCSim(t *testing.T) {
+	env := newVCSimEnv(t, simulator.VPX(), "DC0")
+	ctx := context.Background()
+
+	env.registerListKinds(map[schema.GroupVersionResource]string{longhornNodeGVR: "NodeList"})
+	// node-2's disk is below Longhorn's minimal available percentage, so it
+	// holds data but takes no new replicas.
+	for _, node := range []*unstructured.Unstructured{newLonghornNode("node-1", 100), newLonghornNode("node-2", 10)} {
+		if _, err := env.clients.Dynamic.Resource(longhornNodeGVR).Namespace(longhornNamespace).Create(ctx, node, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	setting := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "longhorn.io/v1beta2",
+		"kind":       "Setting",
+		"metadata":   map[string]interface{}{"name": "storage-over-provisioning-percentage", "namespace": longhornNamespace},
+		"value":      "200",
+	}}
+	if _, err := env.clients.Dynamic.Resource(longhornSettingGVR).Namespace(longhornNamespace).Create(ctx, setting, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	sc := newStorageClass("harvester-longhorn", longhornProvisioner, true, "numberOfReplicas", "3")
+	if _, err := env.clients.Clientset.StorageV1().StorageClasses().Create(ctx, &sc, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	pv := &corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pvc-1"},
+		Spec: corev1.PersistentVolumeSpec{
+			StorageClassName: "harvester-longhorn",
+			Capacity:         corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("50Gi")},
+		},
+		Status: corev1.PersistentVolumeStatus{Phase: corev1.VolumeBound},
+	}
+	if _, err := env.clients.Clientset.CoreV1().PersistentVolumes().Create(ctx, pv, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	rr := executeRequest(HandleGetForkliftCapacityPlan(env.clients), "GET", "/api/v1/forklift/storage/capacity?vm=DC0_H0_VM0", nil, env.forklift)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var plan CapacityPlan
+	if err := json.Unmarshal(rr.Body.Bytes(), &plan); err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.VMs) != 1 || plan.ProvisionedBytes == 0 || plan.CommittedBytes == 0 || plan.CommittedBytes > plan.ProvisionedBytes {
+		t.Errorf("expected DC0_H0_VM0's provisioned and committed sizes, got %+v", plan.VMs)
+	}
+
+	// node-1: (201 GiB max - 1 GiB reserved) * 200% = 400 GiB schedulable.
+	lh := plan.Longhorn
+	if lh == nil || lh.Nodes != 2 || lh.SchedulableNodes != 2 || lh.AvailableBytes != 110*gib || lh.SchedulableBytes != 400*gib || lh.OverProvisioningPercentage != 200 {
+		t.Fatalf("unexpected Longhorn capacity: %+v", lh)
+	}
+	h := plan.StorageClasses[0]
+	if h.UsedBytes != 50*gib || h.Volumes != 1 || *h.SchedulableBytes != 400*gib/3 || h.Fits == nil || !*h.Fits {
+		t.Errorf("unexpected headroom: %+v", h)
+	}
+	if !strings.Contains(strings.Join(h.Notes, "; "), "degraded") {
+		t.Errorf("expected 3 replicas on 2 nodes to be flagged, got %v", h.Notes)
+	}
+
+	rr = executeRequest(HandleGetVCenterCapacityPlan(env.clients), "GET", "/api/v1/vcenter/storage/capacity", nil, env.source)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without ?vm=, got %d", rr.Code)
+	}
+}
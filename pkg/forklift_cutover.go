@@ -0,0 +1,227 @@
+// pkg/forklift_cutover.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// autoCutoverAnnotation on a warm Plan holds the number of successful
+// precopies every VM needs before the backend cuts the migration over.
+const autoCutoverAnnotation = "vm-import-ui.harvesterhci.io/auto-cutover-after"
+
+// ForkliftPrecopy is one precopy iteration of a warm migration VM.
+type ForkliftPrecopy struct {
+	Start           *metav1.Time `json:"start,omitempty"`
+	End             *metav1.Time `json:"end,omitempty"`
+	DurationSeconds int64        `json:"durationSeconds,omitempty"`
+	Disks           int          `json:"disks"` // disks with a delta in this iteration
+}
+
+// ForkliftWarmVM is the precopy history of one VM of a warm migration.
+type ForkliftWarmVM struct {
+	ID                  string            `json:"id,omitempty"`
+	Name                string            `json:"name,omitempty"`
+	Phase               string            `json:"phase,omitempty"`
+	Successes           int64             `json:"successes"`
+	Failures            int64             `json:"failures"`
+	ConsecutiveFailures int64             `json:"consecutiveFailures"`
+	NextPrecopyAt       *metav1.Time      `json:"nextPrecopyAt,omitempty"`
+	Precopies           []ForkliftPrecopy `json:"precopies"`
+}
+
+// ForkliftCutoverStatus is the cutover state of a warm Plan and its latest
+// Migration, if any.
+type ForkliftCutoverStatus struct {
+	Warm             bool             `json:"warm"`
+	Migration        string           `json:"migration,omitempty"`
+	Completed        bool             `json:"completed"`
+	Cutover          *metav1.Time     `json:"cutover,omitempty"`
+	AutoCutoverAfter int              `json:"autoCutoverAfter,omitempty"`
+	VMs              []ForkliftWarmVM `json:"vms"`
+}
+
+// SetForkliftCutoverPayload sets the cutover of a warm Plan's Migration to
+// Cutover or, with Now, to the current time. AutoCutoverAfter, when given,
+// replaces the number of successful precopies after which the backend cuts
+// over by itself; 0 turns that off.
+type SetForkliftCutoverPayload struct {
+	Cutover          *metav1.Time `json:"cutover,omitempty"`
+	Now              bool         `json:"now,omitempty"`
+	AutoCutoverAfter *int         `json:"autoCutoverAfter,omitempty"`
+}
+
+// autoCutoverAfter returns the Plan's auto-cutover threshold, 0 when unset.
+func autoCutoverAfter(plan *unstructured.Unstructured) int {
+	n, err := strconv.Atoi(plan.GetAnnotations()[autoCutoverAnnotation])
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// saveAutoCutoverAfter stores the threshold on the Plan, removing it for 0.
+func saveAutoCutoverAfter(ctx context.Context, clients *K8sClients, namespace, name string, n int) error {
+	var value interface{}
+	if n > 0 {
+		value = strconv.Itoa(n)
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"annotations": map[string]interface{}{autoCutoverAnnotation: value}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = clients.Dynamic.Resource(forkliftPlanGVR).Namespace(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// forkliftMigrationCompleted reports whether Forklift is done with a
+// Migration, so its cutover no longer matters.
+func forkliftMigrationCompleted(migration *unstructured.Unstructured) bool {
+	conditions, _, _ := unstructured.NestedSlice(migration.Object, "status", "conditions")
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if !ok || cond["status"] != "True" {
+			continue
+		}
+		switch cond["type"] {
+		case "Succeeded", "Failed", "Canceled":
+			return true
+		}
+	}
+	return false
+}
+
+// parseTime reads an RFC 3339 timestamp from a status field.
+func parseTime(obj map[string]interface{}, fields ...string) *metav1.Time {
+	s, _, _ := unstructured.NestedString(obj, fields...)
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil
+	}
+	return &metav1.Time{Time: t}
+}
+
+// forkliftWarmVMs reads the precopy history of each VM from a Migration's
+// status.vms[].warm.
+func forkliftWarmVMs(migration *unstructured.Unstructured) []ForkliftWarmVM {
+	vms, _, _ := unstructured.NestedSlice(migration.Object, "status", "vms")
+	result := []ForkliftWarmVM{}
+	for _, v := range vms {
+		vm, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		warm := ForkliftWarmVM{Precopies: []ForkliftPrecopy{}}
+		warm.ID, _, _ = unstructured.NestedString(vm, "id")
+		warm.Name, _, _ = unstructured.NestedString(vm, "name")
+		warm.Phase, _, _ = unstructured.NestedString(vm, "phase")
+		warm.Successes, _, _ = unstructured.NestedInt64(vm, "warm", "successes")
+		warm.Failures, _, _ = unstructured.NestedInt64(vm, "warm", "failures")
+		warm.ConsecutiveFailures, _, _ = unstructured.NestedInt64(vm, "warm", "consecutiveFailures")
+		warm.NextPrecopyAt = parseTime(vm, "warm", "nextPrecopyAt")
+
+		precopies, _, _ := unstructured.NestedSlice(vm, "warm", "precopies")
+		for _, p := range precopies {
+			precopy, ok := p.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			deltas, _, _ := unstructured.NestedSlice(precopy, "deltas")
+			entry := ForkliftPrecopy{Start: parseTime(precopy, "start"), End: parseTime(precopy, "end"), Disks: len(deltas)}
+			if entry.Start != nil && entry.End != nil {
+				entry.DurationSeconds = int64(entry.End.Sub(entry.Start.Time).Seconds())
+			}
+			warm.Precopies = append(warm.Precopies, entry)
+		}
+		result = append(result, warm)
+	}
+	return result
+}
+
+// forkliftCutoverStatus reports the cutover state of a Plan and its latest
+// Migration, which may be nil.
+func forkliftCutoverStatus(plan, migration *unstructured.Unstructured) ForkliftCutoverStatus {
+	warm, _, _ := unstructured.NestedBool(plan.Object, "spec", "warm")
+	status := ForkliftCutoverStatus{Warm: warm, AutoCutoverAfter: autoCutoverAfter(plan), VMs: []ForkliftWarmVM{}}
+	if migration != nil {
+		status.Migration = migration.GetName()
+		status.Completed = forkliftMigrationCompleted(migration)
+		status.Cutover = parseTime(migration.Object, "spec", "cutover")
+		status.VMs = forkliftWarmVMs(migration)
+	}
+	return status
+}
+
+// precopiesDone reports whether every VM of a Migration has at least n
+// successful precopies.
+func precopiesDone(migration *unstructured.Unstructured, n int) bool {
+	vms := forkliftWarmVMs(migration)
+	if len(vms) == 0 {
+		return false
+	}
+	for _, vm := range vms {
+		if vm.Successes < int64(n) {
+			return false
+		}
+	}
+	return true
+}
+
+// cutoverTime is when a cutover requested for now may happen: now, or when
+// the Plan's maintenance window next opens.
+func cutoverTime(plan *unstructured.Unstructured, now time.Time) (time.Time, error) {
+	schedule, err := forkliftScheduleOf(plan)
+	if err != nil || schedule == nil || schedule.Window == nil {
+		return now, err
+	}
+	w, err := parseMaintenanceWindow(*schedule.Window)
+	if err != nil {
+		return now, fmt.Errorf("plan %s has an invalid maintenance window: %w", plan.GetName(), err)
+	}
+	return w.next(now), nil
+}
+
+// runAutoCutovers cuts over the warm migrations whose VMs have all reached
+// their Plan's precopy threshold. Outside the Plan's maintenance window the
+// cutover is set to the window's next opening instead.
+func runAutoCutovers(ctx context.Context, clients *K8sClients, now time.Time) {
+	plans, err := clients.listResources(ctx, forkliftPlanGVR, "", metav1.ListOptions{})
+	if err != nil {
+		log.Warnf("Could not list Forklift plans for automatic cutover: %v", err)
+		return
+	}
+	for i := range plans.Items {
+		plan := &plans.Items[i]
+		n := autoCutoverAfter(plan)
+		if warm, _, _ := unstructured.NestedBool(plan.Object, "spec", "warm"); n == 0 || !warm {
+			continue
+		}
+		migration, err := latestForkliftMigration(ctx, clients, plan.GetNamespace(), plan.GetName())
+		if err != nil {
+			log.Warnf("Could not look up the Migration of Forklift plan %s/%s: %v", plan.GetNamespace(), plan.GetName(), err)
+			continue
+		}
+		if migration == nil || forkliftMigrationCompleted(migration) || parseTime(migration.Object, "spec", "cutover") != nil || !precopiesDone(migration, n) {
+			continue
+		}
+		at, err := cutoverTime(plan, now)
+		if err != nil {
+			log.Warn(err)
+			continue
+		}
+		log.Infof("Forklift plan %s/%s finished %d precopies; cutting over at %s", plan.GetNamespace(), plan.GetName(), n, at.UTC().Format(time.RFC3339))
+		if err := setForkliftCutover(ctx, clients, plan.GetNamespace(), migration.GetName(), &at); err != nil {
+			log.Warnf("Could not set the cutover of Migration %s/%s: %v", plan.GetNamespace(), migration.GetName(), err)
+		}
+	}
+}
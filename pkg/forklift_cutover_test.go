@@ -0,0 +1,186 @@
+// pkg/forklift_cutover_test.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// newWarmMigration returns a Migration of plan db whose VMs have the given
+// numbers of successful precopies.
+func newWarmMigration(successes ...int64) *unstructured.Unstructured {
+	var vms []interface{}
+	for i, n := range successes {
+		var precopies []interface{}
+		for j := int64(0); j < n; j++ {
+			start := time.Date(2026, time.October, 17, 10, int(j)*10, 0, 0, time.UTC)
+			precopies = append(precopies, map[string]interface{}{
+				"start":  start.Format(time.RFC3339),
+				"end":    start.Add(3 * time.Minute).Format(time.RFC3339),
+				"deltas": []interface{}{map[string]interface{}{"disk": "[ds1] vm/vm.vmdk", "deltaId": "52 1c"}},
+			})
+		}
+		vms = append(vms, map[string]interface{}{
+			"id":    fmt.Sprintf("vm-%d", i+1),
+			"name":  fmt.Sprintf("db-%d", i+1),
+			"phase": "CopyDisks",
+			"warm": map[string]interface{}{
+				"successes":     n,
+				"failures":      int64(1),
+				"nextPrecopyAt": "2026-10-17T11:00:00Z",
+				"precopies":     precopies,
+			},
+		})
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "forklift.konveyor.io/v1beta1",
+		"kind":       "Migration",
+		"metadata":   map[string]interface{}{"name": "db-migration", "namespace": "vms"},
+		"spec":       map[string]interface{}{"plan": map[string]interface{}{"name": "db", "namespace": "vms"}},
+		"status":     map[string]interface{}{"vms": vms},
+	}}
+}
+
+func TestForkliftWarmVMs(t *testing.T) {
+	vms := forkliftWarmVMs(newWarmMigration(2, 3))
+	if len(vms) != 2 || vms[0].Name != "db-1" || vms[0].Successes != 2 || vms[0].Failures != 1 || vms[0].NextPrecopyAt == nil {
+		t.Fatalf("unexpected warm VMs: %+v", vms)
+	}
+	if p := vms[1].Precopies; len(p) != 3 || p[2].DurationSeconds != 180 || p[2].Disks != 1 {
+		t.Errorf("unexpected precopy history: %+v", p)
+	}
+	if precopiesDone(newWarmMigration(2, 3), 3) || !precopiesDone(newWarmMigration(3, 4), 3) || precopiesDone(newWarmMigration(), 1) {
+		t.Error("expected a threshold to need every VM, and at least one VM")
+	}
+}
+
+func TestForkliftCutoverHandlers(t *testing.T) {
+	plan := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "forklift.konveyor.io/v1beta1",
+		"kind":       "Plan",
+		"metadata":   map[string]interface{}{"name": "db", "namespace": "vms"},
+		"spec":       map[string]interface{}{"warm": true},
+	}}
+	cold := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "forklift.konveyor.io/v1beta1",
+		"kind":       "Plan",
+		"metadata":   map[string]interface{}{"name": "files", "namespace": "vms"},
+	}}
+	clients := newTestClientsWithDynamic(nil, plan, cold, newWarmMigration(2, 3))
+	ctx := context.Background()
+	vars := map[string]string{"namespace": "vms", "name": "db"}
+	path := "/api/v1/forklift/plans/vms/db/cutover"
+
+	put := func(t *testing.T, payload SetForkliftCutoverPayload, want int) ForkliftCutoverStatus {
+		t.Helper()
+		rr := executeRequest(SetForkliftCutoverHandler(clients), "PUT", path, payload, vars)
+		if rr.Code != want {
+			t.Fatalf("expected %d, got %d: %s", want, rr.Code, rr.Body.String())
+		}
+		var status ForkliftCutoverStatus
+		json.Unmarshal(rr.Body.Bytes(), &status)
+		return status
+	}
+	migrationCutover := func() string {
+		m, err := clients.Dynamic.Resource(forkliftMigrationGVR).Namespace("vms").Get(ctx, "db-migration", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		cutover, _, _ := unstructured.NestedString(m.Object, "spec", "cutover")
+		return cutover
+	}
+
+	rr := executeRequest(GetForkliftCutoverHandler(clients), "GET", path, nil, vars)
+	var status ForkliftCutoverStatus
+	if err := json.Unmarshal(rr.Body.Bytes(), &status); err != nil {
+		t.Fatal(err)
+	}
+	if !status.Warm || status.Migration != "db-migration" || status.Cutover != nil || len(status.VMs) != 2 || len(status.VMs[1].Precopies) != 3 {
+		t.Fatalf("unexpected cutover status: %+v", status)
+	}
+
+	// Auto-cutover waits until every VM has three precopies.
+	three := 3
+	if status := put(t, SetForkliftCutoverPayload{AutoCutoverAfter: &three}, http.StatusOK); status.AutoCutoverAfter != 3 || status.Cutover != nil {
+		t.Fatalf("expected only the threshold set, got %+v", status)
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	runAutoCutovers(ctx, clients, now)
+	if got := migrationCutover(); got != "" {
+		t.Fatalf("expected no cutover after 2 of 3 precopies, got %q", got)
+	}
+	if _, err := clients.Dynamic.Resource(forkliftMigrationGVR).Namespace("vms").Update(ctx, newWarmMigration(3, 4), metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	runAutoCutovers(ctx, clients, now)
+	if got := migrationCutover(); got != now.Format(time.RFC3339) {
+		t.Fatalf("expected a cutover once every VM had 3 precopies, got %q", got)
+	}
+
+	rr = executeRequest(ClearForkliftCutoverHandler(clients), "DELETE", path, nil, vars)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	json.Unmarshal(rr.Body.Bytes(), &status)
+	if migrationCutover() != "" || status.AutoCutoverAfter != 0 {
+		t.Errorf("expected the cutover and the threshold cleared, got %+v", status)
+	}
+
+	later := metav1.NewTime(now.Add(2 * time.Hour))
+	put(t, SetForkliftCutoverPayload{Cutover: &later}, http.StatusOK)
+	if got := migrationCutover(); got != later.UTC().Format(time.RFC3339) {
+		t.Errorf("expected the cutover at %s, got %q", later, got)
+	}
+	if status := put(t, SetForkliftCutoverPayload{Now: true}, http.StatusOK); status.Cutover == nil || status.Cutover.After(time.Now()) {
+		t.Errorf("expected the cutover moved to now, got %+v", status.Cutover)
+	}
+
+	past := metav1.NewTime(now.Add(-time.Hour))
+	put(t, SetForkliftCutoverPayload{Cutover: &past}, http.StatusBadRequest)
+	put(t, SetForkliftCutoverPayload{}, http.StatusBadRequest)
+	put(t, SetForkliftCutoverPayload{Now: true, Cutover: &later}, http.StatusBadRequest)
+
+	// A cutover outside the maintenance window is refused, and an automatic
+	// one waits for the window to open.
+	open := now.Add(3 * time.Hour).Truncate(time.Minute)
+	schedule := &ForkliftSchedule{Window: &MaintenanceWindow{Start: open.Format("15:04"), Duration: "1h"}, Cutover: &metav1.Time{Time: open.Add(time.Minute)}}
+	if err := saveForkliftSchedule(ctx, clients, "vms", "db", schedule); err != nil {
+		t.Fatal(err)
+	}
+	put(t, SetForkliftCutoverPayload{Now: true}, http.StatusConflict)
+	inWindow := metav1.NewTime(open.Add(10 * time.Minute))
+	put(t, SetForkliftCutoverPayload{Cutover: &inWindow}, http.StatusOK)
+	updated, _ := clients.Dynamic.Resource(forkliftPlanGVR).Namespace("vms").Get(ctx, "db", metav1.GetOptions{})
+	if s, _ := forkliftScheduleOf(updated); s == nil || !s.Cutover.Time.Equal(inWindow.Time) {
+		t.Errorf("expected the scheduled cutover kept in step, got %+v", s)
+	}
+	executeRequest(ClearForkliftCutoverHandler(clients), "DELETE", path, nil, vars)
+	put(t, SetForkliftCutoverPayload{AutoCutoverAfter: &three}, http.StatusOK)
+	runAutoCutovers(ctx, clients, now)
+	if got := migrationCutover(); got != open.Format(time.RFC3339) {
+		t.Errorf("expected the automatic cutover at the window's opening %s, got %q", open.Format(time.RFC3339), got)
+	}
+
+	coldVars := map[string]string{"namespace": "vms", "name": "files"}
+	if rr := executeRequest(SetForkliftCutoverHandler(clients), "PUT", "/api/v1/forklift/plans/vms/files/cutover", SetForkliftCutoverPayload{Now: true}, coldVars); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected a cold plan to be refused, got %d", rr.Code)
+	}
+
+	done := newWarmMigration(3, 4)
+	unstructured.SetNestedSlice(done.Object, []interface{}{map[string]interface{}{"type": "Succeeded", "status": "True"}}, "status", "conditions")
+	if _, err := clients.Dynamic.Resource(forkliftMigrationGVR).Namespace("vms").Update(ctx, done, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	put(t, SetForkliftCutoverPayload{Cutover: &inWindow}, http.StatusConflict)
+	if err := clients.Dynamic.Resource(forkliftMigrationGVR).Namespace("vms").Delete(ctx, "db-migration", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	put(t, SetForkliftCutoverPayload{Cutover: &inWindow}, http.StatusNotFound)
+}
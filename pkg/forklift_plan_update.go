@@ -0,0 +1,350 @@
+// pkg/forklift_plan_update.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ForkliftPlanBusyError refuses to edit a Plan, or a map it shares, while
+// Forklift is working on it.
+type ForkliftPlanBusyError struct {
+	Plan, Reason string
+}
+
+func (e *ForkliftPlanBusyError) Error() string {
+	return fmt.Sprintf("plan %s cannot be edited: %s", e.Plan, e.Reason)
+}
+
+// ForkliftPlanUpdateError reports an invalid Forklift Plan update.
+type ForkliftPlanUpdateError struct {
+	Message string
+}
+
+func (e *ForkliftPlanUpdateError) Error() string { return e.Message }
+
+func invalidForkliftUpdate(format string, args ...interface{}) error {
+	return &ForkliftPlanUpdateError{Message: fmt.Sprintf(format, args...)}
+}
+
+// forkliftPlanBusy returns why Forklift would not take changes to a Plan
+// now, or "" if it would: it is archived, executing, or has a Migration that
+// has not finished.
+func forkliftPlanBusy(ctx context.Context, clients *K8sClients, plan *unstructured.Unstructured) (string, error) {
+	if archived, _, _ := unstructured.NestedBool(plan.Object, "spec", "archived"); archived {
+		return "it is archived", nil
+	}
+	conditions, _, _ := unstructured.NestedSlice(plan.Object, "status", "conditions")
+	for _, c := range conditions {
+		if cond, ok := c.(map[string]interface{}); ok && cond["type"] == "Executing" && cond["status"] == "True" {
+			return "a migration is running", nil
+		}
+	}
+	migration, err := latestForkliftMigration(ctx, clients, plan.GetNamespace(), plan.GetName())
+	if err != nil {
+		return "", err
+	}
+	if migration != nil && !forkliftMigrationCompleted(migration) {
+		return fmt.Sprintf("Migration %s has not finished; cancel or delete it first", migration.GetName()), nil
+	}
+	return "", nil
+}
+
+// forkliftMapBusy returns why a map used by plan cannot change: another Plan
+// that uses it too is busy.
+func forkliftMapBusy(ctx context.Context, clients *K8sClients, plan *unstructured.Unstructured, kind, namespace, name string) (string, error) {
+	plans, err := clients.Dynamic.Resource(forkliftPlanGVR).Namespace(plan.GetNamespace()).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", err
+	}
+	for i := range plans.Items {
+		other := &plans.Items[i]
+		if other.GetName() == plan.GetName() {
+			continue
+		}
+		ref, _, _ := unstructured.NestedStringMap(other.Object, "spec", "map", kind)
+		if ref["name"] != name || ref["namespace"] != namespace {
+			continue
+		}
+		reason, err := forkliftPlanBusy(ctx, clients, other)
+		if err != nil {
+			return "", err
+		}
+		if reason != "" {
+			return fmt.Sprintf("its %s map %s is shared with plan %s, where %s", kind, name, other.GetName(), reason), nil
+		}
+	}
+	return "", nil
+}
+
+// checkForkliftPlanUpdate validates the parts of an update that need no
+// cluster lookups.
+func checkForkliftPlanUpdate(payload UpdateForkliftPlanPayload, providerType string) error {
+	if payload.TargetNamespace != nil && *payload.TargetNamespace == "" {
+		return invalidForkliftUpdate("targetNamespace cannot be empty")
+	}
+	if payload.VMs != nil {
+		if len(*payload.VMs) == 0 {
+			return invalidForkliftUpdate("A plan needs at least one VM")
+		}
+		ids, targets := map[string]bool{}, map[string]bool{}
+		for _, vm := range *payload.VMs {
+			if vm.ID == "" && vm.Name == "" {
+				return invalidForkliftUpdate("Every VM needs an id or a name")
+			}
+			ref := vm.ID + "/" + vm.Name
+			if ids[ref] {
+				return invalidForkliftUpdate("VM %s is listed more than once", vm.Name)
+			}
+			ids[ref] = true
+			if vm.TargetName == "" {
+				continue
+			}
+			if len(vm.TargetName) > 63 || !rfc1123Label.MatchString(vm.TargetName) {
+				return invalidForkliftUpdate("Target name %q of VM %s is not a valid RFC-1123 name", vm.TargetName, vm.Name)
+			}
+			if targets[vm.TargetName] {
+				return invalidForkliftUpdate("More than one VM in the plan targets the name %s", vm.TargetName)
+			}
+			targets[vm.TargetName] = true
+		}
+	}
+	if payload.NetworkMappings != nil {
+		podNetworks := 0
+		for _, nm := range *payload.NetworkMappings {
+			switch {
+			case nm.DestinationType == "pod":
+				podNetworks++
+			case nm.DestinationType != "multus":
+				return invalidForkliftUpdate("Destination type %q of source network %s is neither pod nor multus", nm.DestinationType, nm.SourceID)
+			case nm.DestinationName == "":
+				return invalidForkliftUpdate("Source network %s has no destination network", nm.SourceID)
+			}
+		}
+		if podNetworks > 1 {
+			return invalidForkliftUpdate("%d source networks are mapped to the pod network; Forklift allows only one", podNetworks)
+		}
+	}
+	if payload.StorageMappings != nil {
+		for _, sm := range *payload.StorageMappings {
+			if sm.DestinationStorageClass == "" {
+				return invalidForkliftUpdate("Source %s%s has no destination storage class", sm.SourceID, sm.SourceName)
+			}
+		}
+	}
+	if payload.Warm != nil && *payload.Warm && providerType == "ova" {
+		return invalidForkliftUpdate("OVA plans cannot use warm migration")
+	}
+	return nil
+}
+
+// forkliftRollbackTimeout bounds putting maps back after a failed Plan
+// update, which runs even if the request has been cancelled.
+const forkliftRollbackTimeout = 30 * time.Second
+
+// forkliftMapEdit is a pending change to the spec.map of a NetworkMap or
+// StorageMap, with what to restore if the Plan update fails.
+type forkliftMapEdit struct {
+	gvr             schema.GroupVersionResource
+	namespace, name string
+	entries         []interface{}
+	read            bool          // previous holds the map as it was
+	previous        []interface{} // nil if the map had no spec.map
+}
+
+// apply sets the map's spec.map to entries, or removes it for nil entries.
+func (e *forkliftMapEdit) apply(ctx context.Context, clients *K8sClients, entries []interface{}) error {
+	obj, err := clients.Dynamic.Resource(e.gvr).Namespace(e.namespace).Get(ctx, e.name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if !e.read {
+		e.previous, _, _ = unstructured.NestedSlice(obj.Object, "spec", "map")
+		e.read = true
+	}
+	if entries == nil {
+		unstructured.RemoveNestedField(obj.Object, "spec", "map")
+	} else if err := unstructured.SetNestedSlice(obj.Object, entries, "spec", "map"); err != nil {
+		return err
+	}
+	_, err = clients.Dynamic.Resource(e.gvr).Namespace(e.namespace).Update(ctx, obj, metav1.UpdateOptions{})
+	return err
+}
+
+// restore puts the map back as it was before apply, if apply got to read it.
+func (e *forkliftMapEdit) restore(clients *K8sClients) {
+	if !e.read {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), forkliftRollbackTimeout)
+	defer cancel()
+	if err := e.apply(ctx, clients, e.previous); err != nil {
+		log.Warnf("Best-effort rollback: failed to restore %s/%s: %v", e.namespace, e.name, err)
+	}
+}
+
+// updateForkliftPlan applies an update to a Plan and the maps it references.
+// The maps are changed first and put back if the Plan cannot be updated.
+func updateForkliftPlan(ctx context.Context, clients *K8sClients, namespace, name string, payload UpdateForkliftPlanPayload) (*unstructured.Unstructured, error) {
+	plan, err := clients.Dynamic.Resource(forkliftPlanGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	reason, err := forkliftPlanBusy(ctx, clients, plan)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check the plan's migrations: %w", err)
+	}
+	if reason != "" {
+		return nil, &ForkliftPlanBusyError{Plan: name, Reason: reason}
+	}
+
+	providerType := "vsphere"
+	source, _, _ := unstructured.NestedStringMap(plan.Object, "spec", "provider", "source")
+	if provider, err := clients.Dynamic.Resource(forkliftProviderGVR).Namespace(source["namespace"]).Get(ctx, source["name"], metav1.GetOptions{}); err == nil {
+		if t, _, _ := unstructured.NestedString(provider.Object, "spec", "type"); t != "" {
+			providerType = t
+		}
+	} else {
+		log.Warnf("Could not read the source provider of Forklift plan %s/%s, assuming vSphere: %v", namespace, name, err)
+	}
+	if err := checkForkliftPlanUpdate(payload, providerType); err != nil {
+		return nil, err
+	}
+	if payload.TargetNamespace != nil {
+		if _, err := clients.Clientset.CoreV1().Namespaces().Get(ctx, *payload.TargetNamespace, metav1.GetOptions{}); err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, invalidForkliftUpdate("Namespace %s does not exist", *payload.TargetNamespace)
+			}
+			return nil, err
+		}
+	}
+
+	// Refuse a StorageMap that misses datastores the VM's disks live on, as
+	// on create.
+	annotations := plan.GetAnnotations()
+	disks := annotations["migration.harvesterhci.io/original-disks"]
+	if payload.SourceVmDisks != nil {
+		disks = *payload.SourceVmDisks
+	}
+	if providerType != "ova" && (payload.StorageMappings != nil || payload.SourceVmDisks != nil) {
+		mappings := payload.StorageMappings
+		if mappings == nil {
+			mappings = &[]ForkliftStorageMapEntry{}
+			storageRef, _, _ := unstructured.NestedStringMap(plan.Object, "spec", "map", "storage")
+			if sm, err := clients.Dynamic.Resource(forkliftStorageMapGVR).Namespace(storageRef["namespace"]).Get(ctx, storageRef["name"], metav1.GetOptions{}); err == nil {
+				entries, _, _ := unstructured.NestedSlice(sm.Object, "spec", "map")
+				for _, e := range entries {
+					if entry, ok := e.(map[string]interface{}); ok {
+						id, _, _ := unstructured.NestedString(entry, "source", "id")
+						*mappings = append(*mappings, ForkliftStorageMapEntry{SourceID: id})
+					}
+				}
+			}
+		}
+		if missing := unmappedDatastores(disks, *mappings); len(missing) > 0 {
+			return nil, invalidForkliftUpdate("No storage mapping for datastore(s) used by the VM's disks: %s", strings.Join(missing, ", "))
+		}
+	}
+
+	var edits []*forkliftMapEdit
+	for _, m := range []struct {
+		kind    string
+		gvr     schema.GroupVersionResource
+		entries []interface{}
+	}{
+		{"network", forkliftNetworkMapGVR, func() []interface{} {
+			if payload.NetworkMappings == nil {
+				return nil
+			}
+			return forkliftNetworkMapEntries(*payload.NetworkMappings)
+		}()},
+		{"storage", forkliftStorageMapGVR, func() []interface{} {
+			if payload.StorageMappings == nil {
+				return nil
+			}
+			return forkliftStorageMapEntries(*payload.StorageMappings, providerType)
+		}()},
+	} {
+		if m.entries == nil {
+			continue
+		}
+		ref, _, _ := unstructured.NestedStringMap(plan.Object, "spec", "map", m.kind)
+		if ref["name"] == "" {
+			return nil, invalidForkliftUpdate("The plan references no %s map", m.kind)
+		}
+		reason, err := forkliftMapBusy(ctx, clients, plan, m.kind, ref["namespace"], ref["name"])
+		if err != nil {
+			return nil, fmt.Errorf("failed to check the plans sharing %s map %s: %w", m.kind, ref["name"], err)
+		}
+		if reason != "" {
+			return nil, &ForkliftPlanBusyError{Plan: name, Reason: reason}
+		}
+		edits = append(edits, &forkliftMapEdit{gvr: m.gvr, namespace: ref["namespace"], name: ref["name"], entries: m.entries})
+	}
+
+	setBool := func(val *bool, field string) {
+		if val != nil {
+			unstructured.SetNestedField(plan.Object, *val, "spec", field)
+		}
+	}
+	if payload.TargetNamespace != nil {
+		unstructured.SetNestedField(plan.Object, *payload.TargetNamespace, "spec", "targetNamespace")
+	}
+	if payload.VMs != nil {
+		unstructured.SetNestedSlice(plan.Object, forkliftVMEntries(*payload.VMs), "spec", "vms")
+	}
+	setBool(payload.Warm, "warm")
+	setBool(payload.MigrateSharedDisks, "migrateSharedDisks")
+	setBool(payload.PreserveClusterCpuModel, "preserveClusterCpuModel")
+	setBool(payload.PreserveStaticIPs, "preserveStaticIPs")
+
+	// Annotations, set or removed the same way create sets them.
+	setAnnotation := func(key string, set bool, value string) {
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		if set {
+			annotations[key] = value
+		} else {
+			delete(annotations, key)
+		}
+	}
+	if payload.PopulatorLabels != nil {
+		setAnnotation("populatorLabels", *payload.PopulatorLabels, "True")
+	}
+	if payload.DefaultNetworkInterfaceModel != nil {
+		setAnnotation("migration.harvesterhci.io/default-nic-model", *payload.DefaultNetworkInterfaceModel != "", *payload.DefaultNetworkInterfaceModel)
+	}
+	if payload.SourceVmDisks != nil {
+		setAnnotation("migration.harvesterhci.io/original-disks", *payload.SourceVmDisks != "", *payload.SourceVmDisks)
+	}
+	plan.SetAnnotations(annotations)
+
+	rollback := func() {
+		for _, e := range edits {
+			e.restore(clients)
+		}
+	}
+	for _, e := range edits {
+		log.Infof("Updating Forklift %s %s/%s", e.gvr.Resource, e.namespace, e.name)
+		if err := e.apply(ctx, clients, e.entries); err != nil {
+			rollback()
+			return nil, fmt.Errorf("failed to update %s %s: %w", e.gvr.Resource, e.name, err)
+		}
+	}
+	log.Infof("Updating Forklift Plan %s/%s", namespace, name)
+	updated, err := clients.Dynamic.Resource(forkliftPlanGVR).Namespace(namespace).Update(ctx, plan, metav1.UpdateOptions{})
+	if err != nil {
+		rollback()
+		return nil, err
+	}
+	return updated, nil
+}
@@ -0,0 +1,223 @@
+// pkg/forklift_plan_update_test.go
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// newForkliftObject returns a forklift.konveyor.io object in namespace forklift.
+func newForkliftObject(kind, name string, spec map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "forklift.konveyor.io/v1beta1",
+		"kind":       kind,
+		"metadata":   map[string]interface{}{"name": name, "namespace": "forklift"},
+		"spec":       spec,
+	}}
+}
+
+func newUpdatableForkliftPlan(name, storageMap string) *unstructured.Unstructured {
+	ref := func(kind, name string) map[string]interface{} {
+		return map[string]interface{}{"apiVersion": "forklift.konveyor.io/v1beta1", "kind": kind, "name": name, "namespace": "forklift"}
+	}
+	plan := newForkliftObject("Plan", name, map[string]interface{}{
+		"map":             map[string]interface{}{"network": ref("NetworkMap", name+"-network-map"), "storage": ref("StorageMap", storageMap)},
+		"provider":        map[string]interface{}{"source": ref("Provider", "vc"), "destination": ref("Provider", "host")},
+		"targetNamespace": "vms",
+		"warm":            false,
+		"vms":             []interface{}{map[string]interface{}{"id": "vm-1", "name": "DB 01"}},
+	})
+	plan.SetAnnotations(map[string]string{
+		"migration.harvesterhci.io/original-disks": `[{"name":"disk-1","datastoreId":"datastore-1","datastoreName":"ds1"},{"name":"disk-2","datastoreId":"datastore-2","datastoreName":"ds2"}]`,
+	})
+	return plan
+}
+
+func TestUpdateForkliftPlanHandler(t *testing.T) {
+	storageEntries := forkliftStorageMapEntries([]ForkliftStorageMapEntry{
+		{SourceID: "datastore-1", DestinationStorageClass: "harvester-longhorn"},
+		{SourceID: "datastore-2", DestinationStorageClass: "harvester-longhorn"},
+	}, "vsphere")
+	shared := newUpdatableForkliftPlan("shared", "db-storage-map")
+	clients := newTestClientsWithDynamic(
+		[]runtime.Object{
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "vms"}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}},
+		},
+		newForkliftObject("Provider", "vc", map[string]interface{}{"type": "vsphere"}),
+		newUpdatableForkliftPlan("db", "db-storage-map"),
+		shared,
+		newForkliftObject("NetworkMap", "db-network-map", map[string]interface{}{"map": forkliftNetworkMapEntries([]ForkliftNetworkMapEntry{{SourceID: "network-1", DestinationType: "pod"}})}),
+		newForkliftObject("StorageMap", "db-storage-map", map[string]interface{}{"map": storageEntries}),
+		newForkliftObject("Migration", "old-migration", map[string]interface{}{"plan": map[string]interface{}{"name": "old", "namespace": "forklift"}}),
+	)
+	ctx := context.Background()
+	vars := map[string]string{"namespace": "forklift", "name": "db"}
+	path := "/api/v1/forklift/plans/forklift/db"
+	ptr := func(s string) *string { return &s }
+	yes := true
+
+	storageMap := func() []interface{} {
+		sm, err := clients.Dynamic.Resource(forkliftStorageMapGVR).Namespace("forklift").Get(ctx, "db-storage-map", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries, _, _ := unstructured.NestedSlice(sm.Object, "spec", "map")
+		return entries
+	}
+	storageClassOf := func(entries []interface{}, i int) string {
+		class, _, _ := unstructured.NestedString(entries[i].(map[string]interface{}), "destination", "storageClass")
+		return class
+	}
+
+	newMappings := &[]ForkliftStorageMapEntry{
+		{SourceID: "datastore-1", DestinationStorageClass: "harvester-longhorn"},
+		{SourceID: "datastore-2", DestinationStorageClass: "longhorn-ssd", VolumeMode: "Block"},
+	}
+	rr := executeRequest(UpdateForkliftPlanHandler(clients), "PUT", path, UpdateForkliftPlanPayload{
+		TargetNamespace:              ptr("apps"),
+		VMs:                          &[]ForkliftVMEntry{{ID: "vm-1", Name: "DB 01", TargetName: "db-01"}, {ID: "vm-2", Name: "DB 02", TargetName: "db-02"}},
+		StorageMappings:              newMappings,
+		Warm:                         &yes,
+		PreserveStaticIPs:            &yes,
+		PopulatorLabels:              &yes,
+		DefaultNetworkInterfaceModel: ptr("e1000"),
+	}, vars)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	plan, _ := clients.Dynamic.Resource(forkliftPlanGVR).Namespace("forklift").Get(ctx, "db", metav1.GetOptions{})
+	target, _, _ := unstructured.NestedString(plan.Object, "spec", "targetNamespace")
+	warm, _, _ := unstructured.NestedBool(plan.Object, "spec", "warm")
+	staticIPs, _, _ := unstructured.NestedBool(plan.Object, "spec", "preserveStaticIPs")
+	vms, _, _ := unstructured.NestedSlice(plan.Object, "spec", "vms")
+	if target != "apps" || !warm || !staticIPs || len(vms) != 2 || vms[1].(map[string]interface{})["targetName"] != "db-02" {
+		t.Errorf("unexpected plan spec: %v", plan.Object["spec"])
+	}
+	if a := plan.GetAnnotations(); a["populatorLabels"] != "True" || a["migration.harvesterhci.io/default-nic-model"] != "e1000" {
+		t.Errorf("unexpected plan annotations: %v", a)
+	}
+	if got := storageClassOf(storageMap(), 1); got != "longhorn-ssd" {
+		t.Errorf("expected datastore-2 remapped, got %s", got)
+	}
+
+	tests := []struct {
+		name    string
+		payload UpdateForkliftPlanPayload
+		want    string
+	}{
+		{"unmapped datastore", UpdateForkliftPlanPayload{StorageMappings: &[]ForkliftStorageMapEntry{{SourceID: "datastore-1", DestinationStorageClass: "harvester-longhorn"}}}, "ds2 (datastore-2)"},
+		{"missing namespace", UpdateForkliftPlanPayload{TargetNamespace: ptr("nope")}, "Namespace nope does not exist"},
+		{"bad target name", UpdateForkliftPlanPayload{VMs: &[]ForkliftVMEntry{{ID: "vm-1", Name: "DB 01", TargetName: "DB_01"}}}, "RFC-1123"},
+		{"no VMs", UpdateForkliftPlanPayload{VMs: &[]ForkliftVMEntry{}}, "at least one VM"},
+		{"two pod networks", UpdateForkliftPlanPayload{NetworkMappings: &[]ForkliftNetworkMapEntry{{SourceID: "network-1", DestinationType: "pod"}, {SourceID: "network-2", DestinationType: "pod"}}}, "only one"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := executeRequest(UpdateForkliftPlanHandler(clients), "PUT", path, tc.payload, vars)
+			if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), tc.want) {
+				t.Errorf("expected 400 mentioning %q, got %d: %s", tc.want, rr.Code, rr.Body.String())
+			}
+		})
+	}
+	if got := storageClassOf(storageMap(), 1); got != "longhorn-ssd" {
+		t.Errorf("expected rejected updates to leave the StorageMap alone, got %s", got)
+	}
+
+	// A failed Plan update puts the StorageMap back.
+	fakeDynamic := clients.Dynamic.(*dynamicfake.FakeDynamicClient)
+	fakeDynamic.PrependReactor("update", "plans", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("webhook denied the request")
+	})
+	rr = executeRequest(UpdateForkliftPlanHandler(clients), "PUT", path, UpdateForkliftPlanPayload{StorageMappings: &[]ForkliftStorageMapEntry{
+		{SourceID: "datastore-1", DestinationStorageClass: "longhorn-ssd"},
+		{SourceID: "datastore-2", DestinationStorageClass: "longhorn-ssd"},
+	}}, vars)
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if got := storageClassOf(storageMap(), 0); got != "harvester-longhorn" {
+		t.Errorf("expected the StorageMap rolled back, got %s", got)
+	}
+	fakeDynamic.ReactionChain = fakeDynamic.ReactionChain[1:]
+
+	// The StorageMap is shared with a plan that is migrating: it cannot
+	// change, but the plan's own options still can.
+	unstructured.SetNestedSlice(shared.Object, []interface{}{map[string]interface{}{"type": "Executing", "status": "True"}}, "status", "conditions")
+	if _, err := clients.Dynamic.Resource(forkliftPlanGVR).Namespace("forklift").Update(ctx, shared, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	rr = executeRequest(UpdateForkliftPlanHandler(clients), "PUT", path, UpdateForkliftPlanPayload{StorageMappings: newMappings}, vars)
+	if rr.Code != http.StatusConflict || !strings.Contains(rr.Body.String(), "shared with plan shared") {
+		t.Errorf("expected 409 for a shared map, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := executeRequest(UpdateForkliftPlanHandler(clients), "PUT", path, UpdateForkliftPlanPayload{PreserveStaticIPs: new(bool)}, vars); rr.Code != http.StatusOK {
+		t.Errorf("expected options to stay editable, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// A Migration in progress blocks every edit.
+	if _, err := clients.Dynamic.Resource(forkliftMigrationGVR).Namespace("forklift").Create(ctx,
+		newForkliftObject("Migration", "db-migration", map[string]interface{}{"plan": map[string]interface{}{"name": "db", "namespace": "forklift"}}), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	rr = executeRequest(UpdateForkliftPlanHandler(clients), "PUT", path, UpdateForkliftPlanPayload{Warm: new(bool)}, vars)
+	if rr.Code != http.StatusConflict || !strings.Contains(rr.Body.String(), "db-migration has not finished") {
+		t.Errorf("expected 409 while migrating, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	missing := map[string]string{"namespace": "forklift", "name": "nope"}
+	if rr := executeRequest(UpdateForkliftPlanHandler(clients), "PUT", "/api/v1/forklift/plans/forklift/nope", UpdateForkliftPlanPayload{Warm: new(bool)}, missing); rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rr.Code)
+	}
+}
+
+func TestForkliftMapEditRestore(t *testing.T) {
+	entries := []interface{}{map[string]interface{}{"source": map[string]interface{}{"id": "network-1"}, "destination": map[string]interface{}{"type": "pod"}}}
+	for _, tc := range []struct {
+		name string
+		spec map[string]interface{}
+	}{
+		{"empty map", map[string]interface{}{"map": []interface{}{}}},
+		{"no map", map[string]interface{}{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clients := newTestClientsWithDynamic(nil, newForkliftObject("NetworkMap", "db-network-map", tc.spec))
+			e := &forkliftMapEdit{gvr: forkliftNetworkMapGVR, namespace: "forklift", name: "db-network-map", entries: entries}
+
+			// A request cancelled after the edit still gets the map back.
+			ctx, cancel := context.WithCancel(context.Background())
+			if err := e.apply(ctx, clients, e.entries); err != nil {
+				t.Fatal(err)
+			}
+			cancel()
+			e.restore(clients)
+
+			obj, err := clients.Dynamic.Resource(forkliftNetworkMapGVR).Namespace("forklift").Get(context.Background(), "db-network-map", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, found, _ := unstructured.NestedSlice(obj.Object, "spec", "map")
+			_, want := tc.spec["map"]
+			if len(got) != 0 || found != want {
+				t.Errorf("expected spec.map restored as %v, got %v (found %v)", tc.spec["map"], got, found)
+			}
+		})
+	}
+
+	// A map that was never read is left alone.
+	clients := newTestClientsWithDynamic(nil, newForkliftObject("NetworkMap", "db-network-map", map[string]interface{}{"map": entries}))
+	(&forkliftMapEdit{gvr: forkliftNetworkMapGVR, namespace: "forklift", name: "db-network-map"}).restore(clients)
+	for _, action := range clients.Dynamic.(*dynamicfake.FakeDynamicClient).Actions() {
+		t.Errorf("expected no calls for an unread map, got %s", action.GetVerb())
+	}
+}
@@ -0,0 +1,363 @@
+// pkg/forklift_schedule.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	_ "time/tzdata" // maintenance windows name IANA zones; the image may ship none
+
+	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// forkliftScheduleAnnotation holds a Plan's ForkliftSchedule as JSON.
+	forkliftScheduleAnnotation = "vm-import-ui.harvesterhci.io/migration-schedule"
+
+	forkliftScheduleInterval = 30 * time.Second
+)
+
+// MaintenanceWindow is a recurring window, e.g. Saturdays and Sundays from
+// 22:00 for 6h in Europe/Berlin. A window may run past midnight but not past
+// the next opening.
+type MaintenanceWindow struct {
+	Days     []string `json:"days,omitempty"`     // Mon..Sun; every day when empty
+	Start    string   `json:"start"`              // HH:MM in TimeZone
+	Duration string   `json:"duration"`           // e.g. 4h or 90m, at most 24h
+	TimeZone string   `json:"timeZone,omitempty"` // IANA name; UTC when empty
+}
+
+// ForkliftSchedule is when the backend starts a Forklift Plan and, for warm
+// migrations, when it cuts over. With a Window, the Migration is only started
+// inside it, by the schedule or by hand; without a StartAt the schedule starts
+// the Plan when the window next opens.
+type ForkliftSchedule struct {
+	StartAt *metav1.Time       `json:"startAt,omitempty"`
+	Window  *MaintenanceWindow `json:"window,omitempty"`
+	Cutover *metav1.Time       `json:"cutover,omitempty"`
+
+	// Set by the scheduler.
+	StartedAt *metav1.Time `json:"startedAt,omitempty"`
+	Message   string       `json:"message,omitempty"`
+}
+
+// ForkliftScheduleStatus is a Plan's schedule as the API reports it.
+type ForkliftScheduleStatus struct {
+	Scheduled bool              `json:"scheduled"`
+	Schedule  *ForkliftSchedule `json:"schedule,omitempty"`
+	NextStart *metav1.Time      `json:"nextStart,omitempty"`
+	InWindow  bool              `json:"inWindow"`
+	// NextWindow is when the window next opens, or now while it is open.
+	NextWindow *metav1.Time `json:"nextWindow,omitempty"`
+}
+
+// MaintenanceWindowError refuses to start a Plan outside its window.
+type MaintenanceWindowError struct {
+	Plan     string
+	NextOpen time.Time
+}
+
+func (e *MaintenanceWindowError) Error() string {
+	return fmt.Sprintf("plan %s may only start inside its maintenance window, which next opens at %s", e.Plan, e.NextOpen.UTC().Format(time.RFC3339))
+}
+
+// maintenanceWindow is a parsed MaintenanceWindow.
+type maintenanceWindow struct {
+	days         map[time.Weekday]bool // nil means every day
+	hour, minute int
+	duration     time.Duration
+	loc          *time.Location
+}
+
+func parseWeekday(s string) (time.Weekday, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if len(s) < 3 {
+		return 0, false
+	}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if strings.HasPrefix(name, s) {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+func parseMaintenanceWindow(w MaintenanceWindow) (*maintenanceWindow, error) {
+	parsed := &maintenanceWindow{loc: time.UTC}
+	if w.TimeZone != "" {
+		loc, err := time.LoadLocation(w.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("unknown time zone %q", w.TimeZone)
+		}
+		parsed.loc = loc
+	}
+	start, err := time.Parse("15:04", w.Start)
+	if err != nil {
+		return nil, fmt.Errorf("window start %q is not HH:MM", w.Start)
+	}
+	parsed.hour, parsed.minute = start.Hour(), start.Minute()
+	d, err := time.ParseDuration(w.Duration)
+	if err != nil || d <= 0 || d > 24*time.Hour {
+		return nil, fmt.Errorf("window duration %q must be between 1m and 24h, e.g. 4h or 90m", w.Duration)
+	}
+	parsed.duration = d
+	for _, day := range w.Days {
+		wd, ok := parseWeekday(day)
+		if !ok {
+			return nil, fmt.Errorf("unknown window day %q", day)
+		}
+		if parsed.days == nil {
+			parsed.days = map[time.Weekday]bool{}
+		}
+		parsed.days[wd] = true
+	}
+	return parsed, nil
+}
+
+// next returns t if the window is open at t, else when it next opens.
+func (w *maintenanceWindow) next(t time.Time) time.Time {
+	local := t.In(w.loc)
+	// Yesterday's opening may still be open; a week ahead always has one.
+	for d := -1; d <= 7; d++ {
+		open := time.Date(local.Year(), local.Month(), local.Day()+d, w.hour, w.minute, 0, 0, w.loc)
+		if w.days != nil && !w.days[open.Weekday()] {
+			continue
+		}
+		if !t.Before(open) && t.Before(open.Add(w.duration)) {
+			return t
+		}
+		if open.After(t) {
+			return open
+		}
+	}
+	return time.Time{}
+}
+
+func (w *maintenanceWindow) contains(t time.Time) bool {
+	return w.next(t).Equal(t)
+}
+
+// forkliftScheduleOf returns the schedule stored on a Plan, or nil.
+func forkliftScheduleOf(plan *unstructured.Unstructured) (*ForkliftSchedule, error) {
+	data, ok := plan.GetAnnotations()[forkliftScheduleAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var s ForkliftSchedule
+	if err := json.Unmarshal([]byte(data), &s); err != nil {
+		return nil, fmt.Errorf("schedule of plan %s/%s is corrupt: %w", plan.GetNamespace(), plan.GetName(), err)
+	}
+	return &s, nil
+}
+
+// saveForkliftSchedule stores s on the Plan, or removes the schedule when s
+// is nil. A merge patch leaves the status Forklift keeps updating alone.
+func saveForkliftSchedule(ctx context.Context, clients *K8sClients, namespace, name string, s *ForkliftSchedule) error {
+	return patchForkliftSchedule(ctx, clients, namespace, name, "", s)
+}
+
+// claimForkliftSchedule stores s on plan only if plan is still at the
+// resourceVersion it was read at; a conflict means another replica changed
+// the schedule first.
+func claimForkliftSchedule(ctx context.Context, clients *K8sClients, plan *unstructured.Unstructured, s *ForkliftSchedule) error {
+	return patchForkliftSchedule(ctx, clients, plan.GetNamespace(), plan.GetName(), plan.GetResourceVersion(), s)
+}
+
+func patchForkliftSchedule(ctx context.Context, clients *K8sClients, namespace, name, resourceVersion string, s *ForkliftSchedule) error {
+	var value interface{}
+	if s != nil {
+		data, err := json.Marshal(s)
+		if err != nil {
+			return err
+		}
+		value = string(data)
+	}
+	metadata := map[string]interface{}{"annotations": map[string]interface{}{forkliftScheduleAnnotation: value}}
+	if resourceVersion != "" {
+		metadata["resourceVersion"] = resourceVersion
+	}
+	patch, err := json.Marshal(map[string]interface{}{"metadata": metadata})
+	if err != nil {
+		return err
+	}
+	_, err = clients.Dynamic.Resource(forkliftPlanGVR).Namespace(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// setForkliftCutover sets the cutover of a warm Migration, or clears it when
+// cutover is nil.
+func setForkliftCutover(ctx context.Context, clients *K8sClients, namespace, name string, cutover *time.Time) error {
+	var value interface{}
+	if cutover != nil {
+		value = cutover.UTC().Format(time.RFC3339)
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{"cutover": value},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = clients.Dynamic.Resource(forkliftMigrationGVR).Namespace(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// checkForkliftWindow refuses a start outside the Plan's maintenance window.
+func checkForkliftWindow(plan *unstructured.Unstructured, s *ForkliftSchedule, now time.Time) error {
+	if s == nil || s.Window == nil {
+		return nil
+	}
+	w, err := parseMaintenanceWindow(*s.Window)
+	if err != nil {
+		return fmt.Errorf("plan %s has an invalid maintenance window: %w", plan.GetName(), err)
+	}
+	if next := w.next(now); !next.Equal(now) {
+		return &MaintenanceWindowError{Plan: plan.GetName(), NextOpen: next}
+	}
+	return nil
+}
+
+// prepareForkliftSchedule validates a requested schedule for a Plan and
+// fills in StartAt from the window when the Plan has not started yet.
+func prepareForkliftSchedule(s ForkliftSchedule, warm, started bool, now time.Time) (*ForkliftSchedule, error) {
+	s.StartedAt, s.Message = nil, ""
+	if s.StartAt == nil && s.Window == nil && s.Cutover == nil {
+		return nil, fmt.Errorf("a schedule needs a startAt, a window or a cutover")
+	}
+	var w *maintenanceWindow
+	if s.Window != nil {
+		var err error
+		if w, err = parseMaintenanceWindow(*s.Window); err != nil {
+			return nil, err
+		}
+	}
+
+	if started {
+		if s.StartAt != nil {
+			return nil, fmt.Errorf("the plan already has a Migration; only its cutover can be scheduled")
+		}
+	} else {
+		switch {
+		case s.StartAt == nil && w != nil:
+			s.StartAt = &metav1.Time{Time: w.next(now)}
+		case s.StartAt == nil:
+			return nil, fmt.Errorf("a cutover can only be scheduled together with the start, or once the plan runs")
+		case s.StartAt.Time.Before(now.Add(-time.Minute)):
+			return nil, fmt.Errorf("startAt %s is in the past", s.StartAt.UTC().Format(time.RFC3339))
+		case w != nil && !w.contains(s.StartAt.Time):
+			return nil, fmt.Errorf("startAt %s is outside the maintenance window, which next opens at %s",
+				s.StartAt.UTC().Format(time.RFC3339), w.next(s.StartAt.Time).UTC().Format(time.RFC3339))
+		}
+	}
+
+	if s.Cutover != nil {
+		switch {
+		case !warm:
+			return nil, fmt.Errorf("only warm migrations have a cutover")
+		case s.Cutover.Time.Before(now):
+			return nil, fmt.Errorf("cutover %s is in the past", s.Cutover.UTC().Format(time.RFC3339))
+		case s.StartAt != nil && !s.Cutover.Time.After(s.StartAt.Time):
+			return nil, fmt.Errorf("cutover must come after startAt")
+		case w != nil && !w.contains(s.Cutover.Time):
+			return nil, fmt.Errorf("cutover %s is outside the maintenance window, which next opens at %s",
+				s.Cutover.UTC().Format(time.RFC3339), w.next(s.Cutover.Time).UTC().Format(time.RFC3339))
+		}
+	}
+	return &s, nil
+}
+
+// forkliftScheduleStatus reports s as of now.
+func forkliftScheduleStatus(s *ForkliftSchedule, now time.Time) ForkliftScheduleStatus {
+	status := ForkliftScheduleStatus{Scheduled: s != nil, Schedule: s, InWindow: true}
+	if s == nil {
+		return status
+	}
+	if s.StartedAt == nil && s.StartAt != nil {
+		status.NextStart = s.StartAt
+	}
+	if s.Window != nil {
+		if w, err := parseMaintenanceWindow(*s.Window); err == nil {
+			next := w.next(now)
+			status.InWindow = next.Equal(now)
+			status.NextWindow = &metav1.Time{Time: next}
+		}
+	}
+	return status
+}
+
+// runScheduledForkliftMigrations starts the scheduled Plans that are due.
+// A due start that finds its window closed, e.g. after the backend was down,
+// moves to the window's next opening. Each start is first claimed by saving
+// the schedule as started at the Plan's resourceVersion, so of several
+// replicas only one starts it.
+func runScheduledForkliftMigrations(ctx context.Context, clients *K8sClients, now time.Time) {
+	plans, err := clients.listResources(ctx, forkliftPlanGVR, "", metav1.ListOptions{})
+	if err != nil {
+		log.Warnf("Could not list Forklift plans for scheduled migrations: %v", err)
+		return
+	}
+	for i := range plans.Items {
+		plan := &plans.Items[i]
+		s, err := forkliftScheduleOf(plan)
+		if err != nil {
+			log.Warn(err)
+			continue
+		}
+		if s == nil || s.StartedAt != nil || s.StartAt == nil || now.Before(s.StartAt.Time) {
+			continue
+		}
+		s.StartedAt, s.Message = &metav1.Time{Time: now}, "Starting by schedule"
+		if err := claimForkliftSchedule(ctx, clients, plan, s); err != nil {
+			if apierrors.IsConflict(err) {
+				log.Debugf("Scheduled start of Forklift plan %s/%s was claimed elsewhere; rechecking on the next pass", plan.GetNamespace(), plan.GetName())
+			} else {
+				log.Warnf("Could not claim the scheduled start of Forklift plan %s/%s: %v", plan.GetNamespace(), plan.GetName(), err)
+			}
+			continue
+		}
+
+		_, err = startForkliftMigration(ctx, clients, plan.GetNamespace(), plan.GetName(), now)
+		var windowErr *MaintenanceWindowError
+		switch {
+		case err == nil:
+			log.Infof("Started scheduled Forklift migration for plan %s/%s", plan.GetNamespace(), plan.GetName())
+			s.Message = "Started by schedule"
+		case apierrors.IsAlreadyExists(err):
+			s.Message = "The plan already had a Migration when the schedule came due"
+		case errors.As(err, &windowErr):
+			log.Infof("Scheduled start of Forklift plan %s/%s missed its window; moving it to %s", plan.GetNamespace(), plan.GetName(), windowErr.NextOpen)
+			s.StartedAt, s.StartAt, s.Message = nil, &metav1.Time{Time: windowErr.NextOpen}, fmt.Sprintf("Missed the window at %s; rescheduled", s.StartAt.UTC().Format(time.RFC3339))
+		default:
+			// Retried on the next pass.
+			log.Warnf("Scheduled start of Forklift plan %s/%s failed: %v", plan.GetNamespace(), plan.GetName(), err)
+			s.StartedAt, s.Message = nil, "Could not start: "+err.Error()
+		}
+		if err := saveForkliftSchedule(ctx, clients, plan.GetNamespace(), plan.GetName(), s); err != nil {
+			log.Warnf("Could not save the schedule of Forklift plan %s/%s: %v", plan.GetNamespace(), plan.GetName(), err)
+		}
+	}
+}
+
+// runForkliftScheduler starts scheduled Forklift migrations and cuts warm
+// ones over once they have enough precopies, until ctx is done.
+func runForkliftScheduler(ctx context.Context, clients *K8sClients) {
+	ticker := time.NewTicker(forkliftScheduleInterval)
+	defer ticker.Stop()
+	for {
+		now := time.Now()
+		runScheduledForkliftMigrations(ctx, clients, now)
+		runAutoCutovers(ctx, clients, now)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,219 @@
+// pkg/forklift_schedule_test.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestMaintenanceWindow(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Saturday and Sunday nights, 22:00-04:00 Berlin time.
+	w, err := parseMaintenanceWindow(MaintenanceWindow{Days: []string{"sat", "Sunday"}, Start: "22:00", Duration: "6h", TimeZone: "Europe/Berlin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, time.October, day, hour, minute, 0, 0, berlin)
+	}
+
+	tests := []struct {
+		name string
+		t    time.Time
+		want time.Time
+	}{
+		{"saturday night", at(17, 23, 0), at(17, 23, 0)},
+		{"past midnight", at(18, 3, 59), at(18, 3, 59)},
+		{"sunday into monday", at(19, 1, 0), at(19, 1, 0)},
+		{"monday morning", at(19, 4, 0), at(24, 22, 0)},
+		{"saturday afternoon", at(17, 15, 0), at(17, 22, 0)},
+		// Clocks go back on 25 October; the window still opens at 22:00 local.
+		{"after dst change", at(26, 12, 0), time.Date(2026, time.October, 31, 22, 0, 0, 0, berlin)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := w.next(tc.t); !got.Equal(tc.want) {
+				t.Errorf("expected %s, got %s", tc.want, got)
+			}
+			if w.contains(tc.t) != tc.want.Equal(tc.t) {
+				t.Errorf("expected contains to be %v", tc.want.Equal(tc.t))
+			}
+		})
+	}
+
+	for _, bad := range []MaintenanceWindow{
+		{Start: "25:00", Duration: "1h"},
+		{Start: "22:00", Duration: "25h"},
+		{Start: "22:00", Duration: "0s"},
+		{Start: "22:00", Duration: "1h", TimeZone: "Mars/Olympus"},
+		{Start: "22:00", Duration: "1h", Days: []string{"fr"}},
+	} {
+		if _, err := parseMaintenanceWindow(bad); err == nil {
+			t.Errorf("expected %+v to be rejected", bad)
+		}
+	}
+}
+
+func TestPrepareForkliftSchedule(t *testing.T) {
+	now := time.Date(2026, time.October, 17, 12, 0, 0, 0, time.UTC)
+	in := func(d time.Duration) *metav1.Time { return &metav1.Time{Time: now.Add(d)} }
+	// Daily from 14:00 to 16:00 UTC.
+	window := &MaintenanceWindow{Start: "14:00", Duration: "2h"}
+
+	s, err := prepareForkliftSchedule(ForkliftSchedule{Window: window, Message: "stale", StartedAt: in(-time.Hour)}, false, false, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.StartAt.Time.Equal(now.Add(2*time.Hour)) || s.StartedAt != nil || s.Message != "" {
+		t.Errorf("expected the start at the next opening and scheduler fields reset, got %+v", s)
+	}
+	if _, err := prepareForkliftSchedule(ForkliftSchedule{Cutover: in(3 * time.Hour)}, true, true, now); err != nil {
+		t.Errorf("expected a cutover for a running warm migration to be accepted: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		schedule ForkliftSchedule
+		warm     bool
+		started  bool
+		want     string
+	}{
+		{"empty", ForkliftSchedule{}, false, false, "needs a startAt"},
+		{"past", ForkliftSchedule{StartAt: in(-time.Hour)}, false, false, "in the past"},
+		{"outside window", ForkliftSchedule{StartAt: in(time.Hour), Window: window}, false, false, "next opens at 2026-10-17T14:00:00Z"},
+		{"already started", ForkliftSchedule{StartAt: in(time.Hour)}, false, true, "already has a Migration"},
+		{"cold cutover", ForkliftSchedule{StartAt: in(time.Hour), Cutover: in(2 * time.Hour)}, false, false, "only warm"},
+		{"cutover before start", ForkliftSchedule{StartAt: in(2 * time.Hour), Cutover: in(time.Hour)}, true, false, "after startAt"},
+		{"cutover outside window", ForkliftSchedule{Window: window, Cutover: in(5 * time.Hour)}, true, false, "cutover 2026-10-17T17:00:00Z is outside"},
+		{"cutover alone", ForkliftSchedule{Cutover: in(time.Hour)}, true, false, "together with the start"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := prepareForkliftSchedule(tc.schedule, tc.warm, tc.started, now); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected an error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestForkliftScheduleHandlers(t *testing.T) {
+	plan := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "forklift.konveyor.io/v1beta1",
+		"kind":       "Plan",
+		"metadata":   map[string]interface{}{"name": "db", "namespace": "vms", "resourceVersion": "1"},
+		"spec":       map[string]interface{}{"warm": true},
+	}}
+	clients := newTestClientsWithDynamic(nil, plan)
+	ctx := context.Background()
+	vars := map[string]string{"namespace": "vms", "name": "db"}
+
+	// A daily one-hour window opening two hours from now.
+	now := time.Now().UTC().Truncate(time.Minute)
+	open := now.Add(2 * time.Hour)
+	window := &MaintenanceWindow{Start: open.Format("15:04"), Duration: "1h"}
+	cutover := open.Add(24*time.Hour + 30*time.Minute)
+
+	decode := func(t *testing.T, body []byte) ForkliftScheduleStatus {
+		t.Helper()
+		var status ForkliftScheduleStatus
+		if err := json.Unmarshal(body, &status); err != nil {
+			t.Fatal(err)
+		}
+		return status
+	}
+
+	rr := executeRequest(SetForkliftScheduleHandler(clients), "PUT", "/api/v1/forklift/plans/vms/db/schedule",
+		ForkliftSchedule{Window: window, Cutover: &metav1.Time{Time: cutover}}, vars)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if status := decode(t, rr.Body.Bytes()); !status.Scheduled || status.InWindow || status.NextStart == nil || !status.NextStart.Time.Equal(open) {
+		t.Errorf("expected a start at the window's opening, got %+v", status)
+	}
+
+	rr = executeRequest(CreateForkliftMigrationHandler(clients), "POST", "/api/v1/forklift/plans/vms/db/run", nil, vars)
+	if rr.Code != http.StatusConflict || !strings.Contains(rr.Body.String(), "maintenance window") {
+		t.Errorf("expected a manual start outside the window to be refused, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// The backend was down through the whole window: the start moves to the
+	// next day's opening instead of running late.
+	runScheduledForkliftMigrations(ctx, clients, open.Add(90*time.Minute))
+	rr = executeRequest(GetForkliftScheduleHandler(clients), "GET", "/api/v1/forklift/plans/vms/db/schedule", nil, vars)
+	status := decode(t, rr.Body.Bytes())
+	if !status.NextStart.Time.Equal(open.Add(24*time.Hour)) || !strings.Contains(status.Schedule.Message, "Missed the window") {
+		t.Fatalf("expected the start moved to the next opening, got %+v", status.Schedule)
+	}
+
+	// Another replica changed the Plan since it was read: this one leaves the
+	// start to it.
+	conflicted := false
+	clients.Dynamic.(*dynamicfake.FakeDynamicClient).PrependReactor("patch", "plans", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if conflicted || !strings.Contains(string(action.(k8stesting.PatchAction).GetPatch()), "resourceVersion") {
+			return false, nil, nil
+		}
+		conflicted = true
+		return true, nil, apierrors.NewConflict(forkliftPlanGVR.GroupResource(), "db", errors.New("the object has been modified"))
+	})
+	runScheduledForkliftMigrations(ctx, clients, open.Add(24*time.Hour+time.Minute))
+	if _, err := clients.Dynamic.Resource(forkliftMigrationGVR).Namespace("vms").Get(ctx, "db-migration", metav1.GetOptions{}); !conflicted || !apierrors.IsNotFound(err) {
+		t.Fatalf("expected a conflicting claim to start nothing, got %v", err)
+	}
+
+	runScheduledForkliftMigrations(ctx, clients, open.Add(24*time.Hour+time.Minute))
+	migration, err := clients.Dynamic.Resource(forkliftMigrationGVR).Namespace("vms").Get(ctx, "db-migration", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected the scheduler to create the Migration: %v", err)
+	}
+	if got, _, _ := unstructured.NestedString(migration.Object, "spec", "cutover"); got != cutover.Format(time.RFC3339) {
+		t.Errorf("expected the scheduled cutover on the Migration, got %q", got)
+	}
+	rr = executeRequest(GetForkliftScheduleHandler(clients), "GET", "/api/v1/forklift/plans/vms/db/schedule", nil, vars)
+	if status := decode(t, rr.Body.Bytes()); status.NextStart != nil || status.Schedule.StartedAt == nil || status.Schedule.Message != "Started by schedule" {
+		t.Errorf("expected the schedule marked started, got %+v", status.Schedule)
+	}
+
+	rr = executeRequest(SetForkliftScheduleHandler(clients), "PUT", "/api/v1/forklift/plans/vms/db/schedule",
+		ForkliftSchedule{StartAt: &metav1.Time{Time: open}}, vars)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected a new start for a running plan to be refused, got %d", rr.Code)
+	}
+	later := cutover.Add(10 * time.Minute)
+	rr = executeRequest(SetForkliftScheduleHandler(clients), "PUT", "/api/v1/forklift/plans/vms/db/schedule",
+		ForkliftSchedule{Window: window, Cutover: &metav1.Time{Time: later}}, vars)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 moving the cutover, got %d: %s", rr.Code, rr.Body.String())
+	}
+	migration, _ = clients.Dynamic.Resource(forkliftMigrationGVR).Namespace("vms").Get(ctx, "db-migration", metav1.GetOptions{})
+	if got, _, _ := unstructured.NestedString(migration.Object, "spec", "cutover"); got != later.Format(time.RFC3339) {
+		t.Errorf("expected the running Migration's cutover moved, got %q", got)
+	}
+
+	if rr := executeRequest(DeleteForkliftScheduleHandler(clients), "DELETE", "/api/v1/forklift/plans/vms/db/schedule", nil, vars); rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	rr = executeRequest(GetForkliftScheduleHandler(clients), "GET", "/api/v1/forklift/plans/vms/db/schedule", nil, vars)
+	if status := decode(t, rr.Body.Bytes()); status.Scheduled {
+		t.Errorf("expected no schedule after deletion, got %+v", status)
+	}
+
+	missing := map[string]string{"namespace": "vms", "name": "nope"}
+	if rr := executeRequest(GetForkliftScheduleHandler(clients), "GET", "/api/v1/forklift/plans/vms/nope/schedule", nil, missing); rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown plan, got %d", rr.Code)
+	}
+}
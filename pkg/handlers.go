@@ -5,14 +5,20 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"path"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -74,6 +80,13 @@ var (
 		Version:  "v1beta1",
 		Resource: "migrations",
 	}
+
+	// Multus NetworkAttachmentDefinitions, which back Harvester VM networks
+	nadGVR = schema.GroupVersionResource{
+		Group:    "k8s.cni.cncf.io",
+		Version:  "v1",
+		Resource: "network-attachment-definitions",
+	}
 )
 
 // Helper to respond with JSON
@@ -130,7 +143,7 @@ func gatherCapabilities(ctx context.Context, clients *K8sClients) (CapabilityCon
 	if clients == nil || clients.Dynamic == nil {
 		return CapabilityConfig{HarvesterVersion: "unknown", HasAdvancedPower: false}, fmt.Errorf("kubernetes client unavailable")
 	}
-	setting, err := clients.Dynamic.Resource(settingsGVR).Get(ctx, "server-version", metav1.GetOptions{})
+	setting, err := clients.getResource(ctx, settingsGVR, "", "server-version")
 	if err != nil {
 		return CapabilityConfig{HarvesterVersion: "unknown", HasAdvancedPower: false}, err
 	}
@@ -163,63 +176,512 @@ type VCenterCredentials struct {
 	Username   string
 	Password   string
 	Datacenter string
+	// SessionKey identifies the VmwareSource/Provider these credentials belong
+	// to, so the session pool can reuse and invalidate its vCenter login.
+	SessionKey string
+
+	// TLS settings, read from the same secret as the credentials.
+	InsecureSkipVerify bool
+	CACert             string // PEM-encoded CA bundle to verify vCenter against
+	Thumbprint         string // pinned SHA-1 or SHA-256 certificate fingerprint
 }
 
-// gatherVCenterInventory resolves a VmwareSource's endpoint and credentials and
-// returns its inventory tree. Shared by the inventory endpoint and the support
-// bundle so both go through one code path.
-func gatherVCenterInventory(ctx context.Context, clients *K8sClients, namespace, name string) (*InventoryNode, error) {
-	sourceObj, err := clients.Dynamic.Resource(vmwareSourceGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+// resolveVmwareSourceCredentials reads a VmwareSource and its credentials
+// secret into VCenterCredentials.
+func resolveVmwareSourceCredentials(ctx context.Context, clients *K8sClients, namespace, name string) (VCenterCredentials, error) {
+	sourceObj, err := clients.getResource(ctx, vmwareSourceGVR, namespace, name)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get VmwareSource: %w", err)
+		return VCenterCredentials{}, fmt.Errorf("failed to get VmwareSource: %w", err)
 	}
 
 	endpoint, found := getNestedStringOrWarn(sourceObj.Object, "spec", "endpoint")
 	if !found {
-		return nil, fmt.Errorf("VmwareSource missing spec.endpoint")
+		return VCenterCredentials{}, fmt.Errorf("VmwareSource missing spec.endpoint")
 	}
 	datacenter, _ := getNestedStringOrWarn(sourceObj.Object, "spec", "dc")
 
 	secretName, found := getNestedStringOrWarn(sourceObj.Object, "spec", "credentials", "name")
 	if !found {
-		return nil, fmt.Errorf("VmwareSource missing credentials secret name")
+		return VCenterCredentials{}, fmt.Errorf("VmwareSource missing credentials secret name")
 	}
 	secretNamespace, found := getNestedStringOrWarn(sourceObj.Object, "spec", "credentials", "namespace")
 	if !found {
-		return nil, fmt.Errorf("VmwareSource missing credentials secret namespace")
+		return VCenterCredentials{}, fmt.Errorf("VmwareSource missing credentials secret namespace")
 	}
 
 	secret, err := clients.Clientset.CoreV1().Secrets(secretNamespace).Get(ctx, secretName, metav1.GetOptions{})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get credentials secret: %w", err)
+		return VCenterCredentials{}, fmt.Errorf("failed to get credentials secret: %w", err)
+	}
+
+	insecure, caCert, thumbprint := vcenterTLSSettings(secret.Data)
+	return VCenterCredentials{
+		URL:                endpoint,
+		Username:           string(secret.Data["username"]),
+		Password:           string(secret.Data["password"]),
+		Datacenter:         datacenter,
+		SessionKey:         vcenterSessionKey("VmwareSource", namespace, name),
+		InsecureSkipVerify: insecure,
+		CACert:             caCert,
+		Thumbprint:         thumbprint,
+	}, nil
+}
+
+// resolveForkliftProviderCredentials reads a vSphere Forklift Provider and its
+// secret into VCenterCredentials. The datacenter is left empty for auto-discovery.
+func resolveForkliftProviderCredentials(ctx context.Context, clients *K8sClients, namespace, name string) (VCenterCredentials, error) {
+	providerObj, err := clients.getResource(ctx, forkliftProviderGVR, namespace, name)
+	if err != nil {
+		return VCenterCredentials{}, fmt.Errorf("failed to get Forklift Provider: %w", err)
 	}
 
-	creds := VCenterCredentials{
-		URL:        endpoint,
-		Username:   string(secret.Data["username"]),
-		Password:   string(secret.Data["password"]),
-		Datacenter: datacenter,
+	providerURL, found := getNestedStringOrWarn(providerObj.Object, "spec", "url")
+	if !found {
+		return VCenterCredentials{}, fmt.Errorf("Forklift Provider missing URL")
+	}
+	secretName, found := getNestedStringOrWarn(providerObj.Object, "spec", "secret", "name")
+	if !found {
+		return VCenterCredentials{}, fmt.Errorf("Forklift Provider missing secret name")
+	}
+	secretNamespace, found := getNestedStringOrWarn(providerObj.Object, "spec", "secret", "namespace")
+	if !found {
+		return VCenterCredentials{}, fmt.Errorf("Forklift Provider missing secret namespace")
 	}
 
+	secret, err := clients.Clientset.CoreV1().Secrets(secretNamespace).Get(ctx, secretName, metav1.GetOptions{})
+	if err != nil {
+		return VCenterCredentials{}, fmt.Errorf("failed to get Forklift credentials secret: %w", err)
+	}
+
+	// Forklift secrets use "user" and "password" fields, and "url"
+	// The URL from the secret or Provider spec both work; use Provider spec URL
+	// Pass the full URL including /sdk path, same as VM Import Controller
+	insecure, caCert, thumbprint := vcenterTLSSettings(secret.Data)
+	return VCenterCredentials{
+		URL:                providerURL,
+		Username:           string(secret.Data["user"]),
+		Password:           string(secret.Data["password"]),
+		Datacenter:         "", // Will be auto-discovered
+		SessionKey:         vcenterSessionKey("Provider", namespace, name),
+		InsecureSkipVerify: insecure,
+		CACert:             caCert,
+		Thumbprint:         thumbprint,
+	}, nil
+}
+
+// setVCenterTLSSecretFields records TLS settings in a VmwareSource or Forklift
+// Provider secret, using the keys vcenterTLSSettings reads back. Nil settings are
+// left as they are; an empty thumbprint removes the pin.
+func setVCenterTLSSecretFields(secret *v1.Secret, insecureSkipVerify *bool, caCert string, thumbprint *string) error {
+	if secret.StringData == nil {
+		secret.StringData = make(map[string]string)
+	}
+	if thumbprint != nil {
+		if *thumbprint == "" {
+			delete(secret.Data, "thumbprint")
+			delete(secret.StringData, "thumbprint")
+		} else {
+			normalized, err := normalizeThumbprint(*thumbprint)
+			if err != nil {
+				return err
+			}
+			secret.StringData["thumbprint"] = normalized
+		}
+	}
+	if insecureSkipVerify != nil {
+		if *insecureSkipVerify {
+			secret.StringData["insecureSkipVerify"] = "true"
+			delete(secret.Data, "cacert")
+			delete(secret.StringData, "cacert")
+		} else {
+			secret.StringData["insecureSkipVerify"] = "false"
+			if caCert != "" {
+				if _, err := parseCACert(caCert); err != nil {
+					return err
+				}
+				secret.StringData["cacert"] = caCert
+			}
+		}
+	}
+	return nil
+}
+
+// gatherVCenterInventory resolves a VmwareSource's endpoint and credentials and
+// returns its inventory tree. Shared by the inventory endpoint and the support
+// bundle so both go through one code path.
+func gatherVCenterInventory(ctx context.Context, clients *K8sClients, namespace, name string) (*InventoryNode, error) {
+	creds, err := resolveVmwareSourceCredentials(ctx, clients, namespace, name)
+	if err != nil {
+		return nil, err
+	}
 	return GetVCenterInventory(ctx, creds)
 }
 
+// requestedDatacenters returns the datacenters asked for with ?datacenter=,
+// which may be repeated or comma-separated and accepts globs ("*" for all).
+func requestedDatacenters(r *http.Request) []string {
+	return queryList(r, "datacenter")
+}
+
+// queryList collects a query parameter given repeatedly and/or as a
+// comma-separated list.
+func queryList(r *http.Request, key string) []string {
+	var values []string
+	for _, value := range r.URL.Query()[key] {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
+// respondWithInventory picks the part of a cached all-datacenter tree that the
+// request asked for: the source's own datacenter by default, or every
+// datacenter matching ?datacenter= under a "vcenter" root. ?view=hosts
+// switches from the VM-folder view to the host-and-cluster view.
+func respondWithInventory(w http.ResponseWriter, r *http.Request, tree *InventoryNode, creds VCenterCredentials) {
+	view := r.URL.Query().Get("view")
+	switch view {
+	case "":
+		view = inventoryViewVMs
+	case inventoryViewVMs, inventoryViewHosts:
+	default:
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid inventory view %q: expected %q or %q", view, inventoryViewVMs, inventoryViewHosts))
+		return
+	}
+
+	patterns := requestedDatacenters(r)
+	if len(patterns) == 0 {
+		inventory, err := defaultDatacenterInventory(tree, creds.Datacenter, view)
+		if err != nil {
+			log.Errorf("Failed to get vCenter inventory: %v", err)
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondWithJSON(w, http.StatusOK, inventory)
+		return
+	}
+
+	inventory, err := selectDatacenterInventory(tree, patterns, view)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, inventory)
+}
+
+// HandleGetInventory serves a VmwareSource's inventory from the inventory
+// cache. Pass ?refresh=true to wait for a fresh walk of vCenter, and
+// ?datacenter=DC1,DC2 (or ?datacenter=*) to browse datacenters other than the
+// one in the source's spec.
 func HandleGetInventory(clients *K8sClients) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
 		namespace := vars["namespace"]
 		name := vars["name"]
+		force := r.URL.Query().Get("refresh") == "true"
 
-		log.Infof("Fetching inventory for VmwareSource %s/%s", namespace, name)
+		log.Infof("Fetching inventory for VmwareSource %s/%s (refresh=%v)", namespace, name, force)
 
-		inventory, err := gatherVCenterInventory(r.Context(), clients, namespace, name)
+		creds, err := resolveVmwareSourceCredentials(r.Context(), clients, namespace, name)
 		if err != nil {
 			log.Errorf("Failed to get vCenter inventory: %v", err)
 			respondWithError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 
-		respondWithJSON(w, http.StatusOK, inventory)
+		tree, err := inventoryCaches.Get(r.Context(), creds, GetVCenterInventoryAllDatacenters, force)
+		if err != nil {
+			log.Errorf("Failed to get vCenter inventory: %v", err)
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		respondWithInventory(w, r, tree, creds)
+	}
+}
+
+// respondWithNetworks lists the networks in vCenter that the request asked
+// for: those in the source's own datacenter (every datacenter when it has
+// none) or in the datacenters matching ?datacenter=. Uplink portgroups, which
+// VMs cannot attach to, are left out unless ?uplinks=true.
+func respondWithNetworks(w http.ResponseWriter, r *http.Request, creds VCenterCredentials) {
+	networks, err := GetVCenterNetworks(r.Context(), creds)
+	if err != nil {
+		log.Errorf("Failed to list vCenter networks: %v", err)
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, filterNetworks(r, creds, networks))
+}
+
+// filterNetworks applies the ?datacenter= and ?uplinks= selection of the
+// network listing.
+func filterNetworks(r *http.Request, creds VCenterCredentials, networks []VCenterNetwork) []VCenterNetwork {
+	patterns := requestedDatacenters(r)
+	if len(patterns) == 0 && creds.Datacenter != "" {
+		patterns = []string{strings.Trim(creds.Datacenter, "/")}
+	}
+	uplinks := r.URL.Query().Get("uplinks") == "true"
+
+	filtered := []VCenterNetwork{}
+	for _, n := range networks {
+		if n.Uplink && !uplinks {
+			continue
+		}
+		if len(patterns) > 0 && !matchesAnyPattern(n.Datacenter, patterns) {
+			continue
+		}
+		filtered = append(filtered, n)
+	}
+	return filtered
+}
+
+// matchesAnyPattern reports whether name matches one of the glob patterns.
+func matchesAnyPattern(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, err := path.Match(pattern, name); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// HandleGetVCenterNetworks lists the standard, distributed and opaque networks
+// visible to a VmwareSource, with their VLAN configuration.
+func HandleGetVCenterNetworks(clients *K8sClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		creds, err := resolveVmwareSourceCredentials(r.Context(), clients, vars["namespace"], vars["name"])
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondWithNetworks(w, r, creds)
+	}
+}
+
+// HandleGetForkliftNetworks lists the networks visible to a vSphere Forklift
+// Provider, with their VLAN configuration.
+func HandleGetForkliftNetworks(clients *K8sClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		creds, err := resolveForkliftProviderCredentials(r.Context(), clients, vars["namespace"], vars["name"])
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondWithNetworks(w, r, creds)
+	}
+}
+
+// respondWithNetworkSuggestions proposes a Harvester network for each vCenter
+// network selected as in the listing, or for the networks named by ?network=
+// (IDs or names, repeated or comma-separated), e.g. a VM's NICs.
+func respondWithNetworkSuggestions(w http.ResponseWriter, r *http.Request, clients *K8sClients, creds VCenterCredentials) {
+	networks, err := GetVCenterNetworks(r.Context(), creds)
+	if err != nil {
+		log.Errorf("Failed to list vCenter networks: %v", err)
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	requested := queryList(r, "network")
+	sources := filterNetworks(r, creds, networks)
+	if len(requested) > 0 {
+		sources = []VCenterNetwork{}
+		var missing []string
+		for _, want := range requested {
+			found := false
+			for _, n := range networks {
+				if n.ID == want || n.Name == want {
+					sources = append(sources, n)
+					found = true
+					break
+				}
+			}
+			if !found {
+				missing = append(missing, want)
+			}
+		}
+		if len(missing) > 0 {
+			respondWithError(w, http.StatusNotFound, "Network(s) not found in vCenter: "+strings.Join(missing, ", "))
+			return
+		}
+	}
+
+	nads, err := listHarvesterNADs(r.Context(), clients)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list Harvester networks: "+err.Error())
+		return
+	}
+	destinations := make([]HarvesterNetwork, 0, len(nads))
+	for _, nad := range nads {
+		destinations = append(destinations, parseHarvesterNetwork(nad))
+	}
+
+	respondWithJSON(w, http.StatusOK, suggestNetworkMappings(sources, destinations))
+}
+
+// HandleGetVCenterNetworkSuggestions suggests Harvester networks for a
+// VmwareSource's networks.
+func HandleGetVCenterNetworkSuggestions(clients *K8sClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		creds, err := resolveVmwareSourceCredentials(r.Context(), clients, vars["namespace"], vars["name"])
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondWithNetworkSuggestions(w, r, clients, creds)
+	}
+}
+
+// HandleGetForkliftNetworkSuggestions suggests Harvester networks for a vSphere
+// Forklift Provider's networks.
+func HandleGetForkliftNetworkSuggestions(clients *K8sClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		creds, err := resolveForkliftProviderCredentials(r.Context(), clients, vars["namespace"], vars["name"])
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondWithNetworkSuggestions(w, r, clients, creds)
+	}
+}
+
+// selectedInventoryVMs looks up the ?vm= VMs in the cached inventory, writing the error response on failure.
+func selectedInventoryVMs(w http.ResponseWriter, r *http.Request, creds VCenterCredentials) ([]InventoryNode, bool) {
+	refs := queryList(r, "vm")
+	if len(refs) == 0 {
+		respondWithError(w, http.StatusBadRequest, "Select at least one VM with ?vm=")
+		return nil, false
+	}
+
+	tree, err := inventoryCaches.Get(r.Context(), creds, GetVCenterInventoryAllDatacenters, false)
+	if err != nil {
+		log.Errorf("Failed to get vCenter inventory: %v", err)
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	vms, err := findInventoryVMs(tree, refs)
+	if err != nil {
+		var notFound *VMsNotFoundError
+		if errors.As(err, &notFound) {
+			respondWithError(w, http.StatusNotFound, err.Error())
+			return nil, false
+		}
+		respondWithVMOpError(w, err)
+		return nil, false
+	}
+	return vms, true
+}
+
+// respondWithStorageSuggestions proposes a StorageClass per datastore holding
+// the disks of the VMs given by ?vm= (IDs or unique names, repeated or
+// comma-separated), and one for all their disks together.
+func respondWithStorageSuggestions(w http.ResponseWriter, r *http.Request, clients *K8sClients, creds VCenterCredentials) {
+	vms, ok := selectedInventoryVMs(w, r, creds)
+	if !ok {
+		return
+	}
+
+	scs, err := clients.Clientset.StorageV1().StorageClasses().List(r.Context(), metav1.ListOptions{})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list StorageClasses: "+err.Error())
+		return
+	}
+
+	longhorn, err := getLonghornCapacity(r.Context(), clients)
+	suggestions := suggestStorageMappings(vms, scs.Items, longhorn)
+	if err != nil {
+		suggestions.Warnings = append(suggestions.Warnings, "Longhorn capacity is unknown: "+err.Error())
+	}
+	respondWithJSON(w, http.StatusOK, suggestions)
+}
+
+// HandleGetVCenterStorageSuggestions suggests StorageClasses for VMs of a
+// VmwareSource.
+func HandleGetVCenterStorageSuggestions(clients *K8sClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		creds, err := resolveVmwareSourceCredentials(r.Context(), clients, vars["namespace"], vars["name"])
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondWithStorageSuggestions(w, r, clients, creds)
+	}
+}
+
+// HandleGetForkliftStorageSuggestions suggests StorageClasses for VMs of a
+// vSphere Forklift Provider.
+func HandleGetForkliftStorageSuggestions(clients *K8sClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		creds, err := resolveForkliftProviderCredentials(r.Context(), clients, vars["namespace"], vars["name"])
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondWithStorageSuggestions(w, r, clients, creds)
+	}
+}
+
+func respondWithCapacityPlan(w http.ResponseWriter, r *http.Request, clients *K8sClients, creds VCenterCredentials) {
+	vms, ok := selectedInventoryVMs(w, r, creds)
+	if !ok {
+		return
+	}
+
+	scs, err := clients.Clientset.StorageV1().StorageClasses().List(r.Context(), metav1.ListOptions{})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list StorageClasses: "+err.Error())
+		return
+	}
+	usage, err := getClassUsage(r.Context(), clients)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list PersistentVolumes: "+err.Error())
+		return
+	}
+
+	longhorn, err := getLonghornCapacity(r.Context(), clients)
+	csi, csiErr := getCSICapacity(r.Context(), clients)
+	plan := planCapacity(vms, scs.Items, longhorn, usage, csi, csiErr)
+	if err != nil {
+		plan.Warnings = append(plan.Warnings, "Longhorn capacity is unknown: "+err.Error())
+	}
+	respondWithJSON(w, http.StatusOK, plan)
+}
+
+// HandleGetVCenterCapacityPlan reports, per StorageClass, the headroom left
+// after importing VMs of a VmwareSource.
+func HandleGetVCenterCapacityPlan(clients *K8sClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		creds, err := resolveVmwareSourceCredentials(r.Context(), clients, vars["namespace"], vars["name"])
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondWithCapacityPlan(w, r, clients, creds)
+	}
+}
+
+// HandleGetForkliftCapacityPlan reports, per StorageClass, the headroom left
+// after migrating VMs of a vSphere Forklift Provider.
+func HandleGetForkliftCapacityPlan(clients *K8sClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		creds, err := resolveForkliftProviderCredentials(r.Context(), clients, vars["namespace"], vars["name"])
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondWithCapacityPlan(w, r, clients, creds)
 	}
 }
 
@@ -251,9 +713,68 @@ func CreatePlanHandler(clients *K8sClients) http.HandlerFunc {
 	}
 }
 
+// CreateBulkPlansHandler creates a VirtualMachineImport for each VM of a
+// batch from shared defaults. It answers 201 when every plan was created and
+// 207 with the per-VM errors otherwise.
+func CreateBulkPlansHandler(clients *K8sClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload CreateBulkPlansPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if payload.Namespace == "" || payload.SourceCluster.Name == "" || len(payload.VMs) == 0 {
+			respondWithError(w, http.StatusBadRequest, "namespace, sourceCluster and at least one VM are required")
+			return
+		}
+		if payload.SourceCluster.APIVersion == "" {
+			payload.SourceCluster.APIVersion = "migration.harvesterhci.io/v1beta1"
+		}
+		if payload.SourceCluster.Kind == "" {
+			payload.SourceCluster.Kind = "VmwareSource"
+		}
+		if _, err := clients.Clientset.CoreV1().Namespaces().Get(r.Context(), payload.Namespace, metav1.GetOptions{}); err != nil {
+			if apierrors.IsNotFound(err) {
+				respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Namespace %s does not exist", payload.Namespace))
+				return
+			}
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		log.Infof("Creating %d VirtualMachineImport CRs in namespace %s from %s/%s", len(payload.VMs), payload.Namespace, payload.SourceCluster.Namespace, payload.SourceCluster.Name)
+		response, err := createBulkPlans(r.Context(), clients, payload)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		status := http.StatusCreated
+		if response.Failed > 0 {
+			status = http.StatusMultiStatus
+		}
+		respondWithJSON(w, status, response)
+	}
+}
+
+// ValidatePlanHandler dry-runs a VirtualMachineImport: it checks the plan
+// against the cluster and the source without creating anything, and reports
+// the problems VMIC would otherwise only flag once the import is running.
+func ValidatePlanHandler(clients *K8sClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var plan VirtualMachineImport
+		if err := json.NewDecoder(r.Body).Decode(&plan); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		log.Infof("Validating VirtualMachineImport %s/%s", plan.ObjectMeta.Namespace, plan.ObjectMeta.Name)
+		respondWithJSON(w, http.StatusOK, validateVMICPlan(r.Context(), clients, plan))
+	}
+}
+
 func ListPlansHandler(clients *K8sClients) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		list, err := clients.Dynamic.Resource(vmiGVR).List(context.TODO(), metav1.ListOptions{})
+		list, err := clients.listResources(r.Context(), vmiGVR, "", metav1.ListOptions{})
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, "Failed to list VirtualMachineImport CRs: "+err.Error())
 			return
@@ -320,90 +841,323 @@ func UpdatePlanHandler(clients *K8sClients) http.HandlerFunc {
 		if payload.SkipPreflightChecks != nil {
 			unstructured.SetNestedField(item.Object, *payload.SkipPreflightChecks, "spec", "skipPreflightChecks")
 		}
-		if payload.GracefulShutdownTimeoutSeconds != nil {
-			if *payload.GracefulShutdownTimeoutSeconds == 0 {
-				unstructured.RemoveNestedField(item.Object, "spec", "gracefulShutdownTimeoutSeconds")
-			} else {
-				unstructured.SetNestedField(item.Object, *payload.GracefulShutdownTimeoutSeconds, "spec", "gracefulShutdownTimeoutSeconds")
+		if payload.GracefulShutdownTimeoutSeconds != nil {
+			if *payload.GracefulShutdownTimeoutSeconds == 0 {
+				unstructured.RemoveNestedField(item.Object, "spec", "gracefulShutdownTimeoutSeconds")
+			} else {
+				unstructured.SetNestedField(item.Object, *payload.GracefulShutdownTimeoutSeconds, "spec", "gracefulShutdownTimeoutSeconds")
+			}
+		}
+		if payload.NetworkMapping != nil {
+			mappings := make([]interface{}, 0, len(*payload.NetworkMapping))
+			for _, m := range *payload.NetworkMapping {
+				entry := map[string]interface{}{
+					"sourceNetwork":      m.SourceNetwork,
+					"destinationNetwork": m.DestinationNetwork,
+				}
+				if m.NetworkInterfaceModel != "" {
+					entry["networkInterfaceModel"] = m.NetworkInterfaceModel
+				}
+				mappings = append(mappings, entry)
+			}
+			if len(mappings) == 0 {
+				unstructured.RemoveNestedField(item.Object, "spec", "networkMapping")
+			} else {
+				if err := unstructured.SetNestedSlice(item.Object, mappings, "spec", "networkMapping"); err != nil {
+					respondWithError(w, http.StatusInternalServerError, "Failed to set network mapping: "+err.Error())
+					return
+				}
+			}
+		}
+
+		updatedItem, err := clients.Dynamic.Resource(vmiGVR).Namespace(namespace).Update(context.TODO(), item, metav1.UpdateOptions{})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to update plan: "+err.Error())
+			return
+		}
+
+		// The vm-import-controller treats virtualMachineImportInvalid (and other
+		// terminal states) as final: its reconcile switch returns early and never
+		// re-runs preflight on a spec change. Only an empty status hits the `case ""`
+		// branch that re-validates. So after saving the edited spec we clear
+		// status.importStatus via the status subresource to force re-reconciliation;
+		// without this an edit silently leaves the plan stuck in its old state.
+		unstructured.SetNestedField(updatedItem.Object, "", "status", "importStatus")
+		finalItem, err := clients.Dynamic.Resource(vmiGVR).Namespace(namespace).UpdateStatus(context.TODO(), updatedItem, metav1.UpdateOptions{})
+		if err != nil {
+			// Spec saved but status reset failed — the plan may stay in its terminal
+			// state until recreated. Surface a warning rather than failing the edit.
+			log.Warnf("Plan %s/%s spec updated but status reset failed (plan may remain invalid until recreated): %v", namespace, name, err)
+			respondWithJSON(w, http.StatusOK, updatedItem)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, finalItem)
+	}
+}
+
+func RunPlanHandler(clients *K8sClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		namespace := vars["namespace"]
+		name := vars["name"]
+
+		log.Infof("Triggering 'Run Now' for VirtualMachineImport CR: %s in namespace %s", name, namespace)
+
+		updatedItem, err := startVMICImport(r.Context(), clients, namespace, name)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, updatedItem)
+	}
+}
+
+// startVMICImport starts a VirtualMachineImport now by clearing its schedule.
+func startVMICImport(ctx context.Context, clients *K8sClients, namespace, name string) (*unstructured.Unstructured, error) {
+	item, err := clients.Dynamic.Resource(vmiGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	unstructured.RemoveNestedField(item.Object, "spec", "schedule")
+
+	return clients.Dynamic.Resource(vmiGVR).Namespace(namespace).Update(ctx, item, metav1.UpdateOptions{})
+}
+
+// respondWithWaveRunError maps wave run store and state errors to a status.
+func respondWithWaveRunError(w http.ResponseWriter, err error) {
+	var stateErr *WaveRunStateError
+	switch {
+	case apierrors.IsNotFound(err):
+		respondWithError(w, http.StatusNotFound, "Wave run not found")
+	case errors.As(err, &stateErr), apierrors.IsConflict(err):
+		respondWithError(w, http.StatusConflict, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// ListMigrationWaveRunsHandler lists the migration wave runs.
+func ListMigrationWaveRunsHandler(clients *K8sClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		runs, _, err := newWaveStore(clients).list(r.Context())
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to list wave runs: "+err.Error())
+			return
+		}
+		if runs == nil {
+			runs = []*MigrationWaveRun{}
+		}
+		sort.Slice(runs, func(i, j int) bool { return runs[i].Name < runs[j].Name })
+		respondWithJSON(w, http.StatusOK, runs)
+	}
+}
+
+// CreateMigrationWaveRunHandler creates a wave run. Every plan must exist;
+// VMIC plans the controller has not picked up yet are held back with a
+// far-off schedule until their wave starts them. Holds are applied only once
+// the run is saved, and released again if creating it fails after that.
+func CreateMigrationWaveRunHandler(clients *K8sClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload CreateMigrationWaveRunPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		store := newWaveStore(clients)
+		driver := clusterWaveDriver{clients: clients}
+		ctx := r.Context()
+		waveMu.Lock()
+		defer waveMu.Unlock()
+
+		runs, _, err := store.list(ctx)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to list wave runs: "+err.Error())
+			return
+		}
+		for _, existing := range runs {
+			if existing.Name == payload.Name {
+				respondWithError(w, http.StatusConflict, fmt.Sprintf("Wave run %s already exists", payload.Name))
+				return
+			}
+		}
+		now := time.Now()
+		run, err := newMigrationWaveRun(payload, runs, now)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		for _, wave := range run.Waves {
+			for _, p := range wave.Plans {
+				if _, _, err := driver.state(ctx, p); err != nil {
+					if apierrors.IsNotFound(err) {
+						respondWithError(w, http.StatusBadRequest, fmt.Sprintf("%s does not exist", p))
+						return
+					}
+					respondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to read %s: %v", p, err))
+					return
+				}
+			}
+		}
+
+		log.Infof("Creating migration wave run %s with %d wave(s), at most %d concurrent import(s)", run.Name, len(run.Waves), run.MaxConcurrent)
+		cm, err := store.create(ctx, run)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to save wave run: "+err.Error())
+			return
+		}
+		// Past this point a failure deletes the run and releases its holds,
+		// even if the client has gone away.
+		rollback := func() {
+			cleanup := context.Background()
+			if err := releaseWaveHolds(cleanup, store, run); err != nil {
+				log.Warnf("Wave run %s: %v", run.Name, err)
+			}
+			if err := store.delete(cleanup, run.Name); err != nil && !apierrors.IsNotFound(err) {
+				log.Warnf("Could not delete wave run %s: %v", run.Name, err)
 			}
 		}
-		if payload.NetworkMapping != nil {
-			mappings := make([]interface{}, 0, len(*payload.NetworkMapping))
-			for _, m := range *payload.NetworkMapping {
-				entry := map[string]interface{}{
-					"sourceNetwork":      m.SourceNetwork,
-					"destinationNetwork": m.DestinationNetwork,
-				}
-				if m.NetworkInterfaceModel != "" {
-					entry["networkInterfaceModel"] = m.NetworkInterfaceModel
+
+		for i := range run.Waves {
+			for j := range run.Waves[i].Plans {
+				p := &run.Waves[i].Plans[j]
+				if p.Engine != waveEngineVMIC {
+					continue
 				}
-				mappings = append(mappings, entry)
-			}
-			if len(mappings) == 0 {
-				unstructured.RemoveNestedField(item.Object, "spec", "networkMapping")
-			} else {
-				if err := unstructured.SetNestedSlice(item.Object, mappings, "spec", "networkMapping"); err != nil {
-					respondWithError(w, http.StatusInternalServerError, "Failed to set network mapping: "+err.Error())
+				held, err := holdVMICImport(ctx, clients, p.Namespace, p.Name)
+				if err != nil {
+					rollback()
+					respondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to hold %s: %v", p, err))
 					return
 				}
+				if !held {
+					p.Message = "Already picked up by the vm-import-controller; it runs outside the wave's ordering"
+				}
+			}
+		}
+		if payload.Start {
+			if err := startWaveRun(ctx, run, driver, now, store.claimer(ctx, &cm, run)); err != nil {
+				rollback()
+				respondWithWaveRunError(w, err)
+				return
 			}
 		}
+		if _, err := store.update(ctx, cm, run); err != nil {
+			rollback()
+			respondWithError(w, http.StatusInternalServerError, "Failed to save wave run: "+err.Error())
+			return
+		}
+		respondWithJSON(w, http.StatusCreated, run)
+	}
+}
 
-		updatedItem, err := clients.Dynamic.Resource(vmiGVR).Namespace(namespace).Update(context.TODO(), item, metav1.UpdateOptions{})
+// GetMigrationWaveRunHandler returns one wave run.
+func GetMigrationWaveRunHandler(clients *K8sClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		run, _, err := newWaveStore(clients).get(r.Context(), mux.Vars(r)["name"])
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Failed to update plan: "+err.Error())
+			respondWithWaveRunError(w, err)
 			return
 		}
+		respondWithJSON(w, http.StatusOK, run)
+	}
+}
 
-		// The vm-import-controller treats virtualMachineImportInvalid (and other
-		// terminal states) as final: its reconcile switch returns early and never
-		// re-runs preflight on a spec change. Only an empty status hits the `case ""`
-		// branch that re-validates. So after saving the edited spec we clear
-		// status.importStatus via the status subresource to force re-reconciliation;
-		// without this an edit silently leaves the plan stuck in its old state.
-		unstructured.SetNestedField(updatedItem.Object, "", "status", "importStatus")
-		finalItem, err := clients.Dynamic.Resource(vmiGVR).Namespace(namespace).UpdateStatus(context.TODO(), updatedItem, metav1.UpdateOptions{})
+// StartMigrationWaveRunHandler starts a Pending wave run.
+func StartMigrationWaveRunHandler(clients *K8sClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := mux.Vars(r)["name"]
+		log.Infof("Starting migration wave run %s", name)
+		run, err := updateWaveRun(r.Context(), newWaveStore(clients), name, func(run *MigrationWaveRun, claim func() error) error {
+			return startWaveRun(r.Context(), run, clusterWaveDriver{clients: clients}, time.Now(), claim)
+		})
 		if err != nil {
-			// Spec saved but status reset failed — the plan may stay in its terminal
-			// state until recreated. Surface a warning rather than failing the edit.
-			log.Warnf("Plan %s/%s spec updated but status reset failed (plan may remain invalid until recreated): %v", namespace, name, err)
-			respondWithJSON(w, http.StatusOK, updatedItem)
+			respondWithWaveRunError(w, err)
 			return
 		}
-
-		respondWithJSON(w, http.StatusOK, finalItem)
+		respondWithJSON(w, http.StatusOK, run)
 	}
 }
 
-func RunPlanHandler(clients *K8sClients) http.HandlerFunc {
+// CancelMigrationWaveRunHandler stops a wave run from starting more imports.
+// The VMIC plans it held back stay held until they are released.
+func CancelMigrationWaveRunHandler(clients *K8sClients) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		vars := mux.Vars(r)
-		namespace := vars["namespace"]
-		name := vars["name"]
+		name := mux.Vars(r)["name"]
+		log.Infof("Canceling migration wave run %s", name)
+		run, err := updateWaveRun(r.Context(), newWaveStore(clients), name, func(run *MigrationWaveRun, _ func() error) error {
+			return cancelWaveRun(run, time.Now())
+		})
+		if err != nil {
+			respondWithWaveRunError(w, err)
+			return
+		}
+		respondWithJSON(w, http.StatusOK, run)
+	}
+}
 
-		log.Infof("Triggering 'Run Now' for VirtualMachineImport CR: %s in namespace %s", name, namespace)
+// ReleaseMigrationWaveRunHandler removes the hold from the VMIC plans a
+// finished or canceled wave run never started, so the vm-import-controller
+// imports them all at once, outside any wave.
+func ReleaseMigrationWaveRunHandler(clients *K8sClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := mux.Vars(r)["name"]
+		store := newWaveStore(clients)
+		waveMu.Lock()
+		defer waveMu.Unlock()
 
-		item, err := clients.Dynamic.Resource(vmiGVR).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		run, _, err := store.get(r.Context(), name)
 		if err != nil {
+			respondWithWaveRunError(w, err)
+			return
+		}
+		if run.active() {
+			respondWithWaveRunError(w, &WaveRunStateError{Run: name, Phase: run.Phase, Action: "released; cancel it first"})
+			return
+		}
+		log.Infof("Releasing the held plans of migration wave run %s", name)
+		if err := releaseWaveHolds(r.Context(), store, run); err != nil {
 			respondWithError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
+		respondWithJSON(w, http.StatusOK, map[string]string{"message": "Held plans released"})
+	}
+}
 
-		unstructured.RemoveNestedField(item.Object, "spec", "schedule")
+// DeleteMigrationWaveRunHandler deletes a wave run that is not running. Its
+// plans are left as they are: VMIC plans it held back stay held unless the
+// run was released first.
+func DeleteMigrationWaveRunHandler(clients *K8sClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := mux.Vars(r)["name"]
+		store := newWaveStore(clients)
+		waveMu.Lock()
+		defer waveMu.Unlock()
 
-		updatedItem, err := clients.Dynamic.Resource(vmiGVR).Namespace(namespace).Update(context.TODO(), item, metav1.UpdateOptions{})
+		run, _, err := store.get(r.Context(), name)
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, err.Error())
+			respondWithWaveRunError(w, err)
 			return
 		}
-
-		respondWithJSON(w, http.StatusOK, updatedItem)
+		if run.Phase == wavePhaseRunning {
+			respondWithWaveRunError(w, &WaveRunStateError{Run: name, Phase: run.Phase, Action: "deleted; cancel it first"})
+			return
+		}
+		if err := store.delete(r.Context(), name); err != nil {
+			respondWithWaveRunError(w, err)
+			return
+		}
+		log.Infof("Deleted migration wave run %s", name)
+		respondWithJSON(w, http.StatusOK, map[string]string{"message": "Wave run deleted"})
 	}
 }
 
 func ListVmwareSourcesHandler(clients *K8sClients) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		list, err := clients.Dynamic.Resource(vmwareSourceGVR).List(context.TODO(), metav1.ListOptions{})
+		list, err := clients.listResources(r.Context(), vmwareSourceGVR, "", metav1.ListOptions{})
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, "Failed to list VmwareSource CRs: "+err.Error())
 			return
@@ -419,6 +1173,11 @@ type CreateVmwareSourcePayload struct {
 	Datacenter string `json:"datacenter"`
 	Username   string `json:"username"`
 	Password   string `json:"password"`
+	// TLS settings for the UI's own vCenter connections, stored in the
+	// credentials secret. Same semantics as CreateForkliftProviderPayload.
+	InsecureSkipVerify *bool   `json:"insecureSkipVerify,omitempty"`
+	CACert             string  `json:"cacert,omitempty"`
+	Thumbprint         *string `json:"thumbprint,omitempty"`
 }
 
 func CreateVmwareSourceHandler(clients *K8sClients) http.HandlerFunc {
@@ -441,6 +1200,11 @@ func CreateVmwareSourceHandler(clients *K8sClients) http.HandlerFunc {
 				"password": payload.Password,
 			},
 		}
+		insecureSkipVerify := payload.InsecureSkipVerify == nil || *payload.InsecureSkipVerify
+		if err := setVCenterTLSSecretFields(secret, &insecureSkipVerify, payload.CACert, payload.Thumbprint); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid TLS settings: "+err.Error())
+			return
+		}
 		_, err := clients.Clientset.CoreV1().Secrets(payload.Namespace).Create(context.TODO(), secret, metav1.CreateOptions{})
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, "Failed to create credentials secret: "+err.Error())
@@ -487,7 +1251,7 @@ func GetVmwareSourceDetails(clients *K8sClients) http.HandlerFunc {
 		namespace := vars["namespace"]
 		name := vars["name"]
 
-		sourceObj, err := clients.Dynamic.Resource(vmwareSourceGVR).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		sourceObj, err := clients.getResource(r.Context(), vmwareSourceGVR, namespace, name)
 		if err != nil {
 			respondWithError(w, http.StatusNotFound, "Failed to get VmwareSource: "+err.Error())
 			return
@@ -504,7 +1268,12 @@ func GetVmwareSourceDetails(clients *K8sClients) http.HandlerFunc {
 			return
 		}
 
-		sourceObj.Object["spec"].(map[string]interface{})["username"] = string(secret.Data["username"])
+		specMap := sourceObj.Object["spec"].(map[string]interface{})
+		specMap["username"] = string(secret.Data["username"])
+		insecure, caCert, thumbprint := vcenterTLSSettings(secret.Data)
+		specMap["insecureSkipVerify"] = strconv.FormatBool(insecure)
+		specMap["hasCACert"] = caCert != ""
+		specMap["thumbprint"] = thumbprint
 
 		respondWithJSON(w, http.StatusOK, sourceObj)
 	}
@@ -534,8 +1303,10 @@ func UpdateVmwareSourceHandler(clients *K8sClients) http.HandlerFunc {
 			return
 		}
 
-		// 2. Update the Secret, only if new credentials are provided
-		if payload.Username != "" || payload.Password != "" {
+		// 2. Update the Secret, only if new credentials or TLS settings are provided
+		needsSecretUpdate := payload.Username != "" || payload.Password != "" ||
+			payload.InsecureSkipVerify != nil || payload.CACert != "" || payload.Thumbprint != nil
+		if needsSecretUpdate {
 			secret, err := clients.Clientset.CoreV1().Secrets(namespace).Get(context.TODO(), secretName, metav1.GetOptions{})
 			if err != nil {
 				respondWithError(w, http.StatusInternalServerError, "Failed to get associated secret: "+err.Error())
@@ -552,6 +1323,10 @@ func UpdateVmwareSourceHandler(clients *K8sClients) http.HandlerFunc {
 			if payload.Password != "" {
 				secret.StringData["password"] = payload.Password
 			}
+			if err := setVCenterTLSSecretFields(secret, payload.InsecureSkipVerify, payload.CACert, payload.Thumbprint); err != nil {
+				respondWithError(w, http.StatusBadRequest, "Invalid TLS settings: "+err.Error())
+				return
+			}
 			_, err = clients.Clientset.CoreV1().Secrets(namespace).Update(context.TODO(), secret, metav1.UpdateOptions{})
 			if err != nil {
 				respondWithError(w, http.StatusInternalServerError, "Failed to update secret: "+err.Error())
@@ -575,6 +1350,9 @@ func UpdateVmwareSourceHandler(clients *K8sClients) http.HandlerFunc {
 			return
 		}
 
+		// Endpoint or credentials may have changed; drop the pooled vCenter login.
+		invalidateVCenterSource(vcenterSessionKey("VmwareSource", namespace, name))
+
 		respondWithJSON(w, http.StatusOK, updatedObj)
 	}
 }
@@ -599,6 +1377,7 @@ func DeleteVmwareSourceHandler(clients *K8sClients) http.HandlerFunc {
 			respondWithError(w, http.StatusInternalServerError, "Failed to delete VmwareSource: "+err.Error())
 			return
 		}
+		invalidateVCenterSource(vcenterSessionKey("VmwareSource", namespace, name))
 
 		// 3. Delete the associated Secret
 		if secretName != "" {
@@ -649,28 +1428,32 @@ func CreateNamespaceHandler(clients *K8sClients) http.HandlerFunc {
 func ListVlanConfigsHandler(clients *K8sClients) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		log.Info("Listing Harvester VlanConfigs")
-		gvr := schema.GroupVersionResource{
-			Group:    "k8s.cni.cncf.io",
-			Version:  "v1",
-			Resource: "network-attachment-definitions",
-		}
-
-		// Match any Harvester-managed network regardless of type. Filtering on
-		// =L2VlanNetwork dropped UntaggedNetwork NADs (e.g. "local-network"); an
-		// existence selector on the type label includes both VLAN and untagged.
-		listOptions := metav1.ListOptions{
-			LabelSelector: "network.harvesterhci.io/type",
-		}
-
-		list, err := clients.Dynamic.Resource(gvr).Namespace("").List(context.TODO(), listOptions)
+		items, err := listHarvesterNADs(r.Context(), clients)
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 
-		log.Debugf("Fetched VLAN definitions: %+v", list.Items)
-		respondWithJSON(w, http.StatusOK, list.Items)
+		log.Debugf("Fetched VLAN definitions: %+v", items)
+		respondWithJSON(w, http.StatusOK, items)
+	}
+}
+
+// listHarvesterNADs lists the NetworkAttachmentDefinitions Harvester manages,
+// across all namespaces.
+func listHarvesterNADs(ctx context.Context, clients *K8sClients) ([]unstructured.Unstructured, error) {
+	// Match any Harvester-managed network regardless of type. Filtering on
+	// =L2VlanNetwork dropped UntaggedNetwork NADs (e.g. "local-network"); an
+	// existence selector on the type label includes both VLAN and untagged.
+	listOptions := metav1.ListOptions{
+		LabelSelector: "network.harvesterhci.io/type",
+	}
+
+	list, err := clients.listResources(ctx, nadGVR, "", listOptions)
+	if err != nil {
+		return nil, err
 	}
+	return list.Items, nil
 }
 
 func ListStorageClassesHandler(clients *K8sClients) http.HandlerFunc {
@@ -684,6 +1467,11 @@ func ListStorageClassesHandler(clients *K8sClients) http.HandlerFunc {
 	}
 }
 
+// HandleGetPlanLogs returns the vm-import-controller logs about a plan (every
+// line with ?all=true). After a leader change the lines may be in any replica,
+// or in a replica's instance before it restarted, so all of them are read and
+// merged in time order, each line tagged with its pod when there is more than
+// one. X-Controller-Leader names the replica holding the leader Lease.
 func HandleGetPlanLogs(clients *K8sClients) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
@@ -692,48 +1480,34 @@ func HandleGetPlanLogs(clients *K8sClients) http.HandlerFunc {
 
 		log.Infof("Fetching logs related to plan %s/%s", namespace, name)
 
-		// 1. Get the plan to find its source
-		planObj, err := clients.Dynamic.Resource(vmiGVR).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		controller := configuredVMICController()
+		sources, leader, err := vmicLogSources(r.Context(), clients, controller, namespace, name, true)
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Failed to get plan: "+err.Error())
+			respondWithError(w, http.StatusInternalServerError, "Failed to find plan logs: "+err.Error())
 			return
 		}
-		sourceName, _ := getNestedStringOrWarn(planObj.Object, "spec", "sourceCluster", "name")
-		sourceNamespace, _ := getNestedStringOrWarn(planObj.Object, "spec", "sourceCluster", "namespace")
-
-		// 2. Find the controller pod
-		pods, err := clients.Clientset.CoreV1().Pods("harvester-system").List(context.TODO(), metav1.ListOptions{
-			LabelSelector: "app.kubernetes.io/name=harvester-vm-import-controller",
-		})
-		if err != nil || len(pods.Items) == 0 {
-			respondWithError(w, http.StatusInternalServerError, "Could not find vm-import-controller pod")
-			return
-		}
-		podName := pods.Items[0].Name
-
-		// 3. Fetch logs from the pod
-		req := clients.Clientset.CoreV1().Pods("harvester-system").GetLogs(podName, &v1.PodLogOptions{})
-		podLogs, err := req.Stream(context.TODO())
-		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Failed to stream pod logs: "+err.Error())
+		if len(sources) == 0 {
+			respondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Could not find vm-import-controller pods in %s (%s)", controller.Namespace, controller.Selector))
 			return
 		}
-		defer podLogs.Close()
 
-		// 4. Filter logs for the specific plan and its source
-		var logOutput strings.Builder
-		scanner := bufio.NewScanner(podLogs)
-		planSearchString := fmt.Sprintf("'%s/%s'", namespace, name)
-		sourceSearchString := fmt.Sprintf("'%s/%s'", sourceNamespace, sourceName)
 		showAll := r.URL.Query().Get("all") == "true"
-
-		for scanner.Scan() {
-			line := scanner.Text()
-			if showAll || strings.Contains(line, planSearchString) || strings.Contains(line, sourceSearchString) {
-				logOutput.WriteString(line + "\n")
+		keep := func(src logSource, line string) bool { return src.includes(line, showAll, false) }
+		var logOutput strings.Builder
+		for _, l := range readMergedLogs(r.Context(), clients, sources, v1.PodLogOptions{}, keep) {
+			if len(sources) > 1 {
+				tag := l.Source.Pod.Name
+				if l.Source.Previous {
+					tag += ", previous"
+				}
+				logOutput.WriteString("[" + tag + "] ")
 			}
+			logOutput.WriteString(l.Line + "\n")
 		}
 
+		if leader != "" {
+			w.Header().Set("X-Controller-Leader", leader)
+		}
 		w.Header().Set("Content-Type", "text/plain")
 		w.WriteHeader(http.StatusOK)
 		if _, err := w.Write([]byte(logOutput.String())); err != nil {
@@ -750,7 +1524,7 @@ func HandleGetPlanYAML(clients *K8sClients) http.HandlerFunc {
 
 		log.Infof("Fetching YAML for plan %s/%s", namespace, name)
 
-		item, err := clients.Dynamic.Resource(vmiGVR).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		item, err := clients.getResource(r.Context(), vmiGVR, namespace, name)
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, err.Error())
 			return
@@ -778,7 +1552,7 @@ func HandleGetResource(clients *K8sClients, gvr schema.GroupVersionResource) htt
 		namespace := vars["namespace"]
 		name := vars["name"]
 
-		item, err := clients.Dynamic.Resource(gvr).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		item, err := clients.getResource(r.Context(), gvr, namespace, name)
 		if err != nil {
 			respondWithError(w, http.StatusNotFound, err.Error())
 			return
@@ -796,7 +1570,7 @@ func HandleGetSourceYAML(clients *K8sClients, gvr schema.GroupVersionResource) h
 
 		log.Infof("Fetching YAML for source %s/%s", namespace, name)
 
-		item, err := clients.Dynamic.Resource(gvr).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		item, err := clients.getResource(r.Context(), gvr, namespace, name)
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, err.Error())
 			return
@@ -821,12 +1595,12 @@ func ListVMsHandler(clients *K8sClients) http.HandlerFunc {
 		vars := mux.Vars(r)
 		namespace := vars["namespace"]
 
-		list, err := clients.Dynamic.Resource(vmGVR).Namespace(namespace).List(context.TODO(), metav1.ListOptions{})
+		items, err := listHarvesterVMs(r.Context(), clients, namespace)
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, "Failed to list VirtualMachines: "+err.Error())
 			return
 		}
-		respondWithJSON(w, http.StatusOK, list.Items)
+		respondWithJSON(w, http.StatusOK, items)
 	}
 }
 
@@ -834,7 +1608,7 @@ func ListVMsHandler(clients *K8sClients) http.HandlerFunc {
 
 func ListOvaSourcesHandler(clients *K8sClients) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		list, err := clients.Dynamic.Resource(ovaSourceGVR).List(context.TODO(), metav1.ListOptions{})
+		list, err := clients.listResources(r.Context(), ovaSourceGVR, "", metav1.ListOptions{})
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, "Failed to list OvaSource CRs: "+err.Error())
 			return
@@ -919,7 +1693,7 @@ func GetOvaSourceDetails(clients *K8sClients) http.HandlerFunc {
 		namespace := vars["namespace"]
 		name := vars["name"]
 
-		sourceObj, err := clients.Dynamic.Resource(ovaSourceGVR).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		sourceObj, err := clients.getResource(r.Context(), ovaSourceGVR, namespace, name)
 		if err != nil {
 			respondWithError(w, http.StatusNotFound, "Failed to get OvaSource: "+err.Error())
 			return
@@ -1042,8 +1816,28 @@ func DeleteOvaSourceHandler(clients *K8sClients) http.HandlerFunc {
 }
 
 type VirtualMachinePowerRequest struct {
-	VMName    string `json:"vmName"`
-	Operation string `json:"operation"` // "on", "off", "reset", "shutdown"
+	VMID      string `json:"vmId,omitempty"` // moRef value; preferred over vmName
+	VMName    string `json:"vmName"`         // display name or inventory path
+	Operation string `json:"operation"`      // "on", "off", "reset", "shutdown"
+}
+
+// respondWithVMOpError reports a failed VM operation: 409 with the matching VMs
+// when a name was ambiguous, 500 otherwise.
+func respondWithVMOpError(w http.ResponseWriter, err error) {
+	var ambiguous *AmbiguousVMError
+	if errors.As(err, &ambiguous) {
+		respondWithJSON(w, http.StatusConflict, map[string]interface{}{
+			"error":      err.Error(),
+			"candidates": ambiguous.Candidates,
+		})
+		return
+	}
+	var notFound *VMNotFoundError
+	if errors.As(err, &notFound) {
+		respondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	respondWithError(w, http.StatusInternalServerError, err.Error())
 }
 
 func HandleVMPowerOp(clients *K8sClients) http.HandlerFunc {
@@ -1058,56 +1852,34 @@ func HandleVMPowerOp(clients *K8sClients) http.HandlerFunc {
 			return
 		}
 
-		log.Infof("Power operation '%s' requested for VM %s via VmwareSource %s/%s", req.Operation, req.VMName, namespace, name)
-
-		sourceObj, err := clients.Dynamic.Resource(vmwareSourceGVR).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
-		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Failed to get VmwareSource: "+err.Error())
+		ref := VMRef{ID: req.VMID, Name: req.VMName}
+		if ref.String() == "" {
+			respondWithError(w, http.StatusBadRequest, "vmId or vmName is required")
 			return
 		}
 
-		endpoint, found := getNestedStringOrWarn(sourceObj.Object, "spec", "endpoint")
-		if !found {
-			respondWithError(w, http.StatusInternalServerError, "VmwareSource missing spec.endpoint")
-			return
-		}
-		datacenter, _ := getNestedStringOrWarn(sourceObj.Object, "spec", "dc")
-		secretName, found := getNestedStringOrWarn(sourceObj.Object, "spec", "credentials", "name")
-		if !found {
-			respondWithError(w, http.StatusInternalServerError, "VmwareSource missing credentials secret name")
-			return
-		}
-		secretNamespace, found := getNestedStringOrWarn(sourceObj.Object, "spec", "credentials", "namespace")
-		if !found {
-			respondWithError(w, http.StatusInternalServerError, "VmwareSource missing credentials secret namespace")
-			return
-		}
+		log.Infof("Power operation '%s' requested for VM %s via VmwareSource %s/%s", req.Operation, ref, namespace, name)
 
-		secret, err := clients.Clientset.CoreV1().Secrets(secretNamespace).Get(context.TODO(), secretName, metav1.GetOptions{})
+		creds, err := resolveVmwareSourceCredentials(r.Context(), clients, namespace, name)
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Failed to get credentials secret: "+err.Error())
+			respondWithError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 
-		creds := VCenterCredentials{
-			URL:        endpoint,
-			Username:   string(secret.Data["username"]),
-			Password:   string(secret.Data["password"]),
-			Datacenter: datacenter,
-		}
-
-		if err := PowerOpVM(r.Context(), creds, req.VMName, req.Operation); err != nil {
+		if err := PowerOpVM(r.Context(), creds, ref, req.Operation); err != nil {
 			log.Errorf("Failed to perform power operation: %v", err)
-			respondWithError(w, http.StatusInternalServerError, err.Error())
+			respondWithVMOpError(w, err)
 			return
 		}
 
+		inventoryCaches.MarkDirty(creds.SessionKey)
 		respondWithJSON(w, http.StatusOK, map[string]string{"message": "Power operation successful"})
 	}
 }
 
 type VirtualMachineRenameRequest struct {
-	OldName string `json:"oldName"`
+	VMID    string `json:"vmId,omitempty"` // moRef value; preferred over oldName
+	OldName string `json:"oldName"`        // display name or inventory path
 	NewName string `json:"newName"`
 }
 
@@ -1123,56 +1895,34 @@ func HandleVMRename(clients *K8sClients) http.HandlerFunc {
 			return
 		}
 
-		log.Infof("Rename operation requested from '%s' to '%s' via VmwareSource %s/%s", req.OldName, req.NewName, namespace, name)
-
-		sourceObj, err := clients.Dynamic.Resource(vmwareSourceGVR).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
-		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Failed to get VmwareSource: "+err.Error())
+		ref := VMRef{ID: req.VMID, Name: req.OldName}
+		if ref.String() == "" || req.NewName == "" {
+			respondWithError(w, http.StatusBadRequest, "vmId or oldName, and newName are required")
 			return
 		}
 
-		endpoint, found := getNestedStringOrWarn(sourceObj.Object, "spec", "endpoint")
-		if !found {
-			respondWithError(w, http.StatusInternalServerError, "VmwareSource missing spec.endpoint")
-			return
-		}
-		datacenter, _ := getNestedStringOrWarn(sourceObj.Object, "spec", "dc")
-		secretName, found := getNestedStringOrWarn(sourceObj.Object, "spec", "credentials", "name")
-		if !found {
-			respondWithError(w, http.StatusInternalServerError, "VmwareSource missing credentials secret name")
-			return
-		}
-		secretNamespace, found := getNestedStringOrWarn(sourceObj.Object, "spec", "credentials", "namespace")
-		if !found {
-			respondWithError(w, http.StatusInternalServerError, "VmwareSource missing credentials secret namespace")
-			return
-		}
+		log.Infof("Rename operation requested from '%s' to '%s' via VmwareSource %s/%s", ref, req.NewName, namespace, name)
 
-		secret, err := clients.Clientset.CoreV1().Secrets(secretNamespace).Get(context.TODO(), secretName, metav1.GetOptions{})
+		creds, err := resolveVmwareSourceCredentials(r.Context(), clients, namespace, name)
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Failed to get credentials secret: "+err.Error())
+			respondWithError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 
-		creds := VCenterCredentials{
-			URL:        endpoint,
-			Username:   string(secret.Data["username"]),
-			Password:   string(secret.Data["password"]),
-			Datacenter: datacenter,
-		}
-
-		if err := RenameVM(r.Context(), creds, req.OldName, req.NewName); err != nil {
+		if err := RenameVM(r.Context(), creds, ref, req.NewName); err != nil {
 			log.Errorf("Failed to rename VM: %v", err)
-			respondWithError(w, http.StatusInternalServerError, err.Error())
+			respondWithVMOpError(w, err)
 			return
 		}
 
+		inventoryCaches.MarkDirty(creds.SessionKey)
 		respondWithJSON(w, http.StatusOK, map[string]string{"message": "Rename successful"})
 	}
 }
 
 type UpdateVMMACRequest struct {
-	VMName    string `json:"vmName"`
+	VMID      string `json:"vmId,omitempty"` // moRef value; preferred over vmName
+	VMName    string `json:"vmName"`         // display name or inventory path
 	DeviceKey int32  `json:"deviceKey"`
 	NewMAC    string `json:"newMac"`
 }
@@ -1189,50 +1939,27 @@ func HandleUpdateVMMAC(clients *K8sClients) http.HandlerFunc {
 			return
 		}
 
-		log.Infof("MAC address update requested for VM '%s' (device %d) to '%s' via VmwareSource %s/%s", req.VMName, req.DeviceKey, req.NewMAC, namespace, name)
-
-		sourceObj, err := clients.Dynamic.Resource(vmwareSourceGVR).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
-		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Failed to get VmwareSource: "+err.Error())
+		ref := VMRef{ID: req.VMID, Name: req.VMName}
+		if ref.String() == "" {
+			respondWithError(w, http.StatusBadRequest, "vmId or vmName is required")
 			return
 		}
 
-		endpoint, found := getNestedStringOrWarn(sourceObj.Object, "spec", "endpoint")
-		if !found {
-			respondWithError(w, http.StatusInternalServerError, "VmwareSource missing spec.endpoint")
-			return
-		}
-		datacenter, _ := getNestedStringOrWarn(sourceObj.Object, "spec", "dc")
-		secretName, found := getNestedStringOrWarn(sourceObj.Object, "spec", "credentials", "name")
-		if !found {
-			respondWithError(w, http.StatusInternalServerError, "VmwareSource missing credentials secret name")
-			return
-		}
-		secretNamespace, found := getNestedStringOrWarn(sourceObj.Object, "spec", "credentials", "namespace")
-		if !found {
-			respondWithError(w, http.StatusInternalServerError, "VmwareSource missing credentials secret namespace")
-			return
-		}
+		log.Infof("MAC address update requested for VM '%s' (device %d) to '%s' via VmwareSource %s/%s", ref, req.DeviceKey, req.NewMAC, namespace, name)
 
-		secret, err := clients.Clientset.CoreV1().Secrets(secretNamespace).Get(context.TODO(), secretName, metav1.GetOptions{})
+		creds, err := resolveVmwareSourceCredentials(r.Context(), clients, namespace, name)
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Failed to get credentials secret: "+err.Error())
+			respondWithError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 
-		creds := VCenterCredentials{
-			URL:        endpoint,
-			Username:   string(secret.Data["username"]),
-			Password:   string(secret.Data["password"]),
-			Datacenter: datacenter,
-		}
-
-		if err := UpdateVMNetworkMAC(r.Context(), creds, req.VMName, req.DeviceKey, req.NewMAC); err != nil {
+		if err := UpdateVMNetworkMAC(r.Context(), creds, ref, req.DeviceKey, req.NewMAC); err != nil {
 			log.Errorf("Failed to update VM MAC: %v", err)
-			respondWithError(w, http.StatusInternalServerError, err.Error())
+			respondWithVMOpError(w, err)
 			return
 		}
 
+		inventoryCaches.MarkDirty(creds.SessionKey)
 		respondWithJSON(w, http.StatusOK, map[string]string{"message": "MAC address updated successfully"})
 	}
 }
@@ -1248,7 +1975,7 @@ func CheckForkliftAvailability(clients *K8sClients) http.HandlerFunc {
 		}
 
 		// Check if the "host" provider exists
-		_, err := clients.Dynamic.Resource(forkliftProviderGVR).Namespace(namespace).Get(context.TODO(), "host", metav1.GetOptions{})
+		_, err := clients.getResource(r.Context(), forkliftProviderGVR, namespace, "host")
 		if err != nil {
 			respondWithJSON(w, http.StatusOK, map[string]interface{}{
 				"available":        false,
@@ -1268,7 +1995,7 @@ func CheckForkliftAvailability(clients *K8sClients) http.HandlerFunc {
 // ListForkliftProvidersHandler lists Forklift Provider CRs (vsphere type only)
 func ListForkliftProvidersHandler(clients *K8sClients) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		list, err := clients.Dynamic.Resource(forkliftProviderGVR).Namespace("").List(context.TODO(), metav1.ListOptions{})
+		list, err := clients.listResources(r.Context(), forkliftProviderGVR, "", metav1.ListOptions{})
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, "Failed to list Forklift Providers: "+err.Error())
 			return
@@ -1326,18 +2053,10 @@ func CreateForkliftProviderHandler(clients *K8sClients) http.HandlerFunc {
 			}
 		} else {
 			// vSphere providers need credentials
-			insecureSkipVerify := "true"
-			if payload.InsecureSkipVerify != nil && !*payload.InsecureSkipVerify {
-				insecureSkipVerify = "false"
-			}
 			secretData = map[string]string{
-				"user":               payload.Username,
-				"password":           payload.Password,
-				"url":                payload.URL,
-				"insecureSkipVerify": insecureSkipVerify,
-			}
-			if insecureSkipVerify == "false" && payload.CACert != "" {
-				secretData["cacert"] = payload.CACert
+				"user":     payload.Username,
+				"password": payload.Password,
+				"url":      payload.URL,
 			}
 		}
 
@@ -1353,6 +2072,14 @@ func CreateForkliftProviderHandler(clients *K8sClients) http.HandlerFunc {
 			Type: v1.SecretTypeOpaque,
 			StringData: secretData,
 		}
+		if providerType != "ova" {
+			// nil defaults to skipping verification, for backwards compatibility
+			insecureSkipVerify := payload.InsecureSkipVerify == nil || *payload.InsecureSkipVerify
+			if err := setVCenterTLSSecretFields(secret, &insecureSkipVerify, payload.CACert, payload.Thumbprint); err != nil {
+				respondWithError(w, http.StatusBadRequest, "Invalid TLS settings: "+err.Error())
+				return
+			}
+		}
 		_, err := clients.Clientset.CoreV1().Secrets(payload.Namespace).Create(context.TODO(), secret, metav1.CreateOptions{})
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, "Failed to create Forklift secret: "+err.Error())
@@ -1424,7 +2151,7 @@ func GetForkliftProviderDetails(clients *K8sClients) http.HandlerFunc {
 		namespace := vars["namespace"]
 		name := vars["name"]
 
-		providerObj, err := clients.Dynamic.Resource(forkliftProviderGVR).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		providerObj, err := clients.getResource(r.Context(), forkliftProviderGVR, namespace, name)
 		if err != nil {
 			respondWithError(w, http.StatusNotFound, "Failed to get Forklift Provider: "+err.Error())
 			return
@@ -1439,6 +2166,7 @@ func GetForkliftProviderDetails(clients *K8sClients) http.HandlerFunc {
 				specMap["username"] = string(secret.Data["user"])
 				specMap["insecureSkipVerify"] = string(secret.Data["insecureSkipVerify"])
 				specMap["hasCACert"] = len(secret.Data["cacert"]) > 0
+				specMap["thumbprint"] = string(secret.Data["thumbprint"])
 			}
 		}
 
@@ -1472,7 +2200,7 @@ func UpdateForkliftProviderHandler(clients *K8sClients) http.HandlerFunc {
 			return
 		}
 		needsSecretUpdate := payload.Username != "" || payload.Password != "" ||
-			payload.URL != "" || payload.InsecureSkipVerify != nil || payload.CACert != ""
+			payload.URL != "" || payload.InsecureSkipVerify != nil || payload.CACert != "" || payload.Thumbprint != nil
 		if needsSecretUpdate {
 			secret, err := clients.Clientset.CoreV1().Secrets(namespace).Get(context.TODO(), secretName, metav1.GetOptions{})
 			if err != nil {
@@ -1492,16 +2220,9 @@ func UpdateForkliftProviderHandler(clients *K8sClients) http.HandlerFunc {
 			if payload.URL != "" {
 				secret.StringData["url"] = payload.URL
 			}
-			if payload.InsecureSkipVerify != nil {
-				if *payload.InsecureSkipVerify {
-					secret.StringData["insecureSkipVerify"] = "true"
-					delete(secret.Data, "cacert")
-				} else {
-					secret.StringData["insecureSkipVerify"] = "false"
-					if payload.CACert != "" {
-						secret.StringData["cacert"] = payload.CACert
-					}
-				}
+			if err := setVCenterTLSSecretFields(secret, payload.InsecureSkipVerify, payload.CACert, payload.Thumbprint); err != nil {
+				respondWithError(w, http.StatusBadRequest, "Invalid TLS settings: "+err.Error())
+				return
 			}
 			_, err = clients.Clientset.CoreV1().Secrets(namespace).Update(context.TODO(), secret, metav1.UpdateOptions{})
 			if err != nil {
@@ -1548,6 +2269,9 @@ func UpdateForkliftProviderHandler(clients *K8sClients) http.HandlerFunc {
 			return
 		}
 
+		// URL, credentials or TLS settings may have changed; drop the pooled vCenter login.
+		invalidateVCenterSource(vcenterSessionKey("Provider", namespace, name))
+
 		respondWithJSON(w, http.StatusOK, updatedObj)
 	}
 }
@@ -1571,6 +2295,7 @@ func DeleteForkliftProviderHandler(clients *K8sClients) http.HandlerFunc {
 			respondWithError(w, http.StatusInternalServerError, "Failed to delete Forklift Provider: "+err.Error())
 			return
 		}
+		invalidateVCenterSource(vcenterSessionKey("Provider", namespace, name))
 
 		if secretName != "" {
 			err = clients.Clientset.CoreV1().Secrets(namespace).Delete(context.TODO(), secretName, metav1.DeleteOptions{})
@@ -1583,7 +2308,9 @@ func DeleteForkliftProviderHandler(clients *K8sClients) http.HandlerFunc {
 	}
 }
 
-// HandleGetForkliftInventory fetches vCenter inventory using Forklift Provider credentials
+// HandleGetForkliftInventory fetches vCenter inventory using Forklift Provider credentials.
+// Served from the inventory cache; ?refresh=true forces a fresh walk. Without
+// ?datacenter= only the first datacenter is returned.
 func HandleGetForkliftInventory(clients *K8sClients) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
@@ -1592,65 +2319,147 @@ func HandleGetForkliftInventory(clients *K8sClients) http.HandlerFunc {
 
 		log.Infof("Fetching inventory for Forklift Provider %s/%s", namespace, name)
 
-		providerObj, err := clients.Dynamic.Resource(forkliftProviderGVR).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
-		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Failed to get Forklift Provider: "+err.Error())
-			return
-		}
-
-		providerURL, found := getNestedStringOrWarn(providerObj.Object, "spec", "url")
-		if !found {
-			respondWithError(w, http.StatusInternalServerError, "Forklift Provider missing URL")
-			return
-		}
-		secretName, found := getNestedStringOrWarn(providerObj.Object, "spec", "secret", "name")
-		if !found {
-			respondWithError(w, http.StatusInternalServerError, "Forklift Provider missing secret name")
-			return
-		}
-		secretNamespace, found := getNestedStringOrWarn(providerObj.Object, "spec", "secret", "namespace")
-		if !found {
-			respondWithError(w, http.StatusInternalServerError, "Forklift Provider missing secret namespace")
-			return
-		}
-
-		secret, err := clients.Clientset.CoreV1().Secrets(secretNamespace).Get(context.TODO(), secretName, metav1.GetOptions{})
+		creds, err := resolveForkliftProviderCredentials(r.Context(), clients, namespace, name)
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Failed to get Forklift credentials secret: "+err.Error())
+			respondWithError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 
-		// Forklift secrets use "user" and "password" fields, and "url"
-		// The URL from the secret or Provider spec both work; use Provider spec URL
-		// Pass the full URL including /sdk path, same as VM Import Controller
-		creds := VCenterCredentials{
-			URL:        providerURL,
-			Username:   string(secret.Data["user"]),
-			Password:   string(secret.Data["password"]),
-			Datacenter: "", // Will be auto-discovered
-		}
-
-		inventory, err := GetVCenterInventoryAutoDiscover(r.Context(), creds)
+		force := r.URL.Query().Get("refresh") == "true"
+		tree, err := inventoryCaches.Get(r.Context(), creds, GetVCenterInventoryAllDatacenters, force)
 		if err != nil {
 			log.Errorf("Failed to get vCenter inventory via Forklift Provider: %v", err)
 			respondWithError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 
-		respondWithJSON(w, http.StatusOK, inventory)
+		respondWithInventory(w, r, tree, creds)
 	}
 }
 
 // ListForkliftPlansHandler lists Forklift Plan CRs
 func ListForkliftPlansHandler(clients *K8sClients) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		list, err := clients.Dynamic.Resource(forkliftPlanGVR).Namespace("").List(context.TODO(), metav1.ListOptions{})
+		list, err := clients.listResources(r.Context(), forkliftPlanGVR, "", metav1.ListOptions{})
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, "Failed to list Forklift Plans: "+err.Error())
 			return
 		}
-		respondWithJSON(w, http.StatusOK, list.Items)
+		respondWithJSON(w, http.StatusOK, list.Items)
+	}
+}
+
+// unmappedDatastores returns "name (moRef)" for every datastore referenced by
+// the disks in sourceVmDisks (the JSON disk list the UI sends from the
+// inventory) that has no storage mapping. Disk lists without datastore details
+// yield nothing, so older clients are not rejected.
+func unmappedDatastores(sourceVmDisks string, mappings []ForkliftStorageMapEntry) []string {
+	if sourceVmDisks == "" {
+		return nil
+	}
+	var disks []VMDisk
+	if err := json.Unmarshal([]byte(sourceVmDisks), &disks); err != nil {
+		log.Debugf("Could not parse source VM disks to check storage mappings: %v", err)
+		return nil
+	}
+
+	mapped := map[string]bool{}
+	for _, sm := range mappings {
+		mapped[sm.SourceID] = true
+	}
+	var missing []string
+	for _, disk := range disks {
+		if disk.DatastoreID == "" || mapped[disk.DatastoreID] {
+			continue
+		}
+		mapped[disk.DatastoreID] = true // report each datastore once
+		missing = append(missing, fmt.Sprintf("%s (%s)", disk.DatastoreName, disk.DatastoreID))
+	}
+	return missing
+}
+
+// ValidateForkliftPlanHandler dry-runs a Forklift plan payload, checking
+// what CreateForkliftPlanHandler would create without creating anything.
+func ValidateForkliftPlanHandler(clients *K8sClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload CreateForkliftPlanPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		log.Infof("Validating Forklift migration plan %s/%s", payload.Namespace, payload.Name)
+		respondWithJSON(w, http.StatusOK, validateForkliftPlan(r.Context(), clients, payload))
+	}
+}
+
+// forkliftNetworkMapEntries builds the spec.map entries of a NetworkMap.
+func forkliftNetworkMapEntries(mappings []ForkliftNetworkMapEntry) []interface{} {
+	entries := make([]interface{}, len(mappings))
+	for i, nm := range mappings {
+		dest := map[string]interface{}{
+			"type": nm.DestinationType,
+		}
+		if nm.DestinationType == "multus" && nm.DestinationName != "" {
+			dest["name"] = nm.DestinationName
+			dest["namespace"] = nm.DestinationNamespace
+		}
+		source := map[string]interface{}{
+			"id": nm.SourceID,
+		}
+		if nm.SourceName != "" {
+			source["name"] = nm.SourceName
+		}
+		entries[i] = map[string]interface{}{
+			"source":      source,
+			"destination": dest,
+		}
+	}
+	return entries
+}
+
+// forkliftStorageMapEntries builds the spec.map entries of a StorageMap.
+func forkliftStorageMapEntries(mappings []ForkliftStorageMapEntry, providerType string) []interface{} {
+	entries := make([]interface{}, len(mappings))
+	for i, sm := range mappings {
+		dest := map[string]interface{}{
+			"storageClass": sm.DestinationStorageClass,
+		}
+		if sm.VolumeMode != "" {
+			dest["volumeMode"] = sm.VolumeMode
+		}
+		if sm.AccessMode != "" {
+			dest["accessMode"] = sm.AccessMode
+		}
+		// OVA providers use source.name (disk filename), vSphere uses source.id (moRef)
+		source := map[string]interface{}{}
+		if providerType == "ova" && sm.SourceName != "" {
+			source["name"] = sm.SourceName
+		} else {
+			source["id"] = sm.SourceID
+		}
+		entries[i] = map[string]interface{}{
+			"source":      source,
+			"destination": dest,
+		}
+	}
+	return entries
+}
+
+// forkliftVMEntries builds the spec.vms entries of a Plan.
+func forkliftVMEntries(vms []ForkliftVMEntry) []interface{} {
+	entries := make([]interface{}, len(vms))
+	for i, vm := range vms {
+		entry := map[string]interface{}{
+			"id":   vm.ID,
+			"name": vm.Name,
+		}
+		if vm.TargetName != "" {
+			entry["targetName"] = vm.TargetName
+		}
+		entries[i] = entry
 	}
+	return entries
 }
 
 // CreateForkliftPlanHandler creates NetworkMap, StorageMap, and Plan atomically
@@ -1674,29 +2483,25 @@ func CreateForkliftPlanHandler(clients *K8sClients) http.HandlerFunc {
 
 		log.Infof("Creating Forklift migration plan: %s in namespace %s", payload.Name, payload.Namespace)
 
-		// 1. Create NetworkMap
-		networkMapName := payload.Name + "-network-map"
-		networkMapEntries := make([]interface{}, len(payload.NetworkMappings))
-		for i, nm := range payload.NetworkMappings {
-			dest := map[string]interface{}{
-				"type": nm.DestinationType,
-			}
-			if nm.DestinationType == "multus" && nm.DestinationName != "" {
-				dest["name"] = nm.DestinationName
-				dest["namespace"] = nm.DestinationNamespace
-			}
-			source := map[string]interface{}{
-				"id": nm.SourceID,
-			}
-			if nm.SourceName != "" {
-				source["name"] = nm.SourceName
-			}
-			networkMapEntries[i] = map[string]interface{}{
-				"source":      source,
-				"destination": dest,
+		// Determine provider type for plan creation logic
+		providerType := payload.ProviderType
+		if providerType == "" {
+			providerType = "vsphere"
+		}
+
+		// Refuse a StorageMap that misses datastores the VM's disks live on;
+		// Forklift would only fail on them once the migration is running.
+		if providerType != "ova" {
+			if missing := unmappedDatastores(payload.SourceVmDisks, payload.StorageMappings); len(missing) > 0 {
+				respondWithError(w, http.StatusBadRequest, "No storage mapping for datastore(s) used by the VM's disks: "+strings.Join(missing, ", "))
+				return
 			}
 		}
 
+		// 1. Create NetworkMap
+		networkMapName := payload.Name + "-network-map"
+		networkMapEntries := forkliftNetworkMapEntries(payload.NetworkMappings)
+
 		networkMap := &unstructured.Unstructured{
 			Object: map[string]interface{}{
 				"apiVersion": "forklift.konveyor.io/v1beta1",
@@ -1731,37 +2536,9 @@ func CreateForkliftPlanHandler(clients *K8sClients) http.HandlerFunc {
 			return
 		}
 
-		// Determine provider type for plan creation logic
-		providerType := payload.ProviderType
-		if providerType == "" {
-			providerType = "vsphere"
-		}
-
 		// 2. Create StorageMap
 		storageMapName := payload.Name + "-storage-map"
-		storageMapEntries := make([]interface{}, len(payload.StorageMappings))
-		for i, sm := range payload.StorageMappings {
-			dest := map[string]interface{}{
-				"storageClass": sm.DestinationStorageClass,
-			}
-			if sm.VolumeMode != "" {
-				dest["volumeMode"] = sm.VolumeMode
-			}
-			if sm.AccessMode != "" {
-				dest["accessMode"] = sm.AccessMode
-			}
-			// OVA providers use source.name (disk filename), vSphere uses source.id (moRef)
-			source := map[string]interface{}{}
-			if providerType == "ova" && sm.SourceName != "" {
-				source["name"] = sm.SourceName
-			} else {
-				source["id"] = sm.SourceID
-			}
-			storageMapEntries[i] = map[string]interface{}{
-				"source":      source,
-				"destination": dest,
-			}
-		}
+		storageMapEntries := forkliftStorageMapEntries(payload.StorageMappings, providerType)
 
 		storageMap := &unstructured.Unstructured{
 			Object: map[string]interface{}{
@@ -1802,17 +2579,7 @@ func CreateForkliftPlanHandler(clients *K8sClients) http.HandlerFunc {
 		}
 
 		// 3. Create Plan
-		vmEntries := make([]interface{}, len(payload.VMs))
-		for i, vm := range payload.VMs {
-			entry := map[string]interface{}{
-				"id":   vm.ID,
-				"name": vm.Name,
-			}
-			if vm.TargetName != "" {
-				entry["targetName"] = vm.TargetName
-			}
-			vmEntries[i] = entry
-		}
+		vmEntries := forkliftVMEntries(payload.VMs)
 
 		planAnnotations := map[string]interface{}{}
 		if payload.PopulatorLabels {
@@ -1908,6 +2675,38 @@ func CreateForkliftPlanHandler(clients *K8sClients) http.HandlerFunc {
 	}
 }
 
+// UpdateForkliftPlanHandler edits a Forklift Plan and the entries of its
+// NetworkMap and StorageMap, unless Forklift is busy with the Plan.
+func UpdateForkliftPlanHandler(clients *K8sClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		namespace := vars["namespace"]
+		name := vars["name"]
+
+		var payload UpdateForkliftPlanPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		updated, err := updateForkliftPlan(r.Context(), clients, namespace, name, payload)
+		var busyErr *ForkliftPlanBusyError
+		var invalidErr *ForkliftPlanUpdateError
+		switch {
+		case err == nil:
+			respondWithJSON(w, http.StatusOK, updated)
+		case apierrors.IsNotFound(err):
+			respondWithError(w, http.StatusNotFound, "Forklift Plan not found")
+		case errors.As(err, &invalidErr):
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		case errors.As(err, &busyErr), apierrors.IsConflict(err):
+			respondWithError(w, http.StatusConflict, err.Error())
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Failed to update Forklift Plan: "+err.Error())
+		}
+	}
+}
+
 // DeleteForkliftPlanHandler deletes a Forklift Plan and its associated NetworkMap/StorageMap
 func DeleteForkliftPlanHandler(clients *K8sClients) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -1955,7 +2754,7 @@ func HandleGetForkliftPlanYAML(clients *K8sClients) http.HandlerFunc {
 		namespace := vars["namespace"]
 		name := vars["name"]
 
-		item, err := clients.Dynamic.Resource(forkliftPlanGVR).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		item, err := clients.getResource(r.Context(), forkliftPlanGVR, namespace, name)
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, err.Error())
 			return
@@ -1990,7 +2789,7 @@ func HandleGetForkliftOvaInventory(clients *K8sClients) http.HandlerFunc {
 		}
 
 		// 1. Get the Provider CR to obtain its UID
-		providerObj, err := clients.Dynamic.Resource(forkliftProviderGVR).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		providerObj, err := clients.getResource(r.Context(), forkliftProviderGVR, namespace, name)
 		if err != nil {
 			respondWithError(w, http.StatusNotFound, "Failed to get OVA provider: "+err.Error())
 			return
@@ -2061,48 +2860,19 @@ func HandleGetForkliftLogs(clients *K8sClients) http.HandlerFunc {
 
 		log.Debugf("Fetching Forklift logs for plan %s/%s (forklift ns: %s)", planNamespace, planName, forkliftNs)
 
-		// Get the plan to find target namespace and VM IDs/names
-		var targetNamespace string
-		var vmIDs []string
-		planObj, err := clients.Dynamic.Resource(forkliftPlanGVR).Namespace(planNamespace).Get(context.TODO(), planName, metav1.GetOptions{})
-		if err == nil {
-			targetNamespace, _ = getNestedStringOrWarn(planObj.Object, "spec", "targetNamespace")
-			vms, _, vmsErr := unstructured.NestedSlice(planObj.Object, "spec", "vms")
-			if vmsErr != nil {
-				log.Warnf("Error reading spec.vms: %v", vmsErr)
-			}
-			for _, vm := range vms {
-				if vmMap, ok := vm.(map[string]interface{}); ok {
-					if id, ok := vmMap["id"].(string); ok && id != "" {
-						vmIDs = append(vmIDs, id)
-					}
-				}
-			}
-		}
-
-		// Related resource names that appear in controller logs
-		migrationName := planName + "-migration"
-		networkMapName := planName + "-network-map"
-		storageMapName := planName + "-storage-map"
-		controllerMatchTerms := []string{planName, migrationName, networkMapName, storageMapName}
+		sources, targetNamespace := forkliftLogSources(r.Context(), clients, planNamespace, planName, forkliftNs)
 
 		var logOutput strings.Builder
 
-		isErrorLine := func(line string) bool {
-			l := strings.ToLower(line)
-			return strings.Contains(l, "error") || strings.Contains(l, "fail") ||
-				strings.Contains(l, "warn") || strings.Contains(l, "critical") ||
-				strings.Contains(l, "\"level\":\"error\"") || strings.Contains(l, "\"level\":\"warn\"")
-		}
-
-		// fetchAndWriteLogs streams logs from a pod/container, optionally filtering.
-		// If filterTerms is nil, all lines are included (worker pods are already plan-specific).
-		fetchAndWriteLogs := func(ns string, pod v1.Pod, filterTerms []string, header string) {
-			for _, cs := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
-				req := clients.Clientset.CoreV1().Pods(ns).GetLogs(pod.Name, &v1.PodLogOptions{
-					Container: cs.Name,
+		// fetchAndWriteLogs reads the logs of every container of a pod, keeping
+		// the lines the source's filter accepts.
+		fetchAndWriteLogs := func(src logSource) {
+			header := fmt.Sprintf("\n=== %s ===\n", src.Label)
+			for _, container := range podContainers(src.Pod) {
+				req := clients.Clientset.CoreV1().Pods(src.Namespace).GetLogs(src.Pod.Name, &v1.PodLogOptions{
+					Container: container,
 				})
-				stream, err := req.Stream(context.TODO())
+				stream, err := req.Stream(r.Context())
 				if err != nil {
 					// Skip containers that can't be read (not started, etc.)
 					continue
@@ -2115,27 +2885,8 @@ func HandleGetForkliftLogs(clients *K8sClients) http.HandlerFunc {
 
 				for scanner.Scan() {
 					line := scanner.Text()
-					include := showAll
-
-					if !include && filterTerms != nil {
-						// Controller pod: only include lines mentioning our plan/resources
-						for _, term := range filterTerms {
-							if strings.Contains(line, term) {
-								include = true
-								break
-							}
-						}
-					} else if filterTerms == nil {
-						// Worker pod: include everything
-						include = true
-					}
-
-					if include && errorsOnly && !showAll {
-						include = isErrorLine(line)
-					}
-
-					if include {
-						if !headerWritten && header != "" {
+					if src.includes(line, showAll, errorsOnly) {
+						if !headerWritten {
 							logOutput.WriteString(header)
 							headerWritten = true
 						}
@@ -2146,119 +2897,8 @@ func HandleGetForkliftLogs(clients *K8sClients) http.HandlerFunc {
 			}
 		}
 
-		// ── 1. Forklift controller pod (filtered by plan name) ──
-		// The controller pod is labeled app=forklift-controller in the forklift namespace
-		controllerPods, _ := clients.Clientset.CoreV1().Pods(forkliftNs).List(context.TODO(), metav1.ListOptions{
-			LabelSelector: "app=forklift-controller",
-		})
-		if controllerPods == nil || len(controllerPods.Items) == 0 {
-			// Fallback: any pod with "forklift-controller" in the name
-			allPods, _ := clients.Clientset.CoreV1().Pods(forkliftNs).List(context.TODO(), metav1.ListOptions{})
-			if allPods != nil {
-				for _, p := range allPods.Items {
-					if strings.Contains(p.Name, "forklift-controller") {
-						if controllerPods == nil {
-							controllerPods = &v1.PodList{}
-						}
-						controllerPods.Items = append(controllerPods.Items, p)
-					}
-				}
-			}
-		}
-		if controllerPods != nil {
-			for _, pod := range controllerPods.Items {
-				fetchAndWriteLogs(forkliftNs, pod, controllerMatchTerms,
-					fmt.Sprintf("\n=== Forklift Controller: %s ===\n", pod.Name))
-			}
-		}
-
-		// ── 2. Migration worker pods (label: plan-name=<planName>) ──
-		// These run in targetNamespace and include virt-v2v, virt-v2v-inspection, consumer pods.
-		// Forklift labels them with plan-name=<planName>.
-		searchNamespaces := []string{}
-		if targetNamespace != "" {
-			searchNamespaces = append(searchNamespaces, targetNamespace)
-		}
-		if planNamespace != targetNamespace {
-			searchNamespaces = append(searchNamespaces, planNamespace)
-		}
-
-		for _, ns := range searchNamespaces {
-			// Direct label query — most efficient
-			workerPods, err := clients.Clientset.CoreV1().Pods(ns).List(context.TODO(), metav1.ListOptions{
-				LabelSelector: "plan-name=" + planName,
-			})
-			if err == nil {
-				for _, pod := range workerPods.Items {
-					appLabel := pod.Labels["forklift.app"]
-					podType := "Worker"
-					switch appLabel {
-					case "virt-v2v":
-						podType = "virt-v2v Conversion"
-					case "virt-v2v-inspection":
-						podType = "virt-v2v Inspection"
-					case "consumer":
-						podType = "Consumer"
-					}
-					vmID := pod.Labels["vmID"]
-					vmInfo := ""
-					if vmID != "" {
-						vmInfo = fmt.Sprintf(" (VM: %s)", vmID)
-					}
-					fetchAndWriteLogs(ns, pod, nil,
-						fmt.Sprintf("\n=== %s: %s/%s%s ===\n", podType, ns, pod.Name, vmInfo))
-				}
-			}
-
-			// Also look for populator pods (created by CDI, name prefix "populate-")
-			// and pods whose name starts with the plan name (hook jobs, converter jobs)
-			allPods, err := clients.Clientset.CoreV1().Pods(ns).List(context.TODO(), metav1.ListOptions{})
-			if err == nil {
-				seen := map[string]bool{}
-				if workerPods != nil {
-					for _, p := range workerPods.Items {
-						seen[p.Name] = true
-					}
-				}
-				for _, pod := range allPods.Items {
-					if seen[pod.Name] {
-						continue
-					}
-					isRelevant := false
-					podType := "Related Pod"
-
-					// Check if pod name starts with planName (hook jobs, converter jobs)
-					if strings.HasPrefix(pod.Name, planName+"-") {
-						isRelevant = true
-						podType = "Plan Pod"
-					}
-
-					// Check for populator pods by looking at migration label
-					if !isRelevant && strings.HasPrefix(pod.Name, "populate-") {
-						if _, hasMigLabel := pod.Labels["migration"]; hasMigLabel {
-							// Check if this populator's migration label matches our plan's migration
-							isRelevant = true
-							podType = "CDI Populator"
-						}
-					}
-
-					// Check for converter jobs
-					if !isRelevant && strings.HasPrefix(pod.Name, "convert-") {
-						for _, vmID := range vmIDs {
-							if strings.Contains(pod.Name, vmID) {
-								isRelevant = true
-								podType = "Disk Converter"
-								break
-							}
-						}
-					}
-
-					if isRelevant {
-						fetchAndWriteLogs(ns, pod, nil,
-							fmt.Sprintf("\n=== %s: %s/%s ===\n", podType, ns, pod.Name))
-					}
-				}
-			}
+		for _, src := range sources {
+			fetchAndWriteLogs(src)
 		}
 
 		if logOutput.Len() == 0 {
@@ -2289,30 +2929,318 @@ func CreateForkliftMigrationHandler(clients *K8sClients) http.HandlerFunc {
 
 		log.Infof("Creating Migration for Forklift Plan %s/%s", namespace, name)
 
-		migration := &unstructured.Unstructured{
-			Object: map[string]interface{}{
-				"apiVersion": "forklift.konveyor.io/v1beta1",
-				"kind":       "Migration",
-				"metadata": map[string]interface{}{
-					"name":      name + "-migration",
+		createdObj, err := startForkliftMigration(r.Context(), clients, namespace, name, time.Now())
+		var windowErr *MaintenanceWindowError
+		switch {
+		case errors.As(err, &windowErr):
+			respondWithError(w, http.StatusConflict, err.Error())
+			return
+		case apierrors.IsNotFound(err):
+			respondWithError(w, http.StatusNotFound, "Forklift Plan not found")
+			return
+		case err != nil:
+			respondWithError(w, http.StatusInternalServerError, "Failed to create Forklift Migration: "+err.Error())
+			return
+		}
+
+		respondWithJSON(w, http.StatusCreated, createdObj)
+	}
+}
+
+// startForkliftMigration creates the Migration, named after the Plan, that
+// makes Forklift run it. It refuses with a *MaintenanceWindowError outside
+// the Plan's maintenance window at now and carries over a scheduled cutover.
+func startForkliftMigration(ctx context.Context, clients *K8sClients, namespace, name string, now time.Time) (*unstructured.Unstructured, error) {
+	plan, err := clients.Dynamic.Resource(forkliftPlanGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	schedule, err := forkliftScheduleOf(plan)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkForkliftWindow(plan, schedule, now); err != nil {
+		return nil, err
+	}
+
+	migration := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "forklift.konveyor.io/v1beta1",
+			"kind":       "Migration",
+			"metadata": map[string]interface{}{
+				"name":      name + "-migration",
+				"namespace": namespace,
+			},
+			"spec": map[string]interface{}{
+				"plan": map[string]interface{}{
+					"name":      name,
 					"namespace": namespace,
 				},
-				"spec": map[string]interface{}{
-					"plan": map[string]interface{}{
-						"name":      name,
-						"namespace": namespace,
-					},
-				},
 			},
+		},
+	}
+
+	if schedule != nil && schedule.Cutover != nil {
+		unstructured.SetNestedField(migration.Object, schedule.Cutover.UTC().Format(time.RFC3339), "spec", "cutover")
+	}
+
+	return clients.Dynamic.Resource(forkliftMigrationGVR).Namespace(namespace).Create(ctx, migration, metav1.CreateOptions{})
+}
+
+// respondWithForkliftPlanError maps a failed Plan lookup to 404 or 500.
+func respondWithForkliftPlanError(w http.ResponseWriter, err error) {
+	if apierrors.IsNotFound(err) {
+		respondWithError(w, http.StatusNotFound, "Forklift Plan not found")
+		return
+	}
+	respondWithError(w, http.StatusInternalServerError, err.Error())
+}
+
+// GetForkliftScheduleHandler returns a Forklift Plan's schedule and
+// maintenance window state.
+func GetForkliftScheduleHandler(clients *K8sClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		plan, err := clients.getResource(r.Context(), forkliftPlanGVR, vars["namespace"], vars["name"])
+		if err != nil {
+			respondWithForkliftPlanError(w, err)
+			return
+		}
+		schedule, err := forkliftScheduleOf(plan)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondWithJSON(w, http.StatusOK, forkliftScheduleStatus(schedule, time.Now()))
+	}
+}
+
+// SetForkliftScheduleHandler schedules a Forklift Plan's start, maintenance
+// window and warm cutover, replacing any earlier schedule. Once the Plan has
+// a Migration only the window and the cutover can change; a new cutover is
+// applied to the running Migration too.
+func SetForkliftScheduleHandler(clients *K8sClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		namespace, name := vars["namespace"], vars["name"]
+
+		var payload ForkliftSchedule
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+			return
+		}
+		ctx := r.Context()
+		plan, err := clients.Dynamic.Resource(forkliftPlanGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			respondWithForkliftPlanError(w, err)
+			return
 		}
+		_, err = clients.Dynamic.Resource(forkliftMigrationGVR).Namespace(namespace).Get(ctx, name+"-migration", metav1.GetOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			respondWithError(w, http.StatusInternalServerError, "Failed to look up the plan's Migration: "+err.Error())
+			return
+		}
+		started := err == nil
+		warm, _, _ := unstructured.NestedBool(plan.Object, "spec", "warm")
 
-		createdObj, err := clients.Dynamic.Resource(forkliftMigrationGVR).Namespace(namespace).Create(context.TODO(), migration, metav1.CreateOptions{})
+		schedule, err := prepareForkliftSchedule(payload, warm, started, time.Now())
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Failed to create Forklift Migration: "+err.Error())
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Infof("Scheduling Forklift Plan %s/%s", namespace, name)
+		if err := saveForkliftSchedule(ctx, clients, namespace, name, schedule); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to save the schedule: "+err.Error())
 			return
 		}
+		if started && schedule.Cutover != nil {
+			if err := setForkliftCutover(ctx, clients, namespace, name+"-migration", &schedule.Cutover.Time); err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Saved the schedule but failed to set the Migration's cutover: "+err.Error())
+				return
+			}
+		}
+		respondWithJSON(w, http.StatusOK, forkliftScheduleStatus(schedule, time.Now()))
+	}
+}
 
-		respondWithJSON(w, http.StatusCreated, createdObj)
+// DeleteForkliftScheduleHandler removes a Forklift Plan's schedule. A cutover
+// already set on a running Migration is left alone.
+func DeleteForkliftScheduleHandler(clients *K8sClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		if err := saveForkliftSchedule(r.Context(), clients, vars["namespace"], vars["name"], nil); err != nil {
+			respondWithForkliftPlanError(w, err)
+			return
+		}
+		respondWithJSON(w, http.StatusOK, map[string]string{"message": "Schedule removed"})
+	}
+}
+
+// respondWithForkliftCutover reports the cutover state of a Plan.
+func respondWithForkliftCutover(w http.ResponseWriter, r *http.Request, clients *K8sClients, namespace, name string) {
+	plan, err := clients.Dynamic.Resource(forkliftPlanGVR).Namespace(namespace).Get(r.Context(), name, metav1.GetOptions{})
+	if err != nil {
+		respondWithForkliftPlanError(w, err)
+		return
+	}
+	migration, err := latestForkliftMigration(r.Context(), clients, namespace, name)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list Forklift Migrations: "+err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, forkliftCutoverStatus(plan, migration))
+}
+
+// GetForkliftCutoverHandler returns a warm Plan's cutover, auto-cutover
+// threshold and per-VM precopy history.
+func GetForkliftCutoverHandler(clients *K8sClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		respondWithForkliftCutover(w, r, clients, vars["namespace"], vars["name"])
+	}
+}
+
+// SetForkliftCutoverHandler sets or moves the cutover of a warm Plan's
+// running Migration and/or its auto-cutover threshold. The cutover has to
+// fall inside the Plan's maintenance window, if it has one.
+func SetForkliftCutoverHandler(clients *K8sClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		namespace, name := vars["namespace"], vars["name"]
+
+		var payload SetForkliftCutoverPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+			return
+		}
+		switch {
+		case !payload.Now && payload.Cutover == nil && payload.AutoCutoverAfter == nil:
+			respondWithError(w, http.StatusBadRequest, "Give a cutover time, now or autoCutoverAfter")
+			return
+		case payload.Now && payload.Cutover != nil:
+			respondWithError(w, http.StatusBadRequest, "Give either a cutover time or now, not both")
+			return
+		case payload.AutoCutoverAfter != nil && *payload.AutoCutoverAfter < 0:
+			respondWithError(w, http.StatusBadRequest, "autoCutoverAfter cannot be negative")
+			return
+		}
+
+		ctx := r.Context()
+		plan, err := clients.Dynamic.Resource(forkliftPlanGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			respondWithForkliftPlanError(w, err)
+			return
+		}
+		if warm, _, _ := unstructured.NestedBool(plan.Object, "spec", "warm"); !warm {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Plan %s is not a warm migration and has no cutover", name))
+			return
+		}
+		schedule, err := forkliftScheduleOf(plan)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		// Check the cutover before changing anything.
+		var migration *unstructured.Unstructured
+		var cutover time.Time
+		if payload.Now || payload.Cutover != nil {
+			now := time.Now()
+			migration, err = latestForkliftMigration(ctx, clients, namespace, name)
+			switch {
+			case err != nil:
+				respondWithError(w, http.StatusInternalServerError, "Failed to list Forklift Migrations: "+err.Error())
+				return
+			case migration == nil:
+				respondWithError(w, http.StatusNotFound, "The plan has no Migration yet; run it first, or schedule the cutover together with its start")
+				return
+			case forkliftMigrationCompleted(migration):
+				respondWithError(w, http.StatusConflict, fmt.Sprintf("Migration %s has already finished", migration.GetName()))
+				return
+			}
+			cutover = now
+			if payload.Cutover != nil {
+				cutover = payload.Cutover.Time
+				if cutover.Before(now) {
+					respondWithError(w, http.StatusBadRequest, "The cutover time is in the past; cut over now instead")
+					return
+				}
+			}
+			if err := checkForkliftWindow(plan, schedule, cutover); err != nil {
+				respondWithError(w, http.StatusConflict, "Cutover refused: "+err.Error())
+				return
+			}
+		}
+
+		if payload.AutoCutoverAfter != nil {
+			if err := saveAutoCutoverAfter(ctx, clients, namespace, name, *payload.AutoCutoverAfter); err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Failed to save the auto-cutover threshold: "+err.Error())
+				return
+			}
+		}
+		if migration != nil {
+			log.Infof("Setting cutover of Forklift Migration %s/%s to %s", namespace, migration.GetName(), cutover.UTC().Format(time.RFC3339))
+			if err := setForkliftCutover(ctx, clients, namespace, migration.GetName(), &cutover); err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Failed to set the cutover: "+err.Error())
+				return
+			}
+			// Keep a scheduled cutover from disagreeing with the Migration.
+			if schedule != nil && schedule.Cutover != nil {
+				schedule.Cutover = &metav1.Time{Time: cutover}
+				if err := saveForkliftSchedule(ctx, clients, namespace, name, schedule); err != nil {
+					log.Warnf("Could not update the scheduled cutover of Forklift plan %s/%s: %v", namespace, name, err)
+				}
+			}
+		}
+		respondWithForkliftCutover(w, r, clients, namespace, name)
+	}
+}
+
+// ClearForkliftCutoverHandler removes the cutover from a warm Plan's running
+// Migration, along with the scheduled cutover and the auto-cutover
+// threshold, so the Migration keeps precopying until a new cutover is set.
+func ClearForkliftCutoverHandler(clients *K8sClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		namespace, name := vars["namespace"], vars["name"]
+		ctx := r.Context()
+
+		plan, err := clients.Dynamic.Resource(forkliftPlanGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			respondWithForkliftPlanError(w, err)
+			return
+		}
+		migration, err := latestForkliftMigration(ctx, clients, namespace, name)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to list Forklift Migrations: "+err.Error())
+			return
+		}
+		if migration != nil && forkliftMigrationCompleted(migration) {
+			respondWithError(w, http.StatusConflict, fmt.Sprintf("Migration %s has already finished", migration.GetName()))
+			return
+		}
+
+		if autoCutoverAfter(plan) > 0 {
+			if err := saveAutoCutoverAfter(ctx, clients, namespace, name, 0); err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Failed to clear the auto-cutover threshold: "+err.Error())
+				return
+			}
+		}
+		if schedule, err := forkliftScheduleOf(plan); err == nil && schedule != nil && schedule.Cutover != nil {
+			schedule.Cutover = nil
+			if err := saveForkliftSchedule(ctx, clients, namespace, name, schedule); err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Failed to clear the scheduled cutover: "+err.Error())
+				return
+			}
+		}
+		if migration != nil {
+			log.Infof("Clearing cutover of Forklift Migration %s/%s", namespace, migration.GetName())
+			if err := setForkliftCutover(ctx, clients, namespace, migration.GetName(), nil); err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Failed to clear the cutover: "+err.Error())
+				return
+			}
+		}
+		respondWithForkliftCutover(w, r, clients, namespace, name)
 	}
 }
 
@@ -2336,6 +3264,28 @@ func DeleteForkliftMigrationHandler(clients *K8sClients) http.HandlerFunc {
 	}
 }
 
+// latestForkliftMigration returns the most recent Migration of a Plan, or nil
+// if it has none.
+func latestForkliftMigration(ctx context.Context, clients *K8sClients, namespace, plan string) (*unstructured.Unstructured, error) {
+	list, err := clients.listResources(ctx, forkliftMigrationGVR, namespace, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var latest *unstructured.Unstructured
+	var latestTime string
+	for i, item := range list.Items {
+		planName, _ := getNestedStringOrWarn(item.Object, "spec", "plan", "name")
+		if planName == plan {
+			created := item.GetCreationTimestamp().Format("2006-01-02T15:04:05Z")
+			if created > latestTime {
+				latestTime = created
+				latest = &list.Items[i]
+			}
+		}
+	}
+	return latest, nil
+}
+
 // GetForkliftMigrationStatus returns the status of Migrations for a Plan
 func GetForkliftMigrationStatus(clients *K8sClients) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -2343,29 +3293,13 @@ func GetForkliftMigrationStatus(clients *K8sClients) http.HandlerFunc {
 		namespace := vars["namespace"]
 		name := vars["name"]
 
-		// List all migrations in the namespace
-		list, err := clients.Dynamic.Resource(forkliftMigrationGVR).Namespace(namespace).List(context.TODO(), metav1.ListOptions{})
+		latestMigration, err := latestForkliftMigration(r.Context(), clients, namespace, name)
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, "Failed to list Forklift Migrations: "+err.Error())
 			return
 		}
-
-		// Find the migration for this plan (prefer the most recent one)
-		var latestMigration map[string]interface{}
-		var latestTime string
-		for _, item := range list.Items {
-			planName, _ := getNestedStringOrWarn(item.Object, "spec", "plan", "name")
-			if planName == name {
-				created := item.GetCreationTimestamp().Format("2006-01-02T15:04:05Z")
-				if created > latestTime {
-					latestTime = created
-					obj := item.Object
-					latestMigration = obj
-				}
-			}
-		}
 		if latestMigration != nil {
-			respondWithJSON(w, http.StatusOK, latestMigration)
+			respondWithJSON(w, http.StatusOK, latestMigration.Object)
 			return
 		}
 
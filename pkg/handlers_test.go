@@ -435,3 +435,46 @@ func TestDeleteForkliftProviderHandler(t *testing.T) {
 		t.Log("delete of nonexistent provider returned 200 (fake client may not error)")
 	}
 }
+
+func TestCreateForkliftPlanHandlerRequiresEveryDiskDatastoreMapped(t *testing.T) {
+	disks, _ := json.Marshal([]VMDisk{
+		{Name: "Hard disk 1", DatastoreID: "datastore-1", DatastoreName: "ds1"},
+		{Name: "Hard disk 2", DatastoreID: "datastore-2", DatastoreName: "ds2"},
+		{Name: "Hard disk 3", DatastoreID: "datastore-2", DatastoreName: "ds2"},
+	})
+	payload := CreateForkliftPlanPayload{
+		Name:              "web-01",
+		Namespace:         "forklift",
+		ProviderName:      "vcenter",
+		ProviderNamespace: "forklift",
+		TargetNamespace:   "default",
+		StorageMappings:   []ForkliftStorageMapEntry{{SourceID: "datastore-1", DestinationStorageClass: "longhorn"}},
+		VMs:               []ForkliftVMEntry{{ID: "vm-1", Name: "web-01"}},
+		SourceVmDisks:     string(disks),
+	}
+
+	t.Run("missing datastore", func(t *testing.T) {
+		clients := newTestClients()
+		rr := executeRequest(CreateForkliftPlanHandler(clients), "POST", "/api/v1/forklift/plans", payload, nil)
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d; body: %s", rr.Code, rr.Body.String())
+		}
+		var body map[string]string
+		_ = json.Unmarshal(rr.Body.Bytes(), &body)
+		if body["error"] != "No storage mapping for datastore(s) used by the VM's disks: ds2 (datastore-2)" {
+			t.Errorf("unexpected error: %q", body["error"])
+		}
+		if _, err := clients.Dynamic.Resource(forkliftNetworkMapGVR).Namespace("forklift").Get(context.Background(), "web-01-network-map", metav1.GetOptions{}); err == nil {
+			t.Errorf("expected no NetworkMap to be created for a rejected plan")
+		}
+	})
+
+	t.Run("all datastores mapped", func(t *testing.T) {
+		complete := payload
+		complete.StorageMappings = append(complete.StorageMappings, ForkliftStorageMapEntry{SourceID: "datastore-2", DestinationStorageClass: "longhorn"})
+		rr := executeRequest(CreateForkliftPlanHandler(newTestClients()), "POST", "/api/v1/forklift/plans", complete, nil)
+		if rr.Code != http.StatusCreated {
+			t.Errorf("expected status 201, got %d; body: %s", rr.Code, rr.Body.String())
+		}
+	})
+}
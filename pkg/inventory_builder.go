@@ -0,0 +1,690 @@
+// pkg/inventory_builder.go
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// inventoryPropertySpecs lists, per managed object type, the properties the
+// inventory builder needs. They are fetched for every object under a
+// datacenter (or the whole vCenter) in a single RetrieveProperties call, so the
+// number of round trips to vCenter does not grow with the number of
+// datacenters, VMs, NICs or datastores.
+var inventoryPropertySpecs = map[string][]string{
+	"Datacenter":                  {"name", "vmFolder", "hostFolder"},
+	"ClusterComputeResource":      {"name", "host", "resourcePool"},
+	"ComputeResource":             {"name", "host", "resourcePool"},
+	"HostSystem":                  {"name"},
+	"ResourcePool":                {"name", "resourcePool", "vm"},
+	"VirtualApp":                  {"name", "resourcePool", "vm"},
+	"Folder":                      {"name", "childEntity"},
+	"VirtualMachine":              {"name", "guest", "summary", "config", "network", "runtime", "datastore", "resourcePool"},
+	"Network":                     {"name"},
+	"DistributedVirtualPortgroup": {"name", "config.distributedVirtualSwitch"},
+	"DistributedVirtualSwitch":    {"name"},
+	"Datastore":                   {"name"},
+}
+
+// inventorySnapshot is the raw property data for one datacenter or a whole
+// vCenter, indexed by moRef.
+type inventorySnapshot struct {
+	datacenters map[types.ManagedObjectReference]mo.Datacenter
+	folders     map[types.ManagedObjectReference]mo.Folder
+	computes    map[types.ManagedObjectReference]mo.ComputeResource // clusters and standalone hosts
+	pools       map[types.ManagedObjectReference]mo.ResourcePool    // resource pools and vApps
+	vms         map[types.ManagedObjectReference]mo.VirtualMachine
+	names       *inventoryNames
+
+	// vmNodes memoizes VM nodes built by the VM-folder walk so the
+	// host-and-cluster view reuses them, folder path included.
+	vmNodes map[types.ManagedObjectReference]InventoryNode
+	// inFolder holds VMs that are a child of some VM folder, so a vApp does
+	// not list them a second time.
+	inFolder map[types.ManagedObjectReference]bool
+}
+
+// inventoryNames resolves moRefs to display names. It is seeded from the batch
+// retrieval and memoizes anything missing from it (e.g. a portgroup on a switch
+// owned by another datacenter), so each object costs at most one lookup per build.
+type inventoryNames struct {
+	pc       *property.Collector
+	names    map[types.ManagedObjectReference]string
+	switches map[types.ManagedObjectReference]types.ManagedObjectReference // portgroup → dvSwitch
+	fetched  map[types.ManagedObjectReference]bool
+}
+
+func newInventoryNames(pc *property.Collector) *inventoryNames {
+	return &inventoryNames{
+		pc:       pc,
+		names:    map[types.ManagedObjectReference]string{},
+		switches: map[types.ManagedObjectReference]types.ManagedObjectReference{},
+		fetched:  map[types.ManagedObjectReference]bool{},
+	}
+}
+
+// Name returns the display name of ref, fetching and memoizing it on a miss.
+func (n *inventoryNames) Name(ctx context.Context, ref types.ManagedObjectReference) (string, bool) {
+	if name, ok := n.names[ref]; ok {
+		return name, true
+	}
+	if n.fetched[ref] {
+		return "", false
+	}
+	n.fetched[ref] = true
+
+	var me mo.ManagedEntity
+	if err := n.pc.RetrieveOne(ctx, ref, []string{"name"}, &me); err != nil {
+		log.Warnf("Could not resolve name of %s %s: %v", ref.Type, ref.Value, err)
+		return "", false
+	}
+	n.names[ref] = me.Name
+	return me.Name, true
+}
+
+// Portgroup returns the "switch/portgroup" display name for a DVPortgroup key,
+// degrading to the portgroup name, then to the raw key, when lookups fail.
+func (n *inventoryNames) Portgroup(ctx context.Context, key string) string {
+	pgRef := types.ManagedObjectReference{Type: "DistributedVirtualPortgroup", Value: key}
+	if _, seeded := n.names[pgRef]; !seeded && !n.fetched[pgRef] {
+		n.fetched[pgRef] = true
+		var dvpg mo.DistributedVirtualPortgroup
+		if err := n.pc.RetrieveOne(ctx, pgRef, []string{"name", "config.distributedVirtualSwitch"}, &dvpg); err != nil {
+			log.Warnf("Could not resolve DVPortgroup %s: %v", key, err)
+		} else {
+			n.addPortgroup(dvpg)
+		}
+	}
+
+	pgName, ok := n.names[pgRef]
+	if !ok {
+		return key // fallback: raw moref key
+	}
+	dvsRef, ok := n.switches[pgRef]
+	if !ok {
+		return pgName
+	}
+	dvsName, ok := n.Name(ctx, dvsRef)
+	if !ok {
+		log.Warnf("Could not resolve dvSwitch name for portgroup %s", key)
+		return pgName
+	}
+	return dvsName + "/" + pgName
+}
+
+func (n *inventoryNames) addPortgroup(pg mo.DistributedVirtualPortgroup) {
+	n.names[pg.Self] = pg.Name
+	if pg.Config.DistributedVirtualSwitch != nil {
+		n.switches[pg.Self] = *pg.Config.DistributedVirtualSwitch
+	}
+}
+
+// retrieveInventorySnapshot fetches every object the inventory tree needs under
+// root (a Datacenter or the root folder) with one ContainerView and one
+// multi-type RetrieveProperties call.
+func retrieveInventorySnapshot(ctx context.Context, c *govmomi.Client, root types.ManagedObjectReference) (*inventorySnapshot, error) {
+	kinds := make([]string, 0, len(inventoryPropertySpecs))
+	for kind := range inventoryPropertySpecs {
+		kinds = append(kinds, kind)
+	}
+
+	v, err := view.NewManager(c.Client).CreateContainerView(ctx, root, kinds, true)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := v.Destroy(context.Background()); err != nil {
+			log.Debugf("Failed to destroy inventory container view: %v", err)
+		}
+	}()
+
+	spec := types.PropertyFilterSpec{
+		ObjectSet: []types.ObjectSpec{
+			{
+				Obj:       v.Reference(),
+				Skip:      types.NewBool(true),
+				SelectSet: []types.BaseSelectionSpec{v.TraversalSpec()},
+			},
+		},
+	}
+	if root.Type == "Datacenter" {
+		// A datacenter isn't in its own view; fetch it in the same call.
+		spec.ObjectSet = append(spec.ObjectSet, types.ObjectSpec{Obj: root})
+	}
+	for kind, props := range inventoryPropertySpecs {
+		spec.PropSet = append(spec.PropSet, types.PropertySpec{Type: kind, PathSet: props})
+	}
+
+	pc := property.DefaultCollector(c.Client)
+	res, err := pc.RetrieveProperties(ctx, types.RetrieveProperties{SpecSet: []types.PropertyFilterSpec{spec}})
+	if err != nil {
+		return nil, err
+	}
+
+	snap := &inventorySnapshot{
+		datacenters: map[types.ManagedObjectReference]mo.Datacenter{},
+		folders:     map[types.ManagedObjectReference]mo.Folder{},
+		computes:    map[types.ManagedObjectReference]mo.ComputeResource{},
+		pools:       map[types.ManagedObjectReference]mo.ResourcePool{},
+		vms:         map[types.ManagedObjectReference]mo.VirtualMachine{},
+		names:       newInventoryNames(pc),
+		vmNodes:     map[types.ManagedObjectReference]InventoryNode{},
+		inFolder:    map[types.ManagedObjectReference]bool{},
+	}
+	for _, content := range res.Returnval {
+		obj, err := mo.ObjectContentToType(content)
+		if err != nil {
+			log.Warnf("Could not load %s %s: %v", content.Obj.Type, content.Obj.Value, err)
+			continue
+		}
+		switch o := obj.(type) {
+		case mo.Datacenter:
+			snap.datacenters[o.Self] = o
+		case mo.Folder:
+			snap.folders[o.Self] = o
+			for _, child := range o.ChildEntity {
+				if child.Type == "VirtualMachine" {
+					snap.inFolder[child] = true
+				}
+			}
+		case mo.ClusterComputeResource:
+			snap.computes[o.Self] = o.ComputeResource
+		case mo.ComputeResource:
+			snap.computes[o.Self] = o
+		case mo.HostSystem:
+			snap.names.names[o.Self] = o.Name
+		case mo.ResourcePool:
+			snap.pools[o.Self] = o
+		case mo.VirtualApp:
+			snap.pools[o.Self] = o.ResourcePool
+		case mo.VirtualMachine:
+			snap.vms[o.Self] = o
+		case mo.DistributedVirtualPortgroup:
+			snap.names.addPortgroup(o)
+		case mo.DistributedVirtualSwitch:
+			snap.names.names[o.Self] = o.Name
+		case mo.VmwareDistributedVirtualSwitch:
+			snap.names.names[o.Self] = o.Name
+		case mo.Network:
+			snap.names.names[o.Self] = o.Name
+		case mo.OpaqueNetwork:
+			snap.names.names[o.Self] = o.Name
+		case mo.Datastore:
+			snap.names.names[o.Self] = o.Name
+		}
+	}
+	return snap, nil
+}
+
+// buildVCenterInventory walks every datacenter in vCenter into one tree whose
+// root has a "datacenter" child per datacenter, sorted by name.
+func buildVCenterInventory(ctx context.Context, c *govmomi.Client) (*InventoryNode, error) {
+	snap, err := retrieveInventorySnapshot(ctx, c, c.ServiceContent.RootFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	dcs := make([]mo.Datacenter, 0, len(snap.datacenters))
+	for _, mdc := range snap.datacenters {
+		dcs = append(dcs, mdc)
+	}
+	sort.Slice(dcs, func(i, j int) bool { return dcs[i].Name < dcs[j].Name })
+
+	rootNode := &InventoryNode{
+		ID:   c.ServiceContent.RootFolder.Value,
+		Name: c.URL().Hostname(),
+		Type: "vcenter",
+	}
+	for _, mdc := range dcs {
+		rootNode.Children = append(rootNode.Children, *snap.datacenterNode(ctx, mdc))
+	}
+	return rootNode, nil
+}
+
+// datacenterNode builds the subtree for one datacenter. Its children are the
+// VM-folder view; the host-and-cluster view is kept alongside for
+// InventoryNode.view.
+func (s *inventorySnapshot) datacenterNode(ctx context.Context, mdc mo.Datacenter) *InventoryNode {
+	node := &InventoryNode{
+		ID:   mdc.Self.Value,
+		Name: mdc.Name,
+		Type: "datacenter",
+	}
+	// Children of the VM folder start with an empty folder path. The VM-folder
+	// walk must come first: it records each VM's folder for the host view.
+	node.Children = s.folderChildren(ctx, mdc.VmFolder, "", mdc.Name)
+	node.hostView = s.hostFolderChildren(ctx, mdc.HostFolder, mdc.Name)
+	if node.hostView == nil {
+		node.hostView = []InventoryNode{}
+	}
+	return node
+}
+
+// childPath appends name to a slash-separated inventory path.
+func childPath(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "/" + name
+}
+
+// folderChildren builds nodes for a VM folder's children, in vCenter's order.
+func (s *inventorySnapshot) folderChildren(ctx context.Context, folderRef types.ManagedObjectReference, folderPath, datacenter string) []InventoryNode {
+	folder, ok := s.folders[folderRef]
+	if !ok {
+		log.Warnf("Folder %s missing from inventory snapshot", folderRef.Value)
+		return nil
+	}
+
+	var children []InventoryNode
+	for _, childRef := range folder.ChildEntity {
+		switch childRef.Type {
+		case "Folder":
+			child, ok := s.folders[childRef]
+			if !ok {
+				log.Warnf("Could not process entity %s: not in inventory snapshot", childRef.Value)
+				continue
+			}
+			children = append(children, InventoryNode{
+				ID:       childRef.Value,
+				Name:     child.Name,
+				Type:     childRef.Type,
+				Children: s.folderChildren(ctx, childRef, childPath(folderPath, child.Name), datacenter),
+			})
+		case "VirtualApp":
+			if node, ok := s.vAppNode(ctx, childRef, folderPath, datacenter); ok {
+				children = append(children, node)
+			}
+		case "VirtualMachine":
+			if node, ok := s.vmNode(ctx, childRef, folderPath, datacenter); ok {
+				children = append(children, node)
+			}
+		}
+	}
+	return children
+}
+
+// vAppNode builds a vApp for the VM-folder view: its nested vApps and its VMs,
+// which vSphere addresses by a path running through the vApp.
+func (s *inventorySnapshot) vAppNode(ctx context.Context, ref types.ManagedObjectReference, folderPath, datacenter string) (InventoryNode, bool) {
+	vapp, ok := s.pools[ref]
+	if !ok {
+		log.Warnf("Could not process entity %s: not in inventory snapshot", ref.Value)
+		return InventoryNode{}, false
+	}
+	path := childPath(folderPath, vapp.Name)
+	node := InventoryNode{ID: ref.Value, Name: vapp.Name, Type: ref.Type}
+	for _, childRef := range vapp.ResourcePool {
+		if childRef.Type != "VirtualApp" {
+			continue
+		}
+		if child, ok := s.vAppNode(ctx, childRef, path, datacenter); ok {
+			node.Children = append(node.Children, child)
+		}
+	}
+	for _, vmRef := range vapp.Vm {
+		if s.inFolder[vmRef] {
+			continue
+		}
+		if child, ok := s.vmNode(ctx, vmRef, path, datacenter); ok {
+			node.Children = append(node.Children, child)
+		}
+	}
+	return node, true
+}
+
+// vmNode returns the node for a VM, building it on first use. The first
+// caller's folder path sticks, which is why the VM-folder view is built first.
+func (s *inventorySnapshot) vmNode(ctx context.Context, ref types.ManagedObjectReference, folderPath, datacenter string) (InventoryNode, bool) {
+	if node, ok := s.vmNodes[ref]; ok {
+		return node, true
+	}
+	mvm, ok := s.vms[ref]
+	if !ok {
+		log.Warnf("Could not process entity %s: not in inventory snapshot", ref.Value)
+		return InventoryNode{}, false
+	}
+	node := vmInventoryNode(ctx, mvm, folderPath, s.names)
+	node.Datacenter = datacenter
+	s.vmNodes[ref] = node
+	return node, true
+}
+
+// hostFolderChildren builds the host-and-cluster view of a host folder:
+// folders, clusters and standalone hosts, in vCenter's order.
+func (s *inventorySnapshot) hostFolderChildren(ctx context.Context, folderRef types.ManagedObjectReference, datacenter string) []InventoryNode {
+	folder, ok := s.folders[folderRef]
+	if !ok {
+		log.Warnf("Folder %s missing from inventory snapshot", folderRef.Value)
+		return nil
+	}
+
+	var children []InventoryNode
+	for _, childRef := range folder.ChildEntity {
+		switch childRef.Type {
+		case "Folder":
+			child, ok := s.folders[childRef]
+			if !ok {
+				log.Warnf("Could not process entity %s: not in inventory snapshot", childRef.Value)
+				continue
+			}
+			children = append(children, InventoryNode{
+				ID:       childRef.Value,
+				Name:     child.Name,
+				Type:     childRef.Type,
+				Children: s.hostFolderChildren(ctx, childRef, datacenter),
+			})
+		case "ClusterComputeResource", "ComputeResource":
+			cr, ok := s.computes[childRef]
+			if !ok {
+				log.Warnf("Could not process entity %s: not in inventory snapshot", childRef.Value)
+				continue
+			}
+			children = append(children, s.computeResourceNode(ctx, cr, datacenter))
+		}
+	}
+	return children
+}
+
+// computeResourceNode builds a cluster, or a standalone host, the way the
+// vSphere client shows them: hosts with the VMs of the root resource pool that
+// run on them, followed by the child resource pools and vApps.
+func (s *inventorySnapshot) computeResourceNode(ctx context.Context, cr mo.ComputeResource, datacenter string) InventoryNode {
+	var rootPool mo.ResourcePool
+	if cr.ResourcePool != nil {
+		rootPool = s.pools[*cr.ResourcePool]
+	}
+
+	node := InventoryNode{ID: cr.Self.Value, Name: cr.Name, Type: cr.Self.Type}
+	standalone := cr.Self.Type == "ComputeResource" && len(cr.Host) == 1
+	if standalone {
+		// A standalone host is shown as the host itself.
+		node.ID = cr.Host[0].Value
+		node.Type = cr.Host[0].Type
+		if name, ok := s.names.Name(ctx, cr.Host[0]); ok {
+			node.Name = name
+		}
+	}
+
+	hosts := map[types.ManagedObjectReference]*InventoryNode{}
+	if !standalone {
+		for _, hostRef := range cr.Host {
+			name, _ := s.names.Name(ctx, hostRef)
+			node.Children = append(node.Children, InventoryNode{ID: hostRef.Value, Name: name, Type: hostRef.Type})
+		}
+		for i := range node.Children {
+			hosts[types.ManagedObjectReference{Type: node.Children[i].Type, Value: node.Children[i].ID}] = &node.Children[i]
+		}
+	}
+
+	for _, vmRef := range rootPool.Vm {
+		vm, ok := s.vmNode(ctx, vmRef, "", datacenter)
+		if !ok {
+			continue
+		}
+		if host, ok := hosts[types.ManagedObjectReference{Type: "HostSystem", Value: vm.HostID}]; ok {
+			host.Children = append(host.Children, vm)
+		} else {
+			node.Children = append(node.Children, vm)
+		}
+	}
+	node.Children = append(node.Children, s.poolChildren(ctx, rootPool, datacenter)...)
+	return node
+}
+
+// poolChildren builds the child resource pools and vApps of a pool, each with
+// its own children and VMs.
+func (s *inventorySnapshot) poolChildren(ctx context.Context, pool mo.ResourcePool, datacenter string) []InventoryNode {
+	var children []InventoryNode
+	for _, childRef := range pool.ResourcePool {
+		child, ok := s.pools[childRef]
+		if !ok {
+			log.Warnf("Could not process entity %s: not in inventory snapshot", childRef.Value)
+			continue
+		}
+		node := InventoryNode{ID: childRef.Value, Name: child.Name, Type: childRef.Type}
+		node.Children = s.poolChildren(ctx, child, datacenter)
+		for _, vmRef := range child.Vm {
+			if vm, ok := s.vmNode(ctx, vmRef, "", datacenter); ok {
+				node.Children = append(node.Children, vm)
+			}
+		}
+		children = append(children, node)
+	}
+	return children
+}
+
+// vmInventoryNode converts a VM's retrieved properties into an inventory node.
+func vmInventoryNode(ctx context.Context, mvm mo.VirtualMachine, folderPath string, names *inventoryNames) InventoryNode {
+	node := InventoryNode{
+		ID:   mvm.Self.Value,
+		Name: mvm.Name,
+		Type: mvm.Self.Type,
+	}
+
+	log.Debugf("Raw VM data from vCenter for %s: %+v", mvm.Name, mvm)
+
+	var vmNetworks []VMNetwork
+	var vmDisks []VMDisk
+
+	if mvm.Config != nil {
+		// Get the list of virtual devices from the managed object
+		deviceList := object.VirtualDeviceList(mvm.Config.Hardware.Device)
+
+		// Map to find controller types
+		controllers := make(map[int32]string)
+		for _, device := range deviceList {
+			d := device.GetVirtualDevice()
+			switch device.(type) {
+			case *types.VirtualLsiLogicController, *types.VirtualLsiLogicSASController, *types.VirtualBusLogicController, *types.ParaVirtualSCSIController:
+				controllers[d.Key] = "scsi"
+			case *types.VirtualIDEController:
+				controllers[d.Key] = "ide"
+			case *types.VirtualSATAController:
+				controllers[d.Key] = "sata"
+			case *types.VirtualNVMEController:
+				controllers[d.Key] = "nvme"
+			}
+		}
+
+		// Find all network card devices and disks
+		for _, device := range deviceList {
+			// Use a type assertion to see if the device is a network card
+			if card, ok := device.(types.BaseVirtualEthernetCard); ok {
+				// Get the backing info from the network card
+				backing := card.GetVirtualEthernetCard().Backing
+
+				netName := "unknown"
+				netID := ""
+				switch backingInfo := backing.(type) {
+				case *types.VirtualEthernetCardNetworkBackingInfo:
+					netName = backingInfo.DeviceName
+					if backingInfo.Network != nil {
+						netID = backingInfo.Network.Value
+					}
+				case *types.VirtualEthernetCardDistributedVirtualPortBackingInfo:
+					netID = backingInfo.Port.PortgroupKey
+					netName = names.Portgroup(ctx, backingInfo.Port.PortgroupKey)
+				}
+
+				vmNetworks = append(vmNetworks, VMNetwork{
+					Name: netName,
+					ID:   netID,
+					MAC:  card.GetVirtualEthernetCard().MacAddress,
+					Key:  card.GetVirtualEthernetCard().Key,
+				})
+			} else if disk, ok := device.(*types.VirtualDisk); ok {
+				busType := "unknown"
+				if t, ok := controllers[disk.ControllerKey]; ok {
+					busType = t
+				}
+				name := "Disk"
+				if disk.DeviceInfo != nil {
+					name = disk.DeviceInfo.GetDescription().Label
+				}
+				var unitNum int32
+				if disk.UnitNumber != nil {
+					unitNum = *disk.UnitNumber
+				}
+				vmDisk := VMDisk{
+					Name:     name,
+					Capacity: disk.CapacityInBytes,
+					BusType:  busType,
+					UnitNum:  unitNum,
+				}
+				setDiskBacking(ctx, &vmDisk, disk.Backing, names)
+				vmDisks = append(vmDisks, vmDisk)
+			}
+		}
+	} else {
+		log.Warnf("VM '%s' has nil Config, skipping device processing", mvm.Name)
+	}
+
+	log.Debugf("Successfully found networks for VM '%s': %v\n", mvm.Name, vmNetworks)
+
+	node.Networks = vmNetworks
+	node.Disks = vmDisks
+	if mvm.Summary.Storage != nil {
+		node.DiskSizeGB = mvm.Summary.Storage.Committed / (1024 * 1024 * 1024)
+		node.CommittedBytes = mvm.Summary.Storage.Committed
+	}
+
+	node.CPU = mvm.Summary.Config.NumCpu
+	node.MemoryMB = mvm.Summary.Config.MemorySizeMB
+
+	node.PowerState = string(mvm.Runtime.PowerState)
+	setGuestDetails(&node, mvm)
+	if mvm.Runtime.Host != nil {
+		node.HostID = mvm.Runtime.Host.Value
+		if name, ok := names.Name(ctx, *mvm.Runtime.Host); ok {
+			node.Host = name
+		}
+	}
+	node.Folder = folderPath // Store the accumulated folder path
+
+	// Auto-detect datastore ID from the VM's datastore references
+	if len(mvm.Datastore) > 0 {
+		node.DatastoreID = mvm.Datastore[0].Value
+		if name, ok := names.Name(ctx, mvm.Datastore[0]); ok {
+			node.DatastoreName = name
+		}
+	}
+	node.Datastores = vmDatastores(ctx, mvm.Datastore, vmDisks, names)
+
+	return node
+}
+
+// setGuestDetails copies a VM's guest OS, firmware, hardware version, VMware
+// Tools and hot-add settings onto its node.
+func setGuestDetails(node *InventoryNode, mvm mo.VirtualMachine) {
+	if cfg := mvm.Config; cfg != nil {
+		node.GuestID = cfg.GuestId
+		node.GuestOS = cfg.GuestFullName
+		node.Firmware = cfg.Firmware
+		node.HardwareVersion = cfg.Version
+		if cfg.BootOptions != nil && cfg.BootOptions.EfiSecureBootEnabled != nil {
+			node.SecureBoot = *cfg.BootOptions.EfiSecureBootEnabled
+		}
+		node.CPUHotAddEnabled = cfg.CpuHotAddEnabled != nil && *cfg.CpuHotAddEnabled
+		node.CPUHotRemoveEnabled = cfg.CpuHotRemoveEnabled != nil && *cfg.CpuHotRemoveEnabled
+		node.MemoryHotAddEnabled = cfg.MemoryHotAddEnabled != nil && *cfg.MemoryHotAddEnabled
+	}
+
+	guest := mvm.Guest
+	if guest == nil {
+		return
+	}
+	if node.GuestOS == "" {
+		// Fall back to what the running guest reports.
+		node.GuestOS = guest.GuestFullName
+	}
+	node.ToolsRunningStatus = guest.ToolsRunningStatus
+	node.ToolsVersion = guest.ToolsVersion
+	node.ToolsVersionStatus = guest.ToolsVersionStatus2
+	node.GuestHostname = guest.HostName
+
+	seen := map[string]bool{}
+	addIP := func(ip string) {
+		if ip != "" && !seen[ip] {
+			seen[ip] = true
+			node.GuestIPs = append(node.GuestIPs, ip)
+		}
+	}
+	addIP(guest.IpAddress) // the primary address first
+	for _, nic := range guest.Net {
+		for _, ip := range nic.IpAddress {
+			addIP(ip)
+		}
+	}
+}
+
+// setDiskBacking copies a virtual disk's datastore, file, provisioning, mode
+// and RDM details from its backing onto disk.
+func setDiskBacking(ctx context.Context, disk *VMDisk, backing types.BaseVirtualDeviceBackingInfo, names *inventoryNames) {
+	if file, ok := backing.(types.BaseVirtualDeviceFileBackingInfo); ok {
+		info := file.GetVirtualDeviceFileBackingInfo()
+		disk.FilePath = info.FileName
+		if info.Datastore != nil {
+			disk.DatastoreID = info.Datastore.Value
+			disk.DatastoreName, _ = names.Name(ctx, *info.Datastore)
+		}
+	}
+
+	switch b := backing.(type) {
+	case *types.VirtualDiskFlatVer2BackingInfo:
+		disk.DiskMode = b.DiskMode
+		disk.SharingMode = b.Sharing
+		switch {
+		case b.ThinProvisioned != nil && *b.ThinProvisioned:
+			disk.Provisioning = "thin"
+		case b.EagerlyScrub != nil && *b.EagerlyScrub:
+			disk.Provisioning = "eagerZeroedThick"
+		default:
+			disk.Provisioning = "thick"
+		}
+	case *types.VirtualDiskRawDiskMappingVer1BackingInfo:
+		disk.RDM = true
+		disk.RDMCompatibilityMode = b.CompatibilityMode
+		disk.DiskMode = b.DiskMode
+		disk.SharingMode = b.Sharing
+	case *types.VirtualDiskSeSparseBackingInfo:
+		disk.DiskMode = b.DiskMode
+		disk.Provisioning = "thin"
+	case *types.VirtualDiskSparseVer2BackingInfo:
+		disk.DiskMode = b.DiskMode
+		disk.Provisioning = "thin"
+	}
+	disk.Independent = strings.HasPrefix(disk.DiskMode, "independent")
+}
+
+// vmDatastores lists every datastore a VM uses: those vCenter reports for the
+// VM, followed by any further ones only its disks point at.
+func vmDatastores(ctx context.Context, refs []types.ManagedObjectReference, disks []VMDisk, names *inventoryNames) []VMDatastore {
+	var datastores []VMDatastore
+	seen := map[string]bool{}
+	for _, ref := range refs {
+		if seen[ref.Value] {
+			continue
+		}
+		seen[ref.Value] = true
+		name, _ := names.Name(ctx, ref)
+		datastores = append(datastores, VMDatastore{ID: ref.Value, Name: name})
+	}
+	for _, disk := range disks {
+		if disk.DatastoreID == "" || seen[disk.DatastoreID] {
+			continue
+		}
+		seen[disk.DatastoreID] = true
+		datastores = append(datastores, VMDatastore{ID: disk.DatastoreID, Name: disk.DatastoreName})
+	}
+	return datastores
+}
@@ -0,0 +1,178 @@
+// pkg/inventory_builder_test.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// countingRoundTripper counts SOAP calls made through a vim25 client.
+type countingRoundTripper struct {
+	soap.RoundTripper
+	calls int64
+}
+
+func (rt *countingRoundTripper) RoundTrip(ctx context.Context, req, res soap.HasFault) error {
+	atomic.AddInt64(&rt.calls, 1)
+	return rt.RoundTripper.RoundTrip(ctx, req, res)
+}
+
+// inventoryModel returns a vcsim model with vms VMs whose NICs are backed by
+// a distributed portgroup.
+func inventoryModel(vms int) *simulator.Model {
+	model := simulator.VPX()
+	model.Machine = vms
+	model.Portgroup = 1
+	model.Folder = 1
+	return model
+}
+
+// countedInventoryBuild logs into a simulator and returns a function that
+// builds DC0's inventory the way buildVCenterInventory does, from a snapshot
+// of the whole vCenter, and reports the number of round trips it took.
+func countedInventoryBuild(t testing.TB, model *simulator.Model) func() (*InventoryNode, int64) {
+	creds := startSimulator(t, model)
+	ctx := context.Background()
+
+	c, err := loginVCenter(ctx, creds)
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	t.Cleanup(func() { logoutVCenter(c) })
+
+	dc, err := find.NewFinder(c.Client, true).Datacenter(ctx, "DC0")
+	if err != nil {
+		t.Fatalf("failed to find datacenter: %v", err)
+	}
+
+	counter := &countingRoundTripper{RoundTripper: c.Client.RoundTripper}
+	c.Client.RoundTripper = counter
+	return func() (*InventoryNode, int64) {
+		before := atomic.LoadInt64(&counter.calls)
+		snap, err := retrieveInventorySnapshot(ctx, c, c.ServiceContent.RootFolder)
+		if err != nil {
+			t.Fatalf("inventory snapshot failed: %v", err)
+		}
+		mdc, ok := snap.datacenters[dc.Reference()]
+		if !ok {
+			t.Fatalf("datacenter %s missing from inventory snapshot", dc.Name())
+		}
+		tree := snap.datacenterNode(ctx, mdc)
+		return tree, atomic.LoadInt64(&counter.calls) - before
+	}
+}
+
+func countInventoryVMs(node *InventoryNode) int {
+	n := 0
+	if node.Type == "VirtualMachine" {
+		n++
+	}
+	for i := range node.Children {
+		n += countInventoryVMs(&node.Children[i])
+	}
+	return n
+}
+
+func TestInventorySnapshotRoundTripsDoNotScale(t *testing.T) {
+	smallTree, small := countedInventoryBuild(t, inventoryModel(1))()
+	largeTree, large := countedInventoryBuild(t, inventoryModel(12))()
+
+	if small != large {
+		t.Errorf("round trips grew with inventory size: %d for the small model, %d for the large one", small, large)
+	}
+	if got := countInventoryVMs(largeTree); got <= countInventoryVMs(smallTree) {
+		t.Fatalf("expected the large model to have more VMs, got %d", got)
+	}
+
+	vm := findInventoryVM(largeTree)
+	if vm == nil {
+		t.Fatalf("no VM in inventory tree")
+	}
+	if vm.DatastoreName == "" || len(vm.Networks) == 0 {
+		t.Errorf("expected datastore and networks on %s, got %+v", vm.Name, vm)
+	}
+	for _, nic := range vm.Networks {
+		if nic.Name == nic.ID {
+			t.Errorf("portgroup %s was not resolved to a name", nic.ID)
+		}
+	}
+}
+
+func findInventoryVM(node *InventoryNode) *InventoryNode {
+	if node.Type == "VirtualMachine" {
+		return node
+	}
+	for i := range node.Children {
+		if vm := findInventoryVM(&node.Children[i]); vm != nil {
+			return vm
+		}
+	}
+	return nil
+}
+
+func TestSetGuestDetails(t *testing.T) {
+	mvm := mo.VirtualMachine{
+		Config: &types.VirtualMachineConfigInfo{
+			GuestId:             "rhel9_64Guest",
+			Firmware:            "efi",
+			Version:             "vmx-19",
+			BootOptions:         &types.VirtualMachineBootOptions{EfiSecureBootEnabled: types.NewBool(true)},
+			MemoryHotAddEnabled: types.NewBool(true),
+		},
+		Guest: &types.GuestInfo{
+			GuestFullName:       "Red Hat Enterprise Linux 9 (64-bit)",
+			ToolsRunningStatus:  "guestToolsRunning",
+			ToolsVersion:        "12352",
+			ToolsVersionStatus2: "guestToolsCurrent",
+			HostName:            "web-01",
+			IpAddress:           "10.0.0.5",
+			Net: []types.GuestNicInfo{
+				{IpAddress: []string{"fe80::1", "10.0.0.5"}},
+				{IpAddress: []string{"192.168.1.7"}},
+			},
+		},
+	}
+
+	var node InventoryNode
+	setGuestDetails(&node, mvm)
+
+	if node.GuestOS != "Red Hat Enterprise Linux 9 (64-bit)" {
+		t.Errorf("expected the guest-reported OS name when config has none, got %q", node.GuestOS)
+	}
+	if node.Firmware != "efi" || !node.SecureBoot || node.HardwareVersion != "vmx-19" {
+		t.Errorf("unexpected firmware details: %+v", node)
+	}
+	if node.CPUHotAddEnabled || !node.MemoryHotAddEnabled {
+		t.Errorf("expected only memory hot-add, got cpu %v memory %v", node.CPUHotAddEnabled, node.MemoryHotAddEnabled)
+	}
+	if node.ToolsRunningStatus != "guestToolsRunning" || node.ToolsVersion != "12352" || node.ToolsVersionStatus != "guestToolsCurrent" {
+		t.Errorf("unexpected tools state: %+v", node)
+	}
+	want := []string{"10.0.0.5", "fe80::1", "192.168.1.7"}
+	if fmt.Sprint(node.GuestIPs) != fmt.Sprint(want) || node.GuestHostname != "web-01" {
+		t.Errorf("expected IPs %v and hostname web-01, got %v %q", want, node.GuestIPs, node.GuestHostname)
+	}
+}
+
+func BenchmarkBuildDatacenterInventory(b *testing.B) {
+	for _, vms := range []int{4, 32, 128} {
+		b.Run(fmt.Sprintf("vms=%d", vms), func(b *testing.B) {
+			build := countedInventoryBuild(b, inventoryModel(vms))
+			var trips int64
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, n := build()
+				trips += n
+			}
+			b.ReportMetric(float64(trips)/float64(b.N), "roundtrips/op")
+		})
+	}
+}
@@ -0,0 +1,373 @@
+// pkg/inventory_cache.go
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+const (
+	// inventoryCacheTTL bounds how old a served tree can get even if the change
+	// watcher misses something (or cannot run at all).
+	inventoryCacheTTL = 10 * time.Minute
+	// inventoryMinRefreshInterval debounces rebuilds triggered by change
+	// notifications; a burst of vCenter updates yields one rebuild.
+	inventoryMinRefreshInterval = 15 * time.Second
+	// inventoryCacheIdleTTL is how long a source's cache (and its watcher) lives
+	// without anyone asking for it.
+	inventoryCacheIdleTTL = 30 * time.Minute
+	// inventoryBuildTimeout caps a single background walk of the inventory.
+	inventoryBuildTimeout = 15 * time.Minute
+	// inventoryWatchRetryInterval is the back-off after the change watcher fails.
+	inventoryWatchRetryInterval = 30 * time.Second
+	// inventoryWatchStopTimeout bounds how long Invalidate waits for a watcher to
+	// cancel its pending WaitForUpdates call on vCenter.
+	inventoryWatchStopTimeout = 5 * time.Second
+)
+
+// inventoryCaches is the process-wide inventory cache for VmwareSources and
+// Forklift Providers.
+var inventoryCaches = newInventoryCache()
+
+// inventoryLoader builds a fresh inventory tree from vCenter.
+type inventoryLoader func(ctx context.Context, creds VCenterCredentials) (*InventoryNode, error)
+
+// inventoryWatchProps are the properties whose changes invalidate a cached tree.
+// Volatile properties (guest IPs, quick stats) are deliberately left out so an
+// idle vCenter doesn't trigger constant rebuilds.
+var inventoryWatchProps = map[string][]string{
+	"Folder":                 {"name", "parent"},
+	"ClusterComputeResource": {"name", "parent"},
+	"ComputeResource":        {"name", "parent"},
+	"HostSystem":             {"name", "parent"},
+	"ResourcePool":           {"name", "parent"},
+	"VirtualApp":             {"name", "parent"},
+	"VirtualMachine": {
+		"name", "parent", "resourcePool", "datastore",
+		"runtime.powerState", "runtime.host",
+		"summary.config.numCpu", "summary.config.memorySizeMB",
+		"config.hardware.device",
+	},
+}
+
+// inventoryCacheEntry holds one source's tree plus the state of its background
+// refresh and change watcher.
+type inventoryCacheEntry struct {
+	key string
+
+	mu             sync.Mutex
+	creds          VCenterCredentials
+	load           inventoryLoader
+	tree           *InventoryNode
+	lastRefreshed  time.Time // when the current tree finished building
+	treeStarted    time.Time // when the build that produced the current tree started
+	attemptStarted time.Time // when the latest build (successful or not) started
+	refreshErr     error
+	refreshing     bool
+	dirty          bool
+	retryPending   bool
+	done           chan struct{} // closed when the in-flight build finishes
+	lastAccess     time.Time
+	stopWatch      context.CancelFunc
+	watchDone      chan struct{} // closed when the watcher has exited
+}
+
+// inventoryCache serves inventory trees instantly from memory. Each source's
+// tree is built once, then rebuilt in the background when it ages past the TTL
+// or when a property collector watch reports a change in vCenter.
+type inventoryCache struct {
+	mu      sync.Mutex
+	entries map[string]*inventoryCacheEntry
+	janitor sync.Once
+	// watch is swappable so tests can run without a change watcher.
+	watch func(ctx context.Context, c *govmomi.Client, changed func()) error
+}
+
+func newInventoryCache() *inventoryCache {
+	return &inventoryCache{
+		entries: map[string]*inventoryCacheEntry{},
+		watch:   watchInventoryChanges,
+	}
+}
+
+// Get returns the cached tree for creds.SessionKey, building it on first use.
+// A stale or dirty tree is still returned immediately while a rebuild runs in
+// the background. With force, Get waits for a build that started after the call.
+func (c *inventoryCache) Get(ctx context.Context, creds VCenterCredentials, load inventoryLoader, force bool) (*InventoryNode, error) {
+	c.janitor.Do(func() { go c.sweepLoop() })
+
+	e := c.entry(creds, load)
+	requested := time.Now()
+	for {
+		e.mu.Lock()
+		e.lastAccess = time.Now()
+		if e.tree != nil && !(force && e.treeStarted.Before(requested)) {
+			if !e.refreshing && (e.dirty || time.Since(e.lastRefreshed) > inventoryCacheTTL) {
+				c.startRefreshLocked(e)
+			}
+			tree := e.snapshotLocked()
+			e.mu.Unlock()
+			return tree, nil
+		}
+		if !e.refreshing {
+			if e.refreshErr != nil && !e.attemptStarted.Before(requested) {
+				err := e.refreshErr
+				e.mu.Unlock()
+				return nil, err
+			}
+			c.startRefreshLocked(e)
+		}
+		done := e.done
+		e.mu.Unlock()
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// MarkDirty schedules a background rebuild for a source, e.g. after the UI
+// powered on or renamed a VM through us.
+func (c *inventoryCache) MarkDirty(key string) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok {
+		c.markDirty(e)
+	}
+}
+
+// Invalidate drops a source's cached tree and stops its watcher, giving it a
+// moment to cancel its pending wait on vCenter before the session goes away.
+func (c *inventoryCache) Invalidate(key string) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	delete(c.entries, key)
+	c.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	e.mu.Lock()
+	done := e.watchDone
+	if e.stopWatch != nil {
+		e.stopWatch()
+	}
+	e.mu.Unlock()
+	if done == nil {
+		return
+	}
+	select {
+	case <-done:
+	case <-time.After(inventoryWatchStopTimeout):
+		log.Warnf("Inventory change watcher for %s did not stop within %s", key, inventoryWatchStopTimeout)
+	}
+}
+
+// invalidateVCenterSource forgets everything cached for a source after its
+// endpoint or credentials change, or it is deleted.
+func invalidateVCenterSource(key string) {
+	inventoryCaches.Invalidate(key)
+	vcenterSessions.Invalidate(key)
+}
+
+func (c *inventoryCache) entry(creds VCenterCredentials, load inventoryLoader) *inventoryCacheEntry {
+	key := creds.SessionKey
+	if key == "" {
+		key = credentialsFingerprint(creds)
+	}
+
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if !ok {
+		e = &inventoryCacheEntry{key: key}
+		c.entries[key] = e
+	}
+	c.mu.Unlock()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.creds = creds
+	e.load = load
+	if e.stopWatch == nil && c.watch != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		e.stopWatch = cancel
+		e.watchDone = make(chan struct{})
+		go c.watchLoop(ctx, e)
+	}
+	return e
+}
+
+// snapshotLocked returns a shallow copy of the root stamped with its build time.
+// The tree itself is never mutated after a build, so sharing children is safe.
+func (e *inventoryCacheEntry) snapshotLocked() *InventoryNode {
+	root := *e.tree
+	refreshed := e.lastRefreshed
+	root.LastRefreshed = &refreshed
+	root.Refreshing = e.refreshing
+	return &root
+}
+
+func (c *inventoryCache) startRefreshLocked(e *inventoryCacheEntry) {
+	started := time.Now()
+	done := make(chan struct{})
+	creds, load := e.creds, e.load
+	e.refreshing = true
+	e.dirty = false
+	e.attemptStarted = started
+	e.done = done
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), inventoryBuildTimeout)
+		defer cancel()
+		tree, err := load(ctx, creds)
+
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		e.refreshing = false
+		if err != nil {
+			log.Warnf("Inventory refresh for %s failed: %v", e.key, err)
+			e.refreshErr = err
+		} else {
+			log.Debugf("Inventory for %s refreshed in %s", e.key, time.Since(started))
+			e.tree = tree
+			e.treeStarted = started
+			e.lastRefreshed = time.Now()
+			e.refreshErr = nil
+		}
+		close(done)
+		if e.dirty {
+			c.scheduleRefreshLocked(e)
+		}
+	}()
+}
+
+func (c *inventoryCache) markDirty(e *inventoryCacheEntry) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.dirty = true
+	if !e.refreshing {
+		c.scheduleRefreshLocked(e)
+	}
+}
+
+// scheduleRefreshLocked starts a rebuild now, or once inventoryMinRefreshInterval
+// has passed since the previous one started.
+func (c *inventoryCache) scheduleRefreshLocked(e *inventoryCacheEntry) {
+	if e.tree == nil || e.retryPending {
+		// Nothing cached yet: the next Get builds synchronously anyway.
+		return
+	}
+	wait := inventoryMinRefreshInterval - time.Since(e.attemptStarted)
+	if wait <= 0 {
+		c.startRefreshLocked(e)
+		return
+	}
+	e.retryPending = true
+	time.AfterFunc(wait, func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		e.retryPending = false
+		if e.dirty && !e.refreshing {
+			c.startRefreshLocked(e)
+		}
+	})
+}
+
+// watchLoop keeps a change watcher running for a source until ctx is cancelled.
+func (c *inventoryCache) watchLoop(ctx context.Context, e *inventoryCacheEntry) {
+	defer close(e.watchDone)
+	for {
+		e.mu.Lock()
+		creds := e.creds
+		e.mu.Unlock()
+
+		err := vcenterSessions.Do(ctx, creds, func(client *govmomi.Client) error {
+			return c.watch(ctx, client, func() { c.markDirty(e) })
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		log.Warnf("Inventory change watcher for %s stopped: %v (retrying in %s)", e.key, err, inventoryWatchRetryInterval)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(inventoryWatchRetryInterval):
+		}
+	}
+}
+
+// sweep drops caches nobody has asked for in inventoryCacheIdleTTL.
+func (c *inventoryCache) sweep(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, e := range c.entries {
+		e.mu.Lock()
+		idle := now.Sub(e.lastAccess) > inventoryCacheIdleTTL && !e.refreshing
+		if idle {
+			if e.stopWatch != nil {
+				e.stopWatch()
+			}
+			delete(c.entries, key)
+			log.Debugf("Evicting idle inventory cache for %s", key)
+		}
+		e.mu.Unlock()
+	}
+}
+
+func (c *inventoryCache) sweepLoop() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		c.sweep(now)
+	}
+}
+
+// watchInventoryChanges blocks on a property collector WaitForUpdates filter
+// covering every folder, compute resource and VM in vCenter, calling changed
+// for each batch of updates after the initial snapshot.
+func watchInventoryChanges(ctx context.Context, c *govmomi.Client, changed func()) error {
+	kinds := make([]string, 0, len(inventoryWatchProps))
+	for kind := range inventoryWatchProps {
+		kinds = append(kinds, kind)
+	}
+
+	v, err := view.NewManager(c.Client).CreateContainerView(ctx, c.ServiceContent.RootFolder, kinds, true)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = v.Destroy(context.Background())
+	}()
+
+	filter := new(property.WaitFilter)
+	filter.Spec.ObjectSet = []types.ObjectSpec{{
+		Obj:       v.Reference(),
+		Skip:      types.NewBool(true),
+		SelectSet: []types.BaseSelectionSpec{v.TraversalSpec()},
+	}}
+	for kind, props := range inventoryWatchProps {
+		filter.Spec.PropSet = append(filter.Spec.PropSet, types.PropertySpec{Type: kind, PathSet: props})
+	}
+
+	// The first response (possibly split into truncated batches) is the current
+	// state of everything, not a change.
+	initial := true
+	return property.WaitForUpdates(ctx, property.DefaultCollector(c.Client), filter, func([]types.ObjectUpdate) bool {
+		if initial {
+			initial = filter.Truncated
+			return false
+		}
+		changed()
+		return false
+	})
+}
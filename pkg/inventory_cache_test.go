@@ -0,0 +1,134 @@
+// pkg/inventory_cache_test.go
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/simulator"
+)
+
+// countingLoader returns an inventoryLoader that counts builds and names the
+// root after the build number, so tests can tell trees apart.
+func countingLoader(builds *int32) inventoryLoader {
+	return func(ctx context.Context, creds VCenterCredentials) (*InventoryNode, error) {
+		n := atomic.AddInt32(builds, 1)
+		return &InventoryNode{Name: fmt.Sprintf("build-%d", n), Type: "datacenter"}, nil
+	}
+}
+
+func TestInventoryCacheServesCachedTree(t *testing.T) {
+	c := newInventoryCache()
+	c.watch = nil
+	creds := VCenterCredentials{URL: "vcsim", SessionKey: vcenterSessionKey("VmwareSource", "default", "src")}
+	ctx := context.Background()
+
+	var builds int32
+	load := countingLoader(&builds)
+
+	first, err := c.Get(ctx, creds, load, false)
+	if err != nil {
+		t.Fatalf("first Get failed: %v", err)
+	}
+	if first.LastRefreshed == nil {
+		t.Fatalf("expected lastRefreshed on the cached root")
+	}
+	second, err := c.Get(ctx, creds, load, false)
+	if err != nil {
+		t.Fatalf("second Get failed: %v", err)
+	}
+	if builds != 1 || second.Name != first.Name {
+		t.Errorf("expected the cached tree to be served, got %d builds", builds)
+	}
+
+	t.Run("refresh forces a new build", func(t *testing.T) {
+		forced, err := c.Get(ctx, creds, load, true)
+		if err != nil {
+			t.Fatalf("forced Get failed: %v", err)
+		}
+		if builds != 2 || forced.Name == first.Name {
+			t.Errorf("expected a fresh tree after forced refresh, got %q after %d builds", forced.Name, builds)
+		}
+	})
+
+	t.Run("invalidate drops the tree", func(t *testing.T) {
+		c.Invalidate(creds.SessionKey)
+		if _, err := c.Get(ctx, creds, load, false); err != nil {
+			t.Fatalf("Get after invalidate failed: %v", err)
+		}
+		if builds != 3 {
+			t.Errorf("expected a rebuild after Invalidate, got %d builds", builds)
+		}
+	})
+}
+
+func TestInventoryCacheReturnsBuildErrors(t *testing.T) {
+	c := newInventoryCache()
+	c.watch = nil
+	creds := VCenterCredentials{URL: "vcsim", SessionKey: "VmwareSource/default/broken"}
+
+	_, err := c.Get(context.Background(), creds, func(ctx context.Context, creds VCenterCredentials) (*InventoryNode, error) {
+		return nil, errors.New("vcenter unreachable")
+	}, false)
+	if err == nil || err.Error() != "vcenter unreachable" {
+		t.Errorf("expected the build error to surface, got %v", err)
+	}
+}
+
+func TestWatchInventoryChangesReportsRenames(t *testing.T) {
+	creds := startSimulator(t, simulator.VPX())
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	changed := make(chan struct{}, 1)
+	watchErr := make(chan error, 1)
+	// Stop the watcher before the simulator shuts down; vcsim holds a pending
+	// WaitForUpdates open until it is cancelled.
+	t.Cleanup(func() {
+		cancel()
+		<-watchErr
+	})
+	go func() {
+		watchErr <- vcenterSessions.Do(ctx, creds, func(c *govmomi.Client) error {
+			return watchInventoryChanges(ctx, c, func() {
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+			})
+		})
+	}()
+
+	// Give the watcher time to consume the initial snapshot before changing anything.
+	time.Sleep(500 * time.Millisecond)
+
+	err := vcenterSessions.Do(ctx, creds, func(c *govmomi.Client) error {
+		vm, err := find.NewFinder(c.Client, true).VirtualMachine(ctx, "/DC0/vm/DC0_H0_VM0")
+		if err != nil {
+			return err
+		}
+		task, err := vm.Rename(ctx, "renamed-vm")
+		if err != nil {
+			return err
+		}
+		return task.Wait(ctx)
+	})
+	if err != nil {
+		t.Fatalf("rename failed: %v", err)
+	}
+
+	select {
+	case <-changed:
+	case err := <-watchErr:
+		watchErr <- err
+		t.Fatalf("watcher exited early: %v", err)
+	case <-ctx.Done():
+		t.Fatalf("timed out waiting for change notification")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 
 	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -15,6 +16,9 @@ import (
 type K8sClients struct {
 	Clientset kubernetes.Interface
 	Dynamic   dynamic.Interface
+	// Cache serves reads through getResource and listResources; nil reads
+	// straight from the API server.
+	Cache *ResourceCache
 }
 
 func NewK8sClients() (*K8sClients, error) {
@@ -53,8 +57,11 @@ func NewK8sClients() (*K8sClients, error) {
 		return nil, err
 	}
 
+	resourceCache := NewResourceCache(dynamicClient, clientset.Discovery(), wait.NeverStop)
+	resourceCache.Warm()
 	return &K8sClients{
 		Clientset: clientset,
-		Dynamic:   dynamicClient,
+		Dynamic:   resourceCache.TrackWrites(dynamicClient),
+		Cache:     resourceCache,
 	}, nil
 }
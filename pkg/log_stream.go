@@ -0,0 +1,625 @@
+// pkg/log_stream.go
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// logDiscoveryInterval is how often a log stream looks for new pods, such as
+// the worker and populator pods Forklift creates while a migration runs.
+var logDiscoveryInterval = 5 * time.Second
+
+// logSource is a pod whose logs belong to a plan.
+type logSource struct {
+	Namespace string
+	Pod       v1.Pod
+	Label     string   // e.g. "virt-v2v Conversion: vms/db-v2v (VM: vm-1)"
+	Filter    []string // a line must contain one of these; nil keeps every line
+	Previous  bool     // the logs of the pod's containers before they last restarted
+}
+
+// includes applies the source's filter to a line. all keeps every line;
+// errorsOnly then keeps only lines that look like errors or warnings.
+func (s logSource) includes(line string, all, errorsOnly bool) bool {
+	if all {
+		return true
+	}
+	include := s.Filter == nil
+	for _, term := range s.Filter {
+		if strings.Contains(line, term) {
+			include = true
+			break
+		}
+	}
+	return include && (!errorsOnly || isErrorLogLine(line))
+}
+
+func isErrorLogLine(line string) bool {
+	l := strings.ToLower(line)
+	return strings.Contains(l, "error") || strings.Contains(l, "fail") ||
+		strings.Contains(l, "warn") || strings.Contains(l, "critical") ||
+		strings.Contains(l, "\"level\":\"error\"") || strings.Contains(l, "\"level\":\"warn\"")
+}
+
+func podContainers(pod v1.Pod) []string {
+	var names []string
+	for _, c := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+// containers returns the containers whose logs the source reads: all of the
+// pod's, or for Previous the ones that restarted.
+func (s logSource) containers() []string {
+	if !s.Previous {
+		return podContainers(s.Pod)
+	}
+	var names []string
+	for _, status := range append(s.Pod.Status.InitContainerStatuses, s.Pod.Status.ContainerStatuses...) {
+		if status.RestartCount > 0 {
+			names = append(names, status.Name)
+		}
+	}
+	return names
+}
+
+// forkliftLogSources returns the pods whose logs belong to a Forklift plan:
+// the Forklift controller in forkliftNs, filtered to lines about the plan,
+// then its worker, populator and converter pods in the plan's target and own
+// namespaces. It also returns the plan's target namespace.
+func forkliftLogSources(ctx context.Context, clients *K8sClients, planNamespace, planName, forkliftNs string) ([]logSource, string) {
+	// Get the plan to find target namespace and VM IDs/names
+	var targetNamespace string
+	var vmIDs []string
+	planObj, err := clients.getResource(ctx, forkliftPlanGVR, planNamespace, planName)
+	if err == nil {
+		targetNamespace, _ = getNestedStringOrWarn(planObj.Object, "spec", "targetNamespace")
+		vms, _, vmsErr := unstructured.NestedSlice(planObj.Object, "spec", "vms")
+		if vmsErr != nil {
+			log.Warnf("Error reading spec.vms: %v", vmsErr)
+		}
+		for _, vm := range vms {
+			if vmMap, ok := vm.(map[string]interface{}); ok {
+				if id, ok := vmMap["id"].(string); ok && id != "" {
+					vmIDs = append(vmIDs, id)
+				}
+			}
+		}
+	}
+
+	// Related resource names that appear in controller logs
+	migrationName := planName + "-migration"
+	networkMapName := planName + "-network-map"
+	storageMapName := planName + "-storage-map"
+	controllerMatchTerms := []string{planName, migrationName, networkMapName, storageMapName}
+
+	var sources []logSource
+
+	// ── 1. Forklift controller pod (filtered by plan name) ──
+	// The controller pod is labeled app=forklift-controller in the forklift namespace
+	controllerPods, _ := clients.Clientset.CoreV1().Pods(forkliftNs).List(ctx, metav1.ListOptions{
+		LabelSelector: "app=forklift-controller",
+	})
+	if controllerPods == nil || len(controllerPods.Items) == 0 {
+		// Fallback: any pod with "forklift-controller" in the name
+		allPods, _ := clients.Clientset.CoreV1().Pods(forkliftNs).List(ctx, metav1.ListOptions{})
+		if allPods != nil {
+			for _, p := range allPods.Items {
+				if strings.Contains(p.Name, "forklift-controller") {
+					if controllerPods == nil {
+						controllerPods = &v1.PodList{}
+					}
+					controllerPods.Items = append(controllerPods.Items, p)
+				}
+			}
+		}
+	}
+	if controllerPods != nil {
+		for _, pod := range controllerPods.Items {
+			sources = append(sources, logSource{Namespace: forkliftNs, Pod: pod, Label: "Forklift Controller: " + pod.Name, Filter: controllerMatchTerms})
+		}
+	}
+
+	// ── 2. Migration worker pods (label: plan-name=<planName>) ──
+	// These run in targetNamespace and include virt-v2v, virt-v2v-inspection, consumer pods.
+	// Forklift labels them with plan-name=<planName>.
+	searchNamespaces := []string{}
+	if targetNamespace != "" {
+		searchNamespaces = append(searchNamespaces, targetNamespace)
+	}
+	if planNamespace != targetNamespace {
+		searchNamespaces = append(searchNamespaces, planNamespace)
+	}
+
+	for _, ns := range searchNamespaces {
+		// Direct label query — most efficient
+		workerPods, err := clients.Clientset.CoreV1().Pods(ns).List(ctx, metav1.ListOptions{
+			LabelSelector: "plan-name=" + planName,
+		})
+		if err == nil {
+			for _, pod := range workerPods.Items {
+				appLabel := pod.Labels["forklift.app"]
+				podType := "Worker"
+				switch appLabel {
+				case "virt-v2v":
+					podType = "virt-v2v Conversion"
+				case "virt-v2v-inspection":
+					podType = "virt-v2v Inspection"
+				case "consumer":
+					podType = "Consumer"
+				}
+				vmID := pod.Labels["vmID"]
+				vmInfo := ""
+				if vmID != "" {
+					vmInfo = fmt.Sprintf(" (VM: %s)", vmID)
+				}
+				sources = append(sources, logSource{Namespace: ns, Pod: pod, Label: fmt.Sprintf("%s: %s/%s%s", podType, ns, pod.Name, vmInfo)})
+			}
+		}
+
+		// Also look for populator pods (created by CDI, name prefix "populate-")
+		// and pods whose name starts with the plan name (hook jobs, converter jobs)
+		allPods, err := clients.Clientset.CoreV1().Pods(ns).List(ctx, metav1.ListOptions{})
+		if err == nil {
+			seen := map[string]bool{}
+			if workerPods != nil {
+				for _, p := range workerPods.Items {
+					seen[p.Name] = true
+				}
+			}
+			for _, pod := range allPods.Items {
+				if seen[pod.Name] {
+					continue
+				}
+				isRelevant := false
+				podType := "Related Pod"
+
+				// Check if pod name starts with planName (hook jobs, converter jobs)
+				if strings.HasPrefix(pod.Name, planName+"-") {
+					isRelevant = true
+					podType = "Plan Pod"
+				}
+
+				// Check for populator pods by looking at migration label
+				if !isRelevant && strings.HasPrefix(pod.Name, "populate-") {
+					if _, hasMigLabel := pod.Labels["migration"]; hasMigLabel {
+						// Check if this populator's migration label matches our plan's migration
+						isRelevant = true
+						podType = "CDI Populator"
+					}
+				}
+
+				// Check for converter jobs
+				if !isRelevant && strings.HasPrefix(pod.Name, "convert-") {
+					for _, vmID := range vmIDs {
+						if strings.Contains(pod.Name, vmID) {
+							isRelevant = true
+							podType = "Disk Converter"
+							break
+						}
+					}
+				}
+
+				if isRelevant {
+					sources = append(sources, logSource{Namespace: ns, Pod: pod, Label: fmt.Sprintf("%s: %s/%s", podType, ns, pod.Name)})
+				}
+			}
+		}
+	}
+	return sources, targetNamespace
+}
+
+// LogStreamLine is one log line of a streamed pod container, or, for "source"
+// events, a container whose log the stream opened, could not open or reached
+// the end of.
+type LogStreamLine struct {
+	Source    string `json:"source"`
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+	Line      string `json:"line,omitempty"`
+	State     string `json:"state,omitempty"` // "following", "error" or "ended" for source events
+	Error     string `json:"error,omitempty"`
+}
+
+// podLogOptions reads tailLines, sinceTime (RFC 3339) and sinceSeconds from a
+// request into follow-mode PodLogOptions.
+func podLogOptions(r *http.Request) (v1.PodLogOptions, error) {
+	opts := v1.PodLogOptions{Follow: true}
+	q := r.URL.Query()
+	if s := q.Get("tailLines"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n < 0 {
+			return opts, fmt.Errorf("tailLines must be a non-negative number")
+		}
+		opts.TailLines = &n
+	}
+	if s := q.Get("sinceTime"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return opts, fmt.Errorf("sinceTime must be an RFC 3339 time: %v", err)
+		}
+		opts.SinceTime = &metav1.Time{Time: t}
+	}
+	if s := q.Get("sinceSeconds"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n <= 0 {
+			return opts, fmt.Errorf("sinceSeconds must be a positive number")
+		}
+		if opts.SinceTime != nil {
+			return opts, fmt.Errorf("sinceTime and sinceSeconds cannot be combined")
+		}
+		opts.SinceSeconds = &n
+	}
+	return opts, nil
+}
+
+// logFollowMaxBackoff caps how long a container whose log could not be
+// followed waits before it is tried again.
+var logFollowMaxBackoff = 2 * time.Minute
+
+// openContainerLog opens the log of one container; tests replace it.
+var openContainerLog = func(ctx context.Context, clients *K8sClients, namespace, pod string, opts *v1.PodLogOptions) (io.ReadCloser, error) {
+	return clients.Clientset.CoreV1().Pods(namespace).GetLogs(pod, opts).Stream(ctx)
+}
+
+// followEvent is what a container follower sends streamPodLogs, in order: the
+// "following" source event once its log is open, its lines, then the "ended"
+// source event, with err if reading failed. A log that could not be opened
+// sends only an "error" source event with err.
+type followEvent struct {
+	key   string
+	event string // "log" or "source"
+	line  LogStreamLine
+	at    time.Time // when the kubelet received a log line; zero if unknown
+	err   error
+}
+
+// followContainer sends the events of one container's log, keeping the lines
+// keep passes, until the log ends or ctx is done. Lines received at or before
+// after were sent by an earlier follow and are skipped.
+func followContainer(ctx context.Context, clients *K8sClients, src logSource, container, key string, opts v1.PodLogOptions, after time.Time, keep func(string) bool, out chan<- followEvent) {
+	send := func(ev followEvent) bool {
+		ev.key = key
+		select {
+		case out <- ev:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	line := LogStreamLine{Source: src.Label, Namespace: src.Namespace, Pod: src.Pod.Name, Container: container}
+	source := func(state string, err error) followEvent {
+		l := line
+		l.State = state
+		if err != nil {
+			l.Error = err.Error()
+		}
+		return followEvent{event: "source", line: l, err: err}
+	}
+
+	opts.Container = container
+	opts.Timestamps = true
+	stream, err := openContainerLog(ctx, clients, src.Namespace, src.Pod.Name, &opts)
+	if err != nil {
+		send(source("error", err))
+		return
+	}
+	defer stream.Close()
+	if !send(source("following", nil)) {
+		return
+	}
+
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		at, text := splitLogTimestamp(scanner.Text())
+		if (!at.IsZero() && !at.After(after)) || !keep(text) {
+			continue
+		}
+		l := line
+		l.Line = text
+		if !send(followEvent{event: "log", line: l, at: at}) {
+			return
+		}
+	}
+	if ctx.Err() == nil {
+		send(source("ended", scanner.Err()))
+	}
+}
+
+// followedContainer is the state of one container of a log stream.
+type followedContainer struct {
+	instance  string // the pod UID and restart count it was last followed at
+	following bool
+	announced bool           // the "following" source event was sent for instance
+	ended     *LogStreamLine // the "ended" source event, held until the log is known to be over
+	done      bool           // the log of instance is over
+	lastAt    time.Time      // when the last line sent was received
+	untimed   bool           // a line came without a timestamp, so the log cannot be resumed
+	failures  int            // consecutive attempts that failed
+	retryAt   time.Time
+}
+
+// containerInstance identifies one run of a container: the pod's UID and the
+// container's restart count, which change when the pod is recreated or the
+// container restarts.
+func containerInstance(pod v1.Pod, container string) string {
+	var restarts int32
+	for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+		if status.Name == container {
+			restarts = status.RestartCount
+		}
+	}
+	return fmt.Sprintf("%s/%d", pod.UID, restarts)
+}
+
+// containerTerminated reports whether a container of pod has exited, so its
+// log will not grow any more.
+func containerTerminated(pod v1.Pod, container string) bool {
+	if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+		return true
+	}
+	for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+		if status.Name == container {
+			return status.State.Terminated != nil
+		}
+	}
+	return false
+}
+
+// followBackoff is how long a container waits after its nth failed attempt:
+// logDiscoveryInterval, doubling with each failure up to logFollowMaxBackoff.
+func followBackoff(failures int) time.Duration {
+	backoff := logDiscoveryInterval
+	for i := 1; i < failures && backoff < logFollowMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > logFollowMaxBackoff {
+		backoff = logFollowMaxBackoff
+	}
+	return backoff
+}
+
+// streamPodLogs follows the logs of every container of the pods discover
+// returns, merged into one stream, and keeps calling discover to pick up new
+// pods and restarted containers. Lines go out as Server-Sent Events ("log",
+// plus "source" once a container's log is open, when it cannot be opened and
+// after its last line), or with ?format=text as plain text prefixed by
+// "[pod/container]". A container whose log cannot be opened yet is retried
+// with a growing backoff. A log that ends while its container still runs, as
+// when the API server closes a long follow, is followed again from the last
+// line received.
+func streamPodLogs(w http.ResponseWriter, r *http.Request, clients *K8sClients, discover func(context.Context) []logSource, all, errorsOnly bool) {
+	opts, err := podLogOptions(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "Streaming is not supported")
+		return
+	}
+	text := r.URL.Query().Get("format") == "text"
+	if text {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "text/event-stream")
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	events := make(chan followEvent, 64)
+
+	write := func(event string, line LogStreamLine) error {
+		if text {
+			if event != "log" {
+				return nil
+			}
+			_, err := fmt.Fprintf(w, "[%s/%s] %s\n", line.Pod, line.Container, line.Line)
+			return err
+		}
+		data, err := json.Marshal(line)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+		return err
+	}
+
+	followed := map[string]*followedContainer{}
+	// end sends the held "ended" event of f, whose log is over.
+	end := func(f *followedContainer) error {
+		f.done = true
+		if f.ended == nil {
+			return nil
+		}
+		line := *f.ended
+		f.ended = nil
+		return write("source", line)
+	}
+	discoverPods := func() error {
+		now := time.Now()
+		seen := map[string]bool{}
+		for _, src := range discover(ctx) {
+			for _, container := range podContainers(src.Pod) {
+				key := src.Namespace + "/" + src.Pod.Name + "/" + container
+				seen[key] = true
+				instance := containerInstance(src.Pod, container)
+				containerOpts := opts
+				f := followed[key]
+				switch {
+				case f == nil:
+					f = &followedContainer{}
+					followed[key] = f
+				case f.following:
+					continue
+				case f.instance != instance:
+					// A restarted container or recreated pod: the old log is
+					// over and the new one is read from the start.
+					if err := end(f); err != nil {
+						return err
+					}
+					*f = followedContainer{}
+					containerOpts.TailLines, containerOpts.SinceTime, containerOpts.SinceSeconds = nil, nil, nil
+				case f.done || now.Before(f.retryAt):
+					continue
+				case f.ended != nil && (f.untimed || containerTerminated(src.Pod, container)):
+					if err := end(f); err != nil {
+						return err
+					}
+					continue
+				}
+				if !f.lastAt.IsZero() {
+					// Pick up after the last line sent; followContainer skips
+					// the lines of that second it already sent.
+					containerOpts.TailLines, containerOpts.SinceSeconds = nil, nil
+					containerOpts.SinceTime = &metav1.Time{Time: f.lastAt}
+				}
+				f.instance = instance
+				f.following = true
+				f.ended = nil
+				keep := func(l string) bool { return src.includes(l, all, errorsOnly) }
+				go followContainer(ctx, clients, src, container, key, containerOpts, f.lastAt, keep, events)
+			}
+		}
+		// The log of a pod that is gone will not grow any more.
+		for key, f := range followed {
+			if !seen[key] && f.ended != nil {
+				if err := end(f); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	if discoverPods() != nil {
+		return
+	}
+	discovery := time.NewTicker(logDiscoveryInterval)
+	defer discovery.Stop()
+	heartbeat := time.NewTicker(statusStreamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-events:
+			f := followed[ev.key]
+			switch {
+			case ev.event == "log":
+				err = write(ev.event, ev.line)
+				if ev.at.IsZero() {
+					f.untimed = true
+				} else {
+					f.lastAt = ev.at
+				}
+			case ev.line.State == "following":
+				f.failures = 0
+				if !f.announced {
+					f.announced = true
+					err = write(ev.event, ev.line)
+				}
+			case ev.err != nil:
+				// Most often the container has not started yet. Only the
+				// first of a run of failures is sent.
+				f.following = false
+				f.failures++
+				f.retryAt = time.Now().Add(followBackoff(f.failures))
+				log.Debugf("Could not follow logs of %s (attempt %d): %v", ev.key, f.failures, ev.err)
+				if f.failures == 1 {
+					line := ev.line
+					line.State = "error"
+					err = write(ev.event, line)
+				}
+			default:
+				// The log ended. Discovery decides, from a fresh look at
+				// the pod, whether the container exited or the log is to be
+				// followed again.
+				f.following = false
+				line := ev.line
+				f.ended = &line
+			}
+		case <-discovery.C:
+			err = discoverPods()
+		case <-heartbeat.C:
+			if !text {
+				_, err = fmt.Fprint(w, ": heartbeat\n\n")
+			}
+		}
+		if err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// HandleStreamPlanLogs follows the logs of every vm-import-controller replica
+// about a VirtualMachineImport (every line with ?all=true).
+func HandleStreamPlanLogs(clients *K8sClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		namespace := vars["namespace"]
+		name := vars["name"]
+
+		controller := configuredVMICController()
+		if _, _, err := vmicLogSources(r.Context(), clients, controller, namespace, name, false); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to find plan logs: "+err.Error())
+			return
+		}
+		log.Infof("Streaming logs related to plan %s/%s", namespace, name)
+		discover := func(ctx context.Context) []logSource {
+			sources, _, err := vmicLogSources(ctx, clients, controller, namespace, name, false)
+			if err != nil {
+				log.Debugf("Could not look up log sources of plan %s/%s: %v", namespace, name, err)
+			}
+			return sources
+		}
+		streamPodLogs(w, r, clients, discover, r.URL.Query().Get("all") == "true", false)
+	}
+}
+
+// HandleStreamForkliftLogs follows the logs of a Forklift plan: the lines of
+// the Forklift controller about the plan (every line with ?all=true) and its
+// worker, populator and converter pods, including pods created after the
+// stream started. ?errors=true keeps only error and warning lines.
+func HandleStreamForkliftLogs(clients *K8sClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		planNamespace := vars["namespace"]
+		planName := vars["name"]
+		forkliftNs := r.URL.Query().Get("forkliftNamespace")
+		if forkliftNs == "" {
+			forkliftNs = planNamespace
+		}
+
+		log.Debugf("Streaming Forklift logs for plan %s/%s (forklift ns: %s)", planNamespace, planName, forkliftNs)
+		discover := func(ctx context.Context) []logSource {
+			sources, _ := forkliftLogSources(ctx, clients, planNamespace, planName, forkliftNs)
+			return sources
+		}
+		streamPodLogs(w, r, clients, discover, r.URL.Query().Get("all") == "true", r.URL.Query().Get("errors") == "true")
+	}
+}
@@ -0,0 +1,408 @@
+// pkg/log_stream_test.go
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestLogSourceIncludes(t *testing.T) {
+	controller := logSource{Filter: []string{"db", "db-migration"}}
+	worker := logSource{}
+	tests := []struct {
+		name            string
+		src             logSource
+		line            string
+		all, errorsOnly bool
+		want            bool
+	}{
+		{"controller match", controller, `{"plan":"db","msg":"ok"}`, false, false, true},
+		{"controller other plan", controller, `{"plan":"web"}`, false, false, false},
+		{"controller all", controller, `{"plan":"web"}`, true, true, true},
+		{"controller error only", controller, `{"plan":"db","level":"info"}`, false, true, false},
+		{"controller error", controller, `{"plan":"db","level":"error"}`, false, true, true},
+		{"worker", worker, "Copying disk 1/2", false, false, true},
+		{"worker error only", worker, "virt-v2v: warning: no virtio drivers", false, true, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.src.includes(tc.line, tc.all, tc.errorsOnly); got != tc.want {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestPodLogOptions(t *testing.T) {
+	r := httptest.NewRequest("GET", "/logs/stream?tailLines=50&sinceTime=2026-10-17T10:00:00Z", nil)
+	opts, err := podLogOptions(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !opts.Follow || *opts.TailLines != 50 || !opts.SinceTime.Time.Equal(time.Date(2026, time.October, 17, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected options %+v", opts)
+	}
+	for _, query := range []string{"tailLines=-1", "sinceTime=yesterday", "sinceSeconds=0", "sinceSeconds=60&sinceTime=2026-10-17T10:00:00Z"} {
+		if _, err := podLogOptions(httptest.NewRequest("GET", "/logs/stream?"+query, nil)); err == nil {
+			t.Errorf("expected %q to be rejected", query)
+		}
+	}
+}
+
+type logStreamEvent struct {
+	event string
+	data  LogStreamLine
+}
+
+// readLogStream parses the Server-Sent Events of a log stream.
+func readLogStream(t *testing.T, url string) <-chan logStreamEvent {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	events := make(chan logStreamEvent, 32)
+	go func() {
+		defer resp.Body.Close()
+		scanner := bufio.NewScanner(resp.Body)
+		var ev logStreamEvent
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				ev.event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &ev.data)
+			case line == "" && ev.event != "":
+				events <- ev
+				ev = logStreamEvent{}
+			}
+		}
+	}()
+	return events
+}
+
+// collectLogStream reads events until want returns true for the events so far.
+func collectLogStream(t *testing.T, events <-chan logStreamEvent, want func([]logStreamEvent) bool) []logStreamEvent {
+	t.Helper()
+	var got []logStreamEvent
+	timeout := time.After(5 * time.Second)
+	for !want(got) {
+		select {
+		case ev := <-events:
+			got = append(got, ev)
+		case <-timeout:
+			t.Fatalf("timed out; got %+v", got)
+		}
+	}
+	return got
+}
+
+func countLogEvents(events []logStreamEvent, event, pod string) int {
+	n := 0
+	for _, ev := range events {
+		if ev.event == event && ev.data.Pod == pod {
+			n++
+		}
+	}
+	return n
+}
+
+func newLogTestPod(namespace, name string, labels map[string]string, containers ...string) *v1.Pod {
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels}}
+	for _, c := range containers {
+		pod.Spec.Containers = append(pod.Spec.Containers, v1.Container{Name: c})
+	}
+	return pod
+}
+
+func TestHandleStreamForkliftLogs(t *testing.T) {
+	previous := logDiscoveryInterval
+	logDiscoveryInterval = 20 * time.Millisecond
+	t.Cleanup(func() { logDiscoveryInterval = previous })
+
+	plan := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "forklift.konveyor.io/v1beta1",
+		"kind":       "Plan",
+		"metadata":   map[string]interface{}{"name": "db", "namespace": "vms"},
+		"spec":       map[string]interface{}{"targetNamespace": "vms", "vms": []interface{}{map[string]interface{}{"id": "vm-1"}}},
+	}}
+	clients := newTestClientsWithDynamic([]runtime.Object{
+		newLogTestPod("konveyor-forklift", "forklift-controller-abc", map[string]string{"app": "forklift-controller"}, "main", "inventory"),
+		newLogTestPod("vms", "db-vm-1-v2v", map[string]string{"plan-name": "db", "forklift.app": "virt-v2v", "vmID": "vm-1"}, "virt-v2v"),
+		newLogTestPod("vms", "unrelated", nil, "app"),
+	}, plan)
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/forklift/plans/{namespace}/{name}/logs/stream", HandleStreamForkliftLogs(clients))
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	base := server.URL + "/api/v1/forklift/plans/vms/db/logs/stream?forkliftNamespace=konveyor-forklift"
+
+	events := readLogStream(t, base+"&all=true&tailLines=100")
+	got := collectLogStream(t, events, func(evs []logStreamEvent) bool {
+		return countLogEvents(evs, "log", "forklift-controller-abc") == 2 && countLogEvents(evs, "log", "db-vm-1-v2v") == 1
+	})
+	if countLogEvents(got, "source", "unrelated") != 0 {
+		t.Error("expected unrelated pods to be ignored")
+	}
+	for _, ev := range got {
+		if ev.event == "log" && ev.data.Pod == "db-vm-1-v2v" && (ev.data.Source != "virt-v2v Conversion: vms/db-vm-1-v2v (VM: vm-1)" || ev.data.Line != "fake logs") {
+			t.Errorf("unexpected worker line %+v", ev.data)
+		}
+	}
+
+	// A populator pod Forklift creates later joins the stream.
+	populator := newLogTestPod("vms", "populate-xyz", map[string]string{"migration": "abc"}, "populate")
+	if _, err := clients.Clientset.CoreV1().Pods("vms").Create(context.Background(), populator, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	got = collectLogStream(t, events, func(evs []logStreamEvent) bool { return countLogEvents(evs, "log", "populate-xyz") == 1 })
+	announced := false
+	for _, ev := range got {
+		if ev.data.Pod == "populate-xyz" {
+			announced = ev.event == "source" && ev.data.State == "following" && ev.data.Source == "CDI Populator: vms/populate-xyz"
+			break
+		}
+	}
+	if !announced {
+		t.Errorf("expected the new pod announced before its lines, got %+v", got)
+	}
+
+	for _, action := range clients.Clientset.(*fake.Clientset).Actions() {
+		if action.GetSubresource() != "log" {
+			continue
+		}
+		opts, _ := action.(k8stesting.GenericAction).GetValue().(*v1.PodLogOptions)
+		if opts == nil || !opts.Follow || opts.TailLines == nil || *opts.TailLines != 100 || opts.Container == "" {
+			t.Errorf("expected follow-mode options per container, got %+v", opts)
+		}
+	}
+
+	// As text and without ?all, the controller's lines about other plans are
+	// dropped and every line carries its pod and container.
+	req, _ := http.NewRequest("GET", base+"&format=text", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	resp.Body.Close()
+	want := map[string]bool{"[db-vm-1-v2v/virt-v2v] fake logs": true, "[populate-xyz/populate] fake logs": true}
+	if len(lines) != len(want) || !want[lines[0]] || !want[lines[1]] {
+		t.Errorf("unexpected text stream %q", lines)
+	}
+
+	if rr := executeRequest(HandleStreamForkliftLogs(clients), "GET", "/api/v1/forklift/plans/vms/db/logs/stream?tailLines=x", nil, map[string]string{"namespace": "vms", "name": "db"}); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a bad tailLines, got %d", rr.Code)
+	}
+}
+
+func TestStreamPodLogsFollowsContainers(t *testing.T) {
+	previous := logDiscoveryInterval
+	logDiscoveryInterval = 20 * time.Millisecond
+	t.Cleanup(func() { logDiscoveryInterval = previous })
+
+	// The "waiting" container never starts; the others log one line.
+	var mu sync.Mutex
+	attempts := map[string]int{}
+	var mainOpts []v1.PodLogOptions
+	original := openContainerLog
+	openContainerLog = func(ctx context.Context, clients *K8sClients, namespace, pod string, opts *v1.PodLogOptions) (io.ReadCloser, error) {
+		// Streams of earlier tests may still be winding down.
+		if pod != "worker" {
+			return nil, errors.New("not this test's pod")
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		attempts[opts.Container]++
+		switch opts.Container {
+		case "waiting":
+			return nil, errors.New("container is waiting to start")
+		case "main":
+			mainOpts = append(mainOpts, *opts)
+		}
+		return io.NopCloser(strings.NewReader("first\nsecond\n")), nil
+	}
+	t.Cleanup(func() { openContainerLog = original })
+
+	worker := newLogTestPod("vms", "worker", nil, "main", "waiting")
+	worker.UID = "worker-uid"
+	worker.Status.ContainerStatuses = []v1.ContainerStatus{{Name: "main"}}
+	clients := newTestClientsWithDynamic([]runtime.Object{worker})
+	discover := func(ctx context.Context) []logSource {
+		pod, err := clients.Clientset.CoreV1().Pods("vms").Get(ctx, "worker", metav1.GetOptions{})
+		if err != nil {
+			return nil
+		}
+		return []logSource{{Namespace: "vms", Pod: *pod, Label: "Worker"}}
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		streamPodLogs(w, r, clients, discover, true, false)
+	}))
+	t.Cleanup(server.Close)
+
+	// kinds lists the events of a container as "log" or the source state.
+	kinds := func(events []logStreamEvent, container string) []string {
+		var kinds []string
+		for _, ev := range events {
+			if ev.data.Container != container {
+				continue
+			}
+			if ev.event == "source" {
+				kinds = append(kinds, ev.data.State)
+			} else {
+				kinds = append(kinds, ev.event)
+			}
+		}
+		return kinds
+	}
+	events := readLogStream(t, server.URL+"?tailLines=10")
+	got := collectLogStream(t, events, func(evs []logStreamEvent) bool { return len(kinds(evs, "main")) == 4 })
+	if k := strings.Join(kinds(got, "main"), ","); k != "following,log,log,ended" {
+		t.Errorf("expected the source announced once open and ended after its last line, got %s", k)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	mu.Lock()
+	waiting := attempts["waiting"]
+	mu.Unlock()
+	if waiting < 2 || waiting > 6 {
+		t.Errorf("expected the waiting container retried with a backoff, got %d attempts", waiting)
+	}
+
+	// The container restarts: its new instance is followed from the start.
+	worker.Status.ContainerStatuses[0].RestartCount = 1
+	if _, err := clients.Clientset.CoreV1().Pods("vms").UpdateStatus(context.Background(), worker, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, collectLogStream(t, events, func(evs []logStreamEvent) bool { return len(kinds(evs, "main")) == 4 })...)
+	if k := strings.Join(kinds(got, "waiting"), ","); k != "error" {
+		t.Errorf("expected the waiting container reported once as an error, got %s", k)
+	}
+	for _, ev := range got {
+		if ev.data.Container == "waiting" && ev.data.Error != "container is waiting to start" {
+			t.Errorf("expected the open error in the source event, got %+v", ev.data)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(mainOpts) != 2 || *mainOpts[0].TailLines != 10 || mainOpts[1].TailLines != nil {
+		t.Errorf("expected main followed twice, the restart from its first line, got %+v", mainOpts)
+	}
+}
+
+func TestStreamPodLogsResumesRunningContainers(t *testing.T) {
+	previous := logDiscoveryInterval
+	logDiscoveryInterval = 20 * time.Millisecond
+	t.Cleanup(func() { logDiscoveryInterval = previous })
+
+	// Each follow of the log ends after what the kubelet has so far, with
+	// timestamps; it gains a line per follow.
+	var mu sync.Mutex
+	var follows []v1.PodLogOptions
+	lines := []string{
+		"2026-01-02T03:04:05.1Z first",
+		"2026-01-02T03:04:05.2Z second",
+	}
+	original := openContainerLog
+	openContainerLog = func(ctx context.Context, clients *K8sClients, namespace, pod string, opts *v1.PodLogOptions) (io.ReadCloser, error) {
+		// Streams of earlier tests may still be winding down.
+		if pod != "runner" {
+			return nil, errors.New("not this test's pod")
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		follows = append(follows, *opts)
+		logged := strings.Join(lines, "\n") + "\n"
+		at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC).Add(time.Duration(len(lines)+1) * 100 * time.Millisecond)
+		lines = append(lines, fmt.Sprintf("%s line %d", at.Format(time.RFC3339Nano), len(lines)+1))
+		return io.NopCloser(strings.NewReader(logged)), nil
+	}
+	t.Cleanup(func() { openContainerLog = original })
+
+	runner := newLogTestPod("vms", "runner", nil, "main")
+	runner.UID = "runner-uid"
+	runner.Status.ContainerStatuses = []v1.ContainerStatus{{Name: "main", State: v1.ContainerState{Running: &v1.ContainerStateRunning{}}}}
+	clients := newTestClientsWithDynamic([]runtime.Object{runner})
+	discover := func(ctx context.Context) []logSource {
+		pod, err := clients.Clientset.CoreV1().Pods("vms").Get(ctx, "runner", metav1.GetOptions{})
+		if err != nil {
+			return nil
+		}
+		return []logSource{{Namespace: "vms", Pod: *pod, Label: "Worker"}}
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		streamPodLogs(w, r, clients, discover, true, false)
+	}))
+	t.Cleanup(server.Close)
+
+	events := readLogStream(t, server.URL+"?tailLines=10")
+	got := collectLogStream(t, events, func(evs []logStreamEvent) bool { return countLogEvents(evs, "log", "runner") >= 4 })
+	var sent []string
+	for _, ev := range got {
+		if ev.event == "log" {
+			sent = append(sent, ev.data.Line)
+		}
+	}
+	if strings.Join(sent[:4], ",") != "first,second,line 3,line 4" {
+		t.Errorf("expected each line once, without timestamps, got %q", sent)
+	}
+	if countLogEvents(got, "source", "runner") != 1 {
+		t.Errorf("expected the running container announced once and not ended, got %+v", got)
+	}
+	mu.Lock()
+	if len(follows) < 2 || !follows[0].Timestamps || follows[1].TailLines != nil || follows[1].SinceTime == nil ||
+		!follows[1].SinceTime.Time.Equal(time.Date(2026, 1, 2, 3, 4, 5, 200000000, time.UTC)) {
+		t.Errorf("expected the log followed again since its last line, got %+v", follows)
+	}
+	mu.Unlock()
+
+	// Once the container exits, its log is over.
+	runner.Status.ContainerStatuses[0].State = v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 0}}
+	if _, err := clients.Clientset.CoreV1().Pods("vms").UpdateStatus(context.Background(), runner, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	got = collectLogStream(t, events, func(evs []logStreamEvent) bool {
+		return len(evs) > 0 && evs[len(evs)-1].event == "source" && evs[len(evs)-1].data.State == "ended"
+	})
+	time.Sleep(100 * time.Millisecond)
+	mu.Lock()
+	before := len(follows)
+	mu.Unlock()
+	time.Sleep(100 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if len(follows) != before {
+		t.Errorf("expected no follows after the container exited, got %d more", len(follows)-before)
+	}
+}
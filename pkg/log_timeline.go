@@ -0,0 +1,538 @@
+// pkg/log_timeline.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Timeline entry types.
+const (
+	timelinePhase    = "phase"
+	timelineError    = "error"
+	timelineWarning  = "warning"
+	timelineProgress = "progress"
+	timelineEvent    = "event"
+)
+
+// TimelineEntry is one step of a migration, parsed from a log line or taken
+// from a Kubernetes Event.
+type TimelineEntry struct {
+	Time    *metav1.Time `json:"time,omitempty"`
+	Type    string       `json:"type"`
+	VM      string       `json:"vm,omitempty"` // VM ID for Forklift, VM name for VirtualMachineImports
+	Phase   string       `json:"phase,omitempty"`
+	Disk    string       `json:"disk,omitempty"` // "1/2" while virt-v2v copies the first of two disks
+	Percent *float64     `json:"percent,omitempty"`
+	Message string       `json:"message"`
+	Source  string       `json:"source"`           // the log source, or "Event"
+	Reason  string       `json:"reason,omitempty"` // Event reason
+	Object  string       `json:"object,omitempty"` // the Event's object, "Kind/name"
+	Count   int32        `json:"count,omitempty"`  // how often the Event was seen
+}
+
+// TimelineVM is the current status of one VM and its last step in the
+// timeline.
+type TimelineVM struct {
+	VMProgress
+	LastActivity *metav1.Time `json:"lastActivity,omitempty"`
+	LastEntry    string       `json:"lastEntry,omitempty"`
+	Errors       int          `json:"errors"`
+}
+
+// Timeline is the migration timeline of a plan, oldest entry first.
+type Timeline struct {
+	Entries []TimelineEntry `json:"entries"`
+	VMs     []TimelineVM    `json:"vms"`
+}
+
+var (
+	// virt-v2v prints "Copying disk 1/2" before each disk and then its
+	// progress as "(12.34/100%)".
+	v2vDiskPattern     = regexp.MustCompile(`Copying disk (\d+)/(\d+)`)
+	v2vProgressPattern = regexp.MustCompile(`\(\s*(\d+(?:\.\d+)?)/100%\)`)
+	// key=value pairs of logrus text lines, as the vm-import-controller writes
+	// them: time="..." level=info msg="..."
+	logfmtPattern = regexp.MustCompile(`(\w+)=("(?:[^"\\]|\\.)*"|\S+)`)
+	// klog lines, as CDI writes them: E1017 10:00:00.000000 1 file.go:12] message
+	klogPattern = regexp.MustCompile(`^([IWEF])\d{4} [\d:.]+\s+\d+ [^\]]+\] (.*)$`)
+	// The Forklift controller logs a VM as " id:vm-1 name:'db-1' ".
+	forkliftLogVMPattern = regexp.MustCompile(`id:(\S+)`)
+)
+
+// vmicPhases are the status.importStatus values of a VirtualMachineImport; a
+// vm-import-controller line naming one is a phase change.
+var vmicPhases = []string{
+	"sourceReady", "disksExported", "diskImagesSubmitted", "diskImagesReady", "diskImagesFailed",
+	"virtualMachineCreated", "virtualMachineRunning", "virtualMachineImportValid", "virtualMachineImportInvalid",
+	"virtualMachineMigrationFailed",
+}
+
+// timelineParser turns log lines into timeline entries. It remembers each
+// VM's phase, to report only changes, and each container's disk transfer, to
+// report its progress in steps of 10%.
+type timelineParser struct {
+	phases   map[string]string
+	disks    map[string]string
+	progress map[string]int
+}
+
+func newTimelineParser() *timelineParser {
+	return &timelineParser{phases: map[string]string{}, disks: map[string]string{}, progress: map[string]int{}}
+}
+
+// parse returns the timeline entry of a line from a container of src, or nil
+// if the line is not a step of the migration. vm is the VM the source belongs
+// to, for lines that do not name one; at is when the kubelet received the
+// line, if known, and wins over the time in the line.
+func (p *timelineParser) parse(src logSource, container, vm string, at time.Time, line string) *TimelineEntry {
+	var e *TimelineEntry
+	switch {
+	case strings.HasPrefix(line, "{"):
+		e = p.parseJSON(vm, line)
+	case strings.HasPrefix(line, "time="):
+		e = p.parseLogfmt(vm, line)
+	default:
+		e = p.parseText(src.Namespace+"/"+src.Pod.Name+"/"+container, vm, line)
+	}
+	if e == nil {
+		return nil
+	}
+	if !at.IsZero() {
+		e.Time = &metav1.Time{Time: at}
+	}
+	e.Source = src.Label
+	return e
+}
+
+// phaseChanged records phase as the VM's phase and reports whether it is new.
+func (p *timelineParser) phaseChanged(vm, phase string) bool {
+	if phase == "" || p.phases[vm] == phase {
+		return false
+	}
+	p.phases[vm] = phase
+	return true
+}
+
+// parseJSON reads a structured line of the Forklift controller, with its
+// level, msg, ts, vm, phase and error fields.
+func (p *timelineParser) parseJSON(vm, line string) *TimelineEntry {
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &fields); err != nil {
+		return nil
+	}
+	if id := forkliftLogVM(fields["vm"]); id != "" {
+		vm = id
+	}
+	msg, _ := fields["msg"].(string)
+	if errMsg, _ := fields["error"].(string); errMsg != "" {
+		msg += ": " + errMsg
+	}
+	phase, _ := fields["phase"].(string)
+	e := &TimelineEntry{VM: vm, Phase: phase, Message: msg, Time: forkliftLogTime(fields["ts"])}
+
+	level, _ := fields["level"].(string)
+	changed := p.phaseChanged(vm, phase)
+	switch {
+	case level == "error" || level == "dpanic" || level == "panic" || level == "fatal":
+		e.Type = timelineError
+	case level == "warn" || level == "warning":
+		e.Type = timelineWarning
+	case changed:
+		e.Type = timelinePhase
+		if e.Message == "" {
+			e.Message = "Phase " + phase
+		}
+	default:
+		return nil
+	}
+	return e
+}
+
+// forkliftLogVM reads the VM ID from the vm field of a controller line.
+func forkliftLogVM(v interface{}) string {
+	switch vm := v.(type) {
+	case string:
+		if m := forkliftLogVMPattern.FindStringSubmatch(vm); m != nil {
+			return m[1]
+		}
+		return strings.TrimSpace(vm)
+	case map[string]interface{}:
+		id, _ := vm["id"].(string)
+		return id
+	}
+	return ""
+}
+
+// forkliftLogTime reads the ts field of a controller line: a time such as
+// "2026-10-17 10:00:00.000" (UTC), or seconds since the epoch.
+func forkliftLogTime(v interface{}) *metav1.Time {
+	switch ts := v.(type) {
+	case string:
+		for _, layout := range []string{"2006-01-02 15:04:05.000", "2006-01-02 15:04:05", time.RFC3339Nano} {
+			if t, err := time.Parse(layout, ts); err == nil {
+				return &metav1.Time{Time: t}
+			}
+		}
+	case float64:
+		sec := int64(ts)
+		return &metav1.Time{Time: time.Unix(sec, int64((ts-float64(sec))*1e9)).UTC()}
+	}
+	return nil
+}
+
+// parseLogfmt reads a logrus text line of the vm-import-controller.
+func (p *timelineParser) parseLogfmt(vm, line string) *TimelineEntry {
+	fields := map[string]string{}
+	for _, m := range logfmtPattern.FindAllStringSubmatch(line, -1) {
+		value := m[2]
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		fields[m[1]] = value
+	}
+	e := &TimelineEntry{VM: vm, Message: fields["msg"]}
+	if fields["error"] != "" {
+		e.Message += ": " + fields["error"]
+	}
+	if t, err := time.Parse(time.RFC3339Nano, fields["time"]); err == nil {
+		e.Time = &metav1.Time{Time: t}
+	}
+
+	switch fields["level"] {
+	case "error", "fatal", "panic":
+		e.Type = timelineError
+	case "warning":
+		e.Type = timelineWarning
+	default:
+		for _, phase := range vmicPhases {
+			if strings.Contains(e.Message, phase) {
+				e.Phase = phase
+				break
+			}
+		}
+		if !p.phaseChanged(vm, e.Phase) {
+			return nil
+		}
+		e.Type = timelinePhase
+	}
+	return e
+}
+
+// parseText reads an unstructured line of a worker pod: virt-v2v disk
+// transfer progress, and errors and warnings (klog levels, or the words
+// isErrorLogLine looks for).
+func (p *timelineParser) parseText(key, vm, line string) *TimelineEntry {
+	if m := v2vDiskPattern.FindStringSubmatch(line); m != nil {
+		p.disks[key] = m[1] + "/" + m[2]
+		p.progress[key] = 0
+		percent := 0.0
+		return &TimelineEntry{Type: timelineProgress, VM: vm, Disk: p.disks[key], Percent: &percent, Message: strings.TrimSpace(line)}
+	}
+	if m := v2vProgressPattern.FindStringSubmatch(line); m != nil {
+		percent, _ := strconv.ParseFloat(m[1], 64)
+		step := int(percent) / 10
+		if last, ok := p.progress[key]; ok && last >= step {
+			return nil
+		}
+		p.progress[key] = step
+		message := fmt.Sprintf("Copied %.0f%%", percent)
+		if disk := p.disks[key]; disk != "" {
+			message = fmt.Sprintf("Copied %.0f%% of disk %s", percent, disk)
+		}
+		return &TimelineEntry{Type: timelineProgress, VM: vm, Disk: p.disks[key], Percent: &percent, Message: message}
+	}
+
+	e := &TimelineEntry{VM: vm, Message: strings.TrimSpace(line)}
+	level := ""
+	if m := klogPattern.FindStringSubmatch(line); m != nil {
+		level, e.Message = m[1], m[2]
+	}
+	switch {
+	case level == "E" || level == "F":
+		e.Type = timelineError
+	case level == "W":
+		e.Type = timelineWarning
+	case level == "" && isErrorLogLine(line):
+		e.Type = timelineError
+		if strings.Contains(strings.ToLower(line), "warn") {
+			e.Type = timelineWarning
+		}
+	default:
+		return nil
+	}
+	return e
+}
+
+// timelineObject is the key of an object whose Events belong in a timeline.
+func timelineObject(namespace, kind, name string) string {
+	return namespace + "/" + kind + "/" + name
+}
+
+// eventTime is when an Event was last seen.
+func eventTime(ev v1.Event) *metav1.Time {
+	switch {
+	case !ev.LastTimestamp.IsZero():
+		return &metav1.Time{Time: ev.LastTimestamp.Time}
+	case !ev.EventTime.IsZero():
+		return &metav1.Time{Time: ev.EventTime.Time}
+	case !ev.FirstTimestamp.IsZero():
+		return &metav1.Time{Time: ev.FirstTimestamp.Time}
+	}
+	return &metav1.Time{Time: ev.CreationTimestamp.Time}
+}
+
+// timelineEvents returns the Events about the involved objects, keyed by
+// timelineObject, each mapped to the VM it concerns ("" for the whole plan).
+func timelineEvents(ctx context.Context, clients *K8sClients, involved map[string]string) []TimelineEntry {
+	namespaces := map[string]bool{}
+	for key := range involved {
+		namespaces[strings.SplitN(key, "/", 2)[0]] = true
+	}
+	var entries []TimelineEntry
+	for ns := range namespaces {
+		events, err := clients.Clientset.CoreV1().Events(ns).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			log.Warnf("Could not list events in %s: %v", ns, err)
+			continue
+		}
+		for _, ev := range events.Items {
+			obj := ev.InvolvedObject
+			vm, ok := involved[timelineObject(ns, obj.Kind, obj.Name)]
+			if !ok {
+				continue
+			}
+			e := TimelineEntry{Time: eventTime(ev), Type: timelineEvent, VM: vm, Message: ev.Message, Source: "Event", Reason: ev.Reason, Object: obj.Kind + "/" + obj.Name}
+			if ev.Type == v1.EventTypeWarning {
+				e.Type = timelineWarning
+			}
+			if ev.Count > 1 {
+				e.Count = ev.Count
+			}
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// buildTimeline reads the logs of sources and the Events of the involved
+// objects into a timeline sorted by time, and sums it up for each VM of
+// status. vmOf names the VM a source belongs to, for lines that do not say.
+func buildTimeline(ctx context.Context, clients *K8sClients, sources []logSource, vmOf func(logSource) string, involved map[string]string, status []VMProgress, opts v1.PodLogOptions) Timeline {
+	parser := newTimelineParser()
+	entries := []TimelineEntry{}
+	keep := func(src logSource, line string) bool { return src.includes(line, false, false) }
+	// In time order, so the parser sees each VM's phases as they happened,
+	// even across controller replicas.
+	for _, l := range readMergedLogs(ctx, clients, sources, opts, keep) {
+		if e := parser.parse(l.Source, l.Container, vmOf(l.Source), l.Time, l.Line); e != nil {
+			entries = append(entries, *e)
+		}
+	}
+	entries = append(entries, timelineEvents(ctx, clients, involved)...)
+
+	// Entries without a time go last, in the order they were read.
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i].Time, entries[j].Time
+		if a == nil || b == nil {
+			return a != nil && b == nil
+		}
+		return a.Time.Before(b.Time)
+	})
+
+	vms := make([]TimelineVM, len(status))
+	for i, s := range status {
+		vms[i].VMProgress = s
+	}
+	for _, e := range entries {
+		for i := range vms {
+			if e.VM == "" || (e.VM != vms[i].ID && e.VM != vms[i].Name) {
+				continue
+			}
+			if e.Time != nil {
+				vms[i].LastActivity = e.Time
+			}
+			vms[i].LastEntry = e.Message
+			if e.Type == timelineError {
+				vms[i].Errors++
+			}
+		}
+	}
+	return Timeline{Entries: entries, VMs: vms}
+}
+
+// timelineRequest reads the log options (tailLines, sinceTime, sinceSeconds)
+// of a timeline request, and the entry types ?type= keeps (all when empty).
+func timelineRequest(r *http.Request) (v1.PodLogOptions, map[string]bool, error) {
+	opts, err := podLogOptions(r)
+	if err != nil {
+		return opts, nil, err
+	}
+	opts.Follow = false
+	types := map[string]bool{}
+	for _, t := range strings.Split(r.URL.Query().Get("type"), ",") {
+		switch t = strings.TrimSpace(t); t {
+		case "":
+		case timelinePhase, timelineError, timelineWarning, timelineProgress, timelineEvent:
+			types[t] = true
+		default:
+			return opts, nil, fmt.Errorf("unknown timeline entry type %q", t)
+		}
+	}
+	return opts, types, nil
+}
+
+// filterTimeline keeps the entries of the given types.
+func filterTimeline(t Timeline, types map[string]bool) Timeline {
+	if len(types) == 0 {
+		return t
+	}
+	entries := []TimelineEntry{}
+	for _, e := range t.Entries {
+		if types[e.Type] {
+			entries = append(entries, e)
+		}
+	}
+	t.Entries = entries
+	return t
+}
+
+// HandleGetPlanTimeline returns the timeline of a VirtualMachineImport: the
+// phase changes, errors and warnings in the logs of every vm-import-controller
+// replica about it, and the Events of the import and its VirtualMachine.
+func HandleGetPlanTimeline(clients *K8sClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		namespace := vars["namespace"]
+		name := vars["name"]
+
+		opts, types, err := timelineRequest(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		plan, err := clients.getResource(r.Context(), vmiGVR, namespace, name)
+		if apierrors.IsNotFound(err) {
+			respondWithError(w, http.StatusNotFound, "Plan not found")
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to get plan: "+err.Error())
+			return
+		}
+		sources, _, err := vmicLogSources(r.Context(), clients, configuredVMICController(), namespace, name, true)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to find plan logs: "+err.Error())
+			return
+		}
+
+		vmName, _, _ := unstructured.NestedString(plan.Object, "spec", "virtualMachineName")
+		involved := map[string]string{
+			timelineObject(namespace, "VirtualMachineImport", name):              vmName,
+			timelineObject(namespace, "VirtualMachine", strings.ToLower(vmName)): vmName,
+		}
+		status := vmProgress("plans", plan)
+		if len(status) == 0 {
+			status = []VMProgress{{Name: vmName}}
+		}
+		timeline := buildTimeline(r.Context(), clients, sources, func(logSource) string { return vmName }, involved, status, opts)
+		respondWithJSON(w, http.StatusOK, filterTimeline(timeline, types))
+	}
+}
+
+// HandleGetForkliftTimeline returns the timeline of a Forklift plan: phase
+// changes and errors from the Forklift controller's structured logs, disk
+// transfer progress and errors from its worker pods, and the Events of the
+// Plan, its latest Migration, and the pods, PVCs and VirtualMachines of its VMs.
+func HandleGetForkliftTimeline(clients *K8sClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		planNamespace := vars["namespace"]
+		planName := vars["name"]
+		forkliftNs := r.URL.Query().Get("forkliftNamespace")
+		if forkliftNs == "" {
+			forkliftNs = planNamespace
+		}
+
+		opts, types, err := timelineRequest(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		plan, err := clients.getResource(r.Context(), forkliftPlanGVR, planNamespace, planName)
+		if apierrors.IsNotFound(err) {
+			respondWithError(w, http.StatusNotFound, "Forklift Plan not found")
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to get Forklift Plan: "+err.Error())
+			return
+		}
+		sources, targetNamespace := forkliftLogSources(r.Context(), clients, planNamespace, planName, forkliftNs)
+
+		involved := map[string]string{timelineObject(planNamespace, "Plan", planName): ""}
+		var status []VMProgress
+		migration, err := latestForkliftMigration(r.Context(), clients, planNamespace, planName)
+		if err != nil {
+			log.Warnf("Could not find the Migration of Forklift Plan %s/%s: %v", planNamespace, planName, err)
+		}
+		if migration != nil {
+			involved[timelineObject(planNamespace, "Migration", migration.GetName())] = ""
+			status = vmProgress("migrations", migration)
+		}
+
+		// The plan's VMs, and the names Forklift gives the VirtualMachines.
+		specVMs, _, _ := unstructured.NestedSlice(plan.Object, "spec", "vms")
+		for _, v := range specVMs {
+			vm, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			id, _, _ := unstructured.NestedString(vm, "id")
+			name, _, _ := unstructured.NestedString(vm, "name")
+			if migration == nil {
+				status = append(status, VMProgress{ID: id, Name: name})
+			}
+			if target, _, _ := unstructured.NestedString(vm, "targetName"); target != "" {
+				name = target
+			}
+			if targetNamespace != "" && name != "" {
+				involved[timelineObject(targetNamespace, "VirtualMachine", name)] = id
+			}
+		}
+		for _, src := range sources {
+			involved[timelineObject(src.Namespace, "Pod", src.Pod.Name)] = src.Pod.Labels["vmID"]
+		}
+		// Forklift labels the PVCs of a plan with the plan's UID and the VM ID.
+		if targetNamespace != "" && plan.GetUID() != "" {
+			pvcs, err := clients.Clientset.CoreV1().PersistentVolumeClaims(targetNamespace).List(r.Context(), metav1.ListOptions{
+				LabelSelector: "plan=" + string(plan.GetUID()),
+			})
+			if err != nil {
+				log.Warnf("Could not list the PVCs of Forklift Plan %s/%s: %v", planNamespace, planName, err)
+			} else {
+				for _, pvc := range pvcs.Items {
+					involved[timelineObject(targetNamespace, "PersistentVolumeClaim", pvc.Name)] = pvc.Labels["vmID"]
+				}
+			}
+		}
+
+		vmOf := func(src logSource) string { return src.Pod.Labels["vmID"] }
+		timeline := buildTimeline(r.Context(), clients, sources, vmOf, involved, status, opts)
+		respondWithJSON(w, http.StatusOK, filterTimeline(timeline, types))
+	}
+}
@@ -0,0 +1,141 @@
+// pkg/log_timeline_test.go
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestTimelineParser(t *testing.T) {
+	controller := logSource{Namespace: "konveyor-forklift", Pod: v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "forklift-controller-abc"}}, Label: "Forklift Controller"}
+	worker := logSource{Namespace: "vms", Pod: v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "db-vm-1-v2v"}}, Label: "virt-v2v"}
+	vmic := logSource{Namespace: "harvester-system", Label: "VM Import Controller"}
+
+	parser := newTimelineParser()
+	tests := []struct {
+		name  string
+		src   logSource
+		vm    string
+		line  string
+		want  string // entry type, "" for no entry
+		check func(*TimelineEntry) bool
+	}{
+		{"controller phase", controller, "", `{"level":"info","ts":"2026-10-17 10:00:00.000","msg":"Migration [RUN]","vm":" id:vm-1 name:'db-1' ","phase":"CopyDisks"}`, timelinePhase,
+			func(e *TimelineEntry) bool {
+				return e.VM == "vm-1" && e.Phase == "CopyDisks" && e.Time.Equal(&metav1.Time{Time: time.Date(2026, time.October, 17, 10, 0, 0, 0, time.UTC)})
+			}},
+		{"controller same phase", controller, "", `{"level":"info","msg":"Migration [RUN]","vm":" id:vm-1 name:'db-1' ","phase":"CopyDisks"}`, "", nil},
+		{"controller other VM", controller, "", `{"level":"info","msg":"Migration [RUN]","vm":{"id":"vm-2"},"phase":"CopyDisks"}`, timelinePhase, nil},
+		{"controller error", controller, "", `{"level":"error","msg":"Reconcile failed.","error":"pvc not bound","vm":" id:vm-1 "}`, timelineError,
+			func(e *TimelineEntry) bool { return e.Message == "Reconcile failed.: pvc not bound" && e.VM == "vm-1" }},
+		{"kubelet timestamp", controller, "", `2026-10-17T11:00:00Z {"level":"warn","ts":1792224000,"msg":"Retrying"}`, timelineWarning,
+			func(e *TimelineEntry) bool { return e.Time.Hour() == 11 && e.Source == "Forklift Controller" }},
+		{"vmic phase", vmic, "db", `time="2026-10-17T10:00:00Z" level=info msg="vm 'vms/db' import status diskImagesSubmitted"`, timelinePhase,
+			func(e *TimelineEntry) bool { return e.VM == "db" && e.Phase == "diskImagesSubmitted" }},
+		{"vmic info", vmic, "db", `time="2026-10-17T10:00:01Z" level=info msg="reconciling 'vms/db'"`, "", nil},
+		{"vmic error", vmic, "db", `time="2026-10-17T10:00:02Z" level=error msg="error exporting disk" error="timeout"`, timelineError,
+			func(e *TimelineEntry) bool { return e.Message == "error exporting disk: timeout" }},
+		{"disk start", worker, "vm-1", "[  12.0] Copying disk 1/2", timelineProgress,
+			func(e *TimelineEntry) bool { return e.Disk == "1/2" && *e.Percent == 0 }},
+		{"small progress", worker, "vm-1", "    (4.50/100%)", "", nil},
+		{"progress", worker, "vm-1", "    (23.10/100%)", timelineProgress,
+			func(e *TimelineEntry) bool { return e.Message == "Copied 23% of disk 1/2" && e.VM == "vm-1" }},
+		{"progress same step", worker, "vm-1", "    (27.00/100%)", "", nil},
+		{"worker warning", worker, "vm-1", "virt-v2v: warning: no virtio drivers", timelineWarning, nil},
+		{"klog error", worker, "vm-1", "E1017 10:00:00.000000       1 populator.go:42] failed to connect", timelineError,
+			func(e *TimelineEntry) bool { return e.Message == "failed to connect" }},
+		{"plain line", worker, "vm-1", "Inspecting the source", "", nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			at, line := splitLogTimestamp(tc.line)
+			e := parser.parse(tc.src, "main", tc.vm, at, line)
+			switch {
+			case tc.want == "" && e != nil:
+				t.Fatalf("expected no entry, got %+v", e)
+			case tc.want == "":
+			case e == nil || e.Type != tc.want:
+				t.Fatalf("expected a %s entry, got %+v", tc.want, e)
+			case tc.check != nil && !tc.check(e):
+				t.Errorf("unexpected entry %+v", e)
+			}
+		})
+	}
+}
+
+func newTimelineEvent(namespace, name, kind, object, eventType, reason string, at time.Time) *v1.Event {
+	return &v1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: name, Namespace: namespace},
+		InvolvedObject: v1.ObjectReference{Kind: kind, Name: object, Namespace: namespace},
+		Type:           eventType,
+		Reason:         reason,
+		Message:        reason + " " + object,
+		LastTimestamp:  metav1.Time{Time: at},
+	}
+}
+
+func TestHandleGetForkliftTimeline(t *testing.T) {
+	plan := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "forklift.konveyor.io/v1beta1",
+		"kind":       "Plan",
+		"metadata":   map[string]interface{}{"name": "db", "namespace": "vms", "uid": "plan-uid"},
+		"spec": map[string]interface{}{"targetNamespace": "vms", "vms": []interface{}{
+			map[string]interface{}{"id": "vm-1", "name": "db-1"},
+			map[string]interface{}{"id": "vm-2", "name": "db-2", "targetName": "db-two"},
+		}},
+	}}
+	start := time.Date(2026, time.October, 17, 10, 0, 0, 0, time.UTC)
+	pvc := &v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "db-vm-1-disk", Namespace: "vms", Labels: map[string]string{"plan": "plan-uid", "vmID": "vm-1"}}}
+	clients := newTestClientsWithDynamic([]runtime.Object{
+		newLogTestPod("vms", "db-vm-1-v2v", map[string]string{"plan-name": "db", "forklift.app": "virt-v2v", "vmID": "vm-1"}, "virt-v2v"),
+		pvc,
+		newTimelineEvent("vms", "e1", "Plan", "db", v1.EventTypeNormal, "Ready", start),
+		newTimelineEvent("vms", "e2", "PersistentVolumeClaim", "db-vm-1-disk", v1.EventTypeWarning, "ProvisioningFailed", start.Add(2*time.Minute)),
+		newTimelineEvent("vms", "e3", "Pod", "db-vm-1-v2v", v1.EventTypeNormal, "Started", start.Add(time.Minute)),
+		newTimelineEvent("vms", "e4", "VirtualMachine", "db-two", v1.EventTypeNormal, "Created", start.Add(3*time.Minute)),
+		newTimelineEvent("vms", "e5", "Pod", "unrelated", v1.EventTypeWarning, "BackOff", start),
+	}, plan, newStreamMigration(10))
+	vars := map[string]string{"namespace": "vms", "name": "db"}
+
+	rr := executeRequest(HandleGetForkliftTimeline(clients), "GET", "/api/v1/forklift/plans/vms/db/timeline", nil, vars)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var timeline Timeline
+	if err := json.Unmarshal(rr.Body.Bytes(), &timeline); err != nil {
+		t.Fatal(err)
+	}
+	var reasons []string
+	for _, e := range timeline.Entries {
+		reasons = append(reasons, e.Reason)
+	}
+	if len(reasons) != 4 || reasons[0] != "Ready" || reasons[1] != "Started" || reasons[2] != "ProvisioningFailed" || reasons[3] != "Created" {
+		t.Fatalf("expected the plan's events in order, got %v", reasons)
+	}
+	if e := timeline.Entries[2]; e.Type != timelineWarning || e.VM != "vm-1" || e.Object != "PersistentVolumeClaim/db-vm-1-disk" {
+		t.Errorf("unexpected PVC event %+v", e)
+	}
+	if len(timeline.VMs) != 2 || timeline.VMs[0].Phase != "CopyDisks" || timeline.VMs[0].LastEntry != "ProvisioningFailed db-vm-1-disk" || timeline.VMs[1].LastEntry != "Created db-two" {
+		t.Errorf("unexpected VM summaries %+v", timeline.VMs)
+	}
+
+	rr = executeRequest(HandleGetForkliftTimeline(clients), "GET", "/api/v1/forklift/plans/vms/db/timeline?type=warning,error", nil, vars)
+	json.Unmarshal(rr.Body.Bytes(), &timeline)
+	if len(timeline.Entries) != 1 || timeline.Entries[0].Reason != "ProvisioningFailed" {
+		t.Errorf("expected only the warning, got %+v", timeline.Entries)
+	}
+
+	if rr := executeRequest(HandleGetForkliftTimeline(clients), "GET", "/api/v1/forklift/plans/vms/db/timeline?type=debug", nil, vars); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown type, got %d", rr.Code)
+	}
+	if rr := executeRequest(HandleGetForkliftTimeline(clients), "GET", "/api/v1/forklift/plans/vms/web/timeline", nil, map[string]string{"namespace": "vms", "name": "web"}); rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing plan, got %d", rr.Code)
+	}
+}
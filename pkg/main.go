@@ -2,6 +2,7 @@
 package main
 
 import (
+	"context"
 	"mime"
 	"net/http"
 	"os"
@@ -34,24 +35,48 @@ func main() {
 		log.Fatalf("Failed to create Kubernetes clients: %v", err)
 	}
 
+	if k8sClients != nil {
+		go runWaveOrchestrator(context.Background(), k8sClients)
+		go runForkliftScheduler(context.Background(), k8sClients)
+	}
+
 	router := mux.NewRouter()
+	router.HandleFunc("/readyz", ReadinessHandler(k8sClients)).Methods("GET")
 	api := router.PathPrefix("/api/v1").Subrouter()
 
 	// API Handlers
 	api.HandleFunc("/capabilities", GetCapabilitiesHandler(k8sClients)).Methods("GET")
 	api.HandleFunc("/support-bundle", SupportBundleHandler(k8sClients)).Methods("GET")
+	api.HandleFunc("/status/stream", StatusStreamHandler(k8sClients)).Methods("GET")
 	api.HandleFunc("/vcenter/inventory/{namespace}/{name}", HandleGetInventory(k8sClients)).Methods("GET")
+	api.HandleFunc("/vcenter/networks/{namespace}/{name}", HandleGetVCenterNetworks(k8sClients)).Methods("GET")
+	api.HandleFunc("/vcenter/networks/{namespace}/{name}/suggestions", HandleGetVCenterNetworkSuggestions(k8sClients)).Methods("GET")
+	api.HandleFunc("/vcenter/storage/{namespace}/{name}/suggestions", HandleGetVCenterStorageSuggestions(k8sClients)).Methods("GET")
+	api.HandleFunc("/vcenter/storage/{namespace}/{name}/capacity", HandleGetVCenterCapacityPlan(k8sClients)).Methods("GET")
 	api.HandleFunc("/vcenter/vm/{namespace}/{name}/power", HandleVMPowerOp(k8sClients)).Methods("POST")
 	api.HandleFunc("/vcenter/vm/{namespace}/{name}/rename", HandleVMRename(k8sClients)).Methods("POST")
 	api.HandleFunc("/vcenter/vm/{namespace}/{name}/mac", HandleUpdateVMMAC(k8sClients)).Methods("POST")
 	api.HandleFunc("/plans", CreatePlanHandler(k8sClients)).Methods("POST")
 	api.HandleFunc("/plans", ListPlansHandler(k8sClients)).Methods("GET")
+	api.HandleFunc("/plans/validate", ValidatePlanHandler(k8sClients)).Methods("POST")
+	api.HandleFunc("/plans/bulk", CreateBulkPlansHandler(k8sClients)).Methods("POST")
 	api.HandleFunc("/plans/{namespace}/{name}", UpdatePlanHandler(k8sClients)).Methods("PUT")
 	api.HandleFunc("/plans/{namespace}/{name}", DeletePlanHandler(k8sClients)).Methods("DELETE")
 	api.HandleFunc("/plans/{namespace}/{name}/run", RunPlanHandler(k8sClients)).Methods("POST")
 	api.HandleFunc("/plans/{namespace}/{name}/logs", HandleGetPlanLogs(k8sClients)).Methods("GET")
+	api.HandleFunc("/plans/{namespace}/{name}/logs/stream", HandleStreamPlanLogs(k8sClients)).Methods("GET")
+	api.HandleFunc("/plans/{namespace}/{name}/timeline", HandleGetPlanTimeline(k8sClients)).Methods("GET")
 	api.HandleFunc("/plans/{namespace}/{name}/yaml", HandleGetPlanYAML(k8sClients)).Methods("GET")
 
+	// Migration waves (VMIC and Forklift plans)
+	api.HandleFunc("/waves", ListMigrationWaveRunsHandler(k8sClients)).Methods("GET")
+	api.HandleFunc("/waves", CreateMigrationWaveRunHandler(k8sClients)).Methods("POST")
+	api.HandleFunc("/waves/{name}", GetMigrationWaveRunHandler(k8sClients)).Methods("GET")
+	api.HandleFunc("/waves/{name}", DeleteMigrationWaveRunHandler(k8sClients)).Methods("DELETE")
+	api.HandleFunc("/waves/{name}/start", StartMigrationWaveRunHandler(k8sClients)).Methods("POST")
+	api.HandleFunc("/waves/{name}/cancel", CancelMigrationWaveRunHandler(k8sClients)).Methods("POST")
+	api.HandleFunc("/waves/{name}/release", ReleaseMigrationWaveRunHandler(k8sClients)).Methods("POST")
+
 	// Harvester Resource Handlers
 	api.HandleFunc("/harvester/vmwaresources", ListVmwareSourcesHandler(k8sClients)).Methods("GET")
 	api.HandleFunc("/harvester/vmwaresources", CreateVmwareSourceHandler(k8sClients)).Methods("POST")
@@ -82,13 +107,27 @@ func main() {
 	api.HandleFunc("/forklift/providers/{namespace}/{name}", DeleteForkliftProviderHandler(k8sClients)).Methods("DELETE")
 	api.HandleFunc("/forklift/providers/{namespace}/{name}/yaml", HandleGetSourceYAML(k8sClients, forkliftProviderGVR)).Methods("GET")
 	api.HandleFunc("/forklift/inventory/{namespace}/{name}", HandleGetForkliftInventory(k8sClients)).Methods("GET")
+	api.HandleFunc("/forklift/networks/{namespace}/{name}", HandleGetForkliftNetworks(k8sClients)).Methods("GET")
+	api.HandleFunc("/forklift/networks/{namespace}/{name}/suggestions", HandleGetForkliftNetworkSuggestions(k8sClients)).Methods("GET")
+	api.HandleFunc("/forklift/storage/{namespace}/{name}/suggestions", HandleGetForkliftStorageSuggestions(k8sClients)).Methods("GET")
+	api.HandleFunc("/forklift/storage/{namespace}/{name}/capacity", HandleGetForkliftCapacityPlan(k8sClients)).Methods("GET")
 	api.HandleFunc("/forklift/inventory/ova/{namespace}/{name}/{resource}", HandleGetForkliftOvaInventory(k8sClients)).Methods("GET")
 	api.HandleFunc("/forklift/plans", ListForkliftPlansHandler(k8sClients)).Methods("GET")
 	api.HandleFunc("/forklift/plans", CreateForkliftPlanHandler(k8sClients)).Methods("POST")
+	api.HandleFunc("/forklift/plans/validate", ValidateForkliftPlanHandler(k8sClients)).Methods("POST")
+	api.HandleFunc("/forklift/plans/{namespace}/{name}", UpdateForkliftPlanHandler(k8sClients)).Methods("PUT")
 	api.HandleFunc("/forklift/plans/{namespace}/{name}", DeleteForkliftPlanHandler(k8sClients)).Methods("DELETE")
 	api.HandleFunc("/forklift/plans/{namespace}/{name}/logs", HandleGetForkliftLogs(k8sClients)).Methods("GET")
+	api.HandleFunc("/forklift/plans/{namespace}/{name}/logs/stream", HandleStreamForkliftLogs(k8sClients)).Methods("GET")
+	api.HandleFunc("/forklift/plans/{namespace}/{name}/timeline", HandleGetForkliftTimeline(k8sClients)).Methods("GET")
 	api.HandleFunc("/forklift/plans/{namespace}/{name}/yaml", HandleGetForkliftPlanYAML(k8sClients)).Methods("GET")
 	api.HandleFunc("/forklift/plans/{namespace}/{name}/run", CreateForkliftMigrationHandler(k8sClients)).Methods("POST")
+	api.HandleFunc("/forklift/plans/{namespace}/{name}/schedule", GetForkliftScheduleHandler(k8sClients)).Methods("GET")
+	api.HandleFunc("/forklift/plans/{namespace}/{name}/schedule", SetForkliftScheduleHandler(k8sClients)).Methods("PUT")
+	api.HandleFunc("/forklift/plans/{namespace}/{name}/schedule", DeleteForkliftScheduleHandler(k8sClients)).Methods("DELETE")
+	api.HandleFunc("/forklift/plans/{namespace}/{name}/cutover", GetForkliftCutoverHandler(k8sClients)).Methods("GET")
+	api.HandleFunc("/forklift/plans/{namespace}/{name}/cutover", SetForkliftCutoverHandler(k8sClients)).Methods("PUT")
+	api.HandleFunc("/forklift/plans/{namespace}/{name}/cutover", ClearForkliftCutoverHandler(k8sClients)).Methods("DELETE")
 	api.HandleFunc("/forklift/plans/{namespace}/{name}/migration", GetForkliftMigrationStatus(k8sClients)).Methods("GET")
 	api.HandleFunc("/forklift/plans/{namespace}/{name}/migration", DeleteForkliftMigrationHandler(k8sClients)).Methods("DELETE")
 	api.HandleFunc("/forklift/networkmaps/{namespace}/{name}", HandleGetResource(k8sClients, forkliftNetworkMapGVR)).Methods("GET")
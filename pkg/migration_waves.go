@@ -0,0 +1,704 @@
+// pkg/migration_waves.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+const (
+	// waveRunLabel marks the ConfigMaps that hold wave runs.
+	waveRunLabel        = "vm-import-ui.harvesterhci.io/migration-wave-run"
+	waveRunDataKey      = "run.json"
+	waveRunConfigPrefix = "vm-import-ui-waves-"
+
+	waveReconcileInterval = 15 * time.Second
+	// waveStartTimeout is how long a plan saved as starting may stay
+	// unstarted, e.g. after the replica that claimed it died, before it is
+	// pending again.
+	waveStartTimeout    = time.Minute
+	waveStartingMessage = "Starting"
+)
+
+// Engines a wave plan can belong to.
+const (
+	waveEngineVMIC     = "vmic"
+	waveEngineForklift = "forklift"
+)
+
+// Phases of wave runs, waves and the plans in them.
+const (
+	wavePhasePending   = "Pending"
+	wavePhaseRunning   = "Running"
+	wavePhaseSucceeded = "Succeeded"
+	wavePhaseFailed    = "Failed"
+	wavePhaseCanceled  = "Canceled"
+	wavePhaseSkipped   = "Skipped"
+)
+
+// What a run does once an import of the current wave fails: stop starts no
+// further imports and fails the run once the running ones finish; continue
+// carries on with the rest of the wave and the waves after it.
+const (
+	waveFailurePolicyStop     = "stop"
+	waveFailurePolicyContinue = "continue"
+)
+
+// vmicHoldSchedule is the schedule that keeps a VirtualMachineImport from
+// starting until its wave clears it.
+var vmicHoldSchedule = metav1.NewTime(time.Date(2999, time.January, 1, 0, 0, 0, 0, time.UTC))
+
+// waveMu serializes reconcile passes with the API calls that change runs in
+// this replica. Across replicas, a plan is saved as started, with the run's
+// ConfigMap resourceVersion, before it is started (see reconcileWaveRun).
+var waveMu sync.Mutex
+
+// WavePlan is one VMIC or Forklift plan of a wave and how far its import got.
+type WavePlan struct {
+	Engine      string       `json:"engine"`
+	Namespace   string       `json:"namespace"`
+	Name        string       `json:"name"`
+	Phase       string       `json:"phase,omitempty"`
+	Message     string       `json:"message,omitempty"`
+	StartedAt   *metav1.Time `json:"startedAt,omitempty"`
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
+}
+
+func (p WavePlan) String() string {
+	return fmt.Sprintf("%s plan %s/%s", p.Engine, p.Namespace, p.Name)
+}
+
+// MigrationWave is a group of plans that run together; the next wave only
+// starts once all of them have finished.
+type MigrationWave struct {
+	Name  string     `json:"name"`
+	Phase string     `json:"phase,omitempty"`
+	Plans []WavePlan `json:"plans"`
+}
+
+// MigrationWaveRun runs its waves in order, with at most MaxConcurrent
+// imports of the current wave in flight. It is persisted in a ConfigMap, so
+// a restarted backend picks up where it left off.
+type MigrationWaveRun struct {
+	Name          string          `json:"name"`
+	MaxConcurrent int             `json:"maxConcurrent"`
+	FailurePolicy string          `json:"failurePolicy"`
+	Waves         []MigrationWave `json:"waves"`
+	Phase         string          `json:"phase"`
+	CurrentWave   int             `json:"currentWave"`
+	Message       string          `json:"message,omitempty"`
+	CreatedAt     *metav1.Time    `json:"createdAt,omitempty"`
+	StartedAt     *metav1.Time    `json:"startedAt,omitempty"`
+	CompletedAt   *metav1.Time    `json:"completedAt,omitempty"`
+}
+
+// CreateMigrationWaveRunPayload is the JSON payload to create a wave run.
+// Start begins it immediately instead of leaving it Pending.
+type CreateMigrationWaveRunPayload struct {
+	Name          string          `json:"name"`
+	MaxConcurrent int             `json:"maxConcurrent,omitempty"`
+	FailurePolicy string          `json:"failurePolicy,omitempty"`
+	Waves         []MigrationWave `json:"waves"`
+	Start         bool            `json:"start,omitempty"`
+}
+
+// active reports whether the run may still start imports.
+func (run *MigrationWaveRun) active() bool {
+	return run.Phase == wavePhasePending || run.Phase == wavePhaseRunning
+}
+
+// appNamespace is the namespace the UI runs in: $POD_NAMESPACE, else the
+// service account's namespace, else default.
+func appNamespace() string {
+	if ns := os.Getenv("POD_NAMESPACE"); ns != "" {
+		return ns
+	}
+	if data, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace"); err == nil {
+		if ns := strings.TrimSpace(string(data)); ns != "" {
+			return ns
+		}
+	}
+	return "default"
+}
+
+// waveStore keeps each wave run as JSON in its own ConfigMap.
+type waveStore struct {
+	clients   *K8sClients
+	namespace string
+}
+
+func newWaveStore(clients *K8sClients) waveStore {
+	return waveStore{clients: clients, namespace: appNamespace()}
+}
+
+func (s waveStore) configMaps() corev1client.ConfigMapInterface {
+	return s.clients.Clientset.CoreV1().ConfigMaps(s.namespace)
+}
+
+func decodeWaveRun(cm *corev1.ConfigMap) (*MigrationWaveRun, error) {
+	var run MigrationWaveRun
+	if err := json.Unmarshal([]byte(cm.Data[waveRunDataKey]), &run); err != nil {
+		return nil, fmt.Errorf("wave run ConfigMap %s is corrupt: %w", cm.Name, err)
+	}
+	return &run, nil
+}
+
+func (s waveStore) list(ctx context.Context) ([]*MigrationWaveRun, []*corev1.ConfigMap, error) {
+	list, err := s.configMaps().List(ctx, metav1.ListOptions{LabelSelector: waveRunLabel + "=true"})
+	if err != nil {
+		return nil, nil, err
+	}
+	var runs []*MigrationWaveRun
+	var cms []*corev1.ConfigMap
+	for i := range list.Items {
+		run, err := decodeWaveRun(&list.Items[i])
+		if err != nil {
+			log.Warn(err)
+			continue
+		}
+		runs = append(runs, run)
+		cms = append(cms, &list.Items[i])
+	}
+	return runs, cms, nil
+}
+
+func (s waveStore) get(ctx context.Context, name string) (*MigrationWaveRun, *corev1.ConfigMap, error) {
+	cm, err := s.configMaps().Get(ctx, waveRunConfigPrefix+name, metav1.GetOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+	run, err := decodeWaveRun(cm)
+	return run, cm, err
+}
+
+func (s waveStore) create(ctx context.Context, run *MigrationWaveRun) (*corev1.ConfigMap, error) {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return nil, err
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      waveRunConfigPrefix + run.Name,
+			Namespace: s.namespace,
+			Labels:    map[string]string{waveRunLabel: "true", "app.kubernetes.io/managed-by": "vm-import-ui"},
+		},
+		Data: map[string]string{waveRunDataKey: string(data)},
+	}
+	return s.configMaps().Create(ctx, cm, metav1.CreateOptions{})
+}
+
+// update saves run into cm and returns the saved ConfigMap; a conflict means
+// another writer got there first.
+func (s waveStore) update(ctx context.Context, cm *corev1.ConfigMap, run *MigrationWaveRun) (*corev1.ConfigMap, error) {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return nil, err
+	}
+	cm = cm.DeepCopy()
+	cm.Data = map[string]string{waveRunDataKey: string(data)}
+	return s.configMaps().Update(ctx, cm, metav1.UpdateOptions{})
+}
+
+// claimer returns a function that saves run into cm, and into the ConfigMap
+// each save returns after that, for reconcileWaveRun to claim plans with.
+func (s waveStore) claimer(ctx context.Context, cm **corev1.ConfigMap, run *MigrationWaveRun) func() error {
+	return func() error {
+		saved, err := s.update(ctx, *cm, run)
+		if err != nil {
+			return err
+		}
+		*cm = saved
+		return nil
+	}
+}
+
+func (s waveStore) delete(ctx context.Context, name string) error {
+	return s.configMaps().Delete(ctx, waveRunConfigPrefix+name, metav1.DeleteOptions{})
+}
+
+// WaveRunStateError reports a change a wave run's phase does not allow.
+type WaveRunStateError struct {
+	Run, Phase, Action string
+}
+
+func (e *WaveRunStateError) Error() string {
+	return fmt.Sprintf("wave run %s is %s and cannot be %s", e.Run, e.Phase, e.Action)
+}
+
+// updateWaveRun applies change to a stored run and saves it, holding waveMu
+// so the orchestrator does not overwrite the change. change may save the run
+// early with claim.
+func updateWaveRun(ctx context.Context, store waveStore, name string, change func(run *MigrationWaveRun, claim func() error) error) (*MigrationWaveRun, error) {
+	waveMu.Lock()
+	defer waveMu.Unlock()
+
+	run, cm, err := store.get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := change(run, store.claimer(ctx, &cm, run)); err != nil {
+		return nil, err
+	}
+	_, err = store.update(ctx, cm, run)
+	return run, err
+}
+
+// startWaveRun starts a Pending run and takes its first reconcile step,
+// claiming plans with claim as reconcileWaveRun does.
+func startWaveRun(ctx context.Context, run *MigrationWaveRun, driver wavePlanDriver, now time.Time, claim func() error) error {
+	if run.Phase != wavePhasePending {
+		return &WaveRunStateError{Run: run.Name, Phase: run.Phase, Action: "started"}
+	}
+	run.Phase, run.StartedAt = wavePhaseRunning, &metav1.Time{Time: now}
+	reconcileWaveRun(ctx, run, driver, now, claim)
+	return nil
+}
+
+// cancelWaveRun stops a run from starting further imports. Imports already
+// running are left to finish.
+func cancelWaveRun(run *MigrationWaveRun, now time.Time) error {
+	if !run.active() {
+		return &WaveRunStateError{Run: run.Name, Phase: run.Phase, Action: "canceled"}
+	}
+	skipWaveRun(run, run.CurrentWave, "Run was canceled")
+	if run.CurrentWave < len(run.Waves) {
+		run.Waves[run.CurrentWave].Phase = wavePhaseCanceled
+	}
+	run.Phase, run.CompletedAt = wavePhaseCanceled, &metav1.Time{Time: now}
+	run.Message = "Canceled; imports already running were left to finish"
+	return nil
+}
+
+// wavePlanDriver reads and starts the imports behind wave plans.
+type wavePlanDriver interface {
+	// state returns Pending for an import that has not started, Running,
+	// Succeeded or Failed, with a message for failures.
+	state(ctx context.Context, p WavePlan) (string, string, error)
+	start(ctx context.Context, p WavePlan) error
+}
+
+// clusterWaveDriver drives VMIC and Forklift plans on the cluster.
+type clusterWaveDriver struct {
+	clients *K8sClients
+}
+
+func (d clusterWaveDriver) state(ctx context.Context, p WavePlan) (string, string, error) {
+	if p.Engine == waveEngineForklift {
+		return d.forkliftState(ctx, p)
+	}
+	item, err := d.clients.Dynamic.Resource(vmiGVR).Namespace(p.Namespace).Get(ctx, p.Name, metav1.GetOptions{})
+	if err != nil {
+		return "", "", err
+	}
+	status, _, _ := unstructured.NestedString(item.Object, "status", "importStatus")
+	lower := strings.ToLower(status)
+	switch {
+	case lower == "virtualmachinerunning":
+		return wavePhaseSucceeded, "", nil
+	case strings.Contains(lower, "failed") || strings.Contains(lower, "invalid"):
+		return wavePhaseFailed, "VirtualMachineImport is " + status, nil
+	}
+	// VMIC starts an import as soon as it has no schedule.
+	if schedule, found, _ := unstructured.NestedString(item.Object, "spec", "schedule"); found && schedule != "" {
+		return wavePhasePending, "", nil
+	}
+	return wavePhaseRunning, "", nil
+}
+
+func (d clusterWaveDriver) forkliftState(ctx context.Context, p WavePlan) (string, string, error) {
+	plan, err := d.clients.Dynamic.Resource(forkliftPlanGVR).Namespace(p.Namespace).Get(ctx, p.Name, metav1.GetOptions{})
+	if err != nil {
+		return "", "", err
+	}
+	conditions, _, _ := unstructured.NestedSlice(plan.Object, "status", "conditions")
+	set := map[string]string{}
+	for _, c := range conditions {
+		cond, _ := c.(map[string]interface{})
+		if cond["status"] == "True" {
+			t, _ := cond["type"].(string)
+			message, _ := cond["message"].(string)
+			set[t] = message
+		}
+	}
+	if _, ok := set["Succeeded"]; ok {
+		return wavePhaseSucceeded, "", nil
+	}
+	if message, ok := set["Failed"]; ok {
+		return wavePhaseFailed, "Plan failed: " + message, nil
+	}
+	if _, ok := set["Canceled"]; ok {
+		return wavePhaseFailed, "Plan was canceled", nil
+	}
+	if _, ok := set["Executing"]; ok {
+		return wavePhaseRunning, "", nil
+	}
+	// A Migration Forklift has not picked up yet still counts as started.
+	_, err = d.clients.Dynamic.Resource(forkliftMigrationGVR).Namespace(p.Namespace).Get(ctx, p.Name+"-migration", metav1.GetOptions{})
+	switch {
+	case err == nil:
+		return wavePhaseRunning, "", nil
+	case apierrors.IsNotFound(err):
+		return wavePhasePending, "", nil
+	default:
+		return "", "", err
+	}
+}
+
+func (d clusterWaveDriver) start(ctx context.Context, p WavePlan) error {
+	if p.Engine == waveEngineForklift {
+		_, err := startForkliftMigration(ctx, d.clients, p.Namespace, p.Name, time.Now())
+		if apierrors.IsAlreadyExists(err) {
+			return nil
+		}
+		return err
+	}
+	_, err := startVMICImport(ctx, d.clients, p.Namespace, p.Name)
+	return err
+}
+
+// holdVMICImport gives a VirtualMachineImport that VMIC has not picked up yet
+// a far-off schedule, so it waits for its wave. It reports whether the plan
+// is now held.
+func holdVMICImport(ctx context.Context, clients *K8sClients, namespace, name string) (bool, error) {
+	item, err := clients.Dynamic.Resource(vmiGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+	if schedule, found, _ := unstructured.NestedString(item.Object, "spec", "schedule"); found && schedule != "" {
+		return true, nil
+	}
+	if status, _, _ := unstructured.NestedString(item.Object, "status", "importStatus"); status != "" {
+		return false, nil
+	}
+	if err := unstructured.SetNestedField(item.Object, vmicHoldSchedule.UTC().Format(time.RFC3339), "spec", "schedule"); err != nil {
+		return false, err
+	}
+	_, err = clients.Dynamic.Resource(vmiGVR).Namespace(namespace).Update(ctx, item, metav1.UpdateOptions{})
+	return err == nil, err
+}
+
+// vmicHeld reports whether a VirtualMachineImport carries the wave hold
+// (vmicHoldSchedule) rather than no schedule or one of its own.
+func vmicHeld(item *unstructured.Unstructured) bool {
+	schedule, _, _ := unstructured.NestedString(item.Object, "spec", "schedule")
+	t, err := time.Parse(time.RFC3339, schedule)
+	return err == nil && t.Equal(vmicHoldSchedule.Time)
+}
+
+// releaseVMICHold removes the wave hold from a VirtualMachineImport, so VMIC
+// starts it. Imports without the hold, or that are gone, are left alone.
+func releaseVMICHold(ctx context.Context, clients *K8sClients, namespace, name string) error {
+	item, err := clients.Dynamic.Resource(vmiGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !vmicHeld(item) {
+		return nil
+	}
+	unstructured.RemoveNestedField(item.Object, "spec", "schedule")
+	_, err = clients.Dynamic.Resource(vmiGVR).Namespace(namespace).Update(ctx, item, metav1.UpdateOptions{})
+	return err
+}
+
+// releaseWaveHolds releases the holds on the VMIC plans of run that never
+// started, which VMIC then imports at once. A plan another active run has
+// taken over keeps its hold.
+func releaseWaveHolds(ctx context.Context, store waveStore, run *MigrationWaveRun) error {
+	runs, _, err := store.list(ctx)
+	if err != nil {
+		return err
+	}
+	inUse := map[string]bool{}
+	for _, other := range runs {
+		if other.Name == run.Name || !other.active() {
+			continue
+		}
+		for _, wave := range other.Waves {
+			for _, p := range wave.Plans {
+				inUse[p.String()] = true
+			}
+		}
+	}
+
+	var failed []string
+	for _, wave := range run.Waves {
+		for _, p := range wave.Plans {
+			if p.Engine != waveEngineVMIC || inUse[p.String()] || (p.Phase != wavePhasePending && p.Phase != wavePhaseSkipped) {
+				continue
+			}
+			if err := releaseVMICHold(ctx, store.clients, p.Namespace, p.Name); err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %v", p, err))
+			}
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to release held plans: %s", strings.Join(failed, "; "))
+	}
+	log.Debugf("Wave run %s: released the holds of its plans that did not start", run.Name)
+	return nil
+}
+
+// newMigrationWaveRun checks a create payload and turns it into a Pending
+// run. runs are the existing runs: a plan can only be in one active run.
+func newMigrationWaveRun(payload CreateMigrationWaveRunPayload, runs []*MigrationWaveRun, now time.Time) (*MigrationWaveRun, error) {
+	if len(payload.Name) > 63-len(waveRunConfigPrefix) || !rfc1123Label.MatchString(payload.Name) {
+		return nil, fmt.Errorf("wave run name %q must be an RFC-1123 label of at most %d characters", payload.Name, 63-len(waveRunConfigPrefix))
+	}
+	if payload.MaxConcurrent < 0 {
+		return nil, fmt.Errorf("maxConcurrent must be at least 1")
+	}
+	run := &MigrationWaveRun{
+		Name:          payload.Name,
+		MaxConcurrent: payload.MaxConcurrent,
+		FailurePolicy: payload.FailurePolicy,
+		Waves:         payload.Waves,
+		Phase:         wavePhasePending,
+		CreatedAt:     &metav1.Time{Time: now},
+	}
+	if run.MaxConcurrent == 0 {
+		run.MaxConcurrent = 1
+	}
+	switch run.FailurePolicy {
+	case "":
+		run.FailurePolicy = waveFailurePolicyStop
+	case waveFailurePolicyStop, waveFailurePolicyContinue:
+	default:
+		return nil, fmt.Errorf("failurePolicy must be %q or %q", waveFailurePolicyStop, waveFailurePolicyContinue)
+	}
+	if len(run.Waves) == 0 {
+		return nil, fmt.Errorf("a wave run needs at least one wave")
+	}
+
+	inUse := map[string]string{}
+	for _, other := range runs {
+		if !other.active() {
+			continue
+		}
+		for _, wave := range other.Waves {
+			for _, p := range wave.Plans {
+				inUse[p.String()] = other.Name
+			}
+		}
+	}
+	seen := map[string]bool{}
+	for i := range run.Waves {
+		wave := &run.Waves[i]
+		if wave.Name == "" {
+			wave.Name = fmt.Sprintf("wave-%d", i+1)
+		}
+		if len(wave.Plans) == 0 {
+			return nil, fmt.Errorf("wave %s has no plans", wave.Name)
+		}
+		wave.Phase = wavePhasePending
+		for j := range wave.Plans {
+			p := &wave.Plans[j]
+			if p.Engine != waveEngineVMIC && p.Engine != waveEngineForklift {
+				return nil, fmt.Errorf("plan %s/%s: engine must be %q or %q", p.Namespace, p.Name, waveEngineVMIC, waveEngineForklift)
+			}
+			if p.Namespace == "" || p.Name == "" {
+				return nil, fmt.Errorf("wave %s: every plan needs a namespace and a name", wave.Name)
+			}
+			if seen[p.String()] {
+				return nil, fmt.Errorf("%s is listed more than once", p)
+			}
+			if other, ok := inUse[p.String()]; ok {
+				return nil, fmt.Errorf("%s is already part of wave run %s", p, other)
+			}
+			seen[p.String()] = true
+			*p = WavePlan{Engine: p.Engine, Namespace: p.Namespace, Name: p.Name, Phase: wavePhasePending}
+		}
+	}
+	return run, nil
+}
+
+// reconcileWaveRun moves a Running run forward: it refreshes the imports in
+// flight, starts pending ones of the current wave up to MaxConcurrent, and
+// advances to the next wave once the current one is done. Each plan is saved
+// as starting with claim (if not nil) before it is started; a failed claim,
+// most often a conflict with another replica's pass, ends the pass.
+func reconcileWaveRun(ctx context.Context, run *MigrationWaveRun, driver wavePlanDriver, now time.Time, claim func() error) {
+	if run.Phase != wavePhaseRunning {
+		return
+	}
+	stamp := func() *metav1.Time { return &metav1.Time{Time: now} }
+	finish := func(p *WavePlan, phase, message string) {
+		p.Phase, p.Message, p.CompletedAt = phase, message, stamp()
+		log.Infof("Wave run %s: %s %s %s", run.Name, p, strings.ToLower(phase), message)
+	}
+
+	for run.CurrentWave < len(run.Waves) {
+		wave := &run.Waves[run.CurrentWave]
+		wave.Phase = wavePhaseRunning
+
+		// Refresh running imports and adopt pending ones that started or
+		// finished outside the run, e.g. before a restart lost the Running
+		// phase, so they count against MaxConcurrent.
+		running, failed, pending := 0, 0, 0
+		for i := range wave.Plans {
+			p := &wave.Plans[i]
+			if p.Phase == wavePhaseRunning || p.Phase == wavePhasePending {
+				phase, message, err := driver.state(ctx, *p)
+				switch {
+				case err != nil:
+					log.Warnf("Wave run %s: could not read %s: %v", run.Name, p, err)
+					p.Message = err.Error()
+				case phase == wavePhaseSucceeded || phase == wavePhaseFailed:
+					finish(p, phase, message)
+				case phase == wavePhaseRunning && p.Phase == wavePhasePending:
+					p.Phase, p.Message, p.StartedAt = wavePhaseRunning, "", stamp()
+				case phase == wavePhasePending && p.Message == waveStartingMessage && p.StartedAt != nil && now.Sub(p.StartedAt.Time) > waveStartTimeout:
+					log.Warnf("Wave run %s: %s was claimed but never started; retrying it", run.Name, p)
+					p.Phase, p.Message, p.StartedAt = wavePhasePending, "", nil
+				}
+			}
+			switch p.Phase {
+			case wavePhaseRunning:
+				running++
+			case wavePhaseFailed:
+				failed++
+			case wavePhasePending:
+				pending++
+			}
+		}
+
+		stop := func() bool { return failed > 0 && run.FailurePolicy == waveFailurePolicyStop }
+		for i := range wave.Plans {
+			if running >= run.MaxConcurrent || stop() {
+				break
+			}
+			p := &wave.Plans[i]
+			if p.Phase != wavePhasePending {
+				continue
+			}
+			p.Phase, p.Message, p.StartedAt = wavePhaseRunning, waveStartingMessage, stamp()
+			if claim != nil {
+				if err := claim(); err != nil {
+					log.Warnf("Wave run %s: could not claim %s, leaving it to the next pass: %v", run.Name, p, err)
+					p.Phase, p.Message, p.StartedAt = wavePhasePending, "", nil
+					return
+				}
+			}
+			err := driver.start(ctx, *p)
+			var windowErr *MaintenanceWindowError
+			if errors.As(err, &windowErr) {
+				// Waits for its maintenance window.
+				p.Phase, p.Message, p.StartedAt = wavePhasePending, err.Error(), nil
+				continue
+			}
+			pending--
+			if err != nil {
+				finish(p, wavePhaseFailed, "Could not start: "+err.Error())
+				failed++
+				continue
+			}
+			log.Infof("Wave run %s: started %s", run.Name, p)
+			p.Message = ""
+			running++
+		}
+
+		if running > 0 || (pending > 0 && !stop()) {
+			return
+		}
+		if stop() {
+			wave.Phase = wavePhaseFailed
+			skipWaveRun(run, run.CurrentWave, "Skipped after an earlier import failed")
+			run.Phase, run.CompletedAt = wavePhaseFailed, stamp()
+			run.Message = fmt.Sprintf("Stopped: %d import(s) of wave %s failed", failed, wave.Name)
+			return
+		}
+		wave.Phase = wavePhaseSucceeded
+		if failed > 0 {
+			wave.Phase = wavePhaseFailed
+		}
+		run.CurrentWave++
+	}
+
+	run.Phase, run.CompletedAt = wavePhaseSucceeded, stamp()
+	failedWaves := 0
+	for _, wave := range run.Waves {
+		if wave.Phase == wavePhaseFailed {
+			failedWaves++
+		}
+	}
+	if failedWaves > 0 {
+		run.Phase = wavePhaseFailed
+		run.Message = fmt.Sprintf("%d wave(s) had failed imports", failedWaves)
+	}
+}
+
+// skipWaveRun marks every pending plan from wave from onwards as skipped, and
+// the later waves with them.
+func skipWaveRun(run *MigrationWaveRun, from int, message string) {
+	for i := from; i < len(run.Waves); i++ {
+		wave := &run.Waves[i]
+		for j := range wave.Plans {
+			if p := &wave.Plans[j]; p.Phase == wavePhasePending {
+				p.Phase, p.Message = wavePhaseSkipped, message
+			}
+		}
+		if i > from {
+			wave.Phase = wavePhaseSkipped
+		}
+	}
+}
+
+// reconcileWaveRuns runs one pass over every active run and saves the ones
+// that changed.
+func reconcileWaveRuns(ctx context.Context, store waveStore, driver wavePlanDriver) {
+	waveMu.Lock()
+	defer waveMu.Unlock()
+
+	runs, cms, err := store.list(ctx)
+	if err != nil {
+		log.Warnf("Could not list migration wave runs: %v", err)
+		return
+	}
+	for i, run := range runs {
+		if run.Phase != wavePhaseRunning {
+			continue
+		}
+		before, _ := json.Marshal(run)
+		cm := cms[i]
+		reconcileWaveRun(ctx, run, driver, time.Now(), store.claimer(ctx, &cm, run))
+		if after, _ := json.Marshal(run); string(after) == string(before) {
+			continue
+		}
+		if _, err := store.update(ctx, cm, run); err != nil {
+			log.Warnf("Could not save migration wave run %s: %v", run.Name, err)
+		}
+	}
+}
+
+// runWaveOrchestrator reconciles the wave runs until ctx is done.
+func runWaveOrchestrator(ctx context.Context, clients *K8sClients) {
+	store := newWaveStore(clients)
+	driver := clusterWaveDriver{clients: clients}
+	log.Infof("Migration wave orchestrator keeps its state in ConfigMaps in namespace %s", store.namespace)
+
+	ticker := time.NewTicker(waveReconcileInterval)
+	defer ticker.Stop()
+	for {
+		reconcileWaveRuns(ctx, store, driver)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
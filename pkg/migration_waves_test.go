@@ -0,0 +1,445 @@
+// pkg/migration_waves_test.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// fakeWaveDriver keeps import states by plan name; start moves a Pending
+// import to Running unless startErr names it.
+type fakeWaveDriver struct {
+	states   map[string]string
+	startErr map[string]error
+	started  []string
+}
+
+func (d *fakeWaveDriver) state(_ context.Context, p WavePlan) (string, string, error) {
+	if s, ok := d.states[p.Name]; ok {
+		return s, "", nil
+	}
+	return wavePhasePending, "", nil
+}
+
+func (d *fakeWaveDriver) start(_ context.Context, p WavePlan) error {
+	if err := d.startErr[p.Name]; err != nil {
+		return err
+	}
+	d.started = append(d.started, p.Name)
+	d.states[p.Name] = wavePhaseRunning
+	return nil
+}
+
+// newTestWaveRun builds a started run; each wave is a comma-separated list
+// of plan names.
+func newTestWaveRun(t *testing.T, maxConcurrent int, policy string, waves ...string) *MigrationWaveRun {
+	t.Helper()
+	payload := CreateMigrationWaveRunPayload{Name: "app", MaxConcurrent: maxConcurrent, FailurePolicy: policy}
+	for _, w := range waves {
+		var wave MigrationWave
+		for _, name := range strings.Split(w, ",") {
+			wave.Plans = append(wave.Plans, WavePlan{Engine: waveEngineVMIC, Namespace: "vms", Name: name})
+		}
+		payload.Waves = append(payload.Waves, wave)
+	}
+	run, err := newMigrationWaveRun(payload, nil, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	run.Phase = wavePhaseRunning
+	return run
+}
+
+// planPhases summarises a run as "wave:phase[plan=phase ...]" per wave.
+func planPhases(run *MigrationWaveRun) string {
+	var waves []string
+	for _, wave := range run.Waves {
+		var plans []string
+		for _, p := range wave.Plans {
+			plans = append(plans, p.Name+"="+p.Phase)
+		}
+		waves = append(waves, wave.Name+":"+wave.Phase+"["+strings.Join(plans, " ")+"]")
+	}
+	return strings.Join(waves, " ")
+}
+
+func TestReconcileWaveRun(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	t.Run("concurrency and ordering", func(t *testing.T) {
+		run := newTestWaveRun(t, 2, "", "a,b,c", "d")
+		driver := &fakeWaveDriver{states: map[string]string{}}
+
+		reconcileWaveRun(ctx, run, driver, now, nil)
+		if strings.Join(driver.started, ",") != "a,b" {
+			t.Fatalf("expected a and b started, got %v", driver.started)
+		}
+		driver.states["a"] = wavePhaseSucceeded
+		reconcileWaveRun(ctx, run, driver, now, nil)
+		if strings.Join(driver.started, ",") != "a,b,c" {
+			t.Fatalf("expected c to take a's slot, got %v", driver.started)
+		}
+		driver.states["b"], driver.states["c"] = wavePhaseSucceeded, wavePhaseSucceeded
+		reconcileWaveRun(ctx, run, driver, now, nil)
+		if run.CurrentWave != 1 || strings.Join(driver.started, ",") != "a,b,c,d" {
+			t.Fatalf("expected the second wave to start, got %s", planPhases(run))
+		}
+		driver.states["d"] = wavePhaseSucceeded
+		reconcileWaveRun(ctx, run, driver, now, nil)
+		if run.Phase != wavePhaseSucceeded || run.CompletedAt == nil {
+			t.Errorf("expected the run to succeed, got %s: %s", run.Phase, planPhases(run))
+		}
+	})
+
+	t.Run("stop on failure", func(t *testing.T) {
+		run := newTestWaveRun(t, 1, waveFailurePolicyStop, "a,b,c", "d")
+		driver := &fakeWaveDriver{states: map[string]string{}}
+
+		reconcileWaveRun(ctx, run, driver, now, nil)
+		driver.states["a"] = wavePhaseFailed
+		reconcileWaveRun(ctx, run, driver, now, nil)
+		want := "wave-1:Failed[a=Failed b=Skipped c=Skipped] wave-2:Skipped[d=Skipped]"
+		if run.Phase != wavePhaseFailed || planPhases(run) != want {
+			t.Errorf("expected %s, got %s: %s", want, run.Phase, planPhases(run))
+		}
+		if len(driver.started) != 1 {
+			t.Errorf("expected no imports after the failure, got %v", driver.started)
+		}
+	})
+
+	t.Run("continue on failure", func(t *testing.T) {
+		run := newTestWaveRun(t, 2, waveFailurePolicyContinue, "a,b", "c")
+		driver := &fakeWaveDriver{states: map[string]string{}, startErr: map[string]error{"b": errors.New("plan not found")}}
+
+		reconcileWaveRun(ctx, run, driver, now, nil)
+		if p := run.Waves[0].Plans[1]; p.Phase != wavePhaseFailed || !strings.Contains(p.Message, "Could not start: plan not found") {
+			t.Errorf("expected b to fail to start, got %+v", p)
+		}
+		driver.states["a"] = wavePhaseSucceeded
+		reconcileWaveRun(ctx, run, driver, now, nil)
+		driver.states["c"] = wavePhaseSucceeded
+		reconcileWaveRun(ctx, run, driver, now, nil)
+		if run.Phase != wavePhaseFailed || run.Message != "1 wave(s) had failed imports" || run.Waves[1].Phase != wavePhaseSucceeded {
+			t.Errorf("expected every wave to run and the run to report the failure, got %s %q: %s", run.Phase, run.Message, planPhases(run))
+		}
+	})
+
+	t.Run("adopts imports started elsewhere", func(t *testing.T) {
+		run := newTestWaveRun(t, 1, "", "a,b,c")
+		driver := &fakeWaveDriver{states: map[string]string{"a": wavePhaseSucceeded, "b": wavePhaseRunning}}
+
+		reconcileWaveRun(ctx, run, driver, now, nil)
+		if len(driver.started) != 0 || planPhases(run) != "wave-1:Running[a=Succeeded b=Running c=Pending]" {
+			t.Errorf("expected a and b adopted without starting anything, got %v: %s", driver.started, planPhases(run))
+		}
+	})
+
+	t.Run("claims plans before starting them", func(t *testing.T) {
+		run := newTestWaveRun(t, 2, "", "a,b,c")
+		driver := &fakeWaveDriver{states: map[string]string{}}
+		var claimed []string
+		claim := func() error {
+			if len(claimed) == 1 {
+				return errors.New("conflict")
+			}
+			if p := run.Waves[0].Plans[len(claimed)]; p.Phase != wavePhaseRunning || len(driver.started) != len(claimed) {
+				t.Errorf("expected %s saved as started before starting it, got %+v (started %v)", p.Name, p, driver.started)
+			}
+			claimed = append(claimed, run.Waves[0].Plans[len(claimed)].Name)
+			return nil
+		}
+
+		reconcileWaveRun(ctx, run, driver, now, claim)
+		if strings.Join(driver.started, ",") != "a" || planPhases(run) != "wave-1:Running[a=Running b=Pending c=Pending]" {
+			t.Errorf("expected the pass to end at the failed claim, got %v: %s", driver.started, planPhases(run))
+		}
+
+		// A plan claimed by a replica that died before starting it is retried.
+		run.Waves[0].Plans[1] = WavePlan{Name: "b", Phase: wavePhaseRunning, Message: waveStartingMessage, StartedAt: &metav1.Time{Time: now.Add(-2 * waveStartTimeout)}}
+		reconcileWaveRun(ctx, run, driver, now, nil)
+		if strings.Join(driver.started, ",") != "a,b" {
+			t.Errorf("expected b started again, got %v", driver.started)
+		}
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		run := newTestWaveRun(t, 1, "", "a,b", "c")
+		driver := &fakeWaveDriver{states: map[string]string{}}
+		reconcileWaveRun(ctx, run, driver, now, nil)
+
+		if err := cancelWaveRun(run, now); err != nil {
+			t.Fatal(err)
+		}
+		if run.Phase != wavePhaseCanceled || planPhases(run) != "wave-1:Canceled[a=Running b=Skipped] wave-2:Skipped[c=Skipped]" {
+			t.Errorf("expected pending plans skipped, got %s: %s", run.Phase, planPhases(run))
+		}
+		var stateErr *WaveRunStateError
+		if err := cancelWaveRun(run, now); !errors.As(err, &stateErr) {
+			t.Errorf("expected a second cancel to be refused, got %v", err)
+		}
+		reconcileWaveRun(ctx, run, driver, now, nil)
+		if len(driver.started) != 1 {
+			t.Errorf("expected a canceled run to start nothing, got %v", driver.started)
+		}
+	})
+}
+
+func TestNewMigrationWaveRun(t *testing.T) {
+	plan := func(engine, name string) WavePlan {
+		return WavePlan{Engine: engine, Namespace: "vms", Name: name}
+	}
+	active := &MigrationWaveRun{Name: "other", Phase: wavePhaseRunning, Waves: []MigrationWave{{Plans: []WavePlan{plan(waveEngineForklift, "busy")}}}}
+	done := &MigrationWaveRun{Name: "old", Phase: wavePhaseSucceeded, Waves: []MigrationWave{{Plans: []WavePlan{plan(waveEngineVMIC, "web")}}}}
+
+	run, err := newMigrationWaveRun(CreateMigrationWaveRunPayload{
+		Name:  "app",
+		Waves: []MigrationWave{{Plans: []WavePlan{plan(waveEngineVMIC, "web"), plan(waveEngineForklift, "db")}}},
+	}, []*MigrationWaveRun{active, done}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.MaxConcurrent != 1 || run.FailurePolicy != waveFailurePolicyStop || run.Phase != wavePhasePending || run.Waves[0].Name != "wave-1" || run.Waves[0].Plans[1].Phase != wavePhasePending {
+		t.Errorf("expected defaults to be filled in, got %+v", run)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		payload CreateMigrationWaveRunPayload
+		want    string
+	}{
+		{"bad name", CreateMigrationWaveRunPayload{Name: "App_1", Waves: run.Waves}, "RFC-1123"},
+		{"bad policy", CreateMigrationWaveRunPayload{Name: "app", FailurePolicy: "retry", Waves: run.Waves}, "failurePolicy"},
+		{"no waves", CreateMigrationWaveRunPayload{Name: "app"}, "at least one wave"},
+		{"empty wave", CreateMigrationWaveRunPayload{Name: "app", Waves: []MigrationWave{{Name: "first"}}}, "wave first has no plans"},
+		{"bad engine", CreateMigrationWaveRunPayload{Name: "app", Waves: []MigrationWave{{Plans: []WavePlan{plan("mtv", "web")}}}}, "engine"},
+		{"duplicate", CreateMigrationWaveRunPayload{Name: "app", Waves: []MigrationWave{{Plans: []WavePlan{plan(waveEngineVMIC, "web")}}, {Plans: []WavePlan{plan(waveEngineVMIC, "web")}}}}, "more than once"},
+		{"in another run", CreateMigrationWaveRunPayload{Name: "app", Waves: []MigrationWave{{Plans: []WavePlan{plan(waveEngineForklift, "busy")}}}}, "already part of wave run other"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := newMigrationWaveRun(tc.payload, []*MigrationWaveRun{active, done}, time.Now()); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected an error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+// newTestVMIC returns a VirtualMachineImport in vms with the given import
+// status ("" if the controller has not picked it up).
+func newTestVMIC(name, status string) runtime.Object {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "migration.harvesterhci.io/v1beta1",
+		"kind":       "VirtualMachineImport",
+		"metadata":   map[string]interface{}{"name": name, "namespace": "vms"},
+		"spec":       map[string]interface{}{"virtualMachineName": name},
+		"status":     map[string]interface{}{"importStatus": status},
+	}}
+}
+
+// vmicSchedule returns the spec.schedule of a VirtualMachineImport in vms.
+func vmicSchedule(t *testing.T, clients *K8sClients, name string) string {
+	t.Helper()
+	item, err := clients.Dynamic.Resource(vmiGVR).Namespace("vms").Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	schedule, _, _ := unstructured.NestedString(item.Object, "spec", "schedule")
+	return schedule
+}
+
+func TestMigrationWaveRunHandlers(t *testing.T) {
+	t.Setenv("POD_NAMESPACE", "vm-import-ui")
+	forkliftPlan := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "forklift.konveyor.io/v1beta1",
+		"kind":       "Plan",
+		"metadata":   map[string]interface{}{"name": "db", "namespace": "vms"},
+		"status": map[string]interface{}{"conditions": []interface{}{
+			map[string]interface{}{"type": "Ready", "status": "True"},
+		}},
+	}}
+	clients := newTestClientsWithDynamic(nil, newTestVMIC("web", ""), newTestVMIC("cache", "diskImagesReady"), forkliftPlan)
+	ctx := context.Background()
+
+	decode := func(t *testing.T, body []byte) MigrationWaveRun {
+		t.Helper()
+		var run MigrationWaveRun
+		if err := json.Unmarshal(body, &run); err != nil {
+			t.Fatal(err)
+		}
+		return run
+	}
+	payload := CreateMigrationWaveRunPayload{
+		Name:          "app",
+		MaxConcurrent: 1,
+		Waves: []MigrationWave{
+			{Name: "data", Plans: []WavePlan{{Engine: waveEngineForklift, Namespace: "vms", Name: "db"}}},
+			{Name: "frontend", Plans: []WavePlan{{Engine: waveEngineVMIC, Namespace: "vms", Name: "web"}, {Engine: waveEngineVMIC, Namespace: "vms", Name: "cache"}}},
+		},
+	}
+
+	rr := executeRequest(CreateMigrationWaveRunHandler(clients), "POST", "/api/v1/waves", payload, nil)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if run := decode(t, rr.Body.Bytes()); run.Phase != wavePhasePending || !strings.Contains(run.Waves[1].Plans[1].Message, "Already picked up") {
+		t.Errorf("expected a pending run noting cache is already importing, got %+v", run)
+	}
+
+	// web had not been picked up, so it is held until its wave.
+	if schedule := vmicSchedule(t, clients, "web"); !strings.HasPrefix(schedule, "2999-") {
+		t.Errorf("expected web to be held with a far-off schedule, got %q", schedule)
+	}
+	if cm, err := clients.Clientset.CoreV1().ConfigMaps("vm-import-ui").Get(ctx, waveRunConfigPrefix+"app", metav1.GetOptions{}); err != nil || cm.Labels[waveRunLabel] != "true" {
+		t.Fatalf("expected the run persisted in a labelled ConfigMap, got %v (%v)", cm, err)
+	}
+
+	for _, tc := range []struct {
+		payload CreateMigrationWaveRunPayload
+		status  int
+	}{
+		{payload, http.StatusConflict},
+		{CreateMigrationWaveRunPayload{Name: "missing", Waves: []MigrationWave{{Plans: []WavePlan{{Engine: waveEngineVMIC, Namespace: "vms", Name: "nope"}}}}}, http.StatusBadRequest},
+	} {
+		if rr := executeRequest(CreateMigrationWaveRunHandler(clients), "POST", "/api/v1/waves", tc.payload, nil); rr.Code != tc.status {
+			t.Errorf("expected %d creating %s, got %d: %s", tc.status, tc.payload.Name, rr.Code, rr.Body.String())
+		}
+	}
+
+	vars := map[string]string{"name": "app"}
+	rr = executeRequest(StartMigrationWaveRunHandler(clients), "POST", "/api/v1/waves/app/start", nil, vars)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if run := decode(t, rr.Body.Bytes()); run.Phase != wavePhaseRunning || run.Waves[0].Plans[0].Phase != wavePhaseRunning {
+		t.Errorf("expected the first wave's Forklift plan running, got %+v", run)
+	}
+	if _, err := clients.Dynamic.Resource(forkliftMigrationGVR).Namespace("vms").Get(ctx, "db-migration", metav1.GetOptions{}); err != nil {
+		t.Errorf("expected a Migration for db: %v", err)
+	}
+
+	// Forklift reports success: the orchestrator moves on to the VMIC wave.
+	unstructured.SetNestedSlice(forkliftPlan.Object, []interface{}{map[string]interface{}{"type": "Succeeded", "status": "True"}}, "status", "conditions")
+	if _, err := clients.Dynamic.Resource(forkliftPlanGVR).Namespace("vms").Update(ctx, forkliftPlan, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	reconcileWaveRuns(ctx, newWaveStore(clients), clusterWaveDriver{clients: clients})
+
+	rr = executeRequest(GetMigrationWaveRunHandler(clients), "GET", "/api/v1/waves/app", nil, vars)
+	run := decode(t, rr.Body.Bytes())
+	if want := "data:Succeeded[db=Succeeded] frontend:Running[web=Pending cache=Running]"; planPhases(&run) != want {
+		t.Errorf("expected %s, got %s", want, planPhases(&run))
+	}
+
+	if rr := executeRequest(DeleteMigrationWaveRunHandler(clients), "DELETE", "/api/v1/waves/app", nil, vars); rr.Code != http.StatusConflict {
+		t.Errorf("expected a running run to refuse deletion, got %d", rr.Code)
+	}
+	if rr := executeRequest(ReleaseMigrationWaveRunHandler(clients), "POST", "/api/v1/waves/app/release", nil, vars); rr.Code != http.StatusConflict {
+		t.Errorf("expected a running run to refuse releasing its plans, got %d", rr.Code)
+	}
+	if rr := executeRequest(CancelMigrationWaveRunHandler(clients), "POST", "/api/v1/waves/app/cancel", nil, vars); rr.Code != http.StatusOK {
+		t.Errorf("expected 200 canceling, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if schedule := vmicSchedule(t, clients, "web"); !strings.HasPrefix(schedule, "2999-") {
+		t.Errorf("expected canceling to leave web held, got schedule %q", schedule)
+	}
+	if rr := executeRequest(ReleaseMigrationWaveRunHandler(clients), "POST", "/api/v1/waves/app/release", nil, vars); rr.Code != http.StatusOK {
+		t.Errorf("expected 200 releasing, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if schedule := vmicSchedule(t, clients, "web"); schedule != "" {
+		t.Errorf("expected releasing to clear web's hold, got schedule %q", schedule)
+	}
+	if rr := executeRequest(DeleteMigrationWaveRunHandler(clients), "DELETE", "/api/v1/waves/app", nil, vars); rr.Code != http.StatusOK {
+		t.Errorf("expected 200 deleting, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := executeRequest(GetMigrationWaveRunHandler(clients), "GET", "/api/v1/waves/app", nil, vars); rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404 after deletion, got %d", rr.Code)
+	}
+}
+
+func TestMigrationWaveRunHolds(t *testing.T) {
+	t.Setenv("POD_NAMESPACE", "vm-import-ui")
+	payload := func(plans ...string) CreateMigrationWaveRunPayload {
+		wave := MigrationWave{Name: "all"}
+		for _, name := range plans {
+			wave.Plans = append(wave.Plans, WavePlan{Engine: waveEngineVMIC, Namespace: "vms", Name: name})
+		}
+		return CreateMigrationWaveRunPayload{Name: "app", MaxConcurrent: 1, Waves: []MigrationWave{wave}}
+	}
+	failOn := func(verb, resource, name string) k8stesting.ReactionFunc {
+		return func(action k8stesting.Action) (bool, runtime.Object, error) {
+			if name != "" {
+				if update, ok := action.(k8stesting.UpdateAction); !ok || update.GetObject().(*unstructured.Unstructured).GetName() != name {
+					return false, nil, nil
+				}
+			}
+			return true, nil, errors.New(verb + " " + resource + " failed")
+		}
+	}
+
+	tests := []struct {
+		name    string
+		payload CreateMigrationWaveRunPayload
+		setup   func(*K8sClients)
+		status  int
+	}{
+		{"missing plan", payload("web", "nope"), nil, http.StatusBadRequest},
+		{"save fails", payload("web", "api"), func(c *K8sClients) {
+			c.Clientset.(*fake.Clientset).PrependReactor("create", "configmaps", failOn("create", "configmaps", ""))
+		}, http.StatusInternalServerError},
+		{"hold fails", payload("web", "api"), func(c *K8sClients) {
+			c.Dynamic.(*dynamicfake.FakeDynamicClient).PrependReactor("update", "virtualmachineimports", failOn("update", "virtualmachineimports", "api"))
+		}, http.StatusInternalServerError},
+		{"final save fails", payload("web", "api"), func(c *K8sClients) {
+			c.Clientset.(*fake.Clientset).PrependReactor("update", "configmaps", failOn("update", "configmaps", ""))
+		}, http.StatusInternalServerError},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			clients := newTestClientsWithDynamic(nil, newTestVMIC("web", ""), newTestVMIC("api", ""))
+			if tc.setup != nil {
+				tc.setup(clients)
+			}
+			if rr := executeRequest(CreateMigrationWaveRunHandler(clients), "POST", "/api/v1/waves", tc.payload, nil); rr.Code != tc.status {
+				t.Fatalf("expected %d, got %d: %s", tc.status, rr.Code, rr.Body.String())
+			}
+			for _, name := range []string{"web", "api"} {
+				if schedule := vmicSchedule(t, clients, name); schedule != "" {
+					t.Errorf("expected %s left without a hold, got schedule %q", name, schedule)
+				}
+			}
+			if _, err := clients.Clientset.CoreV1().ConfigMaps("vm-import-ui").Get(context.Background(), waveRunConfigPrefix+"app", metav1.GetOptions{}); err == nil {
+				t.Errorf("expected no wave run left behind")
+			}
+		})
+	}
+
+	t.Run("delete pending run", func(t *testing.T) {
+		clients := newTestClientsWithDynamic(nil, newTestVMIC("web", ""), newTestVMIC("api", ""))
+		if rr := executeRequest(CreateMigrationWaveRunHandler(clients), "POST", "/api/v1/waves", payload("web", "api"), nil); rr.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if schedule := vmicSchedule(t, clients, "api"); !strings.HasPrefix(schedule, "2999-") {
+			t.Fatalf("expected api held, got schedule %q", schedule)
+		}
+		if rr := executeRequest(DeleteMigrationWaveRunHandler(clients), "DELETE", "/api/v1/waves/app", nil, map[string]string{"name": "app"}); rr.Code != http.StatusOK {
+			t.Fatalf("expected 200 deleting, got %d: %s", rr.Code, rr.Body.String())
+		}
+		for _, name := range []string{"web", "api"} {
+			if schedule := vmicSchedule(t, clients, name); !strings.HasPrefix(schedule, "2999-") {
+				t.Errorf("expected deleting to leave %s held, got schedule %q", name, schedule)
+			}
+		}
+	})
+}
@@ -0,0 +1,305 @@
+// pkg/network_suggestions.go
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Harvester network types, from the network.harvesterhci.io/type NAD label.
+const (
+	harvesterNetworkVLAN     = "L2VlanNetwork"
+	harvesterNetworkUntagged = "UntaggedNetwork"
+	harvesterNetworkTrunk    = "L2VlanTrunkNetwork"
+)
+
+// Confidence levels of a suggested network mapping.
+const (
+	confidenceHigh   = "high"
+	confidenceMedium = "medium"
+	confidenceLow    = "low"
+)
+
+// nameMatchThreshold is the minimum name similarity for a name-only match.
+const nameMatchThreshold = 0.5
+
+// HarvesterNetwork is the part of a Harvester NetworkAttachmentDefinition that
+// matters for mapping: its type and the VLAN(s) it carries.
+type HarvesterNetwork struct {
+	Namespace      string      `json:"namespace"`
+	Name           string      `json:"name"`
+	Type           string      `json:"type"`
+	ClusterNetwork string      `json:"clusterNetwork,omitempty"`
+	VLANID         int32       `json:"vlanId,omitempty"`
+	VLANRanges     []VLANRange `json:"vlanRanges,omitempty"`
+}
+
+// Destination is the "namespace/name" form NetworkMapping.DestinationNetwork takes.
+func (h HarvesterNetwork) Destination() string {
+	return h.Namespace + "/" + h.Name
+}
+
+// carries reports whether the network passes VLAN id.
+func (h HarvesterNetwork) carries(id int32) bool {
+	for _, r := range h.VLANRanges {
+		if id >= r.Start && id <= r.End {
+			return true
+		}
+	}
+	return false
+}
+
+// NetworkMappingCandidate is one possible destination for a source network.
+type NetworkMappingCandidate struct {
+	Destination          string   `json:"destination"`
+	DestinationNamespace string   `json:"destinationNamespace"`
+	DestinationName      string   `json:"destinationName"`
+	Confidence           string   `json:"confidence"`
+	Score                int      `json:"score"`
+	Reasons              []string `json:"reasons"`
+}
+
+// NetworkMappingSuggestion proposes destinations for one source network.
+// Candidates are ordered best first; Suggested is the best one's destination
+// and is empty when NoViableDestination is set.
+type NetworkMappingSuggestion struct {
+	Source              VCenterNetwork            `json:"source"`
+	Suggested           string                    `json:"suggested,omitempty"`
+	Confidence          string                    `json:"confidence,omitempty"`
+	Candidates          []NetworkMappingCandidate `json:"candidates"`
+	NoViableDestination bool                      `json:"noViableDestination"`
+	Warnings            []string                  `json:"warnings,omitempty"`
+}
+
+// parseHarvesterNetwork reads a NAD's type label and the VLAN settings from
+// its CNI config, e.g. {"type":"bridge","bridge":"mgmt-br","vlan":100} or
+// {"vlanTrunk":[{"minID":100,"maxID":200}]} for trunks.
+func parseHarvesterNetwork(nad unstructured.Unstructured) HarvesterNetwork {
+	labels := nad.GetLabels()
+	h := HarvesterNetwork{
+		Namespace:      nad.GetNamespace(),
+		Name:           nad.GetName(),
+		Type:           labels["network.harvesterhci.io/type"],
+		ClusterNetwork: labels["network.harvesterhci.io/clusternetwork"],
+	}
+
+	raw, _, _ := unstructured.NestedString(nad.Object, "spec", "config")
+	var config struct {
+		VLAN      int32 `json:"vlan"`
+		VLANTrunk []struct {
+			MinID int32 `json:"minID"`
+			MaxID int32 `json:"maxID"`
+		} `json:"vlanTrunk"`
+	}
+	if raw != "" {
+		if err := json.Unmarshal([]byte(raw), &config); err != nil {
+			log.Warnf("Could not parse config of NetworkAttachmentDefinition %s: %v", h.Destination(), err)
+		}
+	}
+	h.VLANID = config.VLAN
+	for _, r := range config.VLANTrunk {
+		h.VLANRanges = append(h.VLANRanges, VLANRange{Start: r.MinID, End: r.MaxID})
+	}
+	if len(h.VLANRanges) > 0 {
+		h.Type = harvesterNetworkTrunk
+	} else if h.Type == "" {
+		if h.VLANID != 0 {
+			h.Type = harvesterNetworkVLAN
+		} else {
+			h.Type = harvesterNetworkUntagged
+		}
+	}
+	return h
+}
+
+// suggestNetworkMappings proposes a Harvester network for each source network.
+// VLANs decide wherever both sides report one: an access VLAN maps to a NAD
+// with the same VLAN ID (or, less confidently, a trunk carrying it), an
+// untagged portgroup to an untagged network and a trunk to a trunk covering
+// all of its ranges. Name similarity breaks ties, and is the only signal for
+// private VLANs and NSX networks, whose VLAN vCenter does not expose.
+func suggestNetworkMappings(sources []VCenterNetwork, destinations []HarvesterNetwork) []NetworkMappingSuggestion {
+	suggestions := make([]NetworkMappingSuggestion, 0, len(sources))
+	for _, src := range sources {
+		s := NetworkMappingSuggestion{Source: src, Candidates: []NetworkMappingCandidate{}}
+		if len(src.HostVLANIDs) > 0 {
+			s.Warnings = append(s.Warnings, fmt.Sprintf("hosts disagree on the portgroup's VLAN (%s); matching on the most common, VLAN %d", joinVLANIDs(src.HostVLANIDs), src.VLANID))
+		}
+		if src.Type == "OpaqueNetwork" {
+			s.Warnings = append(s.Warnings, "NSX network: vCenter does not expose its VLAN, so only the name is compared")
+		}
+		if src.VLANType == vlanTypePVLAN {
+			s.Warnings = append(s.Warnings, fmt.Sprintf("private VLAN %d has no Harvester equivalent, so only the name is compared", src.PVLANID))
+		}
+
+		for _, dst := range destinations {
+			if c, ok := scoreNetworkMapping(src, dst); ok {
+				s.Candidates = append(s.Candidates, c)
+			}
+		}
+		sort.SliceStable(s.Candidates, func(i, j int) bool {
+			if s.Candidates[i].Score != s.Candidates[j].Score {
+				return s.Candidates[i].Score > s.Candidates[j].Score
+			}
+			return s.Candidates[i].Destination < s.Candidates[j].Destination
+		})
+
+		if len(s.Candidates) == 0 {
+			s.NoViableDestination = true
+			s.Warnings = append(s.Warnings, noDestinationReason(src))
+		} else {
+			s.Suggested = s.Candidates[0].Destination
+			s.Confidence = s.Candidates[0].Confidence
+		}
+		suggestions = append(suggestions, s)
+	}
+	return suggestions
+}
+
+// scoreNetworkMapping rates dst as a destination for src, out of 100. A VLAN
+// mismatch rules the destination out whatever its name.
+func scoreNetworkMapping(src VCenterNetwork, dst HarvesterNetwork) (NetworkMappingCandidate, bool) {
+	c := NetworkMappingCandidate{
+		Destination:          dst.Destination(),
+		DestinationNamespace: dst.Namespace,
+		DestinationName:      dst.Name,
+		Reasons:              []string{},
+	}
+	vlanKnown := src.Type != "OpaqueNetwork" && src.VLANType != vlanTypePVLAN
+
+	if vlanKnown {
+		switch src.VLANType {
+		case vlanTypeVLAN:
+			switch {
+			case dst.Type == harvesterNetworkTrunk && dst.carries(src.VLANID):
+				c.Score = 50
+				c.Reasons = append(c.Reasons, fmt.Sprintf("trunk carries VLAN %d, but the VM would have to tag its own traffic", src.VLANID))
+			case dst.Type == harvesterNetworkVLAN && dst.VLANID == src.VLANID:
+				c.Score = 80
+				c.Reasons = append(c.Reasons, fmt.Sprintf("VLAN %d matches", src.VLANID))
+			default:
+				return c, false
+			}
+		case vlanTypeTrunk:
+			if dst.Type != harvesterNetworkTrunk {
+				return c, false
+			}
+			missing := 0
+			for _, r := range src.VLANRanges {
+				for id := r.Start; id <= r.End; id++ {
+					if !dst.carries(id) {
+						missing++
+					}
+				}
+			}
+			if missing > 0 {
+				c.Score = 30
+				c.Reasons = append(c.Reasons, fmt.Sprintf("trunk is missing %d of the source trunk's VLANs", missing))
+			} else {
+				c.Score = 80
+				c.Reasons = append(c.Reasons, "trunk carries every VLAN of the source trunk")
+			}
+		default:
+			if dst.Type != harvesterNetworkUntagged && !(dst.Type == harvesterNetworkVLAN && dst.VLANID == 0) {
+				return c, false
+			}
+			c.Score = 60
+			c.Reasons = append(c.Reasons, "both networks are untagged")
+		}
+		if len(src.HostVLANIDs) > 0 {
+			c.Score -= 20
+			c.Reasons = append(c.Reasons, "hosts disagree on the source VLAN")
+		}
+	}
+
+	similarity := nameSimilarity(src.Name, dst.Name)
+	if !vlanKnown {
+		if similarity < nameMatchThreshold {
+			return c, false
+		}
+		c.Score = int(similarity * 50)
+		c.Reasons = append(c.Reasons, fmt.Sprintf("name is %d%% similar", int(similarity*100)))
+	} else if similarity >= nameMatchThreshold {
+		c.Score += int(similarity * 20)
+		c.Reasons = append(c.Reasons, fmt.Sprintf("name is %d%% similar", int(similarity*100)))
+	}
+
+	switch {
+	case c.Score >= 75:
+		c.Confidence = confidenceHigh
+	case c.Score >= 45:
+		c.Confidence = confidenceMedium
+	default:
+		c.Confidence = confidenceLow
+	}
+	return c, true
+}
+
+// noDestinationReason explains why no Harvester network fits src.
+func noDestinationReason(src VCenterNetwork) string {
+	switch {
+	case src.Type == "OpaqueNetwork" || src.VLANType == vlanTypePVLAN:
+		return "no Harvester network has a similar name"
+	case src.VLANType == vlanTypeVLAN:
+		return fmt.Sprintf("no Harvester network carries VLAN %d", src.VLANID)
+	case src.VLANType == vlanTypeTrunk:
+		return "no Harvester trunk network carries the source trunk's VLANs"
+	default:
+		return "no untagged Harvester network exists"
+	}
+}
+
+func joinVLANIDs(ids []int32) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprint(id)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// nameSimilarity compares two network names on their lowercase letters and
+// digits, so "VLAN_200 Prod" and "vlan200-prod" are identical. It returns a
+// value between 0 and 1 from the edit distance.
+func nameSimilarity(a, b string) float64 {
+	normalize := func(s string) []rune {
+		var out []rune
+		for _, r := range strings.ToLower(s) {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				out = append(out, r)
+			}
+		}
+		return out
+	}
+	x, y := normalize(a), normalize(b)
+	longest := len(x)
+	if len(y) > longest {
+		longest = len(y)
+	}
+	if longest == 0 {
+		return 0
+	}
+
+	prev := make([]int, len(y)+1)
+	cur := make([]int, len(y)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(x); i++ {
+		cur[0] = i
+		for j := 1; j <= len(y); j++ {
+			cost := 1
+			if x[i-1] == y[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return 1 - float64(prev[len(y)])/float64(longest)
+}
@@ -0,0 +1,191 @@
+// pkg/network_suggestions_test.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vmware/govmomi/simulator"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// newNAD builds a Harvester NetworkAttachmentDefinition with the given type
+// label and CNI config.
+func newNAD(namespace, name, networkType, config string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "k8s.cni.cncf.io/v1",
+		"kind":       "NetworkAttachmentDefinition",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": namespace,
+			"labels": map[string]interface{}{
+				"network.harvesterhci.io/type":           networkType,
+				"network.harvesterhci.io/clusternetwork": "mgmt",
+			},
+		},
+		"spec": map[string]interface{}{"config": config},
+	}}
+}
+
+func TestParseHarvesterNetwork(t *testing.T) {
+	vlan := parseHarvesterNetwork(*newNAD("default", "vlan100", harvesterNetworkVLAN, `{"cniVersion":"0.3.1","type":"bridge","bridge":"mgmt-br","vlan":100}`))
+	if vlan.Destination() != "default/vlan100" || vlan.Type != harvesterNetworkVLAN || vlan.VLANID != 100 || vlan.ClusterNetwork != "mgmt" {
+		t.Errorf("unexpected VLAN network: %+v", vlan)
+	}
+
+	trunk := parseHarvesterNetwork(*newNAD("default", "trunk", harvesterNetworkTrunk, `{"type":"bridge","vlanTrunk":[{"minID":100,"maxID":199},{"minID":300,"maxID":300}]}`))
+	if trunk.Type != harvesterNetworkTrunk || len(trunk.VLANRanges) != 2 || !trunk.carries(150) || !trunk.carries(300) || trunk.carries(200) {
+		t.Errorf("unexpected trunk network: %+v", trunk)
+	}
+
+	untagged := parseHarvesterNetwork(*newNAD("harvester-public", "local", harvesterNetworkUntagged, `not json`))
+	if untagged.Type != harvesterNetworkUntagged || untagged.VLANID != 0 {
+		t.Errorf("unexpected untagged network: %+v", untagged)
+	}
+}
+
+func TestSuggestNetworkMappings(t *testing.T) {
+	destinations := []HarvesterNetwork{
+		{Namespace: "default", Name: "prod-vlan200", Type: harvesterNetworkVLAN, VLANID: 200},
+		{Namespace: "default", Name: "other-200", Type: harvesterNetworkVLAN, VLANID: 200},
+		{Namespace: "default", Name: "vlan300", Type: harvesterNetworkVLAN, VLANID: 300},
+		{Namespace: "default", Name: "guest-trunk", Type: harvesterNetworkTrunk, VLANRanges: []VLANRange{{Start: 100, End: 250}}},
+		{Namespace: "harvester-public", Name: "untagged", Type: harvesterNetworkUntagged},
+		{Namespace: "default", Name: "nsx-web", Type: harvesterNetworkVLAN, VLANID: 42},
+	}
+	sources := []VCenterNetwork{
+		{ID: "dvportgroup-1", Name: "Prod VLAN200", Type: "DistributedVirtualPortgroup", VLANType: vlanTypeVLAN, VLANID: 200},
+		{ID: "dvportgroup-2", Name: "Backup", Type: "DistributedVirtualPortgroup", VLANType: vlanTypeVLAN, VLANID: 999},
+		{ID: "dvportgroup-3", Name: "Trunk", Type: "DistributedVirtualPortgroup", VLANType: vlanTypeTrunk, VLANRanges: []VLANRange{{Start: 100, End: 199}}},
+		{ID: "network-4", Name: "VM Network", Type: "Network", VLANType: vlanTypeNone},
+		{ID: "network-5", Name: "NSX_Web", Type: "OpaqueNetwork"},
+		{ID: "network-6", Name: "DB", Type: "Network", VLANType: vlanTypeVLAN, VLANID: 300, HostVLANIDs: []int32{300, 301}},
+	}
+	suggestions := suggestNetworkMappings(sources, destinations)
+	if len(suggestions) != len(sources) {
+		t.Fatalf("expected %d suggestions, got %d", len(sources), len(suggestions))
+	}
+	byID := map[string]NetworkMappingSuggestion{}
+	for _, s := range suggestions {
+		byID[s.Source.ID] = s
+	}
+
+	t.Run("VLAN match with name tie-break", func(t *testing.T) {
+		s := byID["dvportgroup-1"]
+		if s.Suggested != "default/prod-vlan200" || s.Confidence != confidenceHigh || s.NoViableDestination {
+			t.Errorf("expected default/prod-vlan200 with high confidence, got %+v", s)
+		}
+		if len(s.Candidates) != 3 || s.Candidates[1].Destination != "default/other-200" || s.Candidates[2].Destination != "default/guest-trunk" {
+			t.Errorf("expected both VLAN 200 networks then the trunk, got %+v", s.Candidates)
+		}
+	})
+
+	t.Run("unmatched VLAN is flagged", func(t *testing.T) {
+		s := byID["dvportgroup-2"]
+		if !s.NoViableDestination || s.Suggested != "" || len(s.Candidates) != 0 {
+			t.Errorf("expected no viable destination for VLAN 999, got %+v", s)
+		}
+		if len(s.Warnings) == 0 || !strings.Contains(s.Warnings[len(s.Warnings)-1], "VLAN 999") {
+			t.Errorf("expected the reason to name VLAN 999, got %v", s.Warnings)
+		}
+	})
+
+	t.Run("trunk maps to a covering trunk", func(t *testing.T) {
+		s := byID["dvportgroup-3"]
+		if s.Suggested != "default/guest-trunk" || s.Confidence != confidenceHigh {
+			t.Errorf("expected default/guest-trunk, got %+v", s)
+		}
+	})
+
+	t.Run("untagged maps to untagged", func(t *testing.T) {
+		s := byID["network-4"]
+		if s.Suggested != "harvester-public/untagged" || s.Confidence != confidenceMedium || len(s.Candidates) != 1 {
+			t.Errorf("expected harvester-public/untagged only, got %+v", s)
+		}
+	})
+
+	t.Run("opaque network falls back to the name", func(t *testing.T) {
+		s := byID["network-5"]
+		if s.Suggested != "default/nsx-web" || s.Confidence != confidenceMedium || len(s.Warnings) == 0 {
+			t.Errorf("expected a name-based suggestion of default/nsx-web with a warning, got %+v", s)
+		}
+	})
+
+	t.Run("disagreeing hosts lower confidence", func(t *testing.T) {
+		s := byID["network-6"]
+		if s.Suggested != "default/vlan300" || s.Confidence != confidenceMedium || len(s.Warnings) == 0 {
+			t.Errorf("expected default/vlan300 with medium confidence and a warning, got %+v", s)
+		}
+	})
+}
+
+func TestNameSimilarity(t *testing.T) {
+	if got := nameSimilarity("VLAN_200 Prod", "vlan200-prod"); got != 1 {
+		t.Errorf("expected punctuation and case to be ignored, got %v", got)
+	}
+	if got := nameSimilarity("", ""); got != 0 {
+		t.Errorf("expected empty names not to match, got %v", got)
+	}
+	if got := nameSimilarity("web", "database"); got >= nameMatchThreshold {
+		t.Errorf("expected unrelated names to fall below the threshold, got %v", got)
+	}
+}
+
+func TestNetworkSuggestionHandlersWithVCSim(t *testing.T) {
+	model := simulator.VPX()
+	env := newVCSimEnv(t, model, "DC0")
+
+	env.registerListKinds(map[schema.GroupVersionResource]string{nadGVR: "NetworkAttachmentDefinitionList"})
+	for _, nad := range []*unstructured.Unstructured{
+		newNAD("harvester-public", "untagged", harvesterNetworkUntagged, `{"type":"bridge","bridge":"mgmt-br"}`),
+		newNAD("default", "vlan100", harvesterNetworkVLAN, `{"type":"bridge","bridge":"mgmt-br","vlan":100}`),
+	} {
+		if _, err := env.clients.Dynamic.Resource(nadGVR).Namespace(nad.GetNamespace()).Create(context.Background(), nad, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	decode := func(rr *httptest.ResponseRecorder) map[string]NetworkMappingSuggestion {
+		t.Helper()
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d; body: %s", rr.Code, rr.Body.String())
+		}
+		var list []NetworkMappingSuggestion
+		if err := json.Unmarshal(rr.Body.Bytes(), &list); err != nil {
+			t.Fatalf("failed to unmarshal suggestions: %v", err)
+		}
+		byName := map[string]NetworkMappingSuggestion{}
+		for _, s := range list {
+			byName[s.Source.Name] = s
+		}
+		return byName
+	}
+
+	t.Run("every network in the datacenter", func(t *testing.T) {
+		rr := executeRequest(HandleGetVCenterNetworkSuggestions(env.clients), "GET", "/api/v1/vcenter/networks/suggestions", nil, env.source)
+		suggestions := decode(rr)
+		s, ok := suggestions["VM Network"]
+		if !ok || s.Suggested != "harvester-public/untagged" || len(s.Candidates) != 1 {
+			t.Errorf("expected VM Network to map to the untagged network only, got %+v", s)
+		}
+	})
+
+	t.Run("selected networks", func(t *testing.T) {
+		rr := executeRequest(HandleGetForkliftNetworkSuggestions(env.clients), "GET", "/api/v1/forklift/networks/suggestions?network=DC0_DVPG0", nil, env.forklift)
+		suggestions := decode(rr)
+		if len(suggestions) != 1 || suggestions["DC0_DVPG0"].Suggested != "harvester-public/untagged" {
+			t.Errorf("expected only DC0_DVPG0, got %+v", suggestions)
+		}
+
+		rr = executeRequest(HandleGetForkliftNetworkSuggestions(env.clients), "GET", "/api/v1/forklift/networks/suggestions?network=DC0_DVPG0,missing", nil, env.forklift)
+		if rr.Code != http.StatusNotFound || !strings.Contains(rr.Body.String(), "missing") {
+			t.Errorf("expected 404 naming the missing network, got %d: %s", rr.Code, rr.Body.String())
+		}
+	})
+}
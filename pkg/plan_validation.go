@@ -0,0 +1,505 @@
+// pkg/plan_validation.go
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// rfc1123Label matches a DNS-1123 label, the format VM and plan names need.
+var rfc1123Label = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// ValidationIssue is one problem found by a dry-run plan validation. Field
+// points into the submitted payload, e.g. "spec.networkMapping[1]"; Code is a
+// stable identifier the UI can key on.
+type ValidationIssue struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// PlanValidationResult lists what would make a plan fail (Errors) or behave
+// in a way the user may not expect (Warnings). Valid means no errors.
+type PlanValidationResult struct {
+	Valid    bool              `json:"valid"`
+	Errors   []ValidationIssue `json:"errors"`
+	Warnings []ValidationIssue `json:"warnings"`
+}
+
+// planValidator collects issues while checking a plan against the cluster
+// and the source vCenter. Lookups are cached so a plan that maps many NICs
+// to the same network costs one request per network.
+type planValidator struct {
+	ctx     context.Context
+	clients *K8sClients
+	result  PlanValidationResult
+
+	namespaces    map[string]bool
+	vmNames       map[string]map[string]bool
+	nads          map[string]bool
+	storageClass  map[string]*storagev1.StorageClass
+	longhorn      *longhornCapacity
+	longhornKnown bool
+}
+
+func newPlanValidator(ctx context.Context, clients *K8sClients) *planValidator {
+	return &planValidator{
+		ctx:          ctx,
+		clients:      clients,
+		result:       PlanValidationResult{Errors: []ValidationIssue{}, Warnings: []ValidationIssue{}},
+		namespaces:   map[string]bool{},
+		vmNames:      map[string]map[string]bool{},
+		nads:         map[string]bool{},
+		storageClass: map[string]*storagev1.StorageClass{},
+	}
+}
+
+func (v *planValidator) errorf(field, code, format string, args ...interface{}) {
+	v.result.Errors = append(v.result.Errors, ValidationIssue{Field: field, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *planValidator) warnf(field, code, format string, args ...interface{}) {
+	v.result.Warnings = append(v.result.Warnings, ValidationIssue{Field: field, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *planValidator) done() PlanValidationResult {
+	v.result.Valid = len(v.result.Errors) == 0
+	return v.result
+}
+
+// checkNamespace reports false, with an error, when namespace does not exist.
+func (v *planValidator) checkNamespace(field, namespace string) bool {
+	if namespace == "" {
+		v.errorf(field, "namespaceMissing", "No namespace given")
+		return false
+	}
+	exists, cached := v.namespaces[namespace]
+	if !cached {
+		_, err := v.clients.Clientset.CoreV1().Namespaces().Get(v.ctx, namespace, metav1.GetOptions{})
+		switch {
+		case err == nil:
+			exists = true
+		case apierrors.IsNotFound(err):
+		default:
+			v.warnf(field, "namespaceUnchecked", "Could not check namespace %s: %v", namespace, err)
+			exists = true
+		}
+		v.namespaces[namespace] = exists
+	}
+	if !exists {
+		v.errorf(field, "namespaceNotFound", "Namespace %s does not exist", namespace)
+	}
+	return exists
+}
+
+// checkVMName checks a target VM name is a valid RFC-1123 label and that no
+// VirtualMachine of that name exists in namespace yet.
+func (v *planValidator) checkVMName(field, namespace, name string) {
+	if len(name) > 63 || !rfc1123Label.MatchString(name) {
+		v.errorf(field, "invalidVMName", "Target VM name %q is not a valid RFC-1123 name (lowercase letters, digits and hyphens, at most 63 characters)", name)
+		return
+	}
+	if exists, checked := v.namespaces[namespace]; checked && !exists {
+		return // already reported
+	}
+	names, ok := v.vmNames[namespace]
+	if !ok {
+		names = map[string]bool{}
+		items, err := listHarvesterVMs(v.ctx, v.clients, namespace)
+		if err != nil {
+			v.warnf(field, "vmNameUnchecked", "Could not list VirtualMachines in %s: %v", namespace, err)
+		}
+		for _, item := range items {
+			names[item.GetName()] = true
+		}
+		v.vmNames[namespace] = names
+	}
+	if names[name] {
+		v.errorf(field, "vmNameTaken", "A VirtualMachine named %s already exists in namespace %s", name, namespace)
+	}
+}
+
+// checkNAD reports an error when the NetworkAttachmentDefinition does not exist.
+func (v *planValidator) checkNAD(field, namespace, name string) {
+	key := namespace + "/" + name
+	exists, cached := v.nads[key]
+	if !cached {
+		_, err := v.clients.getResource(v.ctx, nadGVR, namespace, name)
+		switch {
+		case err == nil:
+			exists = true
+		case apierrors.IsNotFound(err):
+		default:
+			v.warnf(field, "networkUnchecked", "Could not check network %s: %v", key, err)
+			exists = true
+		}
+		v.nads[key] = exists
+	}
+	if !exists {
+		v.errorf(field, "networkNotFound", "Harvester network %s does not exist", key)
+	}
+}
+
+// checkExists reports an error when a custom resource the plan would create
+// already exists.
+func (v *planValidator) checkExists(field string, gvr schema.GroupVersionResource, namespace, name, kind string) {
+	_, err := v.clients.getResource(v.ctx, gvr, namespace, name)
+	if err == nil {
+		v.errorf(field, "alreadyExists", "A %s named %s already exists in namespace %s", kind, name, namespace)
+	} else if !apierrors.IsNotFound(err) {
+		v.warnf(field, "existenceUnchecked", "Could not check for an existing %s %s/%s: %v", kind, namespace, name, err)
+	}
+}
+
+// checkStorage checks that a StorageClass exists and can hold disks, using
+// the same rules as the storage mapping suggestions. An empty class means
+// the cluster default, which then must exist.
+func (v *planValidator) checkStorage(field, class string, disks []VMDisk) {
+	sc, ok := v.storageClass[class]
+	if !ok {
+		if class == "" {
+			scs, err := v.clients.Clientset.StorageV1().StorageClasses().List(v.ctx, metav1.ListOptions{})
+			if err != nil {
+				v.warnf(field, "storageClassUnchecked", "Could not list StorageClasses: %v", err)
+				return
+			}
+			for i := range scs.Items {
+				if isDefaultStorageClass(scs.Items[i]) {
+					sc = &scs.Items[i]
+					break
+				}
+			}
+		} else {
+			found, err := v.clients.Clientset.StorageV1().StorageClasses().Get(v.ctx, class, metav1.GetOptions{})
+			switch {
+			case err == nil:
+				sc = found
+			case !apierrors.IsNotFound(err):
+				v.warnf(field, "storageClassUnchecked", "Could not check StorageClass %s: %v", class, err)
+				return
+			}
+		}
+		v.storageClass[class] = sc
+	}
+	if sc == nil {
+		if class == "" {
+			v.errorf(field, "storageClassMissing", "No StorageClass given and the cluster has no default StorageClass")
+		} else {
+			v.errorf(field, "storageClassNotFound", "StorageClass %s does not exist", class)
+		}
+		return
+	}
+	if class == "" {
+		v.warnf(field, "defaultStorageClass", "No StorageClass given; the cluster default %s will be used", sc.Name)
+	}
+
+	var required int64
+	shared := false
+	for _, d := range disks {
+		required += d.Capacity
+		shared = shared || d.SharingMode == "sharingMultiWriter"
+	}
+	if !v.longhornKnown && sc.Provisioner == longhornProvisioner {
+		var err error
+		v.longhorn, err = getLonghornCapacity(v.ctx, v.clients)
+		v.longhornKnown = true
+		if err != nil {
+			v.warnf(field, "capacityUnchecked", "Could not read Longhorn capacity, so free space in StorageClass %s was not checked: %v", sc.Name, err)
+		}
+	}
+	c, ok := rateStorageClass(*sc, required, shared, v.longhorn)
+	if !ok {
+		v.errorf(field, "storageRejected", "StorageClass %s cannot hold the disks: %s", sc.Name, strings.Join(c.Reasons, "; "))
+		return
+	}
+	if c.UsableBytes != nil && float64(required) > float64(*c.UsableBytes)*capacityHeadroom {
+		v.warnf(field, "storageNearlyFull", "The disks need %s of the %s free in StorageClass %s", formatDiskSize(required), formatDiskSize(*c.UsableBytes), sc.Name)
+	}
+}
+
+// sourceVM looks a VM up in a source's cached inventory. A source vCenter
+// that cannot be reached only downgrades the source checks to a warning.
+func (v *planValidator) sourceVM(field string, creds VCenterCredentials, ref string, datacenterOnly bool) *InventoryNode {
+	tree, err := inventoryCaches.Get(v.ctx, creds, GetVCenterInventoryAllDatacenters, false)
+	if err == nil && datacenterOnly {
+		tree, err = defaultDatacenterInventory(tree, creds.Datacenter, inventoryViewVMs)
+	}
+	if err != nil {
+		log.Warnf("Plan validation could not read the vCenter inventory: %v", err)
+		v.warnf(field, "sourceUnchecked", "Could not read the vCenter inventory, so the source VM, its networks and its disks were not checked: %v", err)
+		return nil
+	}
+	vms, err := findInventoryVMs(tree, []string{ref})
+	if err != nil {
+		var ambiguous *AmbiguousVMError
+		if errors.As(err, &ambiguous) {
+			v.errorf(field, "ambiguousVM", "%v", err)
+		} else {
+			v.errorf(field, "vmNotFound", "Source VM %s not found in vCenter", ref)
+		}
+		return nil
+	}
+	return &vms[0]
+}
+
+// validateVMICPlan dry-runs a VirtualMachineImport.
+func validateVMICPlan(ctx context.Context, clients *K8sClients, plan VirtualMachineImport) PlanValidationResult {
+	v := newPlanValidator(ctx, clients)
+	namespace := plan.ObjectMeta.Namespace
+
+	if len(plan.ObjectMeta.Name) > 63 || !rfc1123Label.MatchString(plan.ObjectMeta.Name) {
+		v.errorf("metadata.name", "invalidPlanName", "Plan name %q is not a valid RFC-1123 name", plan.ObjectMeta.Name)
+	}
+	if v.checkNamespace("metadata.namespace", namespace) && plan.ObjectMeta.Name != "" {
+		v.checkExists("metadata.name", vmiGVR, namespace, plan.ObjectMeta.Name, "VirtualMachineImport")
+	}
+	if plan.Spec.VirtualMachineName == "" {
+		v.errorf("spec.virtualMachineName", "vmNameMissing", "No source VM given")
+		return v.done()
+	}
+	// VMIC names the Harvester VM after the lowercased source VM name.
+	v.checkVMName("spec.virtualMachineName", namespace, strings.ToLower(plan.Spec.VirtualMachineName))
+
+	for i, m := range plan.Spec.NetworkMapping {
+		field := fmt.Sprintf("spec.networkMapping[%d]", i)
+		if m.DestinationNetwork == "" {
+			v.errorf(field, "networkUnmapped", "Source network %s has no destination network", m.SourceNetwork)
+			continue
+		}
+		nadNamespace, nadName := namespace, m.DestinationNetwork
+		if parts := strings.SplitN(m.DestinationNetwork, "/", 2); len(parts) == 2 {
+			nadNamespace, nadName = parts[0], parts[1]
+		}
+		v.checkNAD(field, nadNamespace, nadName)
+	}
+
+	var vm *InventoryNode
+	switch plan.Spec.SourceCluster.Kind {
+	case "VmwareSource":
+		creds, err := resolveVmwareSourceCredentials(ctx, clients, plan.Spec.SourceCluster.Namespace, plan.Spec.SourceCluster.Name)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				v.errorf("spec.sourceCluster", "sourceNotFound", "VmwareSource %s/%s does not exist", plan.Spec.SourceCluster.Namespace, plan.Spec.SourceCluster.Name)
+			} else {
+				v.warnf("spec.sourceCluster", "sourceUnchecked", "Could not read the VmwareSource: %v", err)
+			}
+			break
+		}
+		vm = v.sourceVM("spec.virtualMachineName", creds, plan.Spec.VirtualMachineName, true)
+	case "OvaSource":
+		if _, err := clients.getResource(ctx, ovaSourceGVR, plan.Spec.SourceCluster.Namespace, plan.Spec.SourceCluster.Name); apierrors.IsNotFound(err) {
+			v.errorf("spec.sourceCluster", "sourceNotFound", "OvaSource %s/%s does not exist", plan.Spec.SourceCluster.Namespace, plan.Spec.SourceCluster.Name)
+		}
+	default:
+		v.errorf("spec.sourceCluster.kind", "invalidSource", "Unsupported source kind %q", plan.Spec.SourceCluster.Kind)
+	}
+
+	if vm == nil {
+		v.checkStorage("spec.storageClass", plan.Spec.StorageClass, nil)
+		return v.done()
+	}
+
+	mapped := map[string]bool{}
+	for _, m := range plan.Spec.NetworkMapping {
+		mapped[m.SourceNetwork] = true
+	}
+	seen := map[string]bool{}
+	for _, nic := range vm.Networks {
+		if !mapped[nic.Name] && !seen[nic.Name] {
+			v.errorf("spec.networkMapping", "networkUnmapped", "Source network %s used by %s has no mapping", nic.Name, vm.Name)
+		}
+		seen[nic.Name] = true
+	}
+	for i, m := range plan.Spec.NetworkMapping {
+		if !seen[m.SourceNetwork] {
+			v.warnf(fmt.Sprintf("spec.networkMapping[%d]", i), "networkNotUsed", "No NIC of %s is on source network %s", vm.Name, m.SourceNetwork)
+		}
+	}
+
+	v.checkStorage("spec.storageClass", plan.Spec.StorageClass, vm.Disks)
+
+	force := plan.Spec.ForcePowerOff != nil && *plan.Spec.ForcePowerOff
+	if vm.PowerState == "poweredOn" && !force {
+		if vm.ToolsRunningStatus != "guestToolsRunning" {
+			v.warnf("spec.forcePowerOff", "gracefulShutdownUnavailable", "%s is powered on without VMware Tools running, so it cannot be shut down gracefully; set forcePowerOff or power it off first", vm.Name)
+		} else {
+			v.warnf("spec.forcePowerOff", "vmPoweredOn", "%s is powered on and will be shut down gracefully before the import", vm.Name)
+		}
+	} else if vm.PowerState == "poweredOn" {
+		v.warnf("spec.forcePowerOff", "vmPoweredOn", "%s is powered on and will be powered off without a guest shutdown", vm.Name)
+	}
+	return v.done()
+}
+
+// validateForkliftPlan dry-runs a Forklift plan payload, covering the
+// NetworkMap, StorageMap and Plan CreateForkliftPlanHandler would create.
+func validateForkliftPlan(ctx context.Context, clients *K8sClients, payload CreateForkliftPlanPayload) PlanValidationResult {
+	v := newPlanValidator(ctx, clients)
+	if payload.Namespace == "" {
+		payload.Namespace = "forklift"
+	}
+	providerType := payload.ProviderType
+	if providerType == "" {
+		providerType = "vsphere"
+	}
+
+	if len(payload.Name) > 63 || !rfc1123Label.MatchString(payload.Name) {
+		v.errorf("name", "invalidPlanName", "Plan name %q is not a valid RFC-1123 name", payload.Name)
+	}
+	if v.checkNamespace("namespace", payload.Namespace) && payload.Name != "" {
+		v.checkExists("name", forkliftPlanGVR, payload.Namespace, payload.Name, "Plan")
+		v.checkExists("name", forkliftNetworkMapGVR, payload.Namespace, payload.Name+"-network-map", "NetworkMap")
+		v.checkExists("name", forkliftStorageMapGVR, payload.Namespace, payload.Name+"-storage-map", "StorageMap")
+	}
+	targetOK := v.checkNamespace("targetNamespace", payload.TargetNamespace)
+
+	if len(payload.VMs) == 0 {
+		v.errorf("vms", "noVMs", "No VMs selected")
+	}
+	targets := map[string]bool{}
+	for i, vm := range payload.VMs {
+		target := vm.TargetName
+		if target == "" {
+			target = vm.Name
+		}
+		field := fmt.Sprintf("vms[%d].targetName", i)
+		if targets[target] {
+			v.errorf(field, "duplicateVMName", "More than one VM in the plan targets the name %s", target)
+			continue
+		}
+		targets[target] = true
+		if targetOK {
+			v.checkVMName(field, payload.TargetNamespace, target)
+		}
+	}
+
+	podNetworks := 0
+	for i, nm := range payload.NetworkMappings {
+		field := fmt.Sprintf("networkMappings[%d]", i)
+		switch nm.DestinationType {
+		case "pod":
+			podNetworks++
+		case "multus":
+			if nm.DestinationName == "" {
+				v.errorf(field, "networkUnmapped", "Source network %s has no destination network", nm.SourceID)
+				continue
+			}
+			nadNamespace := nm.DestinationNamespace
+			if nadNamespace == "" {
+				nadNamespace = payload.TargetNamespace
+			}
+			v.checkNAD(field, nadNamespace, nm.DestinationName)
+		default:
+			v.errorf(field, "invalidDestinationType", "Destination type %q is neither pod nor multus", nm.DestinationType)
+		}
+	}
+	if podNetworks > 1 {
+		v.errorf("networkMappings", "multiplePodNetworks", "%d source networks are mapped to the pod network; Forklift allows only one", podNetworks)
+	}
+
+	if _, err := clients.getResource(ctx, forkliftProviderGVR, payload.ProviderNamespace, payload.ProviderName); err != nil {
+		if apierrors.IsNotFound(err) {
+			v.errorf("providerName", "sourceNotFound", "Forklift Provider %s/%s does not exist", payload.ProviderNamespace, payload.ProviderName)
+		} else {
+			v.warnf("providerName", "sourceUnchecked", "Could not read the Forklift Provider: %v", err)
+		}
+		return v.done()
+	}
+	if providerType == "ova" {
+		for i, sm := range payload.StorageMappings {
+			v.checkStorage(fmt.Sprintf("storageMappings[%d]", i), sm.DestinationStorageClass, nil)
+		}
+		return v.done()
+	}
+
+	creds, err := resolveForkliftProviderCredentials(ctx, clients, payload.ProviderNamespace, payload.ProviderName)
+	if err != nil {
+		v.warnf("providerName", "sourceUnchecked", "Could not read the Forklift Provider's credentials: %v", err)
+		return v.done()
+	}
+
+	mappedNetworks := map[string]bool{}
+	for _, nm := range payload.NetworkMappings {
+		mappedNetworks[nm.SourceID] = true
+		if nm.SourceName != "" {
+			mappedNetworks[nm.SourceName] = true
+		}
+	}
+	storageFor := map[string]int{} // datastore ID → index of its storage mapping
+	for i, sm := range payload.StorageMappings {
+		storageFor[sm.SourceID] = i
+	}
+	disksFor := map[int][]VMDisk{}
+
+	for i, entry := range payload.VMs {
+		field := fmt.Sprintf("vms[%d]", i)
+		ref := entry.ID
+		if ref == "" {
+			ref = entry.Name
+		}
+		vm := v.sourceVM(field, creds, ref, false)
+		if vm == nil {
+			continue
+		}
+		for _, nic := range vm.Networks {
+			if !mappedNetworks[nic.ID] && !mappedNetworks[nic.Name] {
+				v.errorf("networkMappings", "networkUnmapped", "Source network %s (%s) used by %s has no mapping", nic.Name, nic.ID, vm.Name)
+				mappedNetworks[nic.ID] = true // report each network once
+			}
+		}
+		for _, disk := range vm.Disks {
+			idx, ok := storageFor[disk.DatastoreID]
+			if !ok {
+				v.errorf("storageMappings", "datastoreUnmapped", "Datastore %s (%s) used by %s has no storage mapping", disk.DatastoreName, disk.DatastoreID, vm.Name)
+				storageFor[disk.DatastoreID] = -1
+				continue
+			}
+			if idx >= 0 {
+				disksFor[idx] = append(disksFor[idx], disk)
+			}
+		}
+		if vm.PowerState == "poweredOn" {
+			if payload.Warm {
+				v.warnf(field, "vmPoweredOn", "%s stays powered on during the warm migration and is shut down at cutover", vm.Name)
+			} else {
+				v.warnf(field, "vmPoweredOn", "%s is powered on; Forklift will shut it down when the migration starts", vm.Name)
+			}
+		}
+	}
+
+	// Disks of several datastores mapped to one class all land on it, so
+	// capacity is checked per class.
+	byClass := map[string][]VMDisk{}
+	classField := map[string]string{}
+	for i, sm := range payload.StorageMappings {
+		byClass[sm.DestinationStorageClass] = append(byClass[sm.DestinationStorageClass], disksFor[i]...)
+		if _, ok := classField[sm.DestinationStorageClass]; !ok {
+			classField[sm.DestinationStorageClass] = fmt.Sprintf("storageMappings[%d]", i)
+		}
+	}
+	for i, sm := range payload.StorageMappings {
+		if classField[sm.DestinationStorageClass] == fmt.Sprintf("storageMappings[%d]", i) {
+			v.checkStorage(classField[sm.DestinationStorageClass], sm.DestinationStorageClass, byClass[sm.DestinationStorageClass])
+		}
+	}
+	return v.done()
+}
+
+// listHarvesterVMs lists the KubeVirt VirtualMachines in namespace.
+func listHarvesterVMs(ctx context.Context, clients *K8sClients, namespace string) ([]unstructured.Unstructured, error) {
+	list, err := clients.listResources(ctx, vmGVR, namespace, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
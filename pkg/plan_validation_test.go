@@ -0,0 +1,227 @@
+// pkg/plan_validation_test.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/simulator"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// issueCodes returns the sorted codes of issues, for compact assertions.
+func issueCodes(issues []ValidationIssue) string {
+	codes := make([]string, len(issues))
+	for i, issue := range issues {
+		codes[i] = issue.Code
+	}
+	sort.Strings(codes)
+	return strings.Join(codes, ",")
+}
+
+// newValidationEnv seeds vcsim and the fake cluster for plan validation:
+// DC0_H0_VM0 is renamed web-01, namespace vms already holds a VirtualMachine
+// named web-01, Longhorn has 24 GiB free on one node and there are 1- and
+// 3-replica Longhorn classes plus one NAD, default/vlan100.
+func newValidationEnv(t *testing.T) (*vcsimEnv, InventoryNode) {
+	t.Helper()
+	env := newVCSimEnv(t, simulator.VPX(), "DC0")
+	ctx := context.Background()
+
+	env.vcsim(func(ctx context.Context, c *govmomi.Client) error {
+		vm, err := find.NewFinder(c.Client, false).VirtualMachine(ctx, "/DC0/vm/DC0_H0_VM0")
+		if err != nil {
+			return err
+		}
+		task, err := vm.Rename(ctx, "web-01")
+		if err != nil {
+			return err
+		}
+		return task.Wait(ctx)
+	})
+	vm, ok := inventoryVMs(env.inventory())["web-01"]
+	if !ok || len(vm.Networks) == 0 || len(vm.Disks) == 0 {
+		t.Fatalf("expected web-01 with NICs and disks, got %+v", vm)
+	}
+
+	env.registerListKinds(map[schema.GroupVersionResource]string{
+		vmGVR:           "VirtualMachineList",
+		vmiGVR:          "VirtualMachineImportList",
+		longhornNodeGVR: "NodeList",
+	})
+	existing := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "kubevirt.io/v1",
+		"kind":       "VirtualMachine",
+		"metadata":   map[string]interface{}{"name": "web-01", "namespace": "vms"},
+	}}
+	for _, obj := range []struct {
+		gvr schema.GroupVersionResource
+		obj *unstructured.Unstructured
+	}{
+		{vmGVR, existing},
+		{longhornNodeGVR, newLonghornNode("node-1", 24)},
+		{nadGVR, newNAD("default", "vlan100", harvesterNetworkVLAN, `{"vlan":100}`)},
+	} {
+		if _, err := env.clients.Dynamic.Resource(obj.gvr).Namespace(obj.obj.GetNamespace()).Create(ctx, obj.obj, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, ns := range []string{"default", "vms", "forklift"} {
+		if _, err := env.clients.Clientset.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ns}}, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, sc := range []storagev1.StorageClass{
+		newStorageClass("longhorn-1", longhornProvisioner, false, "numberOfReplicas", "1", "migratable", "true"),
+		newStorageClass("harvester-longhorn", longhornProvisioner, true, "numberOfReplicas", "3", "migratable", "true"),
+	} {
+		sc := sc
+		if _, err := env.clients.Clientset.StorageV1().StorageClasses().Create(ctx, &sc, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return env, vm
+}
+
+func validate(t *testing.T, handler http.HandlerFunc, body interface{}) PlanValidationResult {
+	t.Helper()
+	rr := executeRequest(handler, "POST", "/api/v1/plans/validate", body, nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var result PlanValidationResult
+	if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestValidatePlanHandlerWithVCSim(t *testing.T) {
+	env, vm := newValidationEnv(t)
+	handler := ValidatePlanHandler(env.clients)
+
+	plan := func(namespace, storageClass string, mappings ...NetworkMapping) VirtualMachineImport {
+		return VirtualMachineImport{
+			ObjectMeta: metav1.ObjectMeta{Name: "import-web-01", Namespace: namespace},
+			Spec: VirtualMachineImportSpec{
+				VirtualMachineName: "web-01",
+				SourceCluster:      SourceCluster{Kind: "VmwareSource", Namespace: env.source["namespace"], Name: env.source["name"]},
+				NetworkMapping:     mappings,
+				StorageClass:       storageClass,
+			},
+		}
+	}
+	var mapped []NetworkMapping
+	for _, nic := range vm.Networks {
+		mapped = append(mapped, NetworkMapping{SourceNetwork: nic.Name, DestinationNetwork: "default/vlan100"})
+	}
+
+	t.Run("valid plan", func(t *testing.T) {
+		result := validate(t, handler, plan("default", "longhorn-1", mapped...))
+		if !result.Valid || len(result.Errors) != 0 {
+			t.Errorf("expected a valid plan, got errors %+v", result.Errors)
+		}
+		// vcsim VMs are powered on without VMware Tools.
+		if issueCodes(result.Warnings) != "gracefulShutdownUnavailable" {
+			t.Errorf("expected a graceful shutdown warning, got %+v", result.Warnings)
+		}
+	})
+
+	t.Run("missing namespace, mappings and StorageClass", func(t *testing.T) {
+		result := validate(t, handler, plan("nope", "fast"))
+		if result.Valid || issueCodes(result.Errors) != "namespaceNotFound,networkUnmapped,storageClassNotFound" {
+			t.Errorf("unexpected errors: %+v", result.Errors)
+		}
+	})
+
+	t.Run("taken name, missing network and too little capacity", func(t *testing.T) {
+		p := plan("vms", "harvester-longhorn", append([]NetworkMapping{{SourceNetwork: vm.Networks[0].Name, DestinationNetwork: "missing"}}, mapped[1:]...)...)
+		force := true
+		p.Spec.ForcePowerOff = &force
+		result := validate(t, handler, p)
+		if issueCodes(result.Errors) != "networkNotFound,storageRejected,vmNameTaken" {
+			t.Errorf("unexpected errors: %+v", result.Errors)
+		}
+		for _, e := range result.Errors {
+			if e.Code == "networkNotFound" && !strings.Contains(e.Message, "vms/missing") {
+				t.Errorf("expected a bare network name to resolve in the plan's namespace, got %q", e.Message)
+			}
+		}
+		if issueCodes(result.Warnings) != "vmPoweredOn" {
+			t.Errorf("expected a forced power-off warning, got %+v", result.Warnings)
+		}
+	})
+
+	t.Run("unknown source VM", func(t *testing.T) {
+		p := plan("default", "", mapped...)
+		p.Spec.VirtualMachineName = "nope"
+		result := validate(t, handler, p)
+		if issueCodes(result.Errors) != "vmNotFound" || issueCodes(result.Warnings) != "defaultStorageClass" {
+			t.Errorf("unexpected result: %+v", result)
+		}
+	})
+}
+
+func TestValidateForkliftPlanHandlerWithVCSim(t *testing.T) {
+	env, vm := newValidationEnv(t)
+	handler := ValidateForkliftPlanHandler(env.clients)
+
+	payload := func() CreateForkliftPlanPayload {
+		p := CreateForkliftPlanPayload{
+			Name:              "migrate-web-01",
+			ProviderName:      env.forklift["name"],
+			ProviderNamespace: env.forklift["namespace"],
+			TargetNamespace:   "default",
+			VMs:               []ForkliftVMEntry{{ID: vm.ID, Name: vm.Name}},
+		}
+		for _, nic := range vm.Networks {
+			p.NetworkMappings = append(p.NetworkMappings, ForkliftNetworkMapEntry{SourceID: nic.ID, DestinationType: "multus", DestinationName: "vlan100", DestinationNamespace: "default"})
+		}
+		for _, ds := range vm.Datastores {
+			p.StorageMappings = append(p.StorageMappings, ForkliftStorageMapEntry{SourceID: ds.ID, DestinationStorageClass: "longhorn-1"})
+		}
+		return p
+	}
+
+	t.Run("valid plan", func(t *testing.T) {
+		result := validate(t, handler, payload())
+		if !result.Valid || issueCodes(result.Warnings) != "vmPoweredOn" {
+			t.Errorf("expected a valid plan with a power state warning, got %+v", result)
+		}
+	})
+
+	t.Run("conflicts and gaps", func(t *testing.T) {
+		existingPlan := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "forklift.konveyor.io/v1beta1",
+			"kind":       "Plan",
+			"metadata":   map[string]interface{}{"name": "migrate-web-01", "namespace": "forklift"},
+		}}
+		if _, err := env.clients.Dynamic.Resource(forkliftPlanGVR).Namespace("forklift").Create(context.Background(), existingPlan, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+
+		p := payload()
+		p.TargetNamespace = "vms"
+		p.VMs = append(p.VMs, ForkliftVMEntry{ID: vm.ID, Name: vm.Name})
+		p.NetworkMappings = []ForkliftNetworkMapEntry{
+			{SourceID: "network-x", DestinationType: "pod"},
+			{SourceID: "network-y", DestinationType: "pod"},
+		}
+		p.StorageMappings = nil
+		result := validate(t, handler, p)
+		want := "alreadyExists,datastoreUnmapped,duplicateVMName,multiplePodNetworks,networkUnmapped,vmNameTaken"
+		if got := issueCodes(result.Errors); got != want {
+			t.Errorf("expected %s, got %s", want, got)
+		}
+	})
+}
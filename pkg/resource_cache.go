@@ -0,0 +1,439 @@
+// pkg/resource_cache.go
+package main
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+// cachedGVRs are the resources whose reads ResourceCache may serve.
+var cachedGVRs = []schema.GroupVersionResource{
+	vmiGVR, vmwareSourceGVR, ovaSourceGVR, vmGVR, settingsGVR,
+	forkliftProviderGVR, forkliftPlanGVR, forkliftNetworkMapGVR, forkliftStorageMapGVR, forkliftMigrationGVR,
+	nadGVR,
+}
+
+// cacheDiscoveryRetry is how long a resource the API server does not serve
+// (its CRD is not installed) waits before it is looked up again.
+const cacheDiscoveryRetry = time.Minute
+
+// cacheWriteWait is how long reads of a resource written through the
+// tracking client go to the API server at most, if its informer does not
+// catch up with the write sooner.
+const cacheWriteWait = 30 * time.Second
+
+// ResourceCache serves reads of cachedGVRs from shared informers instead of
+// the API server. An informer starts when Warm runs or the first time its
+// resource is read, and only if the API server serves that resource; until it
+// has synced, and while it has not yet seen a write made through the client
+// TrackWrites returns, reads go to the API server.
+type ResourceCache struct {
+	factory   dynamicinformer.DynamicSharedInformerFactory
+	discovery discovery.DiscoveryInterface
+	stop      <-chan struct{}
+	now       func() time.Time
+
+	mu        sync.Mutex
+	resources map[schema.GroupVersionResource]*cachedResource
+}
+
+type cachedResource struct {
+	informer  cache.SharedIndexInformer // nil until the resource is found
+	lister    cache.GenericLister
+	checking  bool
+	checkedAt time.Time
+	message   string // why the resource is not cached, or its last list/watch error
+	warm      bool   // looked up by Warm; readiness waits for it
+	writes    []cacheWrite
+}
+
+// cacheWrite is an object written through the tracking client that the
+// informer may not have seen yet.
+type cacheWrite struct {
+	namespace, name string
+	resourceVersion string // "" for a deletion
+	at              time.Time
+}
+
+// ResourceCacheStatus is the state of one resource in the cache.
+type ResourceCacheStatus struct {
+	Resource  string `json:"resource"`
+	Available bool   `json:"available"`
+	Synced    bool   `json:"synced"`
+	Message   string `json:"message,omitempty"`
+}
+
+// NewResourceCache creates a cache whose informers run until stop is closed.
+func NewResourceCache(client dynamic.Interface, disc discovery.DiscoveryInterface, stop <-chan struct{}) *ResourceCache {
+	return &ResourceCache{
+		factory:   dynamicinformer.NewDynamicSharedInformerFactory(client, 0),
+		discovery: disc,
+		stop:      stop,
+		now:       time.Now,
+		resources: map[schema.GroupVersionResource]*cachedResource{},
+	}
+}
+
+// served reports whether the API server serves gvr.
+func (c *ResourceCache) served(gvr schema.GroupVersionResource) (bool, error) {
+	return resourceServed(c.discovery, gvr)
+}
+
+// resourceServed looks gvr up through disc. A group version the API server
+// does not know is not served rather than an error.
+func resourceServed(disc discovery.DiscoveryInterface, gvr schema.GroupVersionResource) (bool, error) {
+	list, err := disc.ServerResourcesForGroupVersion(gvr.GroupVersion().String())
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	for _, r := range list.APIResources {
+		if r.Name == gvr.Resource {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// start looks gvr up and, if it is served, starts its informer.
+func (c *ResourceCache) start(gvr schema.GroupVersionResource, r *cachedResource) {
+	available, err := c.served(gvr)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r.checking = false
+	r.checkedAt = c.now()
+	switch {
+	case err != nil:
+		r.message = "Discovery failed: " + err.Error()
+		log.Warnf("Could not look up %s for the cache: %v", gvr.String(), err)
+		return
+	case !available:
+		r.message = "Not served by the API server"
+		log.Debugf("%s is not served; reading it without a cache", gvr.String())
+		return
+	}
+
+	generic := c.factory.ForResource(gvr)
+	r.informer, r.lister, r.message = generic.Informer(), generic.Lister(), ""
+	r.informer.SetWatchErrorHandler(func(_ *cache.Reflector, err error) {
+		c.mu.Lock()
+		r.message = err.Error()
+		c.mu.Unlock()
+		log.Warnf("Cache of %s: %v", gvr.Resource, err)
+	})
+	c.factory.Start(c.stop)
+	log.Infof("Started cache of %s", gvr.String())
+}
+
+// Warm looks up every cachedGVR in the background and starts the informers
+// of those the API server serves. Readiness waits for these informers only;
+// ones started later, when a resource first read or installed after startup
+// is found, never make the pod unready.
+func (c *ResourceCache) Warm() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, gvr := range cachedGVRs {
+		if _, ok := c.resources[gvr]; ok {
+			continue
+		}
+		r := &cachedResource{checking: true, warm: true}
+		c.resources[gvr] = r
+		go c.start(gvr, r)
+	}
+}
+
+// lister returns the lister for gvr once its informer has synced and caught
+// up with the writes made through the tracking client, and nil while reads
+// must go to the API server. The first call for a resource starts looking it
+// up in the background.
+func (c *ResourceCache) lister(gvr schema.GroupVersionResource) cache.GenericLister {
+	if c == nil {
+		return nil
+	}
+	cacheable := false
+	for _, g := range cachedGVRs {
+		if g == gvr {
+			cacheable = true
+			break
+		}
+	}
+	if !cacheable {
+		return nil
+	}
+
+	c.mu.Lock()
+	r, ok := c.resources[gvr]
+	if !ok {
+		r = &cachedResource{}
+		c.resources[gvr] = r
+	}
+	if r.informer == nil && !r.checking && (!ok || c.now().Sub(r.checkedAt) >= cacheDiscoveryRetry) {
+		r.checking = true
+		r.warm = false
+		go c.start(gvr, r)
+	}
+	informer, lister := r.informer, r.lister
+	if informer == nil || !informer.HasSynced() || !c.caughtUp(r) {
+		c.mu.Unlock()
+		return nil
+	}
+	c.mu.Unlock()
+	return lister
+}
+
+// caughtUp drops the writes of r its informer has seen, or that are older
+// than cacheWriteWait, and reports whether none are left. c.mu must be held.
+func (c *ResourceCache) caughtUp(r *cachedResource) bool {
+	pending := r.writes[:0]
+	for _, w := range r.writes {
+		if c.now().Sub(w.at) < cacheWriteWait && !informerHasSeen(r.lister, w) {
+			pending = append(pending, w)
+		}
+	}
+	r.writes = pending
+	return len(pending) == 0
+}
+
+// informerHasSeen reports whether lister reflects w: the object is gone for a
+// deletion, or at w's resourceVersion or later for any other write.
+func informerHasSeen(lister cache.GenericLister, w cacheWrite) bool {
+	var obj runtime.Object
+	var err error
+	if w.namespace == "" {
+		obj, err = lister.Get(w.name)
+	} else {
+		obj, err = lister.ByNamespace(w.namespace).Get(w.name)
+	}
+	if w.resourceVersion == "" {
+		return apierrors.IsNotFound(err)
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	if err != nil || !ok {
+		return false
+	}
+	seen := u.GetResourceVersion()
+	if seen == w.resourceVersion {
+		return true
+	}
+	// resourceVersions are opaque, but the API server's are integers.
+	a, errA := strconv.ParseUint(seen, 10, 64)
+	b, errB := strconv.ParseUint(w.resourceVersion, 10, 64)
+	return errA == nil && errB == nil && a > b
+}
+
+// recordWrite notes a write of gvr so reads bypass its informer until it has
+// seen it. Writes of resources the cache has not started are not tracked.
+func (c *ResourceCache) recordWrite(gvr schema.GroupVersionResource, w cacheWrite) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if r, ok := c.resources[gvr]; ok && r.informer != nil {
+		w.at = c.now()
+		r.writes = append(r.writes, w)
+	}
+}
+
+// Status reports every resource the cache has been asked for, and whether it
+// is ready: every resource Warm looked up has been found or not, and each of
+// their informers has synced or is failing to list (those reads keep going to
+// the API server). Informers started later do not count.
+func (c *ResourceCache) Status() ([]ResourceCacheStatus, bool) {
+	statuses := []ResourceCacheStatus{}
+	if c == nil {
+		return statuses, true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ready := true
+	for gvr, r := range c.resources {
+		s := ResourceCacheStatus{Resource: gvr.String(), Available: r.informer != nil, Message: r.message}
+		if r.warm && r.checking {
+			ready = false
+		}
+		if r.informer != nil {
+			s.Synced = r.informer.HasSynced()
+			if s.Synced {
+				s.Message = ""
+			} else if r.message == "" && r.warm {
+				ready = false
+			}
+		}
+		statuses = append(statuses, s)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Resource < statuses[j].Resource })
+	return statuses, ready
+}
+
+// getResource reads one object from the cache when it can and from the API
+// server otherwise. The result is the caller's to modify, but code that
+// updates an object should read it with the Dynamic client, so it never
+// writes back a stale copy.
+func (c *K8sClients) getResource(ctx context.Context, gvr schema.GroupVersionResource, namespace, name string) (*unstructured.Unstructured, error) {
+	if lister := c.Cache.lister(gvr); lister != nil {
+		var obj runtime.Object
+		var err error
+		if namespace == "" {
+			obj, err = lister.Get(name)
+		} else {
+			obj, err = lister.ByNamespace(namespace).Get(name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if u, ok := obj.(*unstructured.Unstructured); ok {
+			return u.DeepCopy(), nil
+		}
+	}
+	return c.Dynamic.Resource(gvr).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+// listResources lists objects in namespace ("" for all) from the cache when it
+// can and from the API server otherwise. The cache handles label selectors;
+// other options always go to the API server. Items are sorted by namespace
+// and name, as the API server returns them.
+func (c *K8sClients) listResources(ctx context.Context, gvr schema.GroupVersionResource, namespace string, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	cacheable := opts.FieldSelector == "" && opts.ResourceVersion == "" && opts.Limit == 0
+	if lister := c.Cache.lister(gvr); lister != nil && cacheable {
+		selector, err := labels.Parse(opts.LabelSelector)
+		if err != nil {
+			return nil, apierrors.NewBadRequest(err.Error())
+		}
+		var objs []runtime.Object
+		if namespace == "" {
+			objs, err = lister.List(selector)
+		} else {
+			objs, err = lister.ByNamespace(namespace).List(selector)
+		}
+		if err != nil {
+			return nil, err
+		}
+		list := &unstructured.UnstructuredList{Object: map[string]interface{}{}}
+		for _, obj := range objs {
+			if u, ok := obj.(*unstructured.Unstructured); ok {
+				list.Items = append(list.Items, *u.DeepCopy())
+			}
+		}
+		sort.Slice(list.Items, func(i, j int) bool {
+			a, b := list.Items[i], list.Items[j]
+			if a.GetNamespace() != b.GetNamespace() {
+				return a.GetNamespace() < b.GetNamespace()
+			}
+			return a.GetName() < b.GetName()
+		})
+		return list, nil
+	}
+	return c.Dynamic.Resource(gvr).Namespace(namespace).List(ctx, opts)
+}
+
+// TrackWrites wraps client so every object written through it is read from
+// the API server until the cache's informer has seen the write. Without it a
+// list right after a create or update could miss the change.
+func (c *ResourceCache) TrackWrites(client dynamic.Interface) dynamic.Interface {
+	if c == nil {
+		return client
+	}
+	return writeTrackingClient{Interface: client, cache: c}
+}
+
+type writeTrackingClient struct {
+	dynamic.Interface
+	cache *ResourceCache
+}
+
+func (c writeTrackingClient) Resource(gvr schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	resource := c.Interface.Resource(gvr)
+	return trackedResource{ResourceInterface: resource, namespaceable: resource, cache: c.cache, gvr: gvr}
+}
+
+// trackedResource records the writes made through ResourceInterface, of
+// objects in namespace ("" for cluster-scoped ones or all namespaces).
+type trackedResource struct {
+	dynamic.ResourceInterface
+	namespaceable dynamic.NamespaceableResourceInterface
+	cache         *ResourceCache
+	gvr           schema.GroupVersionResource
+	namespace     string
+}
+
+func (r trackedResource) Namespace(namespace string) dynamic.ResourceInterface {
+	return trackedResource{ResourceInterface: r.namespaceable.Namespace(namespace), cache: r.cache, gvr: r.gvr, namespace: namespace}
+}
+
+// written records the object a write returned, if it succeeded.
+func (r trackedResource) written(obj *unstructured.Unstructured, err error) (*unstructured.Unstructured, error) {
+	if err == nil && obj != nil {
+		r.cache.recordWrite(r.gvr, cacheWrite{namespace: obj.GetNamespace(), name: obj.GetName(), resourceVersion: obj.GetResourceVersion()})
+	}
+	return obj, err
+}
+
+func (r trackedResource) Create(ctx context.Context, obj *unstructured.Unstructured, options metav1.CreateOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	return r.written(r.ResourceInterface.Create(ctx, obj, options, subresources...))
+}
+
+func (r trackedResource) Update(ctx context.Context, obj *unstructured.Unstructured, options metav1.UpdateOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	return r.written(r.ResourceInterface.Update(ctx, obj, options, subresources...))
+}
+
+func (r trackedResource) UpdateStatus(ctx context.Context, obj *unstructured.Unstructured, options metav1.UpdateOptions) (*unstructured.Unstructured, error) {
+	return r.written(r.ResourceInterface.UpdateStatus(ctx, obj, options))
+}
+
+func (r trackedResource) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, options metav1.PatchOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	return r.written(r.ResourceInterface.Patch(ctx, name, pt, data, options, subresources...))
+}
+
+func (r trackedResource) Apply(ctx context.Context, name string, obj *unstructured.Unstructured, options metav1.ApplyOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	return r.written(r.ResourceInterface.Apply(ctx, name, obj, options, subresources...))
+}
+
+func (r trackedResource) ApplyStatus(ctx context.Context, name string, obj *unstructured.Unstructured, options metav1.ApplyOptions) (*unstructured.Unstructured, error) {
+	return r.written(r.ResourceInterface.ApplyStatus(ctx, name, obj, options))
+}
+
+func (r trackedResource) Delete(ctx context.Context, name string, options metav1.DeleteOptions, subresources ...string) error {
+	err := r.ResourceInterface.Delete(ctx, name, options, subresources...)
+	if err == nil {
+		r.cache.recordWrite(r.gvr, cacheWrite{namespace: r.namespace, name: name})
+	}
+	return err
+}
+
+// ReadinessHandler reports whether the resource cache has synced: 200 when it
+// has, 503 while an informer started by Warm is still loading.
+func ReadinessHandler(clients *K8sClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var resourceCache *ResourceCache
+		if clients != nil {
+			resourceCache = clients.Cache
+		}
+		statuses, ready := resourceCache.Status()
+		code := http.StatusOK
+		if !ready {
+			code = http.StatusServiceUnavailable
+		}
+		respondWithJSON(w, code, map[string]interface{}{"ready": ready, "resources": statuses})
+	}
+}
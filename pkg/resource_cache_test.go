@@ -0,0 +1,224 @@
+// pkg/resource_cache_test.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// newCachedTestClients returns fake clients with a ResourceCache for which
+// the API server serves Forklift plans and nothing else.
+func newCachedTestClients(t *testing.T, objects ...runtime.Object) *K8sClients {
+	t.Helper()
+	clients := newTestClientsWithDynamic(nil, objects...)
+	clients.Clientset.Discovery().(*fakediscovery.FakeDiscovery).Resources = []*metav1.APIResourceList{
+		{GroupVersion: "forklift.konveyor.io/v1beta1", APIResources: []metav1.APIResource{{Name: "plans", Namespaced: true}}},
+	}
+	stop := make(chan struct{})
+	t.Cleanup(func() { close(stop) })
+	clients.Cache = NewResourceCache(clients.Dynamic, clients.Clientset.Discovery(), stop)
+	return clients
+}
+
+func waitForCache(t *testing.T, clients *K8sClients, ready func([]ResourceCacheStatus, bool) bool) []ResourceCacheStatus {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if statuses, ok := clients.Cache.Status(); ready(statuses, ok) {
+			return statuses
+		}
+	}
+	statuses, ok := clients.Cache.Status()
+	t.Fatalf("timed out waiting for the cache: ready=%v %+v", ok, statuses)
+	return nil
+}
+
+func newCacheTestObject(apiVersion, kind, namespace, name string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"metadata":   map[string]interface{}{"name": name, "namespace": namespace, "labels": map[string]interface{}{"tier": name}},
+	}}
+}
+
+func TestResourceCache(t *testing.T) {
+	clients := newCachedTestClients(t,
+		newCacheTestObject("forklift.konveyor.io/v1beta1", "Plan", "vms", "web"),
+		newCacheTestObject("forklift.konveyor.io/v1beta1", "Plan", "apps", "db"),
+		newCacheTestObject("migration.harvesterhci.io/v1beta1", "VirtualMachineImport", "vms", "old"),
+	)
+	fakeDynamic := clients.Dynamic.(*dynamicfake.FakeDynamicClient)
+	ctx := context.Background()
+
+	// The first reads go to the API server while the caches start.
+	if list, err := clients.listResources(ctx, forkliftPlanGVR, "", metav1.ListOptions{}); err != nil || len(list.Items) != 2 {
+		t.Fatalf("expected 2 plans, got %v (%v)", list, err)
+	}
+	if _, err := clients.getResource(ctx, vmiGVR, "vms", "old"); err != nil {
+		t.Fatal(err)
+	}
+	statuses := waitForCache(t, clients, func(s []ResourceCacheStatus, ready bool) bool {
+		return ready && len(s) == 2 && s[0].Synced && s[1].Message != ""
+	})
+	if s := statuses[1]; s.Resource != vmiGVR.String() || s.Available || s.Message != "Not served by the API server" {
+		t.Errorf("expected VirtualMachineImports reported as not cached, got %+v", s)
+	}
+
+	fakeDynamic.ClearActions()
+	list, err := clients.listResources(ctx, forkliftPlanGVR, "", metav1.ListOptions{})
+	if err != nil || len(list.Items) != 2 || list.Items[0].GetName() != "db" || list.Items[1].GetName() != "web" {
+		t.Fatalf("expected both plans sorted by namespace, got %v (%v)", list, err)
+	}
+	if list, _ := clients.listResources(ctx, forkliftPlanGVR, "vms", metav1.ListOptions{LabelSelector: "tier=web"}); len(list.Items) != 1 {
+		t.Errorf("expected the label selector applied, got %d plans", len(list.Items))
+	}
+	plan, err := clients.getResource(ctx, forkliftPlanGVR, "vms", "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan.SetLabels(nil)
+	if again, _ := clients.getResource(ctx, forkliftPlanGVR, "vms", "web"); again.GetLabels()["tier"] != "web" {
+		t.Error("expected callers to get a copy of the cached object")
+	}
+	if _, err := clients.getResource(ctx, forkliftPlanGVR, "vms", "nope"); !apierrors.IsNotFound(err) {
+		t.Errorf("expected NotFound from the cache, got %v", err)
+	}
+	for _, action := range fakeDynamic.Actions() {
+		if action.GetResource() == forkliftPlanGVR {
+			t.Errorf("expected plan reads served from the cache, got %s", action.GetVerb())
+		}
+	}
+
+	// The cache follows changes.
+	if _, err := clients.Dynamic.Resource(forkliftPlanGVR).Namespace("vms").Create(ctx, newCacheTestObject("forklift.konveyor.io/v1beta1", "Plan", "vms", "cache"), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if _, err := clients.getResource(ctx, forkliftPlanGVR, "vms", "cache"); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the cache to see the new plan")
+		}
+	}
+}
+
+func TestReadinessHandler(t *testing.T) {
+	clients := newCachedTestClients(t, newCacheTestObject("forklift.konveyor.io/v1beta1", "Plan", "vms", "web"))
+	release := make(chan struct{})
+	released := false
+	defer func() {
+		if !released {
+			close(release)
+		}
+	}()
+	clients.Dynamic.(*dynamicfake.FakeDynamicClient).PrependReactor("list", "plans", func(k8stesting.Action) (bool, runtime.Object, error) {
+		<-release
+		return false, nil, nil
+	})
+
+	decode := func(t *testing.T, code int) {
+		t.Helper()
+		rr := executeRequest(ReadinessHandler(clients), "GET", "/readyz", nil, nil)
+		var body struct {
+			Ready     bool                  `json:"ready"`
+			Resources []ResourceCacheStatus `json:"resources"`
+		}
+		json.Unmarshal(rr.Body.Bytes(), &body)
+		if rr.Code != code || body.Ready != (code == http.StatusOK) {
+			t.Fatalf("expected %d, got %d: %s", code, rr.Code, rr.Body.String())
+		}
+	}
+	decode(t, http.StatusOK)
+
+	// An informer started by a read does not hold readiness back.
+	clients.Cache.lister(forkliftPlanGVR)
+	waitForCache(t, clients, func(s []ResourceCacheStatus, _ bool) bool { return len(s) == 1 && s[0].Available })
+	decode(t, http.StatusOK)
+
+	// The informers Warm starts do.
+	warmed := newCachedTestClients(t, newCacheTestObject("forklift.konveyor.io/v1beta1", "Plan", "vms", "web"))
+	warmed.Dynamic.(*dynamicfake.FakeDynamicClient).PrependReactor("list", "plans", func(k8stesting.Action) (bool, runtime.Object, error) {
+		<-release
+		return false, nil, nil
+	})
+	warmed.Cache.Warm()
+	clients = warmed
+	waitForCache(t, clients, func(s []ResourceCacheStatus, _ bool) bool {
+		for _, status := range s {
+			if status.Resource == forkliftPlanGVR.String() {
+				return status.Available
+			}
+		}
+		return false
+	})
+	decode(t, http.StatusServiceUnavailable)
+
+	close(release)
+	released = true
+	waitForCache(t, clients, func(_ []ResourceCacheStatus, ready bool) bool { return ready })
+	decode(t, http.StatusOK)
+
+	if rr := executeRequest(ReadinessHandler(nil), "GET", "/readyz", nil, nil); rr.Code != http.StatusOK {
+		t.Errorf("expected mock mode to be ready, got %d", rr.Code)
+	}
+}
+
+func TestResourceCacheTracksWrites(t *testing.T) {
+	clients := newCachedTestClients(t, newCacheTestObject("forklift.konveyor.io/v1beta1", "Plan", "vms", "web"))
+	var elapsed atomic.Int64
+	clients.Cache.now = func() time.Time { return time.Unix(0, 0).Add(time.Duration(elapsed.Load())) }
+	// The informer lists but never hears of changes.
+	clients.Dynamic.(*dynamicfake.FakeDynamicClient).PrependWatchReactor("plans", func(k8stesting.Action) (bool, watch.Interface, error) {
+		return true, watch.NewFake(), nil
+	})
+	clients.Cache.lister(forkliftPlanGVR)
+	waitForCache(t, clients, func(s []ResourceCacheStatus, _ bool) bool { return len(s) == 1 && s[0].Synced })
+	tracked := clients.Cache.TrackWrites(clients.Dynamic)
+	ctx := context.Background()
+	names := func() []string {
+		t.Helper()
+		list, err := clients.listResources(ctx, forkliftPlanGVR, "vms", metav1.ListOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, item := range list.Items {
+			names = append(names, item.GetName())
+		}
+		return names
+	}
+
+	plan := newCacheTestObject("forklift.konveyor.io/v1beta1", "Plan", "vms", "db")
+	plan.SetResourceVersion("7")
+	if _, err := tracked.Resource(forkliftPlanGVR).Namespace("vms").Create(ctx, plan, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := names(); len(got) != 2 {
+		t.Errorf("expected the new plan read from the API server, got %v", got)
+	}
+	if err := tracked.Resource(forkliftPlanGVR).Namespace("vms").Delete(ctx, "web", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := names(); len(got) != 1 || got[0] != "db" {
+		t.Errorf("expected the deletion read from the API server, got %v", got)
+	}
+
+	// An informer that never catches up is trusted again after cacheWriteWait.
+	elapsed.Store(int64(cacheWriteWait))
+	if got := names(); len(got) != 1 || got[0] != "web" {
+		t.Errorf("expected the stale cache served again, got %v", got)
+	}
+}
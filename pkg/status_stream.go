@@ -0,0 +1,405 @@
+// pkg/status_stream.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// statusStreamResources are the resources StatusStreamHandler watches, by the
+// name clients use in ?resources= and that events carry.
+var statusStreamResources = map[string]schema.GroupVersionResource{
+	"plans":         vmiGVR,
+	"forkliftplans": forkliftPlanGVR,
+	"migrations":    forkliftMigrationGVR,
+}
+
+var defaultStatusStreamResources = []string{"plans", "forkliftplans", "migrations"}
+
+const (
+	// statusStreamErrorEvent reports a list or watch the backend could not
+	// keep up; the stream itself stays open and retries.
+	statusStreamErrorEvent = "streamerror"
+	// statusStreamUnavailableEvent reports, once, a resource the API server
+	// does not serve (Forklift or the vm-import-controller is not installed);
+	// it is not watched.
+	statusStreamUnavailableEvent = "unavailable"
+	// statusStreamHeartbeat keeps idle streams from being closed by proxies.
+	statusStreamHeartbeat = 20 * time.Second
+	// statusStreamRetry is how long a disconnected watch waits before retrying.
+	statusStreamRetry = 5 * time.Second
+)
+
+// StatusEvent is the data of one Server-Sent Event. Sync carries the full
+// list of a resource (Items), sent when a stream starts and whenever it could
+// not resume; added, modified and deleted carry one Object; progress carries
+// the VMs whose progress changed; bookmark, streamerror and unavailable carry
+// neither.
+// Failures are sent as streamerror rather than error, which EventSource would
+// take for a broken connection.
+type StatusEvent struct {
+	Type            string                   `json:"-"`
+	Resource        string                   `json:"resource"`
+	ResourceVersion string                   `json:"resourceVersion,omitempty"`
+	Namespace       string                   `json:"namespace,omitempty"`
+	Name            string                   `json:"name,omitempty"`
+	Plan            string                   `json:"plan,omitempty"`
+	Object          map[string]interface{}   `json:"object,omitempty"`
+	Items           []map[string]interface{} `json:"items,omitempty"`
+	VMs             []VMProgress             `json:"vms,omitempty"`
+	Message         string                   `json:"message,omitempty"`
+}
+
+// VMProgress is where one VM of a Forklift Migration or a VirtualMachineImport
+// stands. Completed and Total add up the progress of every pipeline step.
+type VMProgress struct {
+	ID        string `json:"id,omitempty"`
+	Name      string `json:"name"`
+	Phase     string `json:"phase,omitempty"`
+	Step      string `json:"step,omitempty"`
+	Completed int64  `json:"completed"`
+	Total     int64  `json:"total"`
+	Error     string `json:"error,omitempty"`
+}
+
+// parseStreamVersions reads the per-resource resourceVersions a client last
+// saw from an event id ("plans=12&migrations=40").
+func parseStreamVersions(id string) map[string]string {
+	versions := map[string]string{}
+	values, err := url.ParseQuery(id)
+	if err != nil {
+		return versions
+	}
+	for resource := range statusStreamResources {
+		if v := values.Get(resource); v != "" {
+			versions[resource] = v
+		}
+	}
+	return versions
+}
+
+func formatStreamVersions(versions map[string]string) string {
+	values := url.Values{}
+	for resource, v := range versions {
+		if v != "" {
+			values.Set(resource, v)
+		}
+	}
+	return values.Encode()
+}
+
+// vmProgress reads the per-VM progress of a Migration (status.vms[].pipeline)
+// or a VirtualMachineImport (one VM, status.importStatus).
+func vmProgress(resource string, obj *unstructured.Unstructured) []VMProgress {
+	if resource == "plans" {
+		name, _, _ := unstructured.NestedString(obj.Object, "spec", "virtualMachineName")
+		phase, _, _ := unstructured.NestedString(obj.Object, "status", "importStatus")
+		if phase == "" {
+			return nil
+		}
+		return []VMProgress{{Name: name, Phase: phase}}
+	}
+
+	vms, _, _ := unstructured.NestedSlice(obj.Object, "status", "vms")
+	var result []VMProgress
+	for _, v := range vms {
+		vm, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		p := VMProgress{}
+		p.ID, _, _ = unstructured.NestedString(vm, "id")
+		p.Name, _, _ = unstructured.NestedString(vm, "name")
+		p.Phase, _, _ = unstructured.NestedString(vm, "phase")
+		pipeline, _, _ := unstructured.NestedSlice(vm, "pipeline")
+		for _, s := range pipeline {
+			step, ok := s.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			completed, _, _ := unstructured.NestedInt64(step, "progress", "completed")
+			total, _, _ := unstructured.NestedInt64(step, "progress", "total")
+			p.Completed += completed
+			p.Total += total
+			if phase, _, _ := unstructured.NestedString(step, "phase"); phase == "Running" {
+				p.Step, _, _ = unstructured.NestedString(step, "name")
+			}
+			if reasons, _, _ := unstructured.NestedStringSlice(step, "error", "reasons"); len(reasons) > 0 && p.Error == "" {
+				p.Error = reasons[0]
+			}
+		}
+		result = append(result, p)
+	}
+	return result
+}
+
+// progressTracker remembers the last progress sent for every object so only
+// VMs that moved are pushed.
+type progressTracker map[string]map[string]VMProgress
+
+func (t progressTracker) key(resource string, obj *unstructured.Unstructured) string {
+	return resource + "/" + obj.GetNamespace() + "/" + obj.GetName()
+}
+
+// update records obj's progress and returns the VMs that changed since the
+// last call for the same object.
+func (t progressTracker) update(resource string, obj *unstructured.Unstructured) []VMProgress {
+	key := t.key(resource, obj)
+	previous := t[key]
+	current := map[string]VMProgress{}
+	var changed []VMProgress
+	for _, p := range vmProgress(resource, obj) {
+		id := p.ID + "/" + p.Name
+		current[id] = p
+		if previous[id] != p {
+			changed = append(changed, p)
+		}
+	}
+	t[key] = current
+	return changed
+}
+
+// watchStatusResource sends a sync event with the resource's full list (unless
+// resuming from resourceVersion), then its watch events, until ctx ends. A
+// watch that closes is reopened from the last version seen; one that is too
+// old to resume starts over with a fresh list, and one that fails is retried
+// after statusStreamRetry.
+func watchStatusResource(ctx context.Context, clients *K8sClients, resource string, gvr schema.GroupVersionResource, resourceVersion string, out chan<- StatusEvent) {
+	send := func(ev StatusEvent) bool {
+		ev.Resource = resource
+		select {
+		case out <- ev:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	wait := func() bool {
+		select {
+		case <-time.After(statusStreamRetry):
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	needList := resourceVersion == ""
+	for ctx.Err() == nil {
+		if needList {
+			list, err := clients.Dynamic.Resource(gvr).Namespace("").List(ctx, metav1.ListOptions{})
+			if err != nil {
+				if !send(StatusEvent{Type: statusStreamErrorEvent, Message: fmt.Sprintf("Failed to list %s: %v", resource, err)}) || !wait() {
+					return
+				}
+				continue
+			}
+			items := make([]map[string]interface{}, 0, len(list.Items))
+			for _, item := range list.Items {
+				items = append(items, item.Object)
+			}
+			resourceVersion = list.GetResourceVersion()
+			if !send(StatusEvent{Type: "sync", ResourceVersion: resourceVersion, Items: items}) {
+				return
+			}
+			needList = false
+		}
+
+		w, err := clients.Dynamic.Resource(gvr).Namespace("").Watch(ctx, metav1.ListOptions{ResourceVersion: resourceVersion, AllowWatchBookmarks: true})
+		if err != nil {
+			if apierrors.IsResourceExpired(err) || apierrors.IsGone(err) {
+				needList = true
+				continue
+			}
+			if !send(StatusEvent{Type: statusStreamErrorEvent, Message: fmt.Sprintf("Failed to watch %s: %v", resource, err)}) || !wait() {
+				return
+			}
+			continue
+		}
+		resumable, err := relayWatch(ctx, w, &resourceVersion, send)
+		w.Stop()
+		needList = !resumable
+		if err != nil {
+			log.Warnf("Status stream watch of %s failed: %v", resource, err)
+			if !send(StatusEvent{Type: statusStreamErrorEvent, Message: fmt.Sprintf("Watch of %s failed: %v", resource, err)}) || !wait() {
+				return
+			}
+		}
+	}
+}
+
+// relayWatch forwards the events of w until it closes. It returns false if
+// the watch ended because its resourceVersion expired, and the error if it
+// ended with any other watch error.
+func relayWatch(ctx context.Context, w watch.Interface, resourceVersion *string, send func(StatusEvent) bool) (bool, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return true, nil
+		case ev, ok := <-w.ResultChan():
+			if !ok {
+				return true, nil
+			}
+			if ev.Type == watch.Error {
+				err := apierrors.FromObject(ev.Object)
+				if apierrors.IsResourceExpired(err) || apierrors.IsGone(err) {
+					return false, nil
+				}
+				return true, err
+			}
+			obj, ok := ev.Object.(*unstructured.Unstructured)
+			if !ok {
+				continue
+			}
+			if v := obj.GetResourceVersion(); v != "" {
+				*resourceVersion = v
+			}
+			event := StatusEvent{Type: strings.ToLower(string(ev.Type)), ResourceVersion: *resourceVersion}
+			if ev.Type != watch.Bookmark {
+				event.Namespace, event.Name, event.Object = obj.GetNamespace(), obj.GetName(), obj.Object
+			}
+			if !send(event) {
+				return true, nil
+			}
+		}
+	}
+}
+
+// writeStatusEvent writes ev as one Server-Sent Event with the given id.
+func writeStatusEvent(w http.ResponseWriter, id string, ev StatusEvent) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", id, ev.Type, data)
+	return err
+}
+
+// StatusStreamHandler streams changes to VirtualMachineImports, Forklift Plans
+// and Migrations as Server-Sent Events. ?resources= picks a subset of plans,
+// forkliftplans and migrations. Every event id records the resourceVersion
+// reached for each resource, so a client reconnecting with Last-Event-ID (or
+// ?since=) resumes where it left off instead of receiving the lists again.
+// Resources the API server does not serve are looked up once, reported as
+// unavailable and left out.
+func StatusStreamHandler(clients *K8sClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			respondWithError(w, http.StatusInternalServerError, "Streaming is not supported")
+			return
+		}
+		resources := queryList(r, "resources")
+		if len(resources) == 0 {
+			resources = defaultStatusStreamResources
+		}
+		for _, resource := range resources {
+			if _, ok := statusStreamResources[resource]; !ok {
+				respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Unknown resource %q; expected one of %s", resource, strings.Join(defaultStatusStreamResources, ", ")))
+				return
+			}
+		}
+		since := r.Header.Get("Last-Event-ID")
+		if since == "" {
+			since = r.URL.Query().Get("since")
+		}
+		versions := parseStreamVersions(since)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "retry: %d\n\n", statusStreamRetry.Milliseconds())
+		flusher.Flush()
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		events := make(chan StatusEvent)
+		for _, resource := range resources {
+			gvr := statusStreamResources[resource]
+			served, err := resourceServed(clients.Clientset.Discovery(), gvr)
+			if err != nil {
+				// Let the watch report and retry what discovery could not tell.
+				log.Debugf("Could not look up %s for the status stream: %v", gvr.String(), err)
+			} else if !served {
+				ev := StatusEvent{Type: statusStreamUnavailableEvent, Resource: resource, Message: fmt.Sprintf("%s is not served by the API server", gvr.GroupResource())}
+				if err := writeStatusEvent(w, formatStreamVersions(versions), ev); err != nil {
+					return
+				}
+				continue
+			}
+			go watchStatusResource(ctx, clients, resource, gvr, versions[resource], events)
+		}
+		flusher.Flush()
+		log.Debugf("Status stream opened for %v (resuming from %q)", resources, since)
+
+		tracker := progressTracker{}
+		heartbeat := time.NewTicker(statusStreamHeartbeat)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				log.Debugf("Status stream for %v closed", resources)
+				return
+			case <-heartbeat.C:
+				if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+					return
+				}
+			case ev := <-events:
+				if ev.ResourceVersion != "" {
+					versions[ev.Resource] = ev.ResourceVersion
+				}
+				id := formatStreamVersions(versions)
+				if err := writeStatusEvent(w, id, ev); err != nil {
+					return
+				}
+				for _, progress := range statusProgressEvents(tracker, ev) {
+					if err := writeStatusEvent(w, id, progress); err != nil {
+						return
+					}
+				}
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// statusProgressEvents updates tracker with ev and returns the progress events
+// it causes. Listed objects only seed the tracker: the client has them whole.
+func statusProgressEvents(tracker progressTracker, ev StatusEvent) []StatusEvent {
+	switch ev.Type {
+	case "sync":
+		for key := range tracker {
+			if strings.HasPrefix(key, ev.Resource+"/") {
+				delete(tracker, key)
+			}
+		}
+		for _, item := range ev.Items {
+			tracker.update(ev.Resource, &unstructured.Unstructured{Object: item})
+		}
+	case "deleted":
+		delete(tracker, tracker.key(ev.Resource, &unstructured.Unstructured{Object: ev.Object}))
+	case "added", "modified":
+		obj := &unstructured.Unstructured{Object: ev.Object}
+		if changed := tracker.update(ev.Resource, obj); len(changed) > 0 {
+			plan := obj.GetName()
+			if ev.Resource == "migrations" {
+				plan, _, _ = unstructured.NestedString(obj.Object, "spec", "plan", "name")
+			}
+			return []StatusEvent{{Type: "progress", Resource: ev.Resource, Namespace: obj.GetNamespace(), Name: obj.GetName(), Plan: plan, VMs: changed}}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,291 @@
+// pkg/status_stream_test.go
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+type sseEvent struct {
+	id, event string
+	data      StatusEvent
+}
+
+// openStatusStream connects to StatusStreamHandler and returns its parsed events.
+func openStatusStream(t *testing.T, server *httptest.Server, query, lastEventID string) <-chan sseEvent {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/api/v1/status/stream"+query, nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	events := make(chan sseEvent, 16)
+	go func() {
+		defer resp.Body.Close()
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(nil, 1<<20)
+		var ev sseEvent
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "id: "):
+				ev.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				ev.event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &ev.data)
+			case line == "" && ev.event != "":
+				events <- ev
+				ev = sseEvent{}
+			}
+		}
+	}()
+	return events
+}
+
+func nextStatusEvent(t *testing.T, events <-chan sseEvent) sseEvent {
+	t.Helper()
+	select {
+	case ev := <-events:
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a status event")
+		return sseEvent{}
+	}
+}
+
+// waitForWatches waits until the fake client has n watches open, so changes
+// made afterwards reach the stream.
+func waitForWatches(t *testing.T, fakeDynamic *dynamicfake.FakeDynamicClient, n int) []k8stesting.WatchAction {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		var watches []k8stesting.WatchAction
+		for _, action := range fakeDynamic.Actions() {
+			if w, ok := action.(k8stesting.WatchAction); ok {
+				watches = append(watches, w)
+			}
+		}
+		if len(watches) >= n {
+			return watches
+		}
+	}
+	t.Fatalf("timed out waiting for %d watches", n)
+	return nil
+}
+
+func newStreamMigration(completed int64) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "forklift.konveyor.io/v1beta1",
+		"kind":       "Migration",
+		"metadata":   map[string]interface{}{"name": "db-migration", "namespace": "vms", "resourceVersion": "7"},
+		"spec":       map[string]interface{}{"plan": map[string]interface{}{"name": "db", "namespace": "vms"}},
+		"status": map[string]interface{}{"vms": []interface{}{
+			map[string]interface{}{"id": "vm-1", "name": "db-1", "phase": "CopyDisks", "pipeline": []interface{}{
+				map[string]interface{}{"name": "Initialize", "phase": "Completed", "progress": map[string]interface{}{"completed": int64(1), "total": int64(1)}},
+				map[string]interface{}{"name": "DiskTransfer", "phase": "Running", "progress": map[string]interface{}{"completed": completed, "total": int64(100)}},
+			}},
+			map[string]interface{}{"id": "vm-2", "name": "db-2", "phase": "Completed"},
+		}},
+	}}
+}
+
+func TestStreamVersions(t *testing.T) {
+	id := formatStreamVersions(map[string]string{"plans": "12", "migrations": "40", "forkliftplans": ""})
+	if id != "migrations=40&plans=12" {
+		t.Errorf("unexpected event id %q", id)
+	}
+	if got := parseStreamVersions(id + "&pods=3"); len(got) != 2 || got["plans"] != "12" || got["migrations"] != "40" {
+		t.Errorf("unexpected versions %v", got)
+	}
+}
+
+func TestStatusProgressEvents(t *testing.T) {
+	tracker := progressTracker{}
+	statusProgressEvents(tracker, StatusEvent{Type: "sync", Resource: "migrations", Items: []map[string]interface{}{newStreamMigration(10).Object}})
+	if events := statusProgressEvents(tracker, StatusEvent{Type: "modified", Resource: "migrations", Object: newStreamMigration(10).Object}); len(events) != 0 {
+		t.Errorf("expected no progress event without a change, got %+v", events)
+	}
+	events := statusProgressEvents(tracker, StatusEvent{Type: "modified", Resource: "migrations", Object: newStreamMigration(55).Object})
+	if len(events) != 1 || events[0].Plan != "db" || len(events[0].VMs) != 1 {
+		t.Fatalf("expected one progress event for one VM, got %+v", events)
+	}
+	if vm := events[0].VMs[0]; vm.ID != "vm-1" || vm.Step != "DiskTransfer" || vm.Completed != 56 || vm.Total != 101 {
+		t.Errorf("unexpected VM progress %+v", vm)
+	}
+}
+
+func TestStatusStreamHandler(t *testing.T) {
+	plan := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "forklift.konveyor.io/v1beta1",
+		"kind":       "Plan",
+		"metadata":   map[string]interface{}{"name": "db", "namespace": "vms", "resourceVersion": "5"},
+	}}
+	clients := newTestClientsWithDynamic(nil, plan, newStreamMigration(10))
+	clients.Clientset.Discovery().(*fakediscovery.FakeDiscovery).Resources = []*metav1.APIResourceList{
+		{GroupVersion: "forklift.konveyor.io/v1beta1", APIResources: []metav1.APIResource{{Name: "plans"}, {Name: "migrations"}}},
+	}
+	fakeDynamic := clients.Dynamic.(*dynamicfake.FakeDynamicClient)
+	server := httptest.NewServer(StatusStreamHandler(clients))
+	t.Cleanup(server.Close)
+	ctx := context.Background()
+
+	rr := executeRequest(StatusStreamHandler(clients), "GET", "/api/v1/status/stream?resources=pods", nil, nil)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown resource, got %d", rr.Code)
+	}
+
+	events := openStatusStream(t, server, "?resources=forkliftplans,migrations", "")
+	synced := map[string]int{}
+	for i := 0; i < 2; i++ {
+		ev := nextStatusEvent(t, events)
+		if ev.event != "sync" {
+			t.Fatalf("expected sync events first, got %q", ev.event)
+		}
+		synced[ev.data.Resource] = len(ev.data.Items)
+	}
+	if synced["forkliftplans"] != 1 || synced["migrations"] != 1 {
+		t.Fatalf("unexpected sync events %v", synced)
+	}
+	waitForWatches(t, fakeDynamic, 2)
+
+	updated := newStreamMigration(60)
+	updated.SetResourceVersion("8")
+	if _, err := clients.Dynamic.Resource(forkliftMigrationGVR).Namespace("vms").Update(ctx, updated, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if ev := nextStatusEvent(t, events); ev.event != "modified" || ev.data.Name != "db-migration" || !strings.Contains(ev.id, "migrations=8") {
+		t.Fatalf("expected the Migration's update, got %+v", ev)
+	}
+	ev := nextStatusEvent(t, events)
+	if ev.event != "progress" || ev.data.Plan != "db" || len(ev.data.VMs) != 1 || ev.data.VMs[0].Completed != 61 {
+		t.Fatalf("expected a progress delta for vm-1, got %+v", ev)
+	}
+
+	if err := clients.Dynamic.Resource(forkliftPlanGVR).Namespace("vms").Delete(ctx, "db", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if ev := nextStatusEvent(t, events); ev.event != "deleted" || ev.data.Resource != "forkliftplans" || ev.data.Name != "db" {
+		t.Fatalf("expected the Plan's deletion, got %+v", ev)
+	}
+
+	// Resuming watches from the versions in Last-Event-ID without listing.
+	// The Migration watch is too old to resume and falls back to a fresh list.
+	fakeDynamic.ClearActions()
+	expired := true
+	fakeDynamic.PrependWatchReactor("migrations", func(k8stesting.Action) (bool, watch.Interface, error) {
+		if expired {
+			expired = false
+			return true, nil, apierrors.NewResourceExpired("too old resource version: 8")
+		}
+		return false, nil, nil
+	})
+	events = openStatusStream(t, server, "?resources=forkliftplans,migrations", "forkliftplans=5&migrations=8")
+	if ev := nextStatusEvent(t, events); ev.event != "sync" || ev.data.Resource != "migrations" {
+		t.Fatalf("expected only the expired resource to be listed again, got %+v", ev)
+	}
+	for _, w := range waitForWatches(t, fakeDynamic, 3) {
+		if w.GetResource().Resource == "plans" && w.GetWatchRestrictions().ResourceVersion != "5" {
+			t.Errorf("expected the Plan watch to resume from 5, got %q", w.GetWatchRestrictions().ResourceVersion)
+		}
+	}
+	if _, err := clients.Dynamic.Resource(forkliftPlanGVR).Namespace("vms").Create(ctx, plan, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if ev := nextStatusEvent(t, events); ev.event != "added" || ev.data.Resource != "forkliftplans" {
+		t.Fatalf("expected the Plan's creation, got %+v", ev)
+	}
+}
+
+func TestStatusStreamUnservedResources(t *testing.T) {
+	// Forklift is installed, the vm-import-controller is not.
+	clients := newTestClientsWithDynamic(nil, newStreamMigration(10))
+	clients.Clientset.Discovery().(*fakediscovery.FakeDiscovery).Resources = []*metav1.APIResourceList{
+		{GroupVersion: "forklift.konveyor.io/v1beta1", APIResources: []metav1.APIResource{{Name: "migrations"}}},
+	}
+	fakeDynamic := clients.Dynamic.(*dynamicfake.FakeDynamicClient)
+	server := httptest.NewServer(StatusStreamHandler(clients))
+	t.Cleanup(server.Close)
+
+	events := openStatusStream(t, server, "", "")
+	got := map[string]string{}
+	for i := 0; i < 3; i++ {
+		ev := nextStatusEvent(t, events)
+		got[ev.data.Resource] = ev.event
+	}
+	if got["plans"] != statusStreamUnavailableEvent || got["forkliftplans"] != statusStreamUnavailableEvent || got["migrations"] != "sync" {
+		t.Fatalf("expected plans and forkliftplans unavailable and migrations listed, got %v", got)
+	}
+	waitForWatches(t, fakeDynamic, 1)
+	select {
+	case ev := <-events:
+		t.Errorf("expected nothing more for unserved resources, got %+v", ev)
+	case <-time.After(200 * time.Millisecond):
+	}
+	for _, action := range fakeDynamic.Actions() {
+		if action.GetResource().Resource != "migrations" {
+			t.Errorf("expected only migrations to be read, got %s %s", action.GetVerb(), action.GetResource().Resource)
+		}
+	}
+}
+
+func TestWatchStatusResourceErrors(t *testing.T) {
+	clients := newTestClientsWithDynamic(nil)
+	fakeDynamic := clients.Dynamic.(*dynamicfake.FakeDynamicClient)
+	fakeDynamic.PrependWatchReactor("virtualmachineimports", func(k8stesting.Action) (bool, watch.Interface, error) {
+		w := watch.NewFakeWithChanSize(1, false)
+		w.Error(&apierrors.NewInternalError(errors.New("etcd unavailable")).ErrStatus)
+		return true, w, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := make(chan StatusEvent)
+	go watchStatusResource(ctx, clients, "plans", vmiGVR, "1", out)
+	next := func() StatusEvent {
+		t.Helper()
+		select {
+		case ev := <-out:
+			return ev
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for a status event")
+			return StatusEvent{}
+		}
+	}
+
+	if ev := next(); ev.Type != statusStreamErrorEvent || !strings.Contains(ev.Message, "etcd unavailable") {
+		t.Fatalf("expected the watch error as a %s event, got %+v", statusStreamErrorEvent, ev)
+	}
+	time.Sleep(200 * time.Millisecond)
+	watches := 0
+	for _, action := range fakeDynamic.Actions() {
+		if action.GetVerb() == "watch" {
+			watches++
+		}
+	}
+	if watches != 1 {
+		t.Errorf("expected the failed watch to back off before retrying, got %d watches", watches)
+	}
+}
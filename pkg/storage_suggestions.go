@@ -0,0 +1,404 @@
+// pkg/storage_suggestions.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	longhornProvisioner = "driver.longhorn.io"
+	longhornNamespace   = "longhorn-system"
+
+	// defaultLonghornReplicas is what Longhorn uses when a class does not set
+	// numberOfReplicas.
+	defaultLonghornReplicas = 3
+
+	// capacityHeadroom is the share of usable capacity a mapping may use
+	// before it is marked down for leaving the class nearly full.
+	capacityHeadroom = 0.8
+)
+
+var (
+	longhornNodeGVR = schema.GroupVersionResource{
+		Group:    "longhorn.io",
+		Version:  "v1beta2",
+		Resource: "nodes",
+	}
+	longhornSettingGVR = schema.GroupVersionResource{
+		Group:    "longhorn.io",
+		Version:  "v1beta2",
+		Resource: "settings",
+	}
+)
+
+// StorageClassCandidate rates one StorageClass as the destination of a set of
+// disks. UsableBytes is only set when the class's free capacity is known.
+type StorageClassCandidate struct {
+	StorageClass string   `json:"storageClass"`
+	Provisioner  string   `json:"provisioner"`
+	Score        int      `json:"score"`
+	Default      bool     `json:"default,omitempty"`
+	Replicas     int      `json:"replicas,omitempty"`
+	UsableBytes  *int64   `json:"usableBytes,omitempty"`
+	VolumeMode   string   `json:"volumeMode,omitempty"`
+	AccessMode   string   `json:"accessMode,omitempty"`
+	Reasons      []string `json:"reasons"`
+}
+
+// StorageMappingSuggestion proposes a StorageClass for the selected VMs' disks
+// on one datastore. Candidates are ordered best first; classes that cannot
+// take the disks are listed under Rejected with the reason.
+type StorageMappingSuggestion struct {
+	DatastoreID   string                  `json:"datastoreId,omitempty"`
+	DatastoreName string                  `json:"datastoreName,omitempty"`
+	RequiredBytes int64                   `json:"requiredBytes"`
+	Disks         []string                `json:"disks"`
+	Shared        bool                    `json:"shared,omitempty"`
+	Suggested     string                  `json:"suggested,omitempty"`
+	VolumeMode    string                  `json:"volumeMode,omitempty"`
+	AccessMode    string                  `json:"accessMode,omitempty"`
+	Candidates    []StorageClassCandidate `json:"candidates"`
+	Rejected      []StorageClassCandidate `json:"rejected"`
+}
+
+// StorageMappingSuggestions holds a suggestion per source datastore, for
+// Forklift StorageMaps, and one for all disks together, for the single
+// StorageClass of a VirtualMachineImport. Warnings say why free capacity
+// could not be taken into account.
+type StorageMappingSuggestions struct {
+	Datastores []StorageMappingSuggestion `json:"datastores"`
+	Overall    StorageMappingSuggestion   `json:"overall"`
+	Warnings   []string                   `json:"warnings,omitempty"`
+}
+
+// longhornCapacity is the space Longhorn can put replicas on. AvailableBytes
+// is free disk space, what data actually fills; SchedulableBytes is how much
+// more volume size Longhorn accepts under its over-provisioning limit.
+type longhornCapacity struct {
+	Nodes                      int   `json:"nodes"`
+	SchedulableNodes           int   `json:"schedulableNodes"`
+	MaximumBytes               int64 `json:"maximumBytes"`
+	ReservedBytes              int64 `json:"reservedBytes"`
+	AvailableBytes             int64 `json:"availableBytes"`
+	ScheduledBytes             int64 `json:"scheduledBytes"`
+	SchedulableBytes           int64 `json:"schedulableBytes"`
+	OverProvisioningPercentage int64 `json:"overProvisioningPercentage"`
+	MinimalAvailablePercentage int64 `json:"minimalAvailablePercentage"`
+}
+
+// storageDisk is a selected VM disk, labelled "vm/disk" for the response.
+type storageDisk struct {
+	label string
+	disk  VMDisk
+}
+
+// getLonghornCapacity sums the free space, less the reserved space, of every
+// Longhorn disk that accepts replicas. It returns nil when Longhorn is not
+// installed, and an error when its nodes cannot be read.
+func getLonghornCapacity(ctx context.Context, clients *K8sClients) (*longhornCapacity, error) {
+	list, err := clients.Dynamic.Resource(longhornNodeGVR).Namespace(longhornNamespace).List(ctx, metav1.ListOptions{})
+	if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		log.Debugf("Longhorn is not installed, capacity will be unknown: %v", err)
+		return nil, nil
+	}
+	if err != nil {
+		log.Warnf("Could not list Longhorn nodes, capacity will be unknown: %v", err)
+		return nil, fmt.Errorf("could not list Longhorn nodes: %w", err)
+	}
+	capacity := &longhornCapacity{
+		Nodes:                      len(list.Items),
+		OverProvisioningPercentage: getLonghornSetting(ctx, clients, "storage-over-provisioning-percentage", 100),
+		MinimalAvailablePercentage: getLonghornSetting(ctx, clients, "storage-minimal-available-percentage", 25),
+	}
+	for _, node := range list.Items {
+		if allow, found, _ := unstructured.NestedBool(node.Object, "spec", "allowScheduling"); found && !allow {
+			continue
+		}
+		disks, _, _ := unstructured.NestedMap(node.Object, "spec", "disks")
+		statuses, _, _ := unstructured.NestedMap(node.Object, "status", "diskStatus")
+		var nodeAvailable int64
+		for name, d := range disks {
+			spec, _ := d.(map[string]interface{})
+			status, _ := statuses[name].(map[string]interface{})
+			if allow, found, _ := unstructured.NestedBool(spec, "allowScheduling"); found && !allow {
+				continue
+			}
+			available, _, _ := unstructured.NestedInt64(status, "storageAvailable")
+			maximum, _, _ := unstructured.NestedInt64(status, "storageMaximum")
+			scheduled, _, _ := unstructured.NestedInt64(status, "storageScheduled")
+			reserved, _, _ := unstructured.NestedInt64(spec, "storageReserved")
+			if free := available - reserved; free > 0 {
+				nodeAvailable += free
+			}
+			capacity.MaximumBytes += maximum
+			capacity.ReservedBytes += reserved
+			capacity.ScheduledBytes += scheduled
+			// Longhorn stops scheduling on a disk once its free space drops
+			// to the minimal available percentage.
+			if available*100 <= maximum*capacity.MinimalAvailablePercentage {
+				continue
+			}
+			if schedulable := (maximum-reserved)*capacity.OverProvisioningPercentage/100 - scheduled; schedulable > 0 {
+				capacity.SchedulableBytes += schedulable
+			}
+		}
+		if nodeAvailable > 0 {
+			capacity.AvailableBytes += nodeAvailable
+			capacity.SchedulableNodes++
+		}
+	}
+	return capacity, nil
+}
+
+// getLonghornSetting reads an integer Longhorn setting, falling back to def
+// when it is missing or unreadable.
+func getLonghornSetting(ctx context.Context, clients *K8sClients, name string, def int64) int64 {
+	setting, err := clients.Dynamic.Resource(longhornSettingGVR).Namespace(longhornNamespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return def
+	}
+	value, _, _ := unstructured.NestedString(setting.Object, "value")
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return def
+	}
+	return n
+}
+
+// suggestStorageMappings groups the VMs' disks by datastore and rates every
+// StorageClass for each group and for all disks together.
+func suggestStorageMappings(vms []InventoryNode, classes []storagev1.StorageClass, longhorn *longhornCapacity) StorageMappingSuggestions {
+	groups := map[string]*StorageMappingSuggestion{}
+	var order []string
+	overall := StorageMappingSuggestion{}
+	var all []storageDisk
+	byDatastore := map[string][]storageDisk{}
+
+	for _, vm := range vms {
+		for _, disk := range vm.Disks {
+			d := storageDisk{label: vm.Name + "/" + disk.Name, disk: disk}
+			g, ok := groups[disk.DatastoreID]
+			if !ok {
+				g = &StorageMappingSuggestion{DatastoreID: disk.DatastoreID, DatastoreName: disk.DatastoreName}
+				groups[disk.DatastoreID] = g
+				order = append(order, disk.DatastoreID)
+			}
+			byDatastore[disk.DatastoreID] = append(byDatastore[disk.DatastoreID], d)
+			all = append(all, d)
+		}
+	}
+	sort.Slice(order, func(i, j int) bool { return groups[order[i]].DatastoreName < groups[order[j]].DatastoreName })
+
+	result := StorageMappingSuggestions{Datastores: []StorageMappingSuggestion{}}
+	for _, id := range order {
+		g := groups[id]
+		rateStorageClasses(g, byDatastore[id], classes, longhorn)
+		result.Datastores = append(result.Datastores, *g)
+	}
+	rateStorageClasses(&overall, all, classes, longhorn)
+	result.Overall = overall
+	return result
+}
+
+// rateStorageClasses fills in s's size, disks and ranked classes.
+func rateStorageClasses(s *StorageMappingSuggestion, disks []storageDisk, classes []storagev1.StorageClass, longhorn *longhornCapacity) {
+	s.Disks = []string{}
+	s.Candidates = []StorageClassCandidate{}
+	s.Rejected = []StorageClassCandidate{}
+	for _, d := range disks {
+		s.RequiredBytes += d.disk.Capacity
+		s.Disks = append(s.Disks, d.label)
+		if d.disk.SharingMode == "sharingMultiWriter" {
+			s.Shared = true
+		}
+	}
+
+	for _, sc := range classes {
+		c, ok := rateStorageClass(sc, s.RequiredBytes, s.Shared, longhorn)
+		if ok {
+			s.Candidates = append(s.Candidates, c)
+		} else {
+			s.Rejected = append(s.Rejected, c)
+		}
+	}
+	sort.SliceStable(s.Candidates, func(i, j int) bool {
+		if s.Candidates[i].Score != s.Candidates[j].Score {
+			return s.Candidates[i].Score > s.Candidates[j].Score
+		}
+		return s.Candidates[i].StorageClass < s.Candidates[j].StorageClass
+	})
+	sort.SliceStable(s.Rejected, func(i, j int) bool { return s.Rejected[i].StorageClass < s.Rejected[j].StorageClass })
+	if len(s.Candidates) > 0 {
+		s.Suggested = s.Candidates[0].StorageClass
+		s.VolumeMode = s.Candidates[0].VolumeMode
+		s.AccessMode = s.Candidates[0].AccessMode
+	}
+}
+
+// rateStorageClass scores sc as the destination of required bytes of disk,
+// starting from 50. It reports false, with the reason, when sc cannot take
+// the disks at all.
+func rateStorageClass(sc storagev1.StorageClass, required int64, shared bool, longhorn *longhornCapacity) (StorageClassCandidate, bool) {
+	c := StorageClassCandidate{StorageClass: sc.Name, Provisioner: sc.Provisioner, Score: 50, Reasons: []string{}}
+	reject := func(reason string) (StorageClassCandidate, bool) {
+		c.Reasons = append(c.Reasons, reason)
+		c.Score = 0
+		return c, false
+	}
+
+	if sc.Provisioner == noProvisioner {
+		return reject("no dynamic provisioner: volumes would have to be created by hand")
+	}
+	if image := sc.Parameters["backingImage"]; image != "" {
+		return reject(fmt.Sprintf("reserved for volumes of VM image %s", image))
+	}
+
+	if isDefaultStorageClass(sc) {
+		c.Default = true
+		c.Score += 20
+		c.Reasons = append(c.Reasons, "cluster default StorageClass")
+	}
+
+	if sc.Provisioner == longhornProvisioner {
+		c.VolumeMode = "Block"
+		c.AccessMode = "ReadWriteOnce"
+		if sc.Parameters["migratable"] == "true" {
+			c.AccessMode = "ReadWriteMany"
+			c.Score += 10
+			c.Reasons = append(c.Reasons, "migratable: VMs can live-migrate")
+		} else {
+			c.Reasons = append(c.Reasons, "not migratable: VMs cannot live-migrate")
+		}
+
+		c.Replicas = defaultLonghornReplicas
+		if n, err := strconv.Atoi(sc.Parameters["numberOfReplicas"]); err == nil && n > 0 {
+			c.Replicas = n
+		}
+		switch {
+		case c.Replicas >= 3:
+			c.Score += 15
+			c.Reasons = append(c.Reasons, fmt.Sprintf("%d replicas", c.Replicas))
+		case c.Replicas == 2:
+			c.Score += 10
+			c.Reasons = append(c.Reasons, "2 replicas")
+		default:
+			c.Score -= 10
+			c.Reasons = append(c.Reasons, "single replica: no redundancy")
+		}
+
+		if longhorn != nil {
+			usable := longhorn.AvailableBytes / int64(c.Replicas)
+			c.UsableBytes = &usable
+			if longhorn.SchedulableNodes < c.Replicas {
+				c.Score -= 40
+				c.Reasons = append(c.Reasons, fmt.Sprintf("only %d node(s) can hold replicas, so volumes would run degraded", longhorn.SchedulableNodes))
+			}
+			if required > usable {
+				return reject(fmt.Sprintf("needs %s but only %s is free for %d-replica volumes", formatDiskSize(required), formatDiskSize(usable), c.Replicas))
+			}
+			if float64(required) > float64(usable)*capacityHeadroom {
+				c.Score -= 15
+				c.Reasons = append(c.Reasons, fmt.Sprintf("would use %s of the %s free, leaving less than %d%%", formatDiskSize(required), formatDiskSize(usable), int((1-capacityHeadroom)*100)))
+			} else {
+				c.Score += 5
+				c.Reasons = append(c.Reasons, fmt.Sprintf("needs %s of the %s free", formatDiskSize(required), formatDiskSize(usable)))
+			}
+		} else {
+			c.Score -= 5
+			c.Reasons = append(c.Reasons, "free capacity unknown: Longhorn nodes could not be read")
+		}
+	} else {
+		c.Score -= 5
+		c.Reasons = append(c.Reasons, fmt.Sprintf("free capacity unknown for provisioner %s; volume and access mode are left to the CDI StorageProfile", sc.Provisioner))
+	}
+
+	if shared && c.AccessMode != "ReadWriteMany" {
+		return reject("shared (multi-writer) disks need ReadWriteMany volumes")
+	}
+
+	if sc.VolumeBindingMode != nil && *sc.VolumeBindingMode == storagev1.VolumeBindingWaitForFirstConsumer {
+		c.Score -= 5
+		c.Reasons = append(c.Reasons, "WaitForFirstConsumer: volumes bind only once the importer or VM pod is scheduled")
+	}
+	if sc.AllowVolumeExpansion != nil && *sc.AllowVolumeExpansion {
+		c.Score += 5
+		c.Reasons = append(c.Reasons, "volumes can be expanded after migration")
+	} else {
+		c.Reasons = append(c.Reasons, "volumes cannot be expanded")
+	}
+	return c, true
+}
+
+// isDefaultStorageClass reports whether sc carries the default-class annotation.
+func isDefaultStorageClass(sc storagev1.StorageClass) bool {
+	for _, key := range []string{"storageclass.kubernetes.io/is-default-class", "storageclass.beta.kubernetes.io/is-default-class"} {
+		if strings.EqualFold(sc.Annotations[key], "true") {
+			return true
+		}
+	}
+	return false
+}
+
+// VMsNotFoundError lists requested VMs missing from the inventory.
+type VMsNotFoundError struct {
+	Missing []string
+}
+
+func (e *VMsNotFoundError) Error() string {
+	return "VM(s) not found in vCenter inventory: " + strings.Join(e.Missing, ", ")
+}
+
+// findInventoryVMs looks up VMs in an inventory tree by moRef ID or, when
+// unique, by name.
+func findInventoryVMs(tree *InventoryNode, refs []string) ([]InventoryNode, error) {
+	byID := map[string]InventoryNode{}
+	byName := map[string][]InventoryNode{}
+	var walk func(n *InventoryNode)
+	walk = func(n *InventoryNode) {
+		if n.Type == "VirtualMachine" {
+			byID[n.ID] = *n
+			byName[n.Name] = append(byName[n.Name], *n)
+		}
+		for i := range n.Children {
+			walk(&n.Children[i])
+		}
+	}
+	walk(tree)
+
+	var vms []InventoryNode
+	var missing []string
+	for _, ref := range refs {
+		if vm, ok := byID[ref]; ok {
+			vms = append(vms, vm)
+			continue
+		}
+		switch matches := byName[ref]; len(matches) {
+		case 0:
+			missing = append(missing, ref)
+		case 1:
+			vms = append(vms, matches[0])
+		default:
+			ambiguous := &AmbiguousVMError{Name: ref}
+			for _, vm := range matches {
+				ambiguous.Candidates = append(ambiguous.Candidates, VMCandidate{ID: vm.ID, Name: vm.Name, Path: vm.Folder + "/" + vm.Name})
+			}
+			return nil, ambiguous
+		}
+	}
+	if len(missing) > 0 {
+		return nil, &VMsNotFoundError{Missing: missing}
+	}
+	return vms, nil
+}
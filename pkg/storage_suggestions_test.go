@@ -0,0 +1,232 @@
+// pkg/storage_suggestions_test.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/vmware/govmomi/simulator"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const gib = int64(1) << 30
+
+// newStorageClass builds a StorageClass; annotations and parameters alternate
+// key and value.
+func newStorageClass(name, provisioner string, isDefault bool, params ...string) storagev1.StorageClass {
+	expand := true
+	sc := storagev1.StorageClass{
+		ObjectMeta:           metav1.ObjectMeta{Name: name, Annotations: map[string]string{}},
+		Provisioner:          provisioner,
+		Parameters:           map[string]string{},
+		AllowVolumeExpansion: &expand,
+	}
+	if isDefault {
+		sc.Annotations["storageclass.kubernetes.io/is-default-class"] = "true"
+	}
+	for i := 0; i+1 < len(params); i += 2 {
+		sc.Parameters[params[i]] = params[i+1]
+	}
+	return sc
+}
+
+// newLonghornNode builds a Longhorn node with one disk per entry of free,
+// given in GiB. Each disk is 100 GiB larger than its free space, has 1 GiB
+// reserved and nothing scheduled.
+func newLonghornNode(name string, free ...int64) *unstructured.Unstructured {
+	disks := map[string]interface{}{}
+	statuses := map[string]interface{}{}
+	for i, f := range free {
+		disk := "disk-" + string(rune('a'+i))
+		disks[disk] = map[string]interface{}{"allowScheduling": true, "storageReserved": gib}
+		statuses[disk] = map[string]interface{}{"storageAvailable": (f + 1) * gib, "storageMaximum": (f + 101) * gib, "storageScheduled": int64(0)}
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "longhorn.io/v1beta2",
+		"kind":       "Node",
+		"metadata":   map[string]interface{}{"name": name, "namespace": longhornNamespace},
+		"spec":       map[string]interface{}{"allowScheduling": true, "disks": disks},
+		"status":     map[string]interface{}{"diskStatus": statuses},
+	}}
+}
+
+func TestSuggestStorageMappings(t *testing.T) {
+	wffc := storagev1.VolumeBindingWaitForFirstConsumer
+	localPath := newStorageClass("local-path", "rancher.io/local-path", false)
+	localPath.VolumeBindingMode = &wffc
+	classes := []storagev1.StorageClass{
+		newStorageClass("harvester-longhorn", longhornProvisioner, true, "numberOfReplicas", "3", "migratable", "true"),
+		newStorageClass("longhorn-single", longhornProvisioner, false, "numberOfReplicas", "1"),
+		newStorageClass("longhorn-image-abc", longhornProvisioner, false, "backingImage", "default-image-abc", "migratable", "true"),
+		localPath,
+	}
+	vms := []InventoryNode{
+		{Name: "web", Type: "VirtualMachine", Disks: []VMDisk{
+			{Name: "Hard disk 1", Capacity: 40 * gib, DatastoreID: "datastore-1", DatastoreName: "fast"},
+			{Name: "Hard disk 2", Capacity: 200 * gib, DatastoreID: "datastore-2", DatastoreName: "bulk"},
+		}},
+		{Name: "db", Type: "VirtualMachine", Disks: []VMDisk{
+			{Name: "Hard disk 1", Capacity: 20 * gib, DatastoreID: "datastore-1", DatastoreName: "fast", SharingMode: "sharingMultiWriter"},
+		}},
+	}
+	// 3 nodes with 150 GiB free each: 450 GiB raw, 150 GiB for 3-replica volumes.
+	longhorn := &longhornCapacity{AvailableBytes: 450 * gib, SchedulableNodes: 3}
+
+	got := suggestStorageMappings(vms, classes, longhorn)
+	if len(got.Datastores) != 2 || got.Datastores[0].DatastoreName != "bulk" || got.Datastores[1].DatastoreName != "fast" {
+		t.Fatalf("expected suggestions for bulk and fast, got %+v", got.Datastores)
+	}
+	bulk, fast := got.Datastores[0], got.Datastores[1]
+
+	t.Run("shared disks need ReadWriteMany", func(t *testing.T) {
+		if fast.RequiredBytes != 60*gib || !fast.Shared || strings.Join(fast.Disks, ",") != "web/Hard disk 1,db/Hard disk 1" {
+			t.Errorf("unexpected datastore summary: %+v", fast)
+		}
+		if fast.Suggested != "harvester-longhorn" || fast.AccessMode != "ReadWriteMany" || fast.VolumeMode != "Block" {
+			t.Errorf("expected harvester-longhorn as an RWX block volume, got %s %s %s", fast.Suggested, fast.AccessMode, fast.VolumeMode)
+		}
+		if len(fast.Candidates) != 1 {
+			t.Errorf("expected only the migratable class to take shared disks, got %+v", fast.Candidates)
+		}
+		rejected := map[string]string{}
+		for _, c := range fast.Rejected {
+			rejected[c.StorageClass] = strings.Join(c.Reasons, "; ")
+		}
+		if !strings.Contains(rejected["longhorn-image-abc"], "VM image") || !strings.Contains(rejected["local-path"], "ReadWriteMany") {
+			t.Errorf("expected image and RWO classes rejected with reasons, got %v", rejected)
+		}
+	})
+
+	t.Run("capacity rules out classes", func(t *testing.T) {
+		// 200 GiB fits the single-replica class (450 GiB) but not 3 replicas (150 GiB).
+		if bulk.Suggested != "longhorn-single" {
+			t.Errorf("expected longhorn-single for 200 GiB, got %q (candidates %+v)", bulk.Suggested, bulk.Candidates)
+		}
+		var reason string
+		for _, c := range bulk.Rejected {
+			if c.StorageClass == "harvester-longhorn" {
+				reason = strings.Join(c.Reasons, "; ")
+			}
+		}
+		if !strings.Contains(reason, "150.0 GB is free for 3-replica volumes") {
+			t.Errorf("expected harvester-longhorn rejected for capacity, got %q", reason)
+		}
+		for _, c := range bulk.Candidates {
+			if c.StorageClass == "local-path" && !strings.Contains(strings.Join(c.Reasons, "; "), "WaitForFirstConsumer") {
+				t.Errorf("expected local-path to explain its binding mode, got %v", c.Reasons)
+			}
+		}
+	})
+
+	t.Run("overall covers every disk", func(t *testing.T) {
+		if got.Overall.RequiredBytes != 260*gib || len(got.Overall.Disks) != 3 || got.Overall.Suggested != "" {
+			t.Errorf("expected no class to hold 260 GiB including shared disks, got %+v", got.Overall)
+		}
+	})
+
+	t.Run("unknown capacity", func(t *testing.T) {
+		got := suggestStorageMappings(vms[:1], classes, nil)
+		if got.Overall.Suggested != "harvester-longhorn" || got.Overall.Candidates[0].UsableBytes != nil {
+			t.Errorf("expected the default class without a capacity figure, got %+v", got.Overall)
+		}
+	})
+}
+
+func TestFindInventoryVMs(t *testing.T) {
+	tree := &InventoryNode{Type: "vcenter", Children: []InventoryNode{{Type: "Datacenter", Children: []InventoryNode{
+		{ID: "vm-1", Name: "web", Type: "VirtualMachine", Folder: "DC0/vm"},
+		{ID: "vm-2", Name: "db", Type: "VirtualMachine", Folder: "DC0/vm"},
+		{ID: "vm-3", Name: "db", Type: "VirtualMachine", Folder: "DC0/vm/old"},
+	}}}}
+
+	vms, err := findInventoryVMs(tree, []string{"web", "vm-3"})
+	if err != nil || len(vms) != 2 || vms[0].ID != "vm-1" || vms[1].ID != "vm-3" {
+		t.Errorf("expected vm-1 and vm-3, got %+v (%v)", vms, err)
+	}
+
+	var ambiguous *AmbiguousVMError
+	if _, err := findInventoryVMs(tree, []string{"db"}); !errors.As(err, &ambiguous) || len(ambiguous.Candidates) != 2 {
+		t.Errorf("expected an ambiguous name error, got %v", err)
+	}
+
+	var notFound *VMsNotFoundError
+	if _, err := findInventoryVMs(tree, []string{"web", "nope"}); !errors.As(err, &notFound) || notFound.Missing[0] != "nope" {
+		t.Errorf("expected nope to be reported missing, got %v", err)
+	}
+}
+
+func TestStorageSuggestionHandlersWithVCSim(t *testing.T) {
+	env := newVCSimEnv(t, simulator.VPX(), "DC0")
+	ctx := context.Background()
+
+	env.registerListKinds(map[schema.GroupVersionResource]string{longhornNodeGVR: "NodeList"})
+	for _, node := range []*unstructured.Unstructured{newLonghornNode("node-1", 100), newLonghornNode("node-2", 60, 40)} {
+		if _, err := env.clients.Dynamic.Resource(longhornNodeGVR).Namespace(longhornNamespace).Create(ctx, node, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, sc := range []storagev1.StorageClass{
+		newStorageClass("harvester-longhorn", longhornProvisioner, true, "numberOfReplicas", "3", "migratable", "true"),
+		newStorageClass("longhorn-2", longhornProvisioner, false, "numberOfReplicas", "2", "migratable", "true"),
+	} {
+		sc := sc
+		if _, err := env.clients.Clientset.StorageV1().StorageClasses().Create(ctx, &sc, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rr := executeRequest(HandleGetVCenterStorageSuggestions(env.clients), "GET", "/api/v1/vcenter/storage/suggestions?vm=DC0_H0_VM0", nil, env.source)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var got StorageMappingSuggestions
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Datastores) != 1 || got.Datastores[0].DatastoreName != "LocalDS_0" || got.Datastores[0].RequiredBytes == 0 {
+		t.Fatalf("expected the VM's disks on LocalDS_0, got %+v", got.Datastores)
+	}
+	// Two nodes can hold replicas, so 3-replica volumes would run degraded.
+	ds := got.Datastores[0]
+	if ds.Suggested != "longhorn-2" || ds.Candidates[0].UsableBytes == nil || *ds.Candidates[0].UsableBytes != 100*gib {
+		t.Errorf("expected longhorn-2 with 100 GiB usable, got %+v", ds.Candidates)
+	}
+
+	if len(got.Warnings) != 0 {
+		t.Errorf("expected no warnings with readable Longhorn nodes, got %v", got.Warnings)
+	}
+
+	// Longhorn nodes that cannot be read are reported, not hidden.
+	env.clients.Dynamic.(*dynamicfake.FakeDynamicClient).PrependReactor("list", "nodes", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(longhornNodeGVR.GroupResource(), "", errors.New("no RBAC"))
+	})
+	rr = executeRequest(HandleGetVCenterStorageSuggestions(env.clients), "GET", "/api/v1/vcenter/storage/suggestions?vm=DC0_H0_VM0", nil, env.source)
+	got = StorageMappingSuggestions{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if rr.Code != http.StatusOK || len(got.Warnings) != 1 || !strings.Contains(got.Warnings[0], "forbidden") {
+		t.Errorf("expected 200 with a warning about the forbidden Longhorn nodes, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = executeRequest(HandleGetForkliftStorageSuggestions(env.clients), "GET", "/api/v1/forklift/storage/suggestions?vm=DC0_H0_VM0,missing", nil, env.forklift)
+	if rr.Code != http.StatusNotFound || !strings.Contains(rr.Body.String(), "missing") {
+		t.Errorf("expected 404 naming the missing VM, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = executeRequest(HandleGetForkliftStorageSuggestions(env.clients), "GET", "/api/v1/forklift/storage/suggestions", nil, env.forklift)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without ?vm=, got %d", rr.Code)
+	}
+}
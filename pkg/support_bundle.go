@@ -75,7 +75,7 @@ func (b *supportBundle) step(name string, fn func() error) {
 // underlying list errors are already surfaced by the matching dumpCRs step.
 func safeList(ctx context.Context, clients *K8sClients, gvr schema.GroupVersionResource) (items []unstructured.Unstructured) {
 	defer func() { _ = recover() }()
-	list, err := clients.Dynamic.Resource(gvr).Namespace("").List(ctx, metav1.ListOptions{})
+	list, err := clients.listResources(ctx, gvr, "", metav1.ListOptions{})
 	if err != nil {
 		return nil
 	}
@@ -88,7 +88,7 @@ func safeList(ctx context.Context, clients *K8sClients, gvr schema.GroupVersionR
 // diagnosing infra bugs (e.g. spotting a controller that set an invalid state).
 func (b *supportBundle) dumpCRs(ctx context.Context, clients *K8sClients, dir string, gvr schema.GroupVersionResource) {
 	b.step(dir, func() error {
-		list, err := clients.Dynamic.Resource(gvr).Namespace("").List(ctx, metav1.ListOptions{})
+		list, err := clients.listResources(ctx, gvr, "", metav1.ListOptions{})
 		if err != nil {
 			return err
 		}
@@ -166,13 +166,8 @@ func SupportBundleHandler(clients *K8sClients) http.HandlerFunc {
 			b.addJSON("cluster/namespaces.json", ns.Items)
 			return nil
 		})
-		nadGVR := schema.GroupVersionResource{
-			Group:    "k8s.cni.cncf.io",
-			Version:  "v1",
-			Resource: "network-attachment-definitions",
-		}
 		b.step("cluster/nads", func() error {
-			nads, err := clients.Dynamic.Resource(nadGVR).Namespace("").List(ctx, metav1.ListOptions{})
+			nads, err := clients.listResources(ctx, nadGVR, "", metav1.ListOptions{})
 			if err != nil {
 				return err
 			}
@@ -248,7 +243,7 @@ func SupportBundleHandler(clients *K8sClients) http.HandlerFunc {
 		// --- optional inventory (slow; per selected/all VmwareSource) ---
 		if includeInv {
 			b.step("inventory", func() error {
-				list, err := clients.Dynamic.Resource(vmwareSourceGVR).Namespace("").List(ctx, metav1.ListOptions{})
+				list, err := clients.listResources(ctx, vmwareSourceGVR, "", metav1.ListOptions{})
 				if err != nil {
 					return err
 				}
@@ -84,6 +84,7 @@ type CreateForkliftProviderPayload struct {
 	ProviderType       string `json:"providerType,omitempty"`       // "vsphere" (default) or "ova"
 	InsecureSkipVerify *bool  `json:"insecureSkipVerify,omitempty"` // nil = default true (backwards compat); false = validate TLS certs
 	CACert             string `json:"cacert,omitempty"`             // PEM-encoded CA certificate (used when insecureSkipVerify is false)
+	Thumbprint         *string `json:"thumbprint,omitempty"`        // pinned SHA-1/SHA-256 certificate fingerprint; "" clears it on update
 	VddkInitImage      string `json:"vddkInitImage,omitempty"`     // VDDK container image for optimized disk transfers
 }
 
@@ -139,3 +140,22 @@ type CreateForkliftPlanPayload struct {
 	SourceVmNetworks             string `json:"sourceVmNetworks,omitempty"`             // JSON string of network array
 	DefaultNetworkInterfaceModel string `json:"defaultNetworkInterfaceModel,omitempty"` // e.g. virtio, e1000, e1000e
 }
+
+// UpdateForkliftPlanPayload lists the changes to a Forklift Plan and its maps;
+// nil fields are left alone. VMs and the mappings, when non-nil, replace the
+// whole list.
+type UpdateForkliftPlanPayload struct {
+	TargetNamespace              *string                    `json:"targetNamespace,omitempty"`
+	VMs                          *[]ForkliftVMEntry         `json:"vms,omitempty"`
+	NetworkMappings              *[]ForkliftNetworkMapEntry `json:"networkMappings,omitempty"`
+	StorageMappings              *[]ForkliftStorageMapEntry `json:"storageMappings,omitempty"`
+	Warm                         *bool                      `json:"warm,omitempty"`
+	MigrateSharedDisks           *bool                      `json:"migrateSharedDisks,omitempty"`
+	PreserveClusterCpuModel      *bool                      `json:"preserveClusterCpuModel,omitempty"`
+	PreserveStaticIPs            *bool                      `json:"preserveStaticIPs,omitempty"`
+	PopulatorLabels              *bool                      `json:"populatorLabels,omitempty"`
+	DefaultNetworkInterfaceModel *string                    `json:"defaultNetworkInterfaceModel,omitempty"`
+	// SourceVmDisks, when set, replaces the disk summary the StorageMap is
+	// checked against (JSON string of disk array, as on create).
+	SourceVmDisks *string `json:"sourceVmDisks,omitempty"`
+}
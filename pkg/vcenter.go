@@ -3,9 +3,11 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"net/url"
+	"path"
 	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/vmware/govmomi"
@@ -35,6 +37,24 @@ type VMDisk struct {
 	Capacity int64  `json:"capacity"` // in bytes
 	BusType  string `json:"busType"`  // e.g. scsi, ide, sata, nvme
 	UnitNum  int32  `json:"unitNum"`
+
+	// Backing details. For an RDM, the datastore and file are those of the
+	// mapping file.
+	DatastoreID          string `json:"datastoreId,omitempty"`
+	DatastoreName        string `json:"datastoreName,omitempty"`
+	FilePath             string `json:"filePath,omitempty"`     // e.g. [ds1] web-01/web-01.vmdk
+	Provisioning         string `json:"provisioning,omitempty"` // thin, thick or eagerZeroedThick
+	DiskMode             string `json:"diskMode,omitempty"`     // e.g. persistent, independent_persistent
+	Independent          bool   `json:"independent,omitempty"`
+	SharingMode          string `json:"sharingMode,omitempty"` // sharingNone or sharingMultiWriter
+	RDM                  bool   `json:"rdm,omitempty"`
+	RDMCompatibilityMode string `json:"rdmCompatibilityMode,omitempty"` // physicalMode or virtualMode
+}
+
+// VMDatastore is a datastore a VM's files or disks live on.
+type VMDatastore struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
 }
 
 // VMNetwork represents a network interface in vCenter
@@ -47,512 +67,371 @@ type VMNetwork struct {
 
 // InventoryNode represents a generic node in the vCenter inventory tree.
 type InventoryNode struct {
-	ID            string          `json:"id"`
-	Name          string          `json:"name"`
-	Type          string          `json:"type"`
-	Children      []InventoryNode `json:"children,omitempty"`
-	Networks      []VMNetwork     `json:"networks,omitempty"`
-	Disks         []VMDisk        `json:"disks,omitempty"`
-	CPU           int32           `json:"cpu,omitempty"`
-	MemoryMB      int32           `json:"memoryMB,omitempty"`
-	DiskSizeGB    int64           `json:"diskSizeGB,omitempty"`
-	Folder        string          `json:"folder,omitempty"`
-	PowerState    string          `json:"powerState,omitempty"`
-	DatastoreID   string          `json:"datastoreId,omitempty"`
-	DatastoreName string          `json:"datastoreName,omitempty"`
+	ID             string          `json:"id"`
+	Name           string          `json:"name"`
+	Type           string          `json:"type"`
+	Children       []InventoryNode `json:"children,omitempty"`
+	Networks       []VMNetwork     `json:"networks,omitempty"`
+	Disks          []VMDisk        `json:"disks,omitempty"`
+	CPU            int32           `json:"cpu,omitempty"`
+	MemoryMB       int32           `json:"memoryMB,omitempty"`
+	DiskSizeGB     int64           `json:"diskSizeGB,omitempty"`
+	CommittedBytes int64           `json:"committedBytes,omitempty"` // datastore space in use, including swap and snapshots
+	Folder         string          `json:"folder,omitempty"`
+	Datacenter     string          `json:"datacenter,omitempty"`
+	Host           string          `json:"host,omitempty"`
+	HostID         string          `json:"hostId,omitempty"`
+	PowerState     string          `json:"powerState,omitempty"`
+	DatastoreID    string          `json:"datastoreId,omitempty"`
+	DatastoreName  string          `json:"datastoreName,omitempty"`
+	Datastores     []VMDatastore   `json:"datastores,omitempty"`
+
+	// Guest and virtual hardware details of a VM. Tools state and guest
+	// networking are as of the last inventory build; they don't trigger one.
+	GuestID             string   `json:"guestId,omitempty"`
+	GuestOS             string   `json:"guestOS,omitempty"`
+	Firmware            string   `json:"firmware,omitempty"` // bios or efi
+	SecureBoot          bool     `json:"secureBoot,omitempty"`
+	HardwareVersion     string   `json:"hardwareVersion,omitempty"` // e.g. vmx-19
+	ToolsRunningStatus  string   `json:"toolsRunningStatus,omitempty"`
+	ToolsVersion        string   `json:"toolsVersion,omitempty"`
+	ToolsVersionStatus  string   `json:"toolsVersionStatus,omitempty"`
+	GuestHostname       string   `json:"guestHostname,omitempty"`
+	GuestIPs            []string `json:"guestIPs,omitempty"`
+	CPUHotAddEnabled    bool     `json:"cpuHotAddEnabled,omitempty"`
+	CPUHotRemoveEnabled bool     `json:"cpuHotRemoveEnabled,omitempty"`
+	MemoryHotAddEnabled bool     `json:"memoryHotAddEnabled,omitempty"`
+
+	// Set on the root node only when served from the inventory cache.
+	LastRefreshed *time.Time `json:"lastRefreshed,omitempty"`
+	Refreshing    bool       `json:"refreshing,omitempty"`
+
+	// hostView holds a datacenter's host-and-cluster view in cached trees;
+	// Children is its VM-folder view. See InventoryNode.view.
+	hostView []InventoryNode
 }
 
-// GetVCenterInventory connects to vCenter and returns the inventory tree.
-func GetVCenterInventory(ctx context.Context, creds VCenterCredentials) (*InventoryNode, error) {
-	fullURL := creds.URL
-	if !strings.HasPrefix(fullURL, "https://") && !strings.HasPrefix(fullURL, "http://") {
-		fullURL = "https://" + fullURL
-	}
-
-	u, err := url.Parse(fullURL)
-	if err != nil {
-		return nil, err
-	}
-	u.User = url.UserPassword(creds.Username, creds.Password)
-
-	log.Infof("Connecting to vCenter at %s", creds.URL)
-	c, err := govmomi.NewClient(ctx, u, true)
-	if err != nil {
-		return nil, err
-	}
-	defer c.Logout(ctx)
-
-	finder := find.NewFinder(c.Client, true)
-	dc, err := finder.Datacenter(ctx, creds.Datacenter)
-	if err != nil {
-		return nil, err
-	}
-	finder.SetDatacenter(dc)
+// Inventory views: the VM-folder view ("VMs and Templates" in the vSphere
+// client) and the host-and-cluster view.
+const (
+	inventoryViewVMs   = "vms"
+	inventoryViewHosts = "hosts"
+)
 
-	rootNode := &InventoryNode{
-		Name: dc.Name(),
-		Type: "datacenter",
+// view returns a datacenter node showing the requested inventory view.
+func (n InventoryNode) view(view string) InventoryNode {
+	if view == inventoryViewHosts && n.hostView != nil {
+		n.Children = n.hostView
 	}
+	n.hostView = nil
+	return n
+}
 
-	folders, err := dc.Folders(ctx)
+// GetVCenterInventory connects to vCenter and returns the inventory tree of
+// the datacenter named in creds.
+func GetVCenterInventory(ctx context.Context, creds VCenterCredentials) (*InventoryNode, error) {
+	tree, err := GetVCenterInventoryAllDatacenters(ctx, creds)
 	if err != nil {
 		return nil, err
 	}
+	return defaultDatacenterInventory(tree, creds.Datacenter, inventoryViewVMs)
+}
 
-	rootFolder := object.NewFolder(c.Client, folders.VmFolder.Reference())
-	children, err := rootFolder.Children(ctx)
+// GetVCenterInventoryAllDatacenters connects to vCenter and returns one tree
+// covering every datacenter. This is what the inventory cache holds; handlers
+// pick the datacenters a request asked for out of it.
+func GetVCenterInventoryAllDatacenters(ctx context.Context, creds VCenterCredentials) (*InventoryNode, error) {
+	var rootNode *InventoryNode
+	err := vcenterSessions.Do(ctx, creds, func(c *govmomi.Client) error {
+		var err error
+		rootNode, err = buildVCenterInventory(ctx, c)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	for _, child := range children {
-		// Initialize recursion with an empty string
-		node, err := processEntity(ctx, c, child, "")
-		if err != nil {
-			log.Warnf("Could not process entity %s: %v", child.Reference().Value, err)
-			continue
-		}
-		if node != nil {
-			rootNode.Children = append(rootNode.Children, *node)
-		}
-	}
-
 	log.Debugf("Constructed vCenter inventory tree: %+v", rootNode)
 	return rootNode, nil
 }
 
-// processEntity recursively processes vCenter inventory objects.
-func processEntity(ctx context.Context, c *govmomi.Client, entity object.Reference, folderPath string) (*InventoryNode, error) {
-	ref := entity.Reference()
+// DatacenterNotFoundError reports datacenter names or patterns that matched
+// nothing in a vCenter inventory.
+type DatacenterNotFoundError struct {
+	Missing   []string
+	Available []string
+}
 
-	var me mo.ManagedEntity
-	pc := property.DefaultCollector(c.Client)
-	if err := pc.RetrieveOne(ctx, ref, []string{"name"}, &me); err != nil {
-		return nil, err
-	}
+func (e *DatacenterNotFoundError) Error() string {
+	return fmt.Sprintf("datacenter %s not found (available: %s)", strings.Join(e.Missing, ", "), strings.Join(e.Available, ", "))
+}
 
-	node := &InventoryNode{
-		ID:   ref.Value,
-		Name: me.Name,
-		Type: ref.Type,
+// datacenterMatches reports whether a datacenter node matches a name, a glob
+// pattern such as "DC*" or "*", or its moRef value.
+func datacenterMatches(dc *InventoryNode, pattern string) bool {
+	if dc.ID == pattern {
+		return true
 	}
+	ok, err := path.Match(pattern, dc.Name)
+	return err == nil && ok
+}
 
-	switch e := entity.(type) {
-	case *object.VirtualMachine:
-		var mvm mo.VirtualMachine
-		err := pc.RetrieveOne(ctx, ref, []string{"guest", "summary", "config", "network", "config.hardware.device", "runtime", "datastore"}, &mvm)
-		if err != nil {
-			return nil, err
-		}
-
-		log.Debugf("Raw VM data from vCenter for %s: %+v", me.Name, mvm)
-
-		var vmNetworks []VMNetwork
-		var vmDisks []VMDisk
-
-		if mvm.Config != nil {
-			// Get the list of virtual devices from the managed object
-			deviceList := object.VirtualDeviceList(mvm.Config.Hardware.Device)
-
-			// Map to find controller types
-			controllers := make(map[int32]string)
-			for _, device := range deviceList {
-				d := device.GetVirtualDevice()
-				switch device.(type) {
-				case *types.VirtualLsiLogicController, *types.VirtualLsiLogicSASController, *types.VirtualBusLogicController, *types.ParaVirtualSCSIController:
-					controllers[d.Key] = "scsi"
-				case *types.VirtualIDEController:
-					controllers[d.Key] = "ide"
-				case *types.VirtualSATAController:
-					controllers[d.Key] = "sata"
-				case *types.VirtualNVMEController:
-					controllers[d.Key] = "nvme"
-				}
-			}
-
-			// Find all network card devices and disks
-			for _, device := range deviceList {
-				// Use a type assertion to see if the device is a network card
-				if card, ok := device.(types.BaseVirtualEthernetCard); ok {
-					// Get the backing info from the network card
-					backing := card.GetVirtualEthernetCard().Backing
-
-					netName := "unknown"
-					netID := ""
-					switch backingInfo := backing.(type) {
-					case *types.VirtualEthernetCardNetworkBackingInfo:
-						netName = backingInfo.DeviceName
-						if backingInfo.Network != nil {
-							netID = backingInfo.Network.Value
-						}
-					case *types.VirtualEthernetCardDistributedVirtualPortBackingInfo:
-						netID = backingInfo.Port.PortgroupKey
-						netName = backingInfo.Port.PortgroupKey // fallback: raw moref key
-						pgRef := types.ManagedObjectReference{
-							Type:  "DistributedVirtualPortgroup",
-							Value: backingInfo.Port.PortgroupKey,
-						}
-						var dvpg mo.DistributedVirtualPortgroup
-						if err := pc.RetrieveOne(ctx, pgRef, []string{"name", "config.distributedVirtualSwitch"}, &dvpg); err == nil {
-							pgName := dvpg.Name
-							if dvpg.Config.DistributedVirtualSwitch != nil {
-								var dvsMe mo.ManagedEntity
-								if err2 := pc.RetrieveOne(ctx, *dvpg.Config.DistributedVirtualSwitch, []string{"name"}, &dvsMe); err2 == nil {
-									netName = dvsMe.Name + "/" + pgName
-								} else {
-									log.Warnf("Could not resolve dvSwitch name for portgroup %s: %v", backingInfo.Port.PortgroupKey, err2)
-									netName = pgName
-								}
-							} else {
-								netName = pgName
-							}
-						} else {
-							log.Warnf("Could not resolve DVPortgroup %s: %v", backingInfo.Port.PortgroupKey, err)
-						}
-					}
-
-					vmNetworks = append(vmNetworks, VMNetwork{
-						Name: netName,
-						ID:   netID,
-						MAC:  card.GetVirtualEthernetCard().MacAddress,
-						Key:  card.GetVirtualEthernetCard().Key,
-					})
-				} else if disk, ok := device.(*types.VirtualDisk); ok {
-					busType := "unknown"
-					if t, ok := controllers[disk.ControllerKey]; ok {
-						busType = t
-					}
-					name := "Disk"
-					if disk.DeviceInfo != nil {
-						name = disk.DeviceInfo.GetDescription().Label
-					}
-					vmDisks = append(vmDisks, VMDisk{
-						Name:     name,
-						Capacity: disk.CapacityInBytes,
-						BusType:  busType,
-						UnitNum:  *disk.UnitNumber,
-					})
-				}
-			}
-		} else {
-			log.Warnf("VM '%s' has nil Config, skipping device processing", me.Name)
-		}
-
-		log.Debugf("Successfully found networks for VM '%s': %v\n", me.Name, vmNetworks)
-
-		node.Networks = vmNetworks
-		node.Disks = vmDisks
-		node.DiskSizeGB = mvm.Summary.Storage.Committed / (1024 * 1024 * 1024)
-
-		node.CPU = mvm.Summary.Config.NumCpu
-		node.MemoryMB = mvm.Summary.Config.MemorySizeMB
-
-		node.PowerState = string(mvm.Runtime.PowerState)
-		node.Folder = folderPath // Store the accumulated folder path
-
-		// Auto-detect datastore ID from the VM's datastore references
-		if len(mvm.Datastore) > 0 {
-			node.DatastoreID = mvm.Datastore[0].Value
-			// Try to get the datastore name
-			var dsmo mo.Datastore
-			if dsErr := pc.RetrieveOne(ctx, mvm.Datastore[0], []string{"name"}, &dsmo); dsErr == nil {
-				node.DatastoreName = dsmo.Name
-			}
-		}
-
-		return node, nil
-
-	case *object.Folder:
-		// Build the path: parent/current
-		childPath := folderPath
-		if childPath != "" {
-			childPath += "/"
-		}
-		childPath += me.Name
+// datacenterNames lists the datacenters under an all-datacenter tree.
+func datacenterNames(tree *InventoryNode) []string {
+	names := make([]string, len(tree.Children))
+	for i := range tree.Children {
+		names[i] = tree.Children[i].Name
+	}
+	return names
+}
 
-		children, err := e.Children(ctx)
-		if err != nil {
-			return nil, err
-		}
-		for _, child := range children {
-			childNode, err := processEntity(ctx, c, child, childPath)
-			if err != nil {
-				log.Warnf("Could not process child entity %s: %v", child.Reference().Value, err)
-				continue
-			}
-			if childNode != nil {
-				node.Children = append(node.Children, *childNode)
+// selectDatacenterInventory returns a copy of an all-datacenter tree reduced
+// to the datacenters matching any of patterns, in the given view. Every
+// pattern must match at least one datacenter.
+func selectDatacenterInventory(tree *InventoryNode, patterns []string, view string) (*InventoryNode, error) {
+	selected := *tree
+	selected.Children = nil
+	var missing []string
+	matched := make([]bool, len(tree.Children))
+	for _, pattern := range patterns {
+		found := false
+		for i := range tree.Children {
+			if datacenterMatches(&tree.Children[i], pattern) {
+				matched[i] = true
+				found = true
 			}
 		}
-		return node, nil
-
-	case *object.ClusterComputeResource:
-		rp, err := e.ResourcePool(ctx)
-		if err != nil {
-			return node, nil
-		}
-		var mrp mo.ResourcePool
-		err = pc.RetrieveOne(ctx, rp.Reference(), []string{"vm"}, &mrp)
-		if err != nil {
-			return nil, err
+		if !found {
+			missing = append(missing, pattern)
 		}
-		for _, vmRef := range mrp.Vm {
-			// Pass the existing folderPath through clusters
-			childNode, err := processEntity(ctx, c, object.NewVirtualMachine(c.Client, vmRef), folderPath)
-			if err != nil {
-				log.Warnf("Could not process child vm in cluster %s: %v", vmRef.Value, err)
-				continue
-			}
-			if childNode != nil {
-				node.Children = append(node.Children, *childNode)
-			}
-		}
-		return node, nil
-
-	default:
-		return nil, nil
 	}
-}
-
-// PowerOpVM performs a power operation on a VM.
-func PowerOpVM(ctx context.Context, creds VCenterCredentials, vmName string, op string) error {
-	fullURL := creds.URL
-	if !strings.HasPrefix(fullURL, "https://") && !strings.HasPrefix(fullURL, "http://") {
-		fullURL = "https://" + fullURL
+	if len(missing) > 0 {
+		return nil, &DatacenterNotFoundError{Missing: missing, Available: datacenterNames(tree)}
 	}
-	u, err := url.Parse(fullURL)
-	if err != nil {
-		return err
-	}
-	u.User = url.UserPassword(creds.Username, creds.Password)
-
-	c, err := govmomi.NewClient(ctx, u, true)
-	if err != nil {
-		return err
-	}
-	defer c.Logout(ctx)
-
-	finder := find.NewFinder(c.Client, true)
-	dc, err := finder.Datacenter(ctx, creds.Datacenter)
-	if err != nil {
-		return err
-	}
-	finder.SetDatacenter(dc)
-
-	vm, err := finder.VirtualMachine(ctx, vmName)
-	if err != nil {
-		return err
+	for i, ok := range matched {
+		if ok {
+			selected.Children = append(selected.Children, tree.Children[i].view(view))
+		}
 	}
+	return &selected, nil
+}
 
-	var task *object.Task
-	switch op {
-	case "on":
-		task, err = vm.PowerOn(ctx)
-	case "off":
-		task, err = vm.PowerOff(ctx)
-	case "reset":
-		task, err = vm.Reset(ctx)
-	case "shutdown":
-		err = vm.ShutdownGuest(ctx)
-		if err != nil {
-			// Fallback to power off if shutdown fails (e.g. tools not installed)
-			log.Warnf("Guest shutdown failed for %s, falling back to power off: %v", vmName, err)
-			task, err = vm.PowerOff(ctx)
-		} else {
-			return nil // ShutdownGuest doesn't return a task, it's just an error if it fails to initiate
+// defaultDatacenterInventory returns the subtree of the named datacenter, or
+// of the first one when name is empty (Forklift Providers don't record a
+// datacenter), in the given view. The root's cache stamps are carried over to
+// the result.
+func defaultDatacenterInventory(tree *InventoryNode, name, view string) (*InventoryNode, error) {
+	name = strings.Trim(name, "/")
+	for i := range tree.Children {
+		dc := tree.Children[i].view(view)
+		if name == "" || dc.Name == name || dc.ID == name {
+			dc.LastRefreshed = tree.LastRefreshed
+			dc.Refreshing = tree.Refreshing
+			return &dc, nil
 		}
-	default:
-		return fmt.Errorf("unsupported power operation: %s", op)
 	}
-
-	if err != nil {
-		return err
+	if name == "" {
+		return nil, fmt.Errorf("no datacenter found in vCenter")
 	}
+	return nil, &DatacenterNotFoundError{Missing: []string{name}, Available: datacenterNames(tree)}
+}
 
-	if task != nil {
-		return task.Wait(ctx)
-	}
-	return nil
+// VMRef identifies the VM a vCenter operation targets. ID is the moRef value
+// (e.g. "vm-42", as returned in InventoryNode.ID) and is preferred because it
+// survives renames and duplicate names. Without an ID, Name is resolved in the
+// source's datacenter either as an inventory path ("Prod/web-01" or
+// "/DC0/vm/Prod/web-01") or as a display name, which must be unique.
+type VMRef struct {
+	ID   string
+	Name string
 }
 
-// RenameVM renames a VM in vCenter.
-func RenameVM(ctx context.Context, creds VCenterCredentials, oldName string, newName string) error {
-	fullURL := creds.URL
-	if !strings.HasPrefix(fullURL, "https://") && !strings.HasPrefix(fullURL, "http://") {
-		fullURL = "https://" + fullURL
+func (r VMRef) String() string {
+	if r.ID != "" {
+		return r.ID
 	}
-	u, err := url.Parse(fullURL)
-	if err != nil {
-		return err
-	}
-	u.User = url.UserPassword(creds.Username, creds.Password)
+	return r.Name
+}
 
-	c, err := govmomi.NewClient(ctx, u, true)
-	if err != nil {
-		return err
-	}
-	defer c.Logout(ctx)
+// VMCandidate is one of the VMs an ambiguous name matched.
+type VMCandidate struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
 
-	finder := find.NewFinder(c.Client, true)
-	dc, err := finder.Datacenter(ctx, creds.Datacenter)
-	if err != nil {
-		return err
-	}
-	finder.SetDatacenter(dc)
+// AmbiguousVMError is returned when a VM name matches more than one VM.
+type AmbiguousVMError struct {
+	Name       string
+	Candidates []VMCandidate
+}
 
-	vm, err := finder.VirtualMachine(ctx, oldName)
-	if err != nil {
-		return err
+func (e *AmbiguousVMError) Error() string {
+	paths := make([]string, len(e.Candidates))
+	for i, c := range e.Candidates {
+		paths[i] = c.Path
 	}
+	return fmt.Sprintf("VM name %q is ambiguous, it matches %d VMs (%s); specify the VM by ID or inventory path",
+		e.Name, len(e.Candidates), strings.Join(paths, ", "))
+}
 
-	task, err := vm.Rename(ctx, newName)
-	if err != nil {
-		return err
-	}
+// VMNotFoundError is returned when no VM matches a VM ID or name.
+type VMNotFoundError struct {
+	Ref string
+}
 
-	return task.Wait(ctx)
+func (e *VMNotFoundError) Error() string {
+	return fmt.Sprintf("VM %s not found", e.Ref)
 }
 
-// UpdateVMNetworkMAC updates the MAC address of a specific network device.
-func UpdateVMNetworkMAC(ctx context.Context, creds VCenterCredentials, vmName string, deviceKey int32, newMAC string) error {
-	fullURL := creds.URL
-	if !strings.HasPrefix(fullURL, "https://") && !strings.HasPrefix(fullURL, "http://") {
-		fullURL = "https://" + fullURL
-	}
-	u, err := url.Parse(fullURL)
-	if err != nil {
-		return err
+// findVM resolves ref to a VM, see VMRef.
+func findVM(ctx context.Context, c *govmomi.Client, creds VCenterCredentials, ref VMRef) (*object.VirtualMachine, error) {
+	if ref.ID != "" {
+		moref := types.ManagedObjectReference{Type: "VirtualMachine", Value: ref.ID}
+		// Check the moRef up front so a stale ID fails with a clear message
+		// instead of a fault from the middle of the operation.
+		var mvm mo.VirtualMachine
+		if err := property.DefaultCollector(c.Client).RetrieveOne(ctx, moref, []string{"name"}, &mvm); err != nil {
+			if isManagedObjectNotFound(err) {
+				return nil, &VMNotFoundError{Ref: ref.ID}
+			}
+			return nil, err
+		}
+		return object.NewVirtualMachine(c.Client, moref), nil
 	}
-	u.User = url.UserPassword(creds.Username, creds.Password)
-
-	c, err := govmomi.NewClient(ctx, u, true)
-	if err != nil {
-		return err
+	if ref.Name == "" {
+		return nil, fmt.Errorf("no VM ID or name given")
 	}
-	defer c.Logout(ctx)
 
 	finder := find.NewFinder(c.Client, true)
 	dc, err := finder.Datacenter(ctx, creds.Datacenter)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	finder.SetDatacenter(dc)
 
-	vm, err := finder.VirtualMachine(ctx, vmName)
-	if err != nil {
-		return err
+	vms, err := finder.VirtualMachineList(ctx, ref.Name)
+	var notFound *find.NotFoundError
+	if errors.As(err, &notFound) {
+		return nil, &VMNotFoundError{Ref: ref.Name}
 	}
-
-	var mvm mo.VirtualMachine
-	pc := property.DefaultCollector(c.Client)
-	if err := pc.RetrieveOne(ctx, vm.Reference(), []string{"config.hardware.device"}, &mvm); err != nil {
-		return err
+	if err != nil {
+		return nil, err
 	}
-
-	deviceList := object.VirtualDeviceList(mvm.Config.Hardware.Device)
-	device := deviceList.FindByKey(deviceKey)
-	if device == nil {
-		return fmt.Errorf("device with key %d not found", deviceKey)
+	if len(vms) == 1 {
+		return vms[0], nil
 	}
-
-	nic, ok := device.(types.BaseVirtualEthernetCard)
-	if !ok {
-		return fmt.Errorf("device with key %d is not a network card", deviceKey)
+	ambiguous := &AmbiguousVMError{Name: ref.Name}
+	for _, vm := range vms {
+		ambiguous.Candidates = append(ambiguous.Candidates, VMCandidate{
+			ID:   vm.Reference().Value,
+			Name: vm.Name(),
+			Path: vm.InventoryPath,
+		})
 	}
+	return nil, ambiguous
+}
 
-	card := nic.GetVirtualEthernetCard()
-	card.MacAddress = newMAC
-	card.AddressType = "manual"
+// PowerOpVM performs a power operation on a VM.
+func PowerOpVM(ctx context.Context, creds VCenterCredentials, ref VMRef, op string) error {
+	return vcenterSessions.Do(ctx, creds, func(c *govmomi.Client) error {
+		vm, err := findVM(ctx, c, creds, ref)
+		if err != nil {
+			return err
+		}
 
-	spec := types.VirtualMachineConfigSpec{
-		DeviceChange: []types.BaseVirtualDeviceConfigSpec{
-			&types.VirtualDeviceConfigSpec{
-				Operation: types.VirtualDeviceConfigSpecOperationEdit,
-				Device:    device,
-			},
-		},
-	}
+		var task *object.Task
+		switch op {
+		case "on":
+			task, err = vm.PowerOn(ctx)
+		case "off":
+			task, err = vm.PowerOff(ctx)
+		case "reset":
+			task, err = vm.Reset(ctx)
+		case "shutdown":
+			err = vm.ShutdownGuest(ctx)
+			if err != nil {
+				// Fallback to power off if shutdown fails (e.g. tools not installed)
+				log.Warnf("Guest shutdown failed for %s, falling back to power off: %v", ref, err)
+				task, err = vm.PowerOff(ctx)
+			} else {
+				return nil // ShutdownGuest doesn't return a task, it's just an error if it fails to initiate
+			}
+		default:
+			return fmt.Errorf("unsupported power operation: %s", op)
+		}
 
-	task, err := vm.Reconfigure(ctx, spec)
-	if err != nil {
-		return err
-	}
+		if err != nil {
+			return err
+		}
 
-	return task.Wait(ctx)
+		if task != nil {
+			return task.Wait(ctx)
+		}
+		return nil
+	})
 }
 
-// GetVCenterInventoryAutoDiscover connects to vCenter and auto-discovers the first datacenter.
-// This is used by Forklift, which doesn't store the datacenter name in the Provider spec.
-func GetVCenterInventoryAutoDiscover(ctx context.Context, creds VCenterCredentials) (*InventoryNode, error) {
-	fullURL := creds.URL
-	if !strings.HasPrefix(fullURL, "https://") && !strings.HasPrefix(fullURL, "http://") {
-		fullURL = "https://" + fullURL
-	}
-
-	u, err := url.Parse(fullURL)
-	if err != nil {
-		return nil, err
-	}
-	u.User = url.UserPassword(creds.Username, creds.Password)
+// RenameVM renames a VM in vCenter.
+func RenameVM(ctx context.Context, creds VCenterCredentials, ref VMRef, newName string) error {
+	return vcenterSessions.Do(ctx, creds, func(c *govmomi.Client) error {
+		vm, err := findVM(ctx, c, creds, ref)
+		if err != nil {
+			return err
+		}
 
-	log.Infof("Connecting to vCenter at %s (auto-discover mode)", creds.URL)
-	c, err := govmomi.NewClient(ctx, u, true)
-	if err != nil {
-		return nil, err
-	}
-	defer c.Logout(ctx)
+		task, err := vm.Rename(ctx, newName)
+		if err != nil {
+			return err
+		}
 
-	finder := find.NewFinder(c.Client, true)
+		return task.Wait(ctx)
+	})
+}
 
-	// If datacenter is specified, use it; otherwise auto-discover
-	var dc *object.Datacenter
-	if creds.Datacenter != "" {
-		dc, err = finder.Datacenter(ctx, creds.Datacenter)
+// UpdateVMNetworkMAC updates the MAC address of a specific network device.
+func UpdateVMNetworkMAC(ctx context.Context, creds VCenterCredentials, ref VMRef, deviceKey int32, newMAC string) error {
+	return vcenterSessions.Do(ctx, creds, func(c *govmomi.Client) error {
+		vm, err := findVM(ctx, c, creds, ref)
 		if err != nil {
-			return nil, fmt.Errorf("failed to find datacenter %s: %w", creds.Datacenter, err)
+			return err
 		}
-	} else {
-		// Auto-discover: get the default datacenter
-		dc, err = finder.DefaultDatacenter(ctx)
-		if err != nil {
-			// Try listing all datacenters
-			dcs, listErr := finder.DatacenterList(ctx, "*")
-			if listErr != nil || len(dcs) == 0 {
-				return nil, fmt.Errorf("no datacenter found: %v", err)
-			}
-			dc = dcs[0]
+
+		var mvm mo.VirtualMachine
+		pc := property.DefaultCollector(c.Client)
+		if err := pc.RetrieveOne(ctx, vm.Reference(), []string{"config.hardware.device"}, &mvm); err != nil {
+			return err
 		}
-	}
 
-	finder.SetDatacenter(dc)
+		deviceList := object.VirtualDeviceList(mvm.Config.Hardware.Device)
+		device := deviceList.FindByKey(deviceKey)
+		if device == nil {
+			return fmt.Errorf("device with key %d not found", deviceKey)
+		}
 
-	rootNode := &InventoryNode{
-		Name: dc.Name(),
-		Type: "datacenter",
-	}
+		nic, ok := device.(types.BaseVirtualEthernetCard)
+		if !ok {
+			return fmt.Errorf("device with key %d is not a network card", deviceKey)
+		}
 
-	folders, err := dc.Folders(ctx)
-	if err != nil {
-		return nil, err
-	}
+		card := nic.GetVirtualEthernetCard()
+		card.MacAddress = newMAC
+		card.AddressType = "manual"
 
-	rootFolder := object.NewFolder(c.Client, folders.VmFolder.Reference())
-	children, err := rootFolder.Children(ctx)
-	if err != nil {
-		return nil, err
-	}
+		spec := types.VirtualMachineConfigSpec{
+			DeviceChange: []types.BaseVirtualDeviceConfigSpec{
+				&types.VirtualDeviceConfigSpec{
+					Operation: types.VirtualDeviceConfigSpecOperationEdit,
+					Device:    device,
+				},
+			},
+		}
 
-	for _, child := range children {
-		node, err := processEntity(ctx, c, child, "")
+		task, err := vm.Reconfigure(ctx, spec)
 		if err != nil {
-			log.Warnf("Could not process entity %s: %v", child.Reference().Value, err)
-			continue
-		}
-		if node != nil {
-			rootNode.Children = append(rootNode.Children, *node)
+			return err
 		}
-	}
 
-	log.Debugf("Constructed vCenter inventory tree (auto-discover): %+v", rootNode)
-	return rootNode, nil
+		return task.Wait(ctx)
+	})
 }
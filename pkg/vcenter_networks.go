@@ -0,0 +1,298 @@
+// pkg/vcenter_networks.go
+package main
+
+import (
+	"context"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// VLAN types reported for vCenter networks.
+const (
+	vlanTypeNone  = "none"
+	vlanTypeVLAN  = "vlan"
+	vlanTypeTrunk = "trunk"
+	vlanTypePVLAN = "pvlan"
+)
+
+// vlanIDTrunkAll is the standard portgroup VLAN ID for "all VLANs" (guest tagging).
+const vlanIDTrunkAll = 4095
+
+// uplinkPortgroupTag is the system tag vCenter puts on a dvSwitch's uplink portgroup.
+const uplinkPortgroupTag = "SYSTEM/DVS.UPLINKPG"
+
+// VLANRange is an inclusive range of VLAN IDs carried by a trunk.
+type VLANRange struct {
+	Start int32 `json:"start"`
+	End   int32 `json:"end"`
+}
+
+// VCenterNetwork is a network a VM NIC can attach to: a standard portgroup,
+// a distributed portgroup or an opaque (NSX) network. ID is the value NIC
+// backings and Forklift NetworkMaps refer to.
+type VCenterNetwork struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Type       string `json:"type"` // Network, DistributedVirtualPortgroup or OpaqueNetwork
+	Datacenter string `json:"datacenter,omitempty"`
+
+	// Switch is the distributed switch of a DVS portgroup, or the vSwitch
+	// name(s) hosts back a standard portgroup with.
+	Switch   string `json:"switch,omitempty"`
+	SwitchID string `json:"switchId,omitempty"`
+	Uplink   bool   `json:"uplink,omitempty"`
+	Backing  string `json:"backing,omitempty"` // DVS portgroup backing, e.g. standard or nsx
+
+	VLANType   string      `json:"vlanType,omitempty"` // none, vlan, trunk or pvlan
+	VLANID     int32       `json:"vlanId,omitempty"`
+	VLANRanges []VLANRange `json:"vlanRanges,omitempty"`
+	PVLANID    int32       `json:"pvlanId,omitempty"`
+	// HostVLANIDs is set for a standard portgroup whose hosts disagree on its
+	// VLAN; VLANID is then the most common one.
+	HostVLANIDs []int32 `json:"hostVlanIds,omitempty"`
+
+	OpaqueNetworkID   string `json:"opaqueNetworkId,omitempty"`
+	OpaqueNetworkType string `json:"opaqueNetworkType,omitempty"`
+}
+
+// networkPropertySpecs lists the properties the network listing needs; like
+// the inventory, everything comes back from a single RetrieveProperties call.
+var networkPropertySpecs = map[string][]string{
+	"Datacenter":                     {"name", "networkFolder"},
+	"Folder":                         {"childEntity"},
+	"Network":                        {"name", "host"},
+	"OpaqueNetwork":                  {"name", "host", "summary"},
+	"DistributedVirtualPortgroup":    {"name", "tag", "config.distributedVirtualSwitch", "config.defaultPortConfig", "config.backingType"},
+	"DistributedVirtualSwitch":       {"name", "config"},
+	"VmwareDistributedVirtualSwitch": {"name", "config"},
+	"HostSystem":                     {"config.network.portgroup"},
+}
+
+// GetVCenterNetworks lists every network in vCenter, sorted by datacenter
+// and name.
+func GetVCenterNetworks(ctx context.Context, creds VCenterCredentials) ([]VCenterNetwork, error) {
+	var networks []VCenterNetwork
+	err := vcenterSessions.Do(ctx, creds, func(c *govmomi.Client) error {
+		var err error
+		networks, err = listVCenterNetworks(ctx, c)
+		return err
+	})
+	return networks, err
+}
+
+func listVCenterNetworks(ctx context.Context, c *govmomi.Client) ([]VCenterNetwork, error) {
+	kinds := make([]string, 0, len(networkPropertySpecs))
+	for kind := range networkPropertySpecs {
+		kinds = append(kinds, kind)
+	}
+	v, err := view.NewManager(c.Client).CreateContainerView(ctx, c.ServiceContent.RootFolder, kinds, true)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := v.Destroy(context.Background()); err != nil {
+			log.Debugf("Failed to destroy network container view: %v", err)
+		}
+	}()
+
+	spec := types.PropertyFilterSpec{
+		ObjectSet: []types.ObjectSpec{{
+			Obj:       v.Reference(),
+			Skip:      types.NewBool(true),
+			SelectSet: []types.BaseSelectionSpec{v.TraversalSpec()},
+		}},
+	}
+	for kind, props := range networkPropertySpecs {
+		spec.PropSet = append(spec.PropSet, types.PropertySpec{Type: kind, PathSet: props})
+	}
+	res, err := property.DefaultCollector(c.Client).RetrieveProperties(ctx, types.RetrieveProperties{SpecSet: []types.PropertyFilterSpec{spec}})
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		datacenters []mo.Datacenter
+		folders     = map[types.ManagedObjectReference][]types.ManagedObjectReference{}
+		switches    = map[string]string{} // dvSwitch moRef value → name
+		uplinks     = map[types.ManagedObjectReference]bool{}
+		hostGroups  = map[types.ManagedObjectReference][]types.HostPortGroup{}
+		networks    = map[types.ManagedObjectReference]VCenterNetwork{}
+		stdHosts    = map[types.ManagedObjectReference][]types.ManagedObjectReference{}
+	)
+	addSwitch := func(dvs mo.DistributedVirtualSwitch) {
+		switches[dvs.Self.Value] = dvs.Name
+		if dvs.Config != nil {
+			for _, pg := range dvs.Config.GetDVSConfigInfo().UplinkPortgroup {
+				uplinks[pg] = true
+			}
+		}
+	}
+	for _, content := range res.Returnval {
+		obj, err := mo.ObjectContentToType(content)
+		if err != nil {
+			log.Warnf("Could not load %s %s: %v", content.Obj.Type, content.Obj.Value, err)
+			continue
+		}
+		switch o := obj.(type) {
+		case mo.Datacenter:
+			datacenters = append(datacenters, o)
+		case mo.Folder:
+			folders[o.Self] = o.ChildEntity
+		case mo.DistributedVirtualSwitch:
+			addSwitch(o)
+		case mo.VmwareDistributedVirtualSwitch:
+			addSwitch(o.DistributedVirtualSwitch)
+		case mo.HostSystem:
+			if o.Config != nil && o.Config.Network != nil {
+				hostGroups[o.Self] = o.Config.Network.Portgroup
+			}
+		case mo.Network:
+			networks[o.Self] = VCenterNetwork{ID: o.Self.Value, Name: o.Name, Type: o.Self.Type}
+			stdHosts[o.Self] = o.Host
+		case mo.OpaqueNetwork:
+			n := VCenterNetwork{ID: o.Self.Value, Name: o.Name, Type: o.Self.Type}
+			if summary, ok := o.Summary.(*types.OpaqueNetworkSummary); ok {
+				n.OpaqueNetworkID = summary.OpaqueNetworkId
+				n.OpaqueNetworkType = summary.OpaqueNetworkType
+			}
+			networks[o.Self] = n
+		case mo.DistributedVirtualPortgroup:
+			n := VCenterNetwork{ID: o.Self.Value, Name: o.Name, Type: o.Self.Type, Backing: o.Config.BackingType}
+			if o.Config.DistributedVirtualSwitch != nil {
+				n.SwitchID = o.Config.DistributedVirtualSwitch.Value
+			}
+			setPortgroupVLAN(&n, o.Config.DefaultPortConfig)
+			for _, tag := range o.Tag {
+				if tag.Key == uplinkPortgroupTag {
+					uplinks[o.Self] = true
+				}
+			}
+			networks[o.Self] = n
+		}
+	}
+
+	for ref, n := range networks {
+		switch n.Type {
+		case "DistributedVirtualPortgroup":
+			n.Switch = switches[n.SwitchID]
+			n.Uplink = uplinks[ref]
+		case "Network":
+			setStandardPortgroupVLAN(&n, stdHosts[ref], hostGroups)
+		}
+		networks[ref] = n
+	}
+
+	// Attribute networks to datacenters by walking each network folder.
+	var list []VCenterNetwork
+	var walk func(folder types.ManagedObjectReference, datacenter string)
+	walk = func(folder types.ManagedObjectReference, datacenter string) {
+		for _, child := range folders[folder] {
+			if child.Type == "Folder" {
+				walk(child, datacenter)
+				continue
+			}
+			if n, ok := networks[child]; ok {
+				n.Datacenter = datacenter
+				list = append(list, n)
+			}
+		}
+	}
+	for _, dc := range datacenters {
+		walk(dc.NetworkFolder, dc.Name)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Datacenter != list[j].Datacenter {
+			return list[i].Datacenter < list[j].Datacenter
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list, nil
+}
+
+// setPortgroupVLAN reads a distributed portgroup's VLAN from its default port
+// configuration.
+func setPortgroupVLAN(n *VCenterNetwork, setting types.BaseDVPortSetting) {
+	n.VLANType = vlanTypeNone
+	portSetting, ok := setting.(*types.VMwareDVSPortSetting)
+	if !ok || portSetting == nil {
+		return
+	}
+	switch vlan := portSetting.Vlan.(type) {
+	case *types.VmwareDistributedVirtualSwitchVlanIdSpec:
+		if vlan.VlanId != 0 {
+			n.VLANType = vlanTypeVLAN
+			n.VLANID = vlan.VlanId
+		}
+	case *types.VmwareDistributedVirtualSwitchTrunkVlanSpec:
+		n.VLANType = vlanTypeTrunk
+		for _, r := range vlan.VlanId {
+			n.VLANRanges = append(n.VLANRanges, VLANRange{Start: r.Start, End: r.End})
+		}
+	case *types.VmwareDistributedVirtualSwitchPvlanSpec:
+		n.VLANType = vlanTypePVLAN
+		n.PVLANID = vlan.PvlanId
+	}
+}
+
+// setStandardPortgroupVLAN derives a standard portgroup's VLAN and vSwitch from
+// the same-named portgroup on each host that has the network. VLAN 4095 is
+// reported as a trunk of all VLANs.
+func setStandardPortgroupVLAN(n *VCenterNetwork, hosts []types.ManagedObjectReference, hostGroups map[types.ManagedObjectReference][]types.HostPortGroup) {
+	counts := map[int32]int{}
+	vswitches := map[string]bool{}
+	for _, host := range hosts {
+		for _, pg := range hostGroups[host] {
+			if pg.Spec.Name == n.Name {
+				counts[pg.Spec.VlanId]++
+				vswitches[pg.Spec.VswitchName] = true
+			}
+		}
+	}
+
+	var names []string
+	for name := range vswitches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		if i > 0 {
+			n.Switch += ", "
+		}
+		n.Switch += name
+	}
+
+	n.VLANType = vlanTypeNone
+	if len(counts) == 0 {
+		return
+	}
+	var ids []int32
+	for id := range counts {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	vlanID := ids[0]
+	for _, id := range ids {
+		if counts[id] > counts[vlanID] {
+			vlanID = id
+		}
+	}
+	if len(ids) > 1 {
+		n.HostVLANIDs = ids
+	}
+
+	switch {
+	case vlanID == vlanIDTrunkAll:
+		n.VLANType = vlanTypeTrunk
+		n.VLANRanges = []VLANRange{{Start: 0, End: vlanIDTrunkAll - 1}}
+	case vlanID != 0:
+		n.VLANType = vlanTypeVLAN
+		n.VLANID = vlanID
+	}
+}
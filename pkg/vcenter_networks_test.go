@@ -0,0 +1,165 @@
+// pkg/vcenter_networks_test.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// decodeNetworks decodes a network listing response keyed by datacenter/name.
+func decodeNetworks(t *testing.T, code int, body []byte) map[string]VCenterNetwork {
+	t.Helper()
+	if code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", code, body)
+	}
+	var list []VCenterNetwork
+	if err := json.Unmarshal(body, &list); err != nil {
+		t.Fatalf("failed to unmarshal networks: %v", err)
+	}
+	networks := map[string]VCenterNetwork{}
+	for _, n := range list {
+		networks[n.Datacenter+"/"+n.Name] = n
+	}
+	return networks
+}
+
+func TestNetworkHandlersWithVCSim(t *testing.T) {
+	model := simulator.VPX()
+	model.Datacenter = 2
+	model.Portgroup = 1
+	model.OpaqueNetwork = 1
+	env := newVCSimEnv(t, model, "DC0")
+
+	// Add portgroups with an access VLAN, a trunk and a private VLAN to DVS0.
+	env.vcsim(func(ctx context.Context, c *govmomi.Client) error {
+		ref, err := find.NewFinder(c.Client, false).Network(ctx, "/DC0/network/DVS0")
+		if err != nil {
+			return err
+		}
+		specs := map[string]types.BaseVmwareDistributedVirtualSwitchVlanSpec{
+			"VLAN200": &types.VmwareDistributedVirtualSwitchVlanIdSpec{VlanId: 200},
+			"Trunk":   &types.VmwareDistributedVirtualSwitchTrunkVlanSpec{VlanId: []types.NumericRange{{Start: 100, End: 199}, {Start: 300, End: 300}}},
+			"PVLAN":   &types.VmwareDistributedVirtualSwitchPvlanSpec{PvlanId: 301},
+		}
+		var pgs []types.DVPortgroupConfigSpec
+		for name, vlan := range specs {
+			pgs = append(pgs, types.DVPortgroupConfigSpec{
+				Name:              name,
+				Type:              string(types.DistributedVirtualPortgroupPortgroupTypeEarlyBinding),
+				DefaultPortConfig: &types.VMwareDVSPortSetting{Vlan: vlan},
+			})
+		}
+		task, err := ref.(*object.DistributedVirtualSwitch).AddPortgroup(ctx, pgs)
+		if err != nil {
+			return err
+		}
+		return task.Wait(ctx)
+	})
+
+	rr := executeRequest(HandleGetVCenterNetworks(env.clients), "GET", "/api/v1/vcenter/networks", nil, env.source)
+	networks := decodeNetworks(t, rr.Code, rr.Body.Bytes())
+
+	t.Run("VmwareSource lists its own datacenter", func(t *testing.T) {
+		for key, n := range networks {
+			if n.Datacenter != "DC0" {
+				t.Errorf("network %s from another datacenter leaked into DC0's list", key)
+			}
+		}
+	})
+
+	t.Run("standard and opaque networks", func(t *testing.T) {
+		std, ok := networks["DC0/VM Network"]
+		if !ok || std.Type != "Network" || std.ID == "" {
+			t.Errorf("expected standard network VM Network, got %+v", std)
+		}
+		nsx, ok := networks["DC0/DC0_NSX0"]
+		if !ok || nsx.Type != "OpaqueNetwork" || nsx.OpaqueNetworkID == "" || nsx.OpaqueNetworkType == "" {
+			t.Errorf("expected opaque network DC0_NSX0 with its NSX ID and type, got %+v", nsx)
+		}
+	})
+
+	t.Run("distributed portgroup VLANs", func(t *testing.T) {
+		tests := []struct {
+			name, vlanType, want string
+		}{
+			{"DC0_DVPG0", vlanTypeNone, "0 [] 0"},
+			{"VLAN200", vlanTypeVLAN, "200 [] 0"},
+			{"Trunk", vlanTypeTrunk, "0 [{100 199} {300 300}] 0"},
+			{"PVLAN", vlanTypePVLAN, "0 [] 301"},
+		}
+		for _, tc := range tests {
+			n, ok := networks["DC0/"+tc.name]
+			if !ok {
+				t.Errorf("portgroup %s missing", tc.name)
+				continue
+			}
+			if n.Type != "DistributedVirtualPortgroup" || n.Switch != "DVS0" || n.SwitchID == "" {
+				t.Errorf("expected %s on DVS0, got %+v", tc.name, n)
+			}
+			if got := fmt.Sprint(n.VLANID, n.VLANRanges, n.PVLANID); n.VLANType != tc.vlanType || got != tc.want {
+				t.Errorf("%s: expected %s %s, got %s %s", tc.name, tc.vlanType, tc.want, n.VLANType, got)
+			}
+		}
+	})
+
+	t.Run("all datacenters", func(t *testing.T) {
+		rr := executeRequest(HandleGetForkliftNetworks(env.clients), "GET", "/api/v1/forklift/networks", nil, env.forklift)
+		all := decodeNetworks(t, rr.Code, rr.Body.Bytes())
+		if _, ok := all["DC1/DC1_DVPG0"]; !ok {
+			t.Errorf("expected DC1's portgroup in the Forklift Provider's list, got %d networks", len(all))
+		}
+
+		rr = executeRequest(HandleGetVCenterNetworks(env.clients), "GET", "/api/v1/vcenter/networks?datacenter=DC1", nil, env.source)
+		dc1 := decodeNetworks(t, rr.Code, rr.Body.Bytes())
+		if _, ok := dc1["DC1/DC1_DVPG0"]; !ok || len(dc1) == 0 {
+			t.Errorf("expected only DC1's networks for ?datacenter=DC1, got %v", dc1)
+		}
+		for key := range dc1 {
+			if key[:4] != "DC1/" {
+				t.Errorf("network %s listed for ?datacenter=DC1", key)
+			}
+		}
+	})
+}
+
+func TestSetStandardPortgroupVLAN(t *testing.T) {
+	host := func(id string) types.ManagedObjectReference {
+		return types.ManagedObjectReference{Type: "HostSystem", Value: id}
+	}
+	group := func(name string, vlan int32, vswitch string) types.HostPortGroup {
+		return types.HostPortGroup{Spec: types.HostPortGroupSpec{Name: name, VlanId: vlan, VswitchName: vswitch}}
+	}
+	hostGroups := map[types.ManagedObjectReference][]types.HostPortGroup{
+		host("host-1"): {group("Prod", 10, "vSwitch0"), group("Guest Tagged", 4095, "vSwitch1")},
+		host("host-2"): {group("Prod", 10, "vSwitch0"), group("Guest Tagged", 4095, "vSwitch1")},
+		host("host-3"): {group("Prod", 20, "vSwitch2")},
+	}
+	hosts := []types.ManagedObjectReference{host("host-1"), host("host-2"), host("host-3")}
+
+	prod := VCenterNetwork{Name: "Prod"}
+	setStandardPortgroupVLAN(&prod, hosts, hostGroups)
+	if prod.VLANType != vlanTypeVLAN || prod.VLANID != 10 || fmt.Sprint(prod.HostVLANIDs) != "[10 20]" || prod.Switch != "vSwitch0, vSwitch2" {
+		t.Errorf("expected VLAN 10 with hosts disagreeing on [10 20], got %+v", prod)
+	}
+
+	trunk := VCenterNetwork{Name: "Guest Tagged"}
+	setStandardPortgroupVLAN(&trunk, hosts, hostGroups)
+	if trunk.VLANType != vlanTypeTrunk || fmt.Sprint(trunk.VLANRanges) != "[{0 4094}]" || trunk.HostVLANIDs != nil {
+		t.Errorf("expected VLAN 4095 to be reported as a full trunk, got %+v", trunk)
+	}
+
+	orphan := VCenterNetwork{Name: "Unused"}
+	setStandardPortgroupVLAN(&orphan, hosts, hostGroups)
+	if orphan.VLANType != vlanTypeNone {
+		t.Errorf("expected no VLAN for a portgroup no host has, got %+v", orphan)
+	}
+}
@@ -0,0 +1,332 @@
+// pkg/vcenter_session.go
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/session"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+const (
+	// vcenterSessionIdleTTL is how long an unused session stays cached. It is kept
+	// well below vCenter's default 30 minute idle timeout so we normally log out
+	// before the server expires the session on its own.
+	vcenterSessionIdleTTL = 10 * time.Minute
+	// vcenterSessionSweepInterval is how often idle sessions are checked for eviction.
+	vcenterSessionSweepInterval = time.Minute
+)
+
+// vcenterSessions is the process-wide pool every vCenter operation goes through.
+var vcenterSessions = newVCenterSessionManager(vcenterSessionIdleTTL)
+
+// vcenterSession is one authenticated client plus the bookkeeping needed to
+// decide when it is stale (credentials changed) or idle (safe to log out).
+type vcenterSession struct {
+	client      *govmomi.Client
+	fingerprint string
+	lastUsed    time.Time
+	inUse       int
+}
+
+// vcenterSessionManager keeps authenticated govmomi clients alive between HTTP
+// requests. Logging in per request floods vCenter with sessions (each one counts
+// against the server's session limit until it times out) and adds a full SOAP
+// login round trip to every click in the explorer.
+//
+// Sessions are keyed by the source they belong to (a VmwareSource or Forklift
+// Provider), and each remembers a fingerprint of the endpoint + credentials it
+// was created with, so a credential change is picked up even if nobody called
+// Invalidate.
+type vcenterSessionManager struct {
+	mu       sync.Mutex
+	sessions map[string]*vcenterSession
+	// retired holds sessions replaced or invalidated while still in use; they
+	// are logged out when their last user releases them.
+	retired map[*govmomi.Client]*vcenterSession
+	idleTTL time.Duration
+	janitor sync.Once
+	// login and logout are swappable so tests can count or fail them.
+	login  func(ctx context.Context, creds VCenterCredentials) (*govmomi.Client, error)
+	logout func(c *govmomi.Client)
+}
+
+func newVCenterSessionManager(idleTTL time.Duration) *vcenterSessionManager {
+	return &vcenterSessionManager{
+		sessions: map[string]*vcenterSession{},
+		retired:  map[*govmomi.Client]*vcenterSession{},
+		idleTTL:  idleTTL,
+		login:    loginVCenter,
+		logout:   logoutVCenter,
+	}
+}
+
+// vcenterSessionKey identifies the source a set of credentials came from, e.g.
+// "VmwareSource/default/vcenter". Used both as the pool key and for invalidation.
+func vcenterSessionKey(kind, namespace, name string) string {
+	return kind + "/" + namespace + "/" + name
+}
+
+// credentialsFingerprint hashes everything that affects how we connect and
+// authenticate, so passwords never sit in map keys or logs.
+func credentialsFingerprint(creds VCenterCredentials) string {
+	h := sha256.New()
+	for _, part := range []string{
+		creds.URL, creds.Username, creds.Password,
+		strconv.FormatBool(creds.InsecureSkipVerify), creds.CACert, creds.Thumbprint,
+	} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// vcenterURL normalises a VmwareSource endpoint / Provider URL (which may omit
+// the scheme) into a URL carrying the login credentials.
+func vcenterURL(creds VCenterCredentials) (*url.URL, error) {
+	fullURL := creds.URL
+	if !strings.HasPrefix(fullURL, "https://") && !strings.HasPrefix(fullURL, "http://") {
+		fullURL = "https://" + fullURL
+	}
+	u, err := url.Parse(fullURL)
+	if err != nil {
+		return nil, err
+	}
+	u.User = url.UserPassword(creds.Username, creds.Password)
+	return u, nil
+}
+
+// loginVCenter opens a new authenticated session, honouring the source's TLS
+// settings (see configureVCenterTLS).
+func loginVCenter(ctx context.Context, creds VCenterCredentials) (*govmomi.Client, error) {
+	u, err := vcenterURL(creds)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("Opening vCenter session to %s", creds.URL)
+
+	sc := soap.NewClient(u, creds.InsecureSkipVerify)
+	if err := configureVCenterTLS(sc, creds); err != nil {
+		return nil, fmt.Errorf("invalid TLS settings for vCenter %s: %w", u.Host, err)
+	}
+	vimClient, err := vim25.NewClient(ctx, sc)
+	if err != nil {
+		if isCertificateVerificationError(err) {
+			return nil, explainCertificateError(ctx, u, err)
+		}
+		return nil, err
+	}
+
+	c := &govmomi.Client{
+		Client:         vimClient,
+		SessionManager: session.NewManager(vimClient),
+	}
+	if err := c.Login(ctx, u.User); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// vimFault returns the vSphere fault carried by err (or anything it wraps), or
+// nil if err is not a SOAP/vim fault.
+func vimFault(err error) interface{} {
+	for ; err != nil; err = errors.Unwrap(err) {
+		switch {
+		case soap.IsSoapFault(err):
+			return soap.ToSoapFault(err).VimFault()
+		case soap.IsVimFault(err):
+			return soap.ToVimFault(err)
+		}
+	}
+	return nil
+}
+
+// isNotAuthenticated reports whether err is vCenter telling us the session is
+// gone (expired server-side, or logged out by an admin).
+func isNotAuthenticated(err error) bool {
+	switch vimFault(err).(type) {
+	case types.NotAuthenticated, *types.NotAuthenticated:
+		return true
+	}
+	return false
+}
+
+// isManagedObjectNotFound reports whether err is vCenter rejecting a moRef
+// that no longer exists (e.g. the VM was deleted).
+func isManagedObjectNotFound(err error) bool {
+	switch vimFault(err).(type) {
+	case types.ManagedObjectNotFound, *types.ManagedObjectNotFound:
+		return true
+	}
+	return false
+}
+
+// Do runs fn with a pooled client for creds. If vCenter rejects the session as
+// not authenticated, the session is dropped, a fresh login is made and fn is
+// retried once.
+func (m *vcenterSessionManager) Do(ctx context.Context, creds VCenterCredentials, fn func(c *govmomi.Client) error) error {
+	m.janitor.Do(func() { go m.sweepLoop() })
+
+	c, err := m.acquire(ctx, creds)
+	if err != nil {
+		return err
+	}
+	err = fn(c)
+	m.release(creds, c)
+	if !isNotAuthenticated(err) {
+		return err
+	}
+
+	log.Infof("vCenter session for %s is no longer authenticated, logging in again", creds.URL)
+	m.discard(creds, c)
+	c, err = m.acquire(ctx, creds)
+	if err != nil {
+		return err
+	}
+	defer m.release(creds, c)
+	return fn(c)
+}
+
+func (m *vcenterSessionManager) key(creds VCenterCredentials) string {
+	if creds.SessionKey != "" {
+		return creds.SessionKey
+	}
+	return credentialsFingerprint(creds)
+}
+
+func (m *vcenterSessionManager) acquire(ctx context.Context, creds VCenterCredentials) (*govmomi.Client, error) {
+	key := m.key(creds)
+	fp := credentialsFingerprint(creds)
+
+	m.mu.Lock()
+	if s, ok := m.sessions[key]; ok {
+		if s.fingerprint == fp {
+			s.inUse++
+			s.lastUsed = time.Now()
+			m.mu.Unlock()
+			return s.client, nil
+		}
+		// Credentials or endpoint changed under the same source: retire the old
+		// session.
+		delete(m.sessions, key)
+		m.retire(s)
+	}
+	m.mu.Unlock()
+
+	// Log in without holding the lock so a slow vCenter doesn't stall every
+	// other source.
+	c, err := m.login(ctx, creds)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.sessions[key]; ok && s.fingerprint == fp {
+		// Lost a race with a concurrent login for the same source; use theirs.
+		go m.logout(c)
+		s.inUse++
+		s.lastUsed = time.Now()
+		return s.client, nil
+	}
+	m.sessions[key] = &vcenterSession{client: c, fingerprint: fp, lastUsed: time.Now(), inUse: 1}
+	return c, nil
+}
+
+// retire logs out a session taken out of the pool. Anyone still using it
+// keeps their client until they finish, and the last of them logs it out.
+// The caller holds m.mu.
+func (m *vcenterSessionManager) retire(s *vcenterSession) {
+	if s.inUse == 0 {
+		go m.logout(s.client)
+	} else {
+		m.retired[s.client] = s
+	}
+}
+
+func (m *vcenterSessionManager) release(creds VCenterCredentials, c *govmomi.Client) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.sessions[m.key(creds)]; ok && s.client == c {
+		s.inUse--
+		s.lastUsed = time.Now()
+		return
+	}
+	if s, ok := m.retired[c]; ok {
+		s.inUse--
+		if s.inUse <= 0 {
+			delete(m.retired, c)
+			log.Debugf("Logging out retired vCenter session for %s", creds.URL)
+			go m.logout(c)
+		}
+	}
+}
+
+// discard forgets c if it is still the pooled client for creds.
+func (m *vcenterSessionManager) discard(creds VCenterCredentials, c *govmomi.Client) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := m.key(creds)
+	if s, ok := m.sessions[key]; ok && s.client == c {
+		delete(m.sessions, key)
+	}
+}
+
+// Invalidate forgets the session for a source and logs it out once nobody is
+// using it. Called whenever a source's endpoint or credentials are edited or
+// the source is deleted.
+func (m *vcenterSessionManager) Invalidate(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.sessions[key]; ok {
+		delete(m.sessions, key)
+		log.Debugf("Invalidated vCenter session for %s", key)
+		m.retire(s)
+	}
+}
+
+// sweep logs out sessions that have been idle longer than idleTTL.
+func (m *vcenterSessionManager) sweep(now time.Time) {
+	var idle []*govmomi.Client
+	m.mu.Lock()
+	for key, s := range m.sessions {
+		if s.inUse == 0 && now.Sub(s.lastUsed) > m.idleTTL {
+			idle = append(idle, s.client)
+			delete(m.sessions, key)
+			log.Debugf("Evicting idle vCenter session for %s", key)
+		}
+	}
+	m.mu.Unlock()
+	for _, c := range idle {
+		m.logout(c)
+	}
+}
+
+func (m *vcenterSessionManager) sweepLoop() {
+	ticker := time.NewTicker(vcenterSessionSweepInterval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		m.sweep(now)
+	}
+}
+
+func logoutVCenter(c *govmomi.Client) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := c.Logout(ctx); err != nil {
+		log.Debugf("vCenter logout failed (session probably already expired): %v", err)
+	}
+}
@@ -0,0 +1,221 @@
+// pkg/vcenter_session_test.go
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/simulator"
+)
+
+// startSimulator starts an in-process vcsim for model and returns credentials
+// pointing at it. The simulator is torn down when the test finishes.
+func startSimulator(t testing.TB, model *simulator.Model) VCenterCredentials {
+	t.Helper()
+	if err := model.Create(); err != nil {
+		t.Fatalf("failed to create vcsim model: %v", err)
+	}
+	server := model.Service.NewServer()
+	t.Cleanup(func() {
+		server.Close()
+		model.Remove()
+	})
+	return simulatorCredentials(server)
+}
+
+// simulatorCredentials splits a vcsim server URL into VCenterCredentials.
+func simulatorCredentials(server *simulator.Server) VCenterCredentials {
+	u := *server.URL
+	password, _ := u.User.Password()
+	username := u.User.Username()
+	u.User = nil
+	return VCenterCredentials{
+		URL:      u.String(),
+		Username: username,
+		Password: password,
+	}
+}
+
+// countingSessionManager returns a session manager whose logins are counted.
+func countingSessionManager(logins *int32) *vcenterSessionManager {
+	m := newVCenterSessionManager(time.Minute)
+	m.login = func(ctx context.Context, creds VCenterCredentials) (*govmomi.Client, error) {
+		atomic.AddInt32(logins, 1)
+		return loginVCenter(ctx, creds)
+	}
+	return m
+}
+
+func TestVCenterSessionManagerReusesSessions(t *testing.T) {
+	creds := startSimulator(t, simulator.VPX())
+	creds.SessionKey = vcenterSessionKey("VmwareSource", "default", "vcsim")
+
+	var logins int32
+	m := countingSessionManager(&logins)
+	ctx := context.Background()
+
+	var first, second *govmomi.Client
+	if err := m.Do(ctx, creds, func(c *govmomi.Client) error { first = c; return nil }); err != nil {
+		t.Fatalf("first Do failed: %v", err)
+	}
+	if err := m.Do(ctx, creds, func(c *govmomi.Client) error { second = c; return nil }); err != nil {
+		t.Fatalf("second Do failed: %v", err)
+	}
+	if first != second || logins != 1 {
+		t.Errorf("expected one login reused across calls, got %d logins", logins)
+	}
+
+	t.Run("credential change creates a new session", func(t *testing.T) {
+		changed := creds
+		changed.Password = "rotated"
+		if err := m.Do(ctx, changed, func(c *govmomi.Client) error { return nil }); err != nil {
+			t.Fatalf("Do with changed credentials failed: %v", err)
+		}
+		if logins != 2 {
+			t.Errorf("expected a fresh login after credential change, got %d logins", logins)
+		}
+	})
+
+	t.Run("invalidate forces a new login", func(t *testing.T) {
+		before := atomic.LoadInt32(&logins)
+		m.Invalidate(creds.SessionKey)
+		if err := m.Do(ctx, creds, func(c *govmomi.Client) error { return nil }); err != nil {
+			t.Fatalf("Do after invalidate failed: %v", err)
+		}
+		if logins != before+1 {
+			t.Errorf("expected a fresh login after Invalidate, got %d logins (was %d)", logins, before)
+		}
+	})
+}
+
+func TestVCenterSessionManagerReauthenticates(t *testing.T) {
+	creds := startSimulator(t, simulator.VPX())
+	creds.SessionKey = vcenterSessionKey("Provider", "forklift", "vcsim")
+
+	var logins int32
+	m := countingSessionManager(&logins)
+	ctx := context.Background()
+
+	// Log the pooled session out behind the manager's back, as a vCenter
+	// session timeout would.
+	if err := m.Do(ctx, creds, func(c *govmomi.Client) error { return c.SessionManager.Logout(ctx) }); err != nil {
+		t.Fatalf("logout failed: %v", err)
+	}
+
+	var name string
+	err := m.Do(ctx, creds, func(c *govmomi.Client) error {
+		dcs, err := find.NewFinder(c.Client, true).DatacenterList(ctx, "*")
+		if len(dcs) > 0 {
+			name = dcs[0].Name()
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("expected transparent re-authentication, got: %v", err)
+	}
+	if name == "" {
+		t.Errorf("expected a datacenter after re-authentication")
+	}
+	if logins != 2 {
+		t.Errorf("expected exactly one re-login, got %d logins", logins)
+	}
+}
+
+func TestVCenterSessionManagerSweepsIdleSessions(t *testing.T) {
+	creds := startSimulator(t, simulator.VPX())
+
+	var logins int32
+	m := countingSessionManager(&logins)
+	ctx := context.Background()
+
+	if err := m.Do(ctx, creds, func(c *govmomi.Client) error { return nil }); err != nil {
+		t.Fatalf("Do failed: %v", err)
+	}
+	m.sweep(time.Now().Add(2 * time.Minute))
+	if len(m.sessions) != 0 {
+		t.Errorf("expected idle session to be evicted, %d remain", len(m.sessions))
+	}
+}
+
+func TestVCenterSessionManagerLogsOutRetiredSessions(t *testing.T) {
+	creds := startSimulator(t, simulator.VPX())
+	creds.SessionKey = vcenterSessionKey("VmwareSource", "default", "vcsim")
+
+	var logins int32
+	m := countingSessionManager(&logins)
+	loggedOut := make(chan *govmomi.Client, 4)
+	m.logout = func(c *govmomi.Client) { loggedOut <- c }
+	ctx := context.Background()
+
+	// A long-running user, like the inventory watcher, holds the session
+	// while the credentials change.
+	held, err := m.acquire(ctx, creds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := creds
+	changed.Password = "rotated"
+	if err := m.Do(ctx, changed, func(c *govmomi.Client) error { return nil }); err != nil {
+		t.Fatalf("Do with changed credentials failed: %v", err)
+	}
+	select {
+	case c := <-loggedOut:
+		t.Fatalf("expected the held session kept until released, %v was logged out", c == held)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	m.release(creds, held)
+	select {
+	case c := <-loggedOut:
+		if c != held {
+			t.Error("expected the retired session logged out")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the retired session logged out on its last release")
+	}
+	if len(m.retired) != 0 || len(m.sessions) != 1 {
+		t.Errorf("expected only the new session left, got %d retired, %d pooled", len(m.retired), len(m.sessions))
+	}
+}
+
+func TestVCenterSessionManagerInvalidateWaitsForUsers(t *testing.T) {
+	creds := startSimulator(t, simulator.VPX())
+	creds.SessionKey = vcenterSessionKey("VmwareSource", "default", "vcsim")
+
+	var logins int32
+	m := countingSessionManager(&logins)
+	loggedOut := make(chan *govmomi.Client, 4)
+	m.logout = func(c *govmomi.Client) { loggedOut <- c }
+	ctx := context.Background()
+
+	held, err := m.acquire(ctx, creds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Invalidate(creds.SessionKey)
+	select {
+	case <-loggedOut:
+		t.Fatal("expected the session in use kept until released")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if len(m.sessions) != 0 || m.retired[held] == nil {
+		t.Fatalf("expected the session retired, got %d pooled, %d retired", len(m.sessions), len(m.retired))
+	}
+
+	m.release(creds, held)
+	select {
+	case c := <-loggedOut:
+		if c != held {
+			t.Error("expected the invalidated session logged out")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the invalidated session logged out on its last release")
+	}
+	if len(m.retired) != 0 {
+		t.Errorf("expected no retired sessions left, got %d", len(m.retired))
+	}
+}
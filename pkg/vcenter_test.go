@@ -0,0 +1,770 @@
+// pkg/vcenter_test.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+// vcsimEnv is a vcsim instance registered both as a VmwareSource and as a
+// vSphere Forklift Provider in fake Kubernetes clients, so the vCenter
+// handlers can be driven end-to-end.
+type vcsimEnv struct {
+	t        *testing.T
+	creds    VCenterCredentials
+	clients  *K8sClients
+	source   map[string]string // mux vars for the VmwareSource
+	forklift map[string]string // mux vars for the Forklift Provider
+}
+
+// newVCSimEnv starts vcsim for model and seeds a VmwareSource for datacenter
+// plus a Forklift Provider, each with its credentials secret. Cached sessions
+// and inventories are dropped before the simulator is torn down.
+func newVCSimEnv(t *testing.T, model *simulator.Model, datacenter string) *vcsimEnv {
+	t.Helper()
+	creds := startSimulator(t, model)
+	name := strings.ToLower(strings.NewReplacer("/", "-", "_", "-").Replace(t.Name()))
+
+	sourceSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name + "-creds", Namespace: "default"},
+		Data: map[string][]byte{
+			"username": []byte(creds.Username),
+			"password": []byte(creds.Password),
+		},
+	}
+	providerSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name + "-provider", Namespace: "forklift"},
+		Data: map[string][]byte{
+			"user":     []byte(creds.Username),
+			"password": []byte(creds.Password),
+		},
+	}
+	source := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "migration.harvesterhci.io/v1beta1",
+		"kind":       "VmwareSource",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": "default",
+		},
+		"spec": map[string]interface{}{
+			"endpoint": creds.URL,
+			"dc":       datacenter,
+			"credentials": map[string]interface{}{
+				"name":      sourceSecret.Name,
+				"namespace": sourceSecret.Namespace,
+			},
+		},
+	}}
+	provider := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "forklift.konveyor.io/v1beta1",
+		"kind":       "Provider",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": "forklift",
+		},
+		"spec": map[string]interface{}{
+			"type": "vsphere",
+			"url":  creds.URL,
+			"secret": map[string]interface{}{
+				"name":      providerSecret.Name,
+				"namespace": providerSecret.Namespace,
+			},
+		},
+	}}
+
+	t.Cleanup(func() {
+		invalidateVCenterSource(vcenterSessionKey("VmwareSource", "default", name))
+		invalidateVCenterSource(vcenterSessionKey("Provider", "forklift", name))
+	})
+
+	return &vcsimEnv{
+		t:        t,
+		creds:    creds,
+		clients:  newTestClientsWithDynamic([]runtime.Object{sourceSecret, providerSecret}, source, provider),
+		source:   map[string]string{"namespace": "default", "name": name},
+		forklift: map[string]string{"namespace": "forklift", "name": name},
+	}
+}
+
+// registerListKinds rebuilds the fake dynamic client, keeping the VmwareSource
+// and Provider, so it can list resources whose names it cannot guess from
+// their kind (e.g. network-attachment-definitions). Objects of those kinds
+// must then be created through the client.
+func (e *vcsimEnv) registerListKinds(listKinds map[schema.GroupVersionResource]string) {
+	e.t.Helper()
+	ctx := context.Background()
+	source, err := e.clients.Dynamic.Resource(vmwareSourceGVR).Namespace(e.source["namespace"]).Get(ctx, e.source["name"], metav1.GetOptions{})
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	provider, err := e.clients.Dynamic.Resource(forkliftProviderGVR).Namespace(e.forklift["namespace"]).Get(ctx, e.forklift["name"], metav1.GetOptions{})
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	e.clients.Dynamic = dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, source, provider)
+}
+
+// inventory fetches the VmwareSource inventory, bypassing the cached tree.
+func (e *vcsimEnv) inventory() *InventoryNode {
+	e.t.Helper()
+	rr := executeRequest(HandleGetInventory(e.clients), "GET", "/api/v1/vcenter/inventory?refresh=true", nil, e.source)
+	return decodeInventory(e.t, rr.Code, rr.Body.Bytes())
+}
+
+// forkliftInventory fetches the Forklift Provider inventory, bypassing the cache.
+func (e *vcsimEnv) forkliftInventory() *InventoryNode {
+	e.t.Helper()
+	rr := executeRequest(HandleGetForkliftInventory(e.clients), "GET", "/api/v1/forklift/inventory?refresh=true", nil, e.forklift)
+	return decodeInventory(e.t, rr.Code, rr.Body.Bytes())
+}
+
+// post sends body to a VmwareSource VM handler and returns the status code and
+// error message, if any.
+func (e *vcsimEnv) post(handler func(*K8sClients) http.HandlerFunc, body interface{}) (int, string) {
+	e.t.Helper()
+	rr := executeRequest(handler(e.clients), "POST", "/api/v1/vcenter/vm", body, e.source)
+	var result map[string]string
+	_ = json.Unmarshal(rr.Body.Bytes(), &result)
+	return rr.Code, result["error"]
+}
+
+// vcsim runs fn against the simulator with a session outside the pool.
+func (e *vcsimEnv) vcsim(fn func(ctx context.Context, c *govmomi.Client) error) {
+	e.t.Helper()
+	ctx := context.Background()
+	c, err := loginVCenter(ctx, e.creds)
+	if err != nil {
+		e.t.Fatalf("login to vcsim failed: %v", err)
+	}
+	defer logoutVCenter(c)
+	if err := fn(ctx, c); err != nil {
+		e.t.Fatalf("vcsim setup failed: %v", err)
+	}
+}
+
+func decodeInventory(t *testing.T, code int, body []byte) *InventoryNode {
+	t.Helper()
+	if code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", code, body)
+	}
+	var node InventoryNode
+	if err := json.Unmarshal(body, &node); err != nil {
+		t.Fatalf("failed to unmarshal inventory: %v", err)
+	}
+	return &node
+}
+
+// inventoryVMs returns every VM node in the tree, keyed by name.
+func inventoryVMs(node *InventoryNode) map[string]InventoryNode {
+	return indexInventoryVMs(node, func(vm *InventoryNode) string { return vm.Name })
+}
+
+// inventoryVMsByID returns every VM node in the tree, keyed by moRef.
+func inventoryVMsByID(node *InventoryNode) map[string]InventoryNode {
+	return indexInventoryVMs(node, func(vm *InventoryNode) string { return vm.ID })
+}
+
+func indexInventoryVMs(node *InventoryNode, key func(*InventoryNode) string) map[string]InventoryNode {
+	vms := map[string]InventoryNode{}
+	var walk func(n *InventoryNode)
+	walk = func(n *InventoryNode) {
+		if n.Type == "VirtualMachine" {
+			vms[key(n)] = *n
+		}
+		for i := range n.Children {
+			walk(&n.Children[i])
+		}
+	}
+	walk(node)
+	return vms
+}
+
+func TestGetInventoryHandlerWithVCSim(t *testing.T) {
+	model := simulator.VPX()
+	model.Portgroup = 1 // back VM NICs with a distributed portgroup
+	env := newVCSimEnv(t, model, "DC0")
+
+	// Build a two-level folder under the VM folder and move a VM into it.
+	env.vcsim(func(ctx context.Context, c *govmomi.Client) error {
+		finder := find.NewFinder(c.Client, true)
+		vmFolder, err := finder.Folder(ctx, "/DC0/vm")
+		if err != nil {
+			return err
+		}
+		prod, err := vmFolder.CreateFolder(ctx, "Prod")
+		if err != nil {
+			return err
+		}
+		web, err := prod.CreateFolder(ctx, "Web")
+		if err != nil {
+			return err
+		}
+		vm, err := finder.VirtualMachine(ctx, "/DC0/vm/DC0_C0_RP0_VM0")
+		if err != nil {
+			return err
+		}
+		task, err := web.MoveInto(ctx, []types.ManagedObjectReference{vm.Reference()})
+		if err != nil {
+			return err
+		}
+		if err := task.Wait(ctx); err != nil {
+			return err
+		}
+
+		// Switch one VM to UEFI with secure boot and CPU hot-add.
+		efi, err := finder.VirtualMachine(ctx, "/DC0/vm/DC0_H0_VM1")
+		if err != nil {
+			return err
+		}
+		task, err = efi.Reconfigure(ctx, types.VirtualMachineConfigSpec{
+			Firmware:         string(types.GuestOsDescriptorFirmwareTypeEfi),
+			BootOptions:      &types.VirtualMachineBootOptions{EfiSecureBootEnabled: types.NewBool(true)},
+			CpuHotAddEnabled: types.NewBool(true),
+		})
+		if err != nil {
+			return err
+		}
+		return task.Wait(ctx)
+	})
+
+	root := env.inventory()
+	if root.Type != "datacenter" || root.Name != "DC0" {
+		t.Errorf("expected datacenter DC0 at the root, got %s %q", root.Type, root.Name)
+	}
+	if root.LastRefreshed == nil {
+		t.Errorf("expected lastRefreshed on the root")
+	}
+
+	vms := inventoryVMs(root)
+	if len(vms) != model.Count().Machine {
+		t.Errorf("expected %d VMs, got %d", model.Count().Machine, len(vms))
+	}
+
+	t.Run("nested folders", func(t *testing.T) {
+		vm, ok := vms["DC0_C0_RP0_VM0"]
+		if !ok {
+			t.Fatalf("moved VM missing from inventory")
+		}
+		if vm.Folder != "Prod/Web" {
+			t.Errorf("expected folder Prod/Web, got %q", vm.Folder)
+		}
+	})
+
+	t.Run("cluster and standalone host VMs", func(t *testing.T) {
+		for _, name := range []string{"DC0_H0_VM0", "DC0_C0_RP0_VM1"} {
+			vm, ok := vms[name]
+			if !ok {
+				t.Errorf("VM %s missing from inventory", name)
+				continue
+			}
+			if vm.PowerState != "poweredOn" || vm.CPU == 0 || vm.MemoryMB == 0 {
+				t.Errorf("unexpected VM summary for %s: %+v", name, vm)
+			}
+			if vm.DatastoreID == "" || vm.DatastoreName != "LocalDS_0" {
+				t.Errorf("expected datastore LocalDS_0 on %s, got %q (%s)", name, vm.DatastoreName, vm.DatastoreID)
+			}
+			if len(vm.Disks) == 0 || vm.Disks[0].Capacity == 0 {
+				t.Errorf("expected a sized disk on %s, got %+v", name, vm.Disks)
+			}
+			if len(vm.Datastores) == 0 || vm.Datastores[0].Name != "LocalDS_0" {
+				t.Errorf("expected LocalDS_0 in the datastores of %s, got %+v", name, vm.Datastores)
+			}
+			for _, disk := range vm.Disks {
+				if disk.DatastoreName != "LocalDS_0" || !strings.HasPrefix(disk.FilePath, "[LocalDS_0] ") || disk.Provisioning == "" || disk.DiskMode == "" || disk.RDM {
+					t.Errorf("expected flat vmdk backing details on %s, got %+v", name, disk)
+				}
+			}
+		}
+	})
+
+	t.Run("guest and hardware details", func(t *testing.T) {
+		bios := vms["DC0_H0_VM0"]
+		if bios.GuestID == "" || bios.GuestOS == "" || bios.Firmware != "bios" || !strings.HasPrefix(bios.HardwareVersion, "vmx-") {
+			t.Errorf("expected guest OS, BIOS firmware and hardware version on %s, got %+v", bios.Name, bios)
+		}
+		if bios.SecureBoot || bios.CPUHotAddEnabled {
+			t.Errorf("expected secure boot and CPU hot-add off on %s", bios.Name)
+		}
+		if bios.ToolsRunningStatus == "" {
+			t.Errorf("expected a VMware Tools status on %s", bios.Name)
+		}
+
+		efi := vms["DC0_H0_VM1"]
+		if efi.Firmware != "efi" || !efi.SecureBoot || !efi.CPUHotAddEnabled {
+			t.Errorf("expected EFI, secure boot and CPU hot-add on %s, got firmware %q secureBoot %v cpuHotAdd %v",
+				efi.Name, efi.Firmware, efi.SecureBoot, efi.CPUHotAddEnabled)
+		}
+	})
+
+	t.Run("DVS-backed NICs", func(t *testing.T) {
+		vm := vms["DC0_H0_VM0"]
+		if len(vm.Networks) == 0 {
+			t.Fatalf("expected NICs on %s", vm.Name)
+		}
+		nic := vm.Networks[0]
+		if nic.Name != "DVS0/DC0_DVPG0" {
+			t.Errorf("expected NIC on DVS0/DC0_DVPG0, got %q", nic.Name)
+		}
+		if nic.ID == "" || nic.MAC == "" || nic.Key == 0 {
+			t.Errorf("expected portgroup key, MAC and device key on NIC, got %+v", nic)
+		}
+	})
+
+	t.Run("unknown source", func(t *testing.T) {
+		rr := executeRequest(HandleGetInventory(env.clients), "GET", "/api/v1/vcenter/inventory", nil,
+			map[string]string{"namespace": "default", "name": "missing"})
+		if rr.Code != http.StatusInternalServerError {
+			t.Errorf("expected status 500 for a missing VmwareSource, got %d", rr.Code)
+		}
+	})
+}
+
+// findInventoryNode returns the first node of the given type and name.
+func findInventoryNode(node *InventoryNode, typ, name string) *InventoryNode {
+	if node.Type == typ && node.Name == name {
+		return node
+	}
+	for i := range node.Children {
+		if found := findInventoryNode(&node.Children[i], typ, name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func TestInventoryHostAndClusterView(t *testing.T) {
+	model := simulator.VPX()
+	model.Pool = 1 // cluster VMs go into a child resource pool
+	model.App = 1
+	env := newVCSimEnv(t, model, "DC0")
+
+	folderView := inventoryVMs(env.inventory())
+	rr := executeRequest(HandleGetInventory(env.clients), "GET", "/api/v1/vcenter/inventory?view=hosts", nil, env.source)
+	root := decodeInventory(t, rr.Code, rr.Body.Bytes())
+	hostView := inventoryVMs(root)
+
+	t.Run("both views list the same VMs", func(t *testing.T) {
+		if len(hostView) != model.Count().Machine || len(folderView) != len(hostView) {
+			t.Errorf("expected %d VMs in both views, got %d (hosts) and %d (folders)", model.Count().Machine, len(hostView), len(folderView))
+		}
+		for name, vm := range hostView {
+			if folderView[name].Folder != vm.Folder {
+				t.Errorf("VM %s has folder %q in the host view but %q in the folder view", name, vm.Folder, folderView[name].Folder)
+			}
+		}
+	})
+
+	t.Run("VMs record their host", func(t *testing.T) {
+		for name, vm := range folderView {
+			if vm.Host == "" || !strings.HasPrefix(vm.HostID, "host-") {
+				t.Errorf("expected host name and moRef on %s, got %q (%s)", name, vm.Host, vm.HostID)
+			}
+		}
+	})
+
+	t.Run("standalone host", func(t *testing.T) {
+		host := findInventoryNode(root, "HostSystem", "DC0_H0")
+		if host == nil {
+			t.Fatalf("standalone host DC0_H0 missing from the host view")
+		}
+		for name := range inventoryVMs(host) {
+			if !strings.HasPrefix(name, "DC0_H0_") {
+				t.Errorf("VM %s listed under standalone host DC0_H0", name)
+			}
+		}
+		if len(inventoryVMs(host)) != model.Machine {
+			t.Errorf("expected %d VMs on DC0_H0, got %d", model.Machine, len(inventoryVMs(host)))
+		}
+	})
+
+	t.Run("cluster hosts, resource pools and vApps", func(t *testing.T) {
+		cluster := findInventoryNode(root, "ClusterComputeResource", "DC0_C0")
+		if cluster == nil {
+			t.Fatalf("cluster DC0_C0 missing from the host view")
+		}
+		hosts := 0
+		for _, child := range cluster.Children {
+			if child.Type == "HostSystem" {
+				hosts++
+			}
+		}
+		if hosts != model.ClusterHost {
+			t.Errorf("expected %d hosts in DC0_C0, got %d", model.ClusterHost, hosts)
+		}
+		for typ, name := range map[string]string{"ResourcePool": "DC0_C0_RP1", "VirtualApp": "DC0_C0_APP0"} {
+			pool := findInventoryNode(cluster, typ, name)
+			if pool == nil {
+				t.Errorf("%s %s missing from the host view", typ, name)
+				continue
+			}
+			vms := inventoryVMs(pool)
+			if len(vms) != model.Machine {
+				t.Errorf("expected %d VMs in %s, got %d", model.Machine, name, len(vms))
+			}
+			for vmName := range vms {
+				if !strings.HasPrefix(vmName, name+"_") {
+					t.Errorf("VM %s listed under %s", vmName, name)
+				}
+			}
+		}
+	})
+
+	t.Run("invalid view", func(t *testing.T) {
+		rr := executeRequest(HandleGetInventory(env.clients), "GET", "/api/v1/vcenter/inventory?view=storage", nil, env.source)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for an unknown view, got %d", rr.Code)
+		}
+	})
+}
+
+func TestInventoryHandlersWithMultipleDatacenters(t *testing.T) {
+	model := simulator.VPX()
+	model.Datacenter = 2
+	env := newVCSimEnv(t, model, "DC1")
+
+	t.Run("VmwareSource uses its configured datacenter", func(t *testing.T) {
+		root := env.inventory()
+		if root.Name != "DC1" {
+			t.Errorf("expected datacenter DC1, got %q", root.Name)
+		}
+		for name := range inventoryVMs(root) {
+			if !strings.HasPrefix(name, "DC1_") {
+				t.Errorf("VM %s from another datacenter leaked into DC1's inventory", name)
+			}
+		}
+	})
+
+	t.Run("Forklift Provider falls back to the first datacenter", func(t *testing.T) {
+		root := env.forkliftInventory()
+		if root.Name != "DC0" {
+			t.Errorf("expected auto-discovered datacenter DC0, got %q", root.Name)
+		}
+		if len(inventoryVMs(root)) == 0 {
+			t.Errorf("expected VMs in the auto-discovered datacenter")
+		}
+	})
+
+	t.Run("VM nodes record their datacenter", func(t *testing.T) {
+		for name, vm := range inventoryVMs(env.inventory()) {
+			if vm.Datacenter != "DC1" {
+				t.Errorf("expected datacenter DC1 on %s, got %q", name, vm.Datacenter)
+			}
+		}
+	})
+
+	t.Run("all datacenters", func(t *testing.T) {
+		rr := executeRequest(HandleGetForkliftInventory(env.clients), "GET", "/api/v1/forklift/inventory?datacenter=*", nil, env.forklift)
+		root := decodeInventory(t, rr.Code, rr.Body.Bytes())
+		if root.Type != "vcenter" || len(root.Children) != 2 {
+			t.Fatalf("expected a vcenter root with 2 datacenters, got %s with %d children", root.Type, len(root.Children))
+		}
+		for i, want := range []string{"DC0", "DC1"} {
+			dc := root.Children[i]
+			if dc.Name != want || dc.Type != "datacenter" || dc.ID == "" {
+				t.Errorf("expected datacenter %s with a moRef, got %+v", want, dc)
+			}
+			vms := inventoryVMs(&dc)
+			if len(vms) == 0 {
+				t.Errorf("expected VMs in %s", want)
+			}
+			for name, vm := range vms {
+				if vm.Datacenter != want || !strings.HasPrefix(name, want+"_") {
+					t.Errorf("VM %s listed under %s records datacenter %q", name, want, vm.Datacenter)
+				}
+			}
+		}
+	})
+
+	t.Run("selected datacenters", func(t *testing.T) {
+		rr := executeRequest(HandleGetInventory(env.clients), "GET", "/api/v1/vcenter/inventory?datacenter=DC0", nil, env.source)
+		root := decodeInventory(t, rr.Code, rr.Body.Bytes())
+		if len(root.Children) != 1 || root.Children[0].Name != "DC0" {
+			t.Errorf("expected only DC0, got %+v", root.Children)
+		}
+		if root.LastRefreshed == nil {
+			t.Errorf("expected the cache timestamp on the selected tree")
+		}
+	})
+
+	t.Run("unknown selected datacenter", func(t *testing.T) {
+		rr := executeRequest(HandleGetInventory(env.clients), "GET", "/api/v1/vcenter/inventory?datacenter=DC0,DC9", nil, env.source)
+		if rr.Code != http.StatusNotFound || !strings.Contains(rr.Body.String(), "DC9") {
+			t.Errorf("expected 404 naming DC9, got %d: %s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("missing datacenter", func(t *testing.T) {
+		missing := simulator.VPX()
+		env := newVCSimEnv(t, missing, "DC9")
+		rr := executeRequest(HandleGetInventory(env.clients), "GET", "/api/v1/vcenter/inventory", nil, env.source)
+		if rr.Code != http.StatusInternalServerError {
+			t.Errorf("expected status 500 for an unknown datacenter, got %d", rr.Code)
+		}
+	})
+}
+
+func TestVMPowerOpHandlerWithVCSim(t *testing.T) {
+	env := newVCSimEnv(t, simulator.VPX(), "DC0")
+	const vmName = "DC0_H0_VM0"
+
+	for _, tc := range []struct {
+		op    string
+		state string
+	}{
+		{"off", "poweredOff"},
+		{"on", "poweredOn"},
+		{"reset", "poweredOn"},
+		{"shutdown", "poweredOff"},
+	} {
+		t.Run(tc.op, func(t *testing.T) {
+			code, msg := env.post(HandleVMPowerOp, VirtualMachinePowerRequest{VMName: vmName, Operation: tc.op})
+			if code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", code, msg)
+			}
+			if got := inventoryVMs(env.inventory())[vmName].PowerState; got != tc.state {
+				t.Errorf("expected %s after %q, got %s", tc.state, tc.op, got)
+			}
+		})
+	}
+
+	t.Run("unsupported operation", func(t *testing.T) {
+		code, msg := env.post(HandleVMPowerOp, VirtualMachinePowerRequest{VMName: vmName, Operation: "suspend"})
+		if code != http.StatusInternalServerError || !strings.Contains(msg, "unsupported power operation") {
+			t.Errorf("expected 500 for an unsupported operation, got %d: %s", code, msg)
+		}
+	})
+
+	t.Run("unknown VM", func(t *testing.T) {
+		code, msg := env.post(HandleVMPowerOp, VirtualMachinePowerRequest{VMName: "no-such-vm", Operation: "on"})
+		if code != http.StatusNotFound || !strings.Contains(msg, "VM no-such-vm not found") {
+			t.Errorf("expected 404 for an unknown VM, got %d: %s", code, msg)
+		}
+	})
+}
+
+func TestVMRenameHandlerWithVCSim(t *testing.T) {
+	env := newVCSimEnv(t, simulator.VPX(), "DC0")
+
+	code, msg := env.post(HandleVMRename, VirtualMachineRenameRequest{OldName: "DC0_C0_RP0_VM1", NewName: "web-01"})
+	if code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", code, msg)
+	}
+
+	vms := inventoryVMs(env.inventory())
+	if _, ok := vms["web-01"]; !ok {
+		t.Errorf("renamed VM missing from inventory")
+	}
+	if _, ok := vms["DC0_C0_RP0_VM1"]; ok {
+		t.Errorf("old VM name still in inventory")
+	}
+
+	t.Run("unknown VM", func(t *testing.T) {
+		code, _ := env.post(HandleVMRename, VirtualMachineRenameRequest{OldName: "DC0_C0_RP0_VM1", NewName: "web-02"})
+		if code != http.StatusNotFound {
+			t.Errorf("expected 404 when renaming a VM that no longer exists, got %d", code)
+		}
+	})
+}
+
+func TestUpdateVMMACHandlerWithVCSim(t *testing.T) {
+	model := simulator.VPX()
+	model.Portgroup = 1
+	env := newVCSimEnv(t, model, "DC0")
+	const vmName = "DC0_H0_VM1"
+	const newMAC = "00:50:56:aa:bb:cc"
+
+	nics := inventoryVMs(env.inventory())[vmName].Networks
+	if len(nics) == 0 {
+		t.Fatalf("expected NICs on %s", vmName)
+	}
+	key := nics[0].Key
+
+	code, msg := env.post(HandleUpdateVMMAC, UpdateVMMACRequest{VMName: vmName, DeviceKey: key, NewMAC: newMAC})
+	if code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", code, msg)
+	}
+
+	var updated *VMNetwork
+	for _, nic := range inventoryVMs(env.inventory())[vmName].Networks {
+		if nic.Key == key {
+			nic := nic
+			updated = &nic
+		}
+	}
+	if updated == nil || updated.MAC != newMAC {
+		t.Errorf("expected MAC %s on device %d, got %+v", newMAC, key, updated)
+	}
+	if updated != nil && updated.Name != "DVS0/DC0_DVPG0" {
+		t.Errorf("expected the NIC to stay on its portgroup, got %q", updated.Name)
+	}
+
+	t.Run("missing device", func(t *testing.T) {
+		code, msg := env.post(HandleUpdateVMMAC, UpdateVMMACRequest{VMName: vmName, DeviceKey: 99999, NewMAC: newMAC})
+		if code != http.StatusInternalServerError || !strings.Contains(msg, "not found") {
+			t.Errorf("expected 500 for a missing device, got %d: %s", code, msg)
+		}
+	})
+
+	t.Run("device is not a NIC", func(t *testing.T) {
+		disks := inventoryVMs(env.inventory())[vmName].Disks
+		if len(disks) == 0 {
+			t.Skip("VM has no disks")
+		}
+		var diskKey int32
+		env.vcsim(func(ctx context.Context, c *govmomi.Client) error {
+			vm, err := find.NewFinder(c.Client, true).VirtualMachine(ctx, "/DC0/vm/"+vmName)
+			if err != nil {
+				return err
+			}
+			devices, err := vm.Device(ctx)
+			if err != nil {
+				return err
+			}
+			diskKey = devices.SelectByType((*types.VirtualDisk)(nil))[0].GetVirtualDevice().Key
+			return nil
+		})
+		code, msg := env.post(HandleUpdateVMMAC, UpdateVMMACRequest{VMName: vmName, DeviceKey: diskKey, NewMAC: newMAC})
+		if code != http.StatusInternalServerError || !strings.Contains(msg, "not a network card") {
+			t.Errorf("expected 500 for a non-NIC device, got %d: %s", code, msg)
+		}
+	})
+}
+
+func TestVMOperationsResolveVMsByIDAndPath(t *testing.T) {
+	env := newVCSimEnv(t, simulator.VPX(), "DC0")
+
+	// Give two VMs in different folders the same name.
+	env.vcsim(func(ctx context.Context, c *govmomi.Client) error {
+		finder := find.NewFinder(c.Client, true)
+		vmFolder, err := finder.Folder(ctx, "/DC0/vm")
+		if err != nil {
+			return err
+		}
+		prod, err := vmFolder.CreateFolder(ctx, "Prod")
+		if err != nil {
+			return err
+		}
+		vm, err := finder.VirtualMachine(ctx, "/DC0/vm/DC0_H0_VM1")
+		if err != nil {
+			return err
+		}
+		task, err := prod.MoveInto(ctx, []types.ManagedObjectReference{vm.Reference()})
+		if err != nil {
+			return err
+		}
+		if err := task.Wait(ctx); err != nil {
+			return err
+		}
+		task, err = vm.Rename(ctx, "DC0_H0_VM0")
+		if err != nil {
+			return err
+		}
+		return task.Wait(ctx)
+	})
+
+	var rootID, prodID string
+	for id, vm := range inventoryVMsByID(env.inventory()) {
+		if vm.Name != "DC0_H0_VM0" {
+			continue
+		}
+		if vm.Folder == "Prod" {
+			prodID = id
+		} else {
+			rootID = id
+		}
+	}
+	if rootID == "" || prodID == "" {
+		t.Fatalf("expected two VMs named DC0_H0_VM0, got IDs %q and %q", rootID, prodID)
+	}
+
+	t.Run("ambiguous name returns 409 with candidates", func(t *testing.T) {
+		rr := executeRequest(HandleVMPowerOp(env.clients), "POST", "/api/v1/vcenter/vm", VirtualMachinePowerRequest{VMName: "DC0_H0_VM0", Operation: "off"}, env.source)
+		if rr.Code != http.StatusConflict {
+			t.Fatalf("expected status 409, got %d; body: %s", rr.Code, rr.Body.String())
+		}
+		var result struct {
+			Error      string        `json:"error"`
+			Candidates []VMCandidate `json:"candidates"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+			t.Fatalf("failed to unmarshal: %v", err)
+		}
+		ids := map[string]string{}
+		for _, c := range result.Candidates {
+			ids[c.ID] = c.Path
+		}
+		if len(ids) != 2 || ids[rootID] != "/DC0/vm/DC0_H0_VM0" || ids[prodID] != "/DC0/vm/Prod/DC0_H0_VM0" {
+			t.Errorf("unexpected candidates: %+v", result.Candidates)
+		}
+	})
+
+	t.Run("moRef targets exactly one VM", func(t *testing.T) {
+		code, msg := env.post(HandleVMPowerOp, VirtualMachinePowerRequest{VMID: prodID, VMName: "DC0_H0_VM0", Operation: "off"})
+		if code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", code, msg)
+		}
+		vms := inventoryVMsByID(env.inventory())
+		if vms[prodID].PowerState != "poweredOff" || vms[rootID].PowerState != "poweredOn" {
+			t.Errorf("expected only %s powered off, got %s and %s", prodID, vms[prodID].PowerState, vms[rootID].PowerState)
+		}
+	})
+
+	t.Run("inventory path disambiguates", func(t *testing.T) {
+		code, msg := env.post(HandleVMRename, VirtualMachineRenameRequest{OldName: "Prod/DC0_H0_VM0", NewName: "prod-vm"})
+		if code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", code, msg)
+		}
+		if vm, ok := inventoryVMs(env.inventory())["prod-vm"]; !ok || vm.ID != prodID {
+			t.Errorf("expected %s to be renamed, got %+v", prodID, vm)
+		}
+	})
+
+	t.Run("rename by moRef", func(t *testing.T) {
+		code, msg := env.post(HandleVMRename, VirtualMachineRenameRequest{VMID: rootID, NewName: "root-vm"})
+		if code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", code, msg)
+		}
+		if vm, ok := inventoryVMs(env.inventory())["root-vm"]; !ok || vm.ID != rootID {
+			t.Errorf("expected %s to be renamed, got %+v", rootID, vm)
+		}
+	})
+
+	t.Run("MAC update by moRef", func(t *testing.T) {
+		vm := inventoryVMs(env.inventory())["root-vm"]
+		if len(vm.Networks) == 0 {
+			t.Fatalf("expected NICs on %s", vm.ID)
+		}
+		code, msg := env.post(HandleUpdateVMMAC, UpdateVMMACRequest{VMID: rootID, DeviceKey: vm.Networks[0].Key, NewMAC: "00:50:56:01:02:03"})
+		if code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", code, msg)
+		}
+	})
+
+	t.Run("stale moRef", func(t *testing.T) {
+		code, msg := env.post(HandleVMPowerOp, VirtualMachinePowerRequest{VMID: "vm-does-not-exist", Operation: "on"})
+		if code != http.StatusNotFound || !strings.Contains(msg, "not found") {
+			t.Errorf("expected 404 for a stale moRef, got %d: %s", code, msg)
+		}
+	})
+
+	t.Run("no VM given", func(t *testing.T) {
+		code, _ := env.post(HandleVMPowerOp, VirtualMachinePowerRequest{Operation: "on"})
+		if code != http.StatusBadRequest {
+			t.Errorf("expected 400 without vmId or vmName, got %d", code)
+		}
+	})
+}
@@ -0,0 +1,183 @@
+// pkg/vcenter_tls_test.go
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/vmware/govmomi/simulator"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// startTLSSimulator starts vcsim over HTTPS with a self-signed certificate and
+// returns credentials for it (with verification on) plus that certificate.
+func startTLSSimulator(t *testing.T) (VCenterCredentials, *x509.Certificate) {
+	t.Helper()
+	model := simulator.VPX()
+	if err := model.Create(); err != nil {
+		t.Fatalf("failed to create vcsim model: %v", err)
+	}
+	model.Service.TLS = new(tls.Config)
+	server := model.Service.NewServer()
+	t.Cleanup(func() {
+		server.Close()
+		model.Remove()
+	})
+	return simulatorCredentials(server), server.Certificate()
+}
+
+func TestLoginVCenterTLS(t *testing.T) {
+	secure, cert := startTLSSimulator(t)
+	sha1Sum := sha1.Sum(cert.Raw)
+	sha256Sum := sha256.Sum256(cert.Raw)
+	sha1Print := certificateFingerprint(sha1Sum[:])
+	caPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+
+	tests := []struct {
+		name    string
+		modify  func(c *VCenterCredentials)
+		wantErr string // substring; empty means the login must succeed
+	}{
+		{"insecure skip verify", func(c *VCenterCredentials) { c.InsecureSkipVerify = true }, ""},
+		{"untrusted certificate", func(c *VCenterCredentials) {}, sha1Print},
+		{"custom CA", func(c *VCenterCredentials) { c.CACert = caPEM }, ""},
+		{"pinned SHA-1 thumbprint", func(c *VCenterCredentials) { c.Thumbprint = sha1Print }, ""},
+		{"pinned SHA-256 thumbprint without separators", func(c *VCenterCredentials) {
+			c.Thumbprint = hex.EncodeToString(sha256Sum[:])
+		}, ""},
+		{"pin wins over insecure skip verify", func(c *VCenterCredentials) {
+			c.InsecureSkipVerify = true
+			c.Thumbprint = strings.Repeat("AB:", sha1.Size-1) + "AB"
+		}, "does not match pinned thumbprint"},
+		{"malformed CA", func(c *VCenterCredentials) { c.CACert = "not a certificate" }, "PEM"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			creds := secure
+			tc.modify(&creds)
+			c, err := loginVCenter(context.Background(), creds)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected login to succeed, got: %v", err)
+				}
+				logoutVCenter(c)
+				return
+			}
+			if err == nil {
+				logoutVCenter(c)
+				t.Fatalf("expected login to fail with %q", tc.wantErr)
+			}
+			if !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("expected error containing %q, got: %v", tc.wantErr, err)
+			}
+		})
+	}
+
+	t.Run("mismatch reports the presented certificate", func(t *testing.T) {
+		creds := secure
+		creds.Thumbprint = strings.Repeat("ab", sha256.Size)
+		_, err := loginVCenter(context.Background(), creds)
+		if err == nil || !strings.Contains(err.Error(), sha1Print) {
+			t.Errorf("expected the presented SHA-1 fingerprint %s in the error, got: %v", sha1Print, err)
+		}
+	})
+}
+
+func TestNormalizeThumbprint(t *testing.T) {
+	sha1Colons := strings.TrimSuffix(strings.Repeat("0A:", sha1.Size), ":")
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{sha1Colons, sha1Colons, false},
+		{strings.ToLower(strings.ReplaceAll(sha1Colons, ":", "")), sha1Colons, false},
+		{strings.Repeat("ff", sha256.Size), strings.TrimSuffix(strings.Repeat("FF:", sha256.Size), ":"), false},
+		{"0A:0B", "", true},
+		{strings.Repeat("zz", sha1.Size), "", true},
+	}
+	for _, tc := range tests {
+		got, err := normalizeThumbprint(tc.in)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("normalizeThumbprint(%q) = %q, %v; want %q (error: %v)", tc.in, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func TestResolveVmwareSourceTLSSettings(t *testing.T) {
+	source := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "migration.harvesterhci.io/v1beta1",
+		"kind":       "VmwareSource",
+		"metadata":   map[string]interface{}{"name": "vc", "namespace": "default"},
+		"spec": map[string]interface{}{
+			"endpoint":    "https://vcenter.example.com/sdk",
+			"credentials": map[string]interface{}{"name": "vc-credentials", "namespace": "default"},
+		},
+	}}
+
+	t.Run("defaults to skipping verification", func(t *testing.T) {
+		secret := &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "vc-credentials", Namespace: "default"},
+			Data:       map[string][]byte{"username": []byte("admin"), "password": []byte("secret")},
+		}
+		clients := newTestClientsWithDynamic([]runtime.Object{secret}, source.DeepCopy())
+		creds, err := resolveVmwareSourceCredentials(context.Background(), clients, "default", "vc")
+		if err != nil {
+			t.Fatalf("resolve failed: %v", err)
+		}
+		if !creds.InsecureSkipVerify || creds.CACert != "" || creds.Thumbprint != "" {
+			t.Errorf("expected legacy insecure defaults, got %+v", creds)
+		}
+	})
+
+	t.Run("reads CA and thumbprint from the secret", func(t *testing.T) {
+		secret := &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "vc-credentials", Namespace: "default"},
+			Data: map[string][]byte{
+				"username":           []byte("admin"),
+				"password":           []byte("secret"),
+				"insecureSkipVerify": []byte("false"),
+				"cacert":             []byte("-----BEGIN CERTIFICATE-----"),
+				"thumbprint":         []byte("AA:BB\n"),
+			},
+		}
+		clients := newTestClientsWithDynamic([]runtime.Object{secret}, source.DeepCopy())
+		creds, err := resolveVmwareSourceCredentials(context.Background(), clients, "default", "vc")
+		if err != nil {
+			t.Fatalf("resolve failed: %v", err)
+		}
+		if creds.InsecureSkipVerify || creds.CACert == "" || creds.Thumbprint != "AA:BB" {
+			t.Errorf("expected TLS settings from the secret, got %+v", creds)
+		}
+	})
+}
+
+func TestCreateVmwareSourceHandlerRejectsBadThumbprint(t *testing.T) {
+	clients := newTestClients()
+	bad := "not-a-thumbprint"
+	rr := executeRequest(CreateVmwareSourceHandler(clients), "POST", "/api/v1/harvester/vmwaresources", CreateVmwareSourcePayload{
+		Name:       "vc",
+		Namespace:  "default",
+		Endpoint:   "https://vcenter.example.com/sdk",
+		Username:   "admin",
+		Password:   "secret",
+		Thumbprint: &bad,
+	}, nil)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d; body: %s", rr.Code, rr.Body.String())
+	}
+	if _, err := clients.Clientset.CoreV1().Secrets("default").Get(context.Background(), "vc-credentials", metav1.GetOptions{}); err == nil {
+		t.Errorf("expected no secret to be created for invalid TLS settings")
+	}
+}
@@ -0,0 +1,169 @@
+// pkg/vmic_logs.go
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Where Harvester runs the vm-import-controller.
+const (
+	defaultVMICNamespace = "harvester-system"
+	defaultVMICSelector  = "app.kubernetes.io/name=harvester-vm-import-controller"
+	defaultVMICLease     = "vm-import-controller"
+)
+
+// vmicController locates the vm-import-controller replicas.
+type vmicController struct {
+	Namespace string
+	Selector  string // label selector of the replica pods
+	Lease     string // the leader election Lease in Namespace
+}
+
+func envOrDefault(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
+
+// configuredVMICController returns the vm-import-controller to read logs
+// from: the Harvester defaults, unless $VMIC_NAMESPACE, $VMIC_SELECTOR or
+// $VMIC_LEASE say otherwise. Requests cannot change it, so they cannot read
+// the logs of other pods.
+func configuredVMICController() vmicController {
+	return vmicController{
+		Namespace: envOrDefault("VMIC_NAMESPACE", defaultVMICNamespace),
+		Selector:  envOrDefault("VMIC_SELECTOR", defaultVMICSelector),
+		Lease:     envOrDefault("VMIC_LEASE", defaultVMICLease),
+	}
+}
+
+// leader returns the holder of the controller's Lease, or "" when there is no
+// Lease or nobody holds it.
+func (c vmicController) leader(ctx context.Context, clients *K8sClients) string {
+	lease, err := clients.Clientset.CoordinationV1().Leases(c.Namespace).Get(ctx, c.Lease, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			log.Debugf("Could not read Lease %s/%s: %v", c.Namespace, c.Lease, err)
+		}
+		return ""
+	}
+	if lease.Spec.HolderIdentity == nil {
+		return ""
+	}
+	return *lease.Spec.HolderIdentity
+}
+
+// holdsLease reports whether pod is the Lease holder: leader election
+// identities are the pod name, optionally followed by "_" and a unique ID.
+func holdsLease(pod v1.Pod, holder string) bool {
+	return holder != "" && (holder == pod.Name || strings.HasPrefix(holder, pod.Name+"_"))
+}
+
+// vmicLogSources returns every vm-import-controller replica, the leader
+// first, filtered to the lines about a VirtualMachineImport and its source,
+// and the name of the leader pod ("" if unknown). With previous, replicas
+// whose containers restarted also contribute their logs from before the
+// restart.
+func vmicLogSources(ctx context.Context, clients *K8sClients, controller vmicController, namespace, name string, previous bool) ([]logSource, string, error) {
+	planObj, err := clients.getResource(ctx, vmiGVR, namespace, name)
+	if err != nil {
+		return nil, "", err
+	}
+	sourceName, _ := getNestedStringOrWarn(planObj.Object, "spec", "sourceCluster", "name")
+	sourceNamespace, _ := getNestedStringOrWarn(planObj.Object, "spec", "sourceCluster", "namespace")
+	filter := []string{fmt.Sprintf("'%s/%s'", namespace, name), fmt.Sprintf("'%s/%s'", sourceNamespace, sourceName)}
+
+	pods, err := clients.Clientset.CoreV1().Pods(controller.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: controller.Selector,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	holder := controller.leader(ctx, clients)
+	leader := ""
+	sort.SliceStable(pods.Items, func(i, j int) bool {
+		return holdsLease(pods.Items[i], holder) && !holdsLease(pods.Items[j], holder)
+	})
+
+	var sources []logSource
+	for _, pod := range pods.Items {
+		label := "VM Import Controller: " + pod.Name
+		if holdsLease(pod, holder) {
+			leader = pod.Name
+			label += " (leader)"
+		}
+		src := logSource{Namespace: controller.Namespace, Pod: pod, Label: label, Filter: filter}
+		sources = append(sources, src)
+		if previous {
+			src.Label += " (previous instance)"
+			src.Previous = true
+			if len(src.containers()) > 0 {
+				sources = append(sources, src)
+			}
+		}
+	}
+	return sources, leader, nil
+}
+
+// mergedLogLine is a log line of one container of a source, with the time the
+// kubelet received it (zero if unknown).
+type mergedLogLine struct {
+	Time      time.Time
+	Source    logSource
+	Container string
+	Line      string
+}
+
+// splitLogTimestamp splits the RFC 3339 time the kubelet puts in front of
+// each line of logs read with Timestamps from the line itself.
+func splitLogTimestamp(line string) (time.Time, string) {
+	if i := strings.IndexByte(line, ' '); i > 0 {
+		if t, err := time.Parse(time.RFC3339Nano, line[:i]); err == nil {
+			return t, line[i+1:]
+		}
+	}
+	return time.Time{}, line
+}
+
+// readMergedLogs reads the logs of every container of sources and merges the
+// lines keep accepts into time order. Containers whose logs cannot be read
+// (not started yet, or no previous instance) are skipped.
+func readMergedLogs(ctx context.Context, clients *K8sClients, sources []logSource, opts v1.PodLogOptions, keep func(logSource, string) bool) []mergedLogLine {
+	var lines []mergedLogLine
+	for _, src := range sources {
+		for _, container := range src.containers() {
+			containerOpts := opts
+			containerOpts.Container = container
+			containerOpts.Previous = src.Previous
+			containerOpts.Timestamps = true
+			stream, err := clients.Clientset.CoreV1().Pods(src.Namespace).GetLogs(src.Pod.Name, &containerOpts).Stream(ctx)
+			if err != nil {
+				log.Debugf("Could not read logs of %s/%s/%s: %v", src.Namespace, src.Pod.Name, container, err)
+				continue
+			}
+			scanner := bufio.NewScanner(stream)
+			scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+			for scanner.Scan() {
+				at, line := splitLogTimestamp(scanner.Text())
+				if keep(src, line) {
+					lines = append(lines, mergedLogLine{Time: at, Source: src, Container: container, Line: line})
+				}
+			}
+			stream.Close()
+		}
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].Time.Before(lines[j].Time) })
+	return lines
+}
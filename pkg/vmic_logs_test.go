@@ -0,0 +1,93 @@
+// pkg/vmic_logs_test.go
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestHandleGetPlanLogs(t *testing.T) {
+	vmi := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "migration.harvesterhci.io/v1beta1",
+		"kind":       "VirtualMachineImport",
+		"metadata":   map[string]interface{}{"name": "db", "namespace": "vms"},
+		"spec":       map[string]interface{}{"sourceCluster": map[string]interface{}{"name": "vcenter", "namespace": "vms"}},
+	}}
+	controllerLabels := map[string]string{"app.kubernetes.io/name": "harvester-vm-import-controller"}
+	restarted := newLogTestPod("harvester-system", "vmic-a", controllerLabels, "controller")
+	restarted.Status.ContainerStatuses = []v1.ContainerStatus{{Name: "controller", RestartCount: 2}}
+	holder := "vmic-b_5f2c"
+	clients := newTestClientsWithDynamic([]runtime.Object{
+		restarted,
+		newLogTestPod("harvester-system", "vmic-b", controllerLabels, "controller"),
+		newLogTestPod("custom", "importer", map[string]string{"app": "importer"}, "controller"),
+		&coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: "vm-import-controller", Namespace: "harvester-system"},
+			Spec:       coordinationv1.LeaseSpec{HolderIdentity: &holder},
+		},
+	}, vmi)
+	vars := map[string]string{"namespace": "vms", "name": "db"}
+
+	rr := executeRequest(HandleGetPlanLogs(clients), "GET", "/api/v1/plans/vms/db/logs?all=true", nil, vars)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if leader := rr.Header().Get("X-Controller-Leader"); leader != "vmic-b" {
+		t.Errorf("expected vmic-b as the leader, got %q", leader)
+	}
+	want := "[vmic-b] fake logs\n[vmic-a] fake logs\n[vmic-a, previous] fake logs\n"
+	if rr.Body.String() != want {
+		t.Errorf("expected every replica and the restarted instance, leader first, got %q", rr.Body.String())
+	}
+	previous := 0
+	for _, action := range clients.Clientset.(*fake.Clientset).Actions() {
+		if action.GetSubresource() != "log" {
+			continue
+		}
+		opts, _ := action.(k8stesting.GenericAction).GetValue().(*v1.PodLogOptions)
+		if opts == nil || !opts.Timestamps {
+			t.Errorf("expected logs read with timestamps, got %+v", opts)
+		} else if opts.Previous {
+			previous++
+		}
+	}
+	if previous != 1 {
+		t.Errorf("expected one read of a previous instance, got %d", previous)
+	}
+
+	// Lines about other plans are dropped.
+	rr = executeRequest(HandleGetPlanLogs(clients), "GET", "/api/v1/plans/vms/db/logs", nil, vars)
+	if rr.Code != http.StatusOK || rr.Body.Len() != 0 {
+		t.Errorf("expected no lines about the plan, got %d %q", rr.Code, rr.Body.String())
+	}
+
+	// The controller comes from the environment; the query cannot point the
+	// handler at other pods.
+	rr = executeRequest(HandleGetPlanLogs(clients), "GET", "/api/v1/plans/vms/db/logs?all=true&controllerNamespace=custom&controllerSelector=app%3Dimporter", nil, vars)
+	if rr.Code != http.StatusOK || rr.Body.String() != want {
+		t.Errorf("expected the query to be ignored, got %d %q", rr.Code, rr.Body.String())
+	}
+
+	t.Setenv("VMIC_NAMESPACE", "custom")
+	t.Setenv("VMIC_SELECTOR", "app=importer")
+	rr = executeRequest(HandleGetPlanLogs(clients), "GET", "/api/v1/plans/vms/db/logs?all=true", nil, vars)
+	if rr.Code != http.StatusOK || rr.Body.String() != "fake logs\n" || rr.Header().Get("X-Controller-Leader") != "" {
+		t.Errorf("expected the single configured controller untagged, got %d %q", rr.Code, rr.Body.String())
+	}
+
+	t.Setenv("VMIC_NAMESPACE", "elsewhere")
+	t.Setenv("VMIC_SELECTOR", "")
+	rr = executeRequest(HandleGetPlanLogs(clients), "GET", "/api/v1/plans/vms/db/logs", nil, vars)
+	if rr.Code != http.StatusInternalServerError || !strings.Contains(rr.Body.String(), "pods in elsewhere") {
+		t.Errorf("expected no controller in the configured namespace, got %d %s", rr.Code, rr.Body.String())
+	}
+}